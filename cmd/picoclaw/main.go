@@ -35,6 +35,7 @@ import (
 	"github.com/sipeed/picoclaw/pkg/skills"
 	"github.com/sipeed/picoclaw/pkg/state"
 	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/utils"
 	"github.com/sipeed/picoclaw/pkg/voice"
 )
 
@@ -402,6 +403,7 @@ func agentCmd() {
 		fmt.Printf("Error creating provider: %v\n", err)
 		os.Exit(1)
 	}
+	defer providers.Close(provider)
 
 	msgBus := bus.NewMessageBus()
 	agentLoop := agent.NewAgentLoop(cfg, msgBus, provider)
@@ -595,6 +597,11 @@ func gatewayCmd() {
 	var transcriber *voice.GroqTranscriber
 	if cfg.Providers.Groq.APIKey != "" {
 		transcriber = voice.NewGroqTranscriber(cfg.Providers.Groq.APIKey)
+		transcriber.SetChunking(voice.ChunkOptions{
+			Enabled:       cfg.Voice.Chunked,
+			ChunkDuration: time.Duration(cfg.Voice.ChunkSeconds) * time.Second,
+			MaxFileBytes:  int64(cfg.Voice.MaxFileMB) * 1024 * 1024,
+		})
 		logger.InfoC("voice", "Groq voice transcription enabled")
 	}
 
@@ -619,6 +626,14 @@ func gatewayCmd() {
 		}
 	}
 
+	if oneBotChannel, ok := channelManager.GetChannel("onebot"); ok {
+		if oc, ok := oneBotChannel.(*channels.OneBotChannel); ok {
+			agentLoop.RegisterTool(tools.NewOneBotAdminTool(oc))
+		}
+	}
+
+	agentLoop.SetIndicator(channelManager.SetIndicator)
+
 	enabledChannels := channelManager.GetEnabledChannels()
 	if len(enabledChannels) > 0 {
 		fmt.Printf("✓ Channels enabled: %s\n", enabledChannels)
@@ -671,6 +686,7 @@ func gatewayCmd() {
 	cronService.Stop()
 	agentLoop.Stop()
 	channelManager.StopAll(ctx)
+	providers.Close(provider)
 	fmt.Println("✓ Gateway stopped")
 }
 
@@ -762,6 +778,8 @@ func authCmd() {
 		authLogoutCmd()
 	case "status":
 		authStatusCmd()
+	case "whoami":
+		authWhoamiCmd()
 	default:
 		fmt.Printf("Unknown auth command: %s\n", os.Args[2])
 		authHelp()
@@ -773,6 +791,7 @@ func authHelp() {
 	fmt.Println("  login       Login via OAuth or paste token")
 	fmt.Println("  logout      Remove stored credentials")
 	fmt.Println("  status      Show current auth status")
+	fmt.Println("  whoami      Show the logged-in account for each provider")
 	fmt.Println()
 	fmt.Println("Login options:")
 	fmt.Println("  --provider <name>    Provider to login with (openai, anthropic)")
@@ -784,6 +803,7 @@ func authHelp() {
 	fmt.Println("  picoclaw auth login --provider anthropic")
 	fmt.Println("  picoclaw auth logout --provider openai")
 	fmt.Println("  picoclaw auth status")
+	fmt.Println("  picoclaw auth whoami --provider openai")
 }
 
 func authLoginCmd() {
@@ -933,6 +953,46 @@ func authLogoutCmd() {
 	}
 }
 
+func authWhoamiCmd() {
+	providers := auth.LoginProviders
+
+	args := os.Args[3:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--provider", "-p":
+			if i+1 < len(args) {
+				providers = []string{args[i+1]}
+				i++
+			}
+		}
+	}
+
+	// Mark the providers the config is set to use the stored login for
+	active := map[string]bool{}
+	if appCfg, err := loadConfig(); err == nil {
+		active["openai"] = appCfg.Providers.OpenAI.AuthMethod != ""
+		active["anthropic"] = appCfg.Providers.Anthropic.AuthMethod != ""
+	}
+
+	loggedIn := false
+	for _, provider := range providers {
+		info, err := auth.AccountInfo(provider)
+		if err != nil {
+			fmt.Printf("Error loading auth store: %v\n", err)
+			return
+		}
+		line := info.String()
+		if info.LoggedIn && active[provider] {
+			line += " (in use)"
+		}
+		fmt.Println(line)
+		loggedIn = loggedIn || info.LoggedIn
+	}
+	if !loggedIn {
+		fmt.Println("Run: picoclaw auth login --provider <name>")
+	}
+}
+
 func authStatusCmd() {
 	store, err := auth.LoadStore()
 	if err != nil {
@@ -993,7 +1053,12 @@ func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace
 }
 
 func loadConfig() (*config.Config, error) {
-	return config.LoadConfig(getConfigPath())
+	cfg, err := config.LoadConfig(getConfigPath())
+	if err != nil {
+		return cfg, err
+	}
+	utils.SetEgressPolicy(cfg.Network.EgressAllow, cfg.Network.EgressDeny)
+	return cfg, nil
 }
 
 func cronCmd() {
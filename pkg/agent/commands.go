@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// maxListedModels caps the model list shown when /model is given an unknown
+// name; providers such as OpenRouter serve hundreds.
+const maxListedModels = 30
+
+// handleCommand answers chat commands that need no model call. It reports
+// false for anything else, so unknown slash commands still reach the model.
+func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage) (string, bool) {
+	fields := strings.Fields(msg.Content)
+	if len(fields) == 0 {
+		return "", false
+	}
+	// Telegram addresses group commands as /account@botname
+	command, _, _ := strings.Cut(fields[0], "@")
+
+	switch command {
+	case "/account", "/whoami":
+		return al.accountSummary(msg.SessionKey), true
+	case "/model":
+		return al.selectModel(ctx, msg.SessionKey, fields[1:]), true
+	case "/reset", "/new":
+		return al.resetConversation(msg.SessionKey), true
+	}
+	return "", false
+}
+
+// resetConversation clears the chat's history so the next message starts a
+// new conversation. Other chats and the chat's /model choice are kept.
+func (al *AgentLoop) resetConversation(sessionKey string) string {
+	if !al.sessions.Reset(sessionKey) {
+		return "Nothing to clear, this conversation is already empty."
+	}
+	if err := al.sessions.Save(sessionKey); err != nil {
+		logger.WarnCF("agent", "Failed to save reset session",
+			map[string]interface{}{
+				"session_key": sessionKey,
+				"error":       err.Error(),
+			})
+	}
+	return "Conversation cleared. Starting fresh."
+}
+
+// modelFor returns the model chosen for the session with /model, or the
+// configured default.
+func (al *AgentLoop) modelFor(sessionKey string) string {
+	if m, ok := al.chatModels.Load(sessionKey); ok {
+		return m.(string)
+	}
+	return al.model
+}
+
+// selectModel handles "/model [name|default]": without arguments it shows
+// the current model, "default" clears the override, and a name is checked
+// against the provider's model list before it is used for the session.
+func (al *AgentLoop) selectModel(ctx context.Context, sessionKey string, args []string) string {
+	if len(args) == 0 {
+		return fmt.Sprintf("Current model: %s\nUse /model <name> to switch, or /model default to go back to %s.",
+			al.modelFor(sessionKey), al.model)
+	}
+
+	name := args[0]
+	if name == "default" || name == al.model {
+		al.chatModels.Delete(sessionKey)
+		return fmt.Sprintf("Model reset to the default, %s.", al.model)
+	}
+
+	models, err := providers.ListModels(ctx, al.provider)
+	switch {
+	case errors.Is(err, providers.ErrListModelsUnsupported):
+		al.chatModels.Store(sessionKey, name)
+		return fmt.Sprintf("Model set to %s. The provider cannot list its models, so the name was not checked.", name)
+	case err != nil:
+		return fmt.Sprintf("Could not fetch the model list: %v", err)
+	}
+
+	for _, m := range models {
+		if m == name {
+			al.chatModels.Store(sessionKey, name)
+			return fmt.Sprintf("Model set to %s for this chat.", name)
+		}
+	}
+	return unknownModelReply(name, models)
+}
+
+// unknownModelReply lists the available models, those containing name first.
+func unknownModelReply(name string, models []string) string {
+	lower := strings.ToLower(name)
+	var similar, others []string
+	for _, m := range models {
+		if strings.Contains(strings.ToLower(m), lower) {
+			similar = append(similar, m)
+		} else {
+			others = append(others, m)
+		}
+	}
+	listed := append(similar, others...)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Unknown model: %s", name)
+	if len(listed) == 0 {
+		sb.WriteString("\nThe provider reported no models.")
+		return sb.String()
+	}
+	sb.WriteString("\nAvailable models:")
+	for i, m := range listed {
+		if i == maxListedModels {
+			fmt.Fprintf(&sb, "\n... and %d more", len(listed)-maxListedModels)
+			break
+		}
+		sb.WriteString("\n- " + m)
+	}
+	return sb.String()
+}
+
+// accountSummary lists the model in use and the stored login of each
+// provider, without tokens.
+func (al *AgentLoop) accountSummary(sessionKey string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Model: %s\n", al.modelFor(sessionKey))
+	for _, provider := range auth.LoginProviders {
+		info, err := auth.AccountInfo(provider)
+		if err != nil {
+			fmt.Fprintf(&sb, "%s: could not read stored login: %v\n", provider, err)
+			continue
+		}
+		sb.WriteString(info.String())
+		if info.LoggedIn && al.loginsInUse[provider] {
+			sb.WriteString(" (in use)")
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
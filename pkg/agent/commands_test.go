@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestAgentLoop_AccountCommand(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := auth.SetCredential("openai", &auth.AuthCredential{
+		AccessToken: "secret-access-token",
+		AccountID:   "acct-123",
+		Provider:    "openai",
+		AuthMethod:  "oauth",
+	}); err != nil {
+		t.Fatalf("SetCredential() error: %v", err)
+	}
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "gpt-4o",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	cfg.Providers.OpenAI.AuthMethod = "oauth"
+	provider := &simpleMockProvider{response: "from the model"}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+
+	response := testHelper{al: al}.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+		Channel:    "telegram",
+		SenderID:   "user1",
+		ChatID:     "chat1",
+		Content:    "/account@picoclaw_bot",
+		SessionKey: "test-session",
+	})
+
+	for _, want := range []string{"Model: gpt-4o", "openai: logged in via oauth, account acct-123", "(in use)", "anthropic: not logged in"} {
+		if !strings.Contains(response, want) {
+			t.Errorf("response %q missing %q", response, want)
+		}
+	}
+	if strings.Contains(response, "secret") {
+		t.Errorf("response leaks the token: %q", response)
+	}
+
+	// Other slash commands still go to the model
+	response = testHelper{al: al}.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+		Channel:    "telegram",
+		SenderID:   "user1",
+		ChatID:     "chat1",
+		Content:    "/start",
+		SessionKey: "test-session",
+	})
+	if response != "from the model" {
+		t.Errorf("/start response = %q, want model reply", response)
+	}
+}
+
+// modelListingProvider records the model of each Chat call and serves a
+// fixed model list.
+type modelListingProvider struct {
+	simpleMockProvider
+	models []string
+	used   []string
+}
+
+func (m *modelListingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	m.used = append(m.used, model)
+	return m.simpleMockProvider.Chat(ctx, messages, tools, model, opts)
+}
+
+func (m *modelListingProvider) ListModels(ctx context.Context) ([]string, error) {
+	return m.models, nil
+}
+
+func TestAgentLoop_ModelCommand(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "gpt-4o-mini",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	provider := &modelListingProvider{
+		simpleMockProvider: simpleMockProvider{response: "ok"},
+		models:             []string{"gpt-4o", "gpt-4o-mini", "o3"},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	h := testHelper{al: al}
+	send := func(sessionKey, content string) string {
+		return h.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+			Channel:    "telegram",
+			SenderID:   "user1",
+			ChatID:     sessionKey,
+			Content:    content,
+			SessionKey: sessionKey,
+		})
+	}
+
+	if got := send("chat1", "/model gpt-5"); !strings.Contains(got, "Unknown model: gpt-5") || !strings.Contains(got, "- gpt-4o") {
+		t.Errorf("invalid model reply = %q", got)
+	}
+	if got := send("chat1", "/model gpt-4o"); !strings.Contains(got, "Model set to gpt-4o") {
+		t.Errorf("/model reply = %q", got)
+	}
+
+	send("chat1", "hello")
+	send("chat2", "hello")
+	if want := []string{"gpt-4o", "gpt-4o-mini"}; !reflect.DeepEqual(provider.used, want) {
+		t.Errorf("models used = %v, want %v", provider.used, want)
+	}
+
+	if got := send("chat1", "/model"); !strings.Contains(got, "Current model: gpt-4o\n") {
+		t.Errorf("/model reply = %q", got)
+	}
+	send("chat1", "/model default")
+	send("chat1", "hello")
+	if last := provider.used[len(provider.used)-1]; last != "gpt-4o-mini" {
+		t.Errorf("model after reset = %q, want gpt-4o-mini", last)
+	}
+}
+
+func TestAgentLoop_ResetCommand(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &simpleMockProvider{response: "ok"})
+	h := testHelper{al: al}
+	send := func(sessionKey, content string) string {
+		return h.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+			Channel:    "telegram",
+			SenderID:   "user1",
+			ChatID:     sessionKey,
+			Content:    content,
+			SessionKey: sessionKey,
+		})
+	}
+
+	send("chat1", "hello")
+	send("chat2", "hello")
+
+	if got := send("chat1", "/reset"); !strings.Contains(got, "Conversation cleared") {
+		t.Errorf("/reset reply = %q", got)
+	}
+	if got := al.sessions.GetHistory("chat1"); len(got) != 0 {
+		t.Errorf("chat1 history after /reset = %v, want empty", got)
+	}
+	if got := al.sessions.GetHistory("chat2"); len(got) == 0 {
+		t.Error("chat2 history was cleared by chat1's /reset")
+	}
+	if got := send("chat1", "/new"); !strings.Contains(got, "Nothing to clear") {
+		t.Errorf("/new on empty chat reply = %q", got)
+	}
+}
+
+func TestUnknownModelReply_ListsSimilarFirstAndCaps(t *testing.T) {
+	models := make([]string, 0, 40)
+	for i := 0; i < 39; i++ {
+		models = append(models, fmt.Sprintf("model-%02d", i))
+	}
+	models = append(models, "llama-3.3-70b")
+
+	got := unknownModelReply("llama", models)
+	lines := strings.Split(got, "\n")
+	if lines[2] != "- llama-3.3-70b" {
+		t.Errorf("first listed model = %q, want the similar one", lines[2])
+	}
+	if !strings.HasSuffix(got, "... and 10 more") {
+		t.Errorf("reply not capped: %q", got)
+	}
+}
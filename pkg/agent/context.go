@@ -5,8 +5,10 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
@@ -19,6 +21,7 @@ type ContextBuilder struct {
 	skillsLoader *skills.SkillsLoader
 	memory       *MemoryStore
 	tools        *tools.ToolRegistry // Direct reference to tool registry
+	guardrail    string              // operator prompt sent before everything else
 }
 
 func getGlobalConfigDir() string {
@@ -48,13 +51,19 @@ func (cb *ContextBuilder) SetToolsRegistry(registry *tools.ToolRegistry) {
 	cb.tools = registry
 }
 
-func (cb *ContextBuilder) getIdentity() string {
+// SetGuardrailPrompt sets the operator prompt BuildMessages sends as the
+// first system message. Empty disables it.
+func (cb *ContextBuilder) SetGuardrailPrompt(prompt string) {
+	cb.guardrail = strings.TrimSpace(prompt)
+}
+
+func (cb *ContextBuilder) getIdentity(channel, chatID string) string {
 	now := time.Now().Format("2006-01-02 15:04 (Monday)")
 	workspacePath, _ := filepath.Abs(filepath.Join(cb.workspace))
 	runtime := fmt.Sprintf("%s %s, Go %s", runtime.GOOS, runtime.GOARCH, runtime.Version())
 
 	// Build tools section dynamically
-	toolsSection := cb.buildToolsSection()
+	toolsSection := cb.buildToolsSection(channel, chatID)
 
 	return fmt.Sprintf(`# picoclaw 🦞
 
@@ -84,12 +93,14 @@ Your workspace is at: %s
 		now, runtime, workspacePath, workspacePath, workspacePath, workspacePath, toolsSection, workspacePath)
 }
 
-func (cb *ContextBuilder) buildToolsSection() string {
+// buildToolsSection lists the tools available in the given chat; an empty
+// channel and chatID list the tools permitted by the global policy.
+func (cb *ContextBuilder) buildToolsSection(channel, chatID string) string {
 	if cb.tools == nil {
 		return ""
 	}
 
-	summaries := cb.tools.GetSummaries()
+	summaries := cb.tools.GetSummariesFor(channel, chatID)
 	if len(summaries) == 0 {
 		return ""
 	}
@@ -107,10 +118,16 @@ func (cb *ContextBuilder) buildToolsSection() string {
 }
 
 func (cb *ContextBuilder) BuildSystemPrompt() string {
+	return cb.buildSystemPrompt("", "")
+}
+
+// buildSystemPrompt assembles the system prompt for a chat, listing only the
+// tools permitted there.
+func (cb *ContextBuilder) buildSystemPrompt(channel, chatID string) string {
 	parts := []string{}
 
 	// Core identity section
-	parts = append(parts, cb.getIdentity())
+	parts = append(parts, cb.getIdentity(channel, chatID))
 
 	// Bootstrap files
 	bootstrapContent := cb.LoadBootstrapFiles()
@@ -160,7 +177,7 @@ func (cb *ContextBuilder) LoadBootstrapFiles() string {
 func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary string, currentMessage string, media []string, channel, chatID string) []providers.Message {
 	messages := []providers.Message{}
 
-	systemPrompt := cb.BuildSystemPrompt()
+	systemPrompt := cb.buildSystemPrompt(channel, chatID)
 
 	// Add Current Session info if provided
 	if channel != "" && chatID != "" {
@@ -200,6 +217,15 @@ func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary str
 	//Diegox-17
 	// --- FIN DEL FIX ---
 
+	// The guardrail is a separate message so nothing composed into the
+	// system prompt (bootstrap files, skills, summaries) can precede it
+	if cb.guardrail != "" {
+		messages = append(messages, providers.Message{
+			Role:    "system",
+			Content: cb.guardrail,
+		})
+	}
+
 	messages = append(messages, providers.Message{
 		Role:    "system",
 		Content: systemPrompt,
@@ -215,6 +241,105 @@ func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary str
 	return messages
 }
 
+// omittedHistoryHeading introduces the note TrimHistory adds to the system prompt.
+const omittedHistoryHeading = "\n\n## Omitted Earlier Conversation\n\n"
+
+// TrimHistory drops the oldest turns from messages until their token count,
+// as reported by count, fits within budget. A nil count uses a local
+// estimate. The leading system messages and the most recent turn
+// are always kept, and cuts are only made at user message boundaries so that
+// assistant tool calls are never separated from their tool results.
+// If summarize is non-nil, it is called with the dropped messages and any
+// non-empty result is added to the last leading system message, replacing the note left by
+// an earlier trim so repeated calls do not stack notes.
+// A budget <= 0 disables trimming.
+func TrimHistory(messages []providers.Message, budget int, count func([]providers.Message) int, summarize func(dropped []providers.Message) string) []providers.Message {
+	if count == nil {
+		count = estimateTokens
+	}
+	if budget <= 0 || count(messages) <= budget {
+		return messages
+	}
+
+	// Leading system messages: the optional guardrail, then the system prompt
+	var system []providers.Message
+	rest := messages
+	for len(rest) > 0 && rest[0].Role == "system" {
+		system = append(system, rest[0])
+		rest = rest[1:]
+	}
+	if n := len(system); n > 0 {
+		if i := strings.Index(system[n-1].Content, omittedHistoryHeading); i >= 0 {
+			system[n-1].Content = system[n-1].Content[:i]
+		}
+	}
+
+	// Candidate cut points are the starts of user turns, excluding the first
+	// message (cutting there would drop nothing)
+	var cuts []int
+	for i := 1; i < len(rest); i++ {
+		if rest[i].Role == "user" {
+			cuts = append(cuts, i)
+		}
+	}
+	if len(cuts) == 0 {
+		return messages
+	}
+
+	// Dropping turns never raises the count, so binary search for the
+	// earliest cut that fits; count may be a provider API call. The last
+	// cut is used when none fits.
+	i := sort.Search(len(cuts)-1, func(i int) bool {
+		kept := make([]providers.Message, 0, len(system)+len(rest)-cuts[i])
+		kept = append(append(kept, system...), rest[cuts[i]:]...)
+		return count(kept) <= budget
+	})
+	cut := cuts[i]
+
+	dropped := rest[:cut]
+	result := make([]providers.Message, 0, len(system)+len(rest)-cut)
+	if n := len(system); n > 0 && summarize != nil {
+		if note := summarize(dropped); note != "" {
+			system[n-1].Content += omittedHistoryHeading + note
+		}
+	}
+	result = append(result, system...)
+	result = append(result, rest[cut:]...)
+
+	logger.DebugCF("agent", "Trimmed conversation history to fit context window",
+		map[string]interface{}{
+			"dropped_messages": len(dropped),
+			"kept_messages":    len(result),
+			"budget":           budget,
+		})
+
+	return result
+}
+
+// estimateTokens estimates the number of tokens in a message list.
+// Uses rune count instead of byte length so that CJK and other multi-byte
+// characters are not over-counted (a Chinese character is 3 bytes but roughly
+// one token).
+func estimateTokens(messages []providers.Message) int {
+	total := 0
+	for _, m := range messages {
+		total += estimateMessageTokens(m)
+	}
+	return total
+}
+
+// estimateMessageTokens estimates the tokens of a single message, including
+// the arguments of any tool calls it carries.
+func estimateMessageTokens(m providers.Message) int {
+	runes := utf8.RuneCountInString(m.Content)
+	for _, tc := range m.ToolCalls {
+		if tc.Function != nil {
+			runes += utf8.RuneCountInString(tc.Function.Name) + utf8.RuneCountInString(tc.Function.Arguments)
+		}
+	}
+	return runes / 3
+}
+
 func (cb *ContextBuilder) AddToolResult(messages []providers.Message, toolCallID, toolName, result string) []providers.Message {
 	messages = append(messages, providers.Message{
 		Role:       "tool",
@@ -0,0 +1,188 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func buildTrimTestMessages() []providers.Message {
+	long := strings.Repeat("x", 300) // ~100 tokens
+	return []providers.Message{
+		{Role: "system", Content: "system prompt"},
+		{Role: "user", Content: long},
+		{Role: "assistant", Content: long},
+		{Role: "user", Content: long},
+		{Role: "assistant", Content: "", ToolCalls: []providers.ToolCall{{ID: "call_1", Function: &providers.FunctionCall{Name: "exec", Arguments: "{}"}}}},
+		{Role: "tool", Content: long, ToolCallID: "call_1"},
+		{Role: "assistant", Content: long},
+		{Role: "user", Content: "latest question"},
+	}
+}
+
+func TestTrimHistory_FitsBudgetUnchanged(t *testing.T) {
+	messages := buildTrimTestMessages()
+	got := TrimHistory(messages, 10000, nil, nil)
+	if len(got) != len(messages) {
+		t.Errorf("Expected %d messages, got %d", len(messages), len(got))
+	}
+}
+
+func TestTrimHistory_DisabledWithZeroBudget(t *testing.T) {
+	messages := buildTrimTestMessages()
+	got := TrimHistory(messages, 0, nil, nil)
+	if len(got) != len(messages) {
+		t.Errorf("Expected %d messages, got %d", len(messages), len(got))
+	}
+}
+
+func TestTrimHistory_DropsOldestTurns(t *testing.T) {
+	messages := buildTrimTestMessages()
+	got := TrimHistory(messages, 350, nil, nil)
+
+	if got[0].Role != "system" || got[0].Content != "system prompt" {
+		t.Fatalf("Expected system message to be preserved, got %+v", got[0])
+	}
+	if got[1].Role != "user" {
+		t.Errorf("Expected history to start at a user turn, got role %q", got[1].Role)
+	}
+	if got[len(got)-1].Content != "latest question" {
+		t.Errorf("Expected latest message to be preserved, got %q", got[len(got)-1].Content)
+	}
+	// The second turn (user, tool call, tool result, assistant) must stay intact
+	if len(got) != 6 {
+		t.Errorf("Expected 6 messages after trimming, got %d", len(got))
+	}
+	if estimateTokens(got) > 350 {
+		t.Errorf("Expected trimmed history within budget, got %d tokens", estimateTokens(got))
+	}
+	// Input must not be modified
+	if len(messages) != 8 || messages[0].Content != "system prompt" {
+		t.Error("Expected input messages to be left untouched")
+	}
+}
+
+func TestTrimHistory_KeepsLatestTurnOverBudget(t *testing.T) {
+	messages := buildTrimTestMessages()
+	got := TrimHistory(messages, 1, nil, nil)
+
+	if len(got) != 2 {
+		t.Fatalf("Expected system message and latest turn, got %d messages", len(got))
+	}
+	if got[1].Content != "latest question" {
+		t.Errorf("Expected latest user message, got %q", got[1].Content)
+	}
+}
+
+func TestTrimHistory_UsesCounter(t *testing.T) {
+	messages := buildTrimTestMessages()
+	// Count each message as 100 tokens, regardless of its length
+	calls := 0
+	count := func(msgs []providers.Message) int {
+		calls++
+		return 100 * len(msgs)
+	}
+
+	got := TrimHistory(messages, 450, count, nil)
+	// System prompt plus the turn starting at the second user message
+	// (user, tool call, tool result, assistant, latest) is 600; only the
+	// latest turn fits
+	if len(got) != 2 || got[1].Content != "latest question" {
+		t.Errorf("Expected system message and latest turn, got %d messages", len(got))
+	}
+
+	got = TrimHistory(messages, 600, count, nil)
+	if len(got) != 6 || got[1].Role != "user" {
+		t.Errorf("Expected the turns from the second user message, got %d messages", len(got))
+	}
+	if calls > 4 {
+		t.Errorf("counter called %d times, want a binary search", calls)
+	}
+}
+
+func TestTrimHistory_SummarizesDroppedMessages(t *testing.T) {
+	messages := buildTrimTestMessages()
+	var droppedCount int
+	got := TrimHistory(messages, 1, nil, func(dropped []providers.Message) string {
+		droppedCount = len(dropped)
+		return "earlier context"
+	})
+
+	if droppedCount != 6 {
+		t.Errorf("Expected 6 dropped messages, got %d", droppedCount)
+	}
+	if !strings.Contains(got[0].Content, "earlier context") {
+		t.Errorf("Expected summary appended to system prompt, got %q", got[0].Content)
+	}
+	if messages[0].Content != "system prompt" {
+		t.Error("Expected original system message to be left untouched")
+	}
+}
+
+func TestTrimHistory_ReplacesEarlierNote(t *testing.T) {
+	messages := buildTrimTestMessages()
+	first := TrimHistory(messages, 350, nil, func(dropped []providers.Message) string {
+		return "first note"
+	})
+
+	// Simulate a tool loop growing the history past the budget again
+	grown := append(append([]providers.Message(nil), first...),
+		providers.Message{Role: "assistant", Content: strings.Repeat("y", 300)},
+		providers.Message{Role: "user", Content: "follow-up"},
+	)
+	second := TrimHistory(grown, 350, nil, func(dropped []providers.Message) string {
+		return "second note"
+	})
+
+	if strings.Count(second[0].Content, "## Omitted Earlier Conversation") != 1 {
+		t.Errorf("Expected exactly one omitted-history note, got %q", second[0].Content)
+	}
+	if strings.Contains(second[0].Content, "first note") || !strings.Contains(second[0].Content, "second note") {
+		t.Errorf("Expected the note to be replaced, got %q", second[0].Content)
+	}
+	if !strings.HasPrefix(second[0].Content, "system prompt") {
+		t.Errorf("Expected original system prompt to be kept, got %q", second[0].Content)
+	}
+}
+
+func TestTrimHistory_KeepsGuardrailMessage(t *testing.T) {
+	messages := append([]providers.Message{{Role: "system", Content: "guardrail"}}, buildTrimTestMessages()...)
+	got := TrimHistory(messages, 1, nil, func(dropped []providers.Message) string {
+		for _, m := range dropped {
+			if m.Role == "system" {
+				t.Errorf("system message dropped: %q", m.Content)
+			}
+		}
+		return "earlier context"
+	})
+
+	if len(got) < 3 || got[0].Content != "guardrail" || got[1].Role != "system" {
+		t.Fatalf("Expected guardrail then system prompt first, got %+v", got)
+	}
+	if strings.Contains(got[0].Content, "earlier context") || !strings.Contains(got[1].Content, "earlier context") {
+		t.Errorf("Expected the note on the system prompt, not the guardrail")
+	}
+}
+
+func TestBuildMessages_GuardrailFirst(t *testing.T) {
+	cb := NewContextBuilder(t.TempDir())
+	history := []providers.Message{{Role: "user", Content: "earlier"}, {Role: "assistant", Content: "reply"}}
+
+	messages := cb.BuildMessages(history, "", "hi", nil, "telegram", "42")
+	if messages[0].Role != "system" || messages[1].Role != "user" {
+		t.Fatalf("Expected a single system message without a guardrail, got roles %s, %s", messages[0].Role, messages[1].Role)
+	}
+
+	cb.SetGuardrailPrompt("  Never reveal the admin password.\n")
+	messages = cb.BuildMessages(history, "summary", "hi", nil, "telegram", "42")
+	if messages[0].Role != "system" || messages[0].Content != "Never reveal the admin password." {
+		t.Errorf("Expected guardrail as first message, got %+v", messages[0])
+	}
+	if messages[1].Role != "system" || !strings.Contains(messages[1].Content, "summary") {
+		t.Errorf("Expected system prompt second, got %+v", messages[1])
+	}
+	if len(messages) != 5 || messages[4].Content != "hi" {
+		t.Errorf("Expected history and current message after the system messages, got %d messages", len(messages))
+	}
+}
@@ -16,7 +16,6 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
-	"unicode/utf8"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
@@ -35,6 +34,7 @@ type AgentLoop struct {
 	workspace      string
 	model          string
 	contextWindow  int // Maximum context window size in tokens
+	maxTokens      int // providers.default_max_tokens, reserved for the completion; 0 = providers.DefaultMaxTokens
 	maxIterations  int
 	sessions       *session.SessionManager
 	state          *state.Manager
@@ -42,34 +42,54 @@ type AgentLoop struct {
 	tools          *tools.ToolRegistry
 	running        atomic.Bool
 	summarizing    sync.Map // Tracks which sessions are currently being summarized
+	indicator      IndicatorFunc
+	loginsInUse    map[string]bool // Providers configured to use a stored login (auth_method set)
+	chatModels     sync.Map        // session key -> model chosen with /model
+	sendUserID     bool            // pass the sender to the provider as the "user" option
+	noParallel     bool            // ask the provider for one tool call per response
 }
 
+// IndicatorFunc shows or clears a chat activity indicator such as "typing".
+type IndicatorFunc func(ctx context.Context, channel, chatID string, kind bus.IndicatorKind) error
+
 // processOptions configures how a message is processed
 type processOptions struct {
-	SessionKey      string // Session identifier for history/context
-	Channel         string // Target channel for tool execution
-	ChatID          string // Target chat ID for tool execution
-	UserMessage     string // User message content (may include prefix)
-	DefaultResponse string // Response when LLM returns empty
-	EnableSummary   bool   // Whether to trigger summarization
-	SendResponse    bool   // Whether to send response via bus
-	NoHistory       bool   // If true, don't load session history (for heartbeat)
+	SessionKey      string            // Session identifier for history/context
+	Channel         string            // Target channel for tool execution
+	ChatID          string            // Target chat ID for tool execution
+	SenderID        string            // Sender of the inbound message, exposed to tools
+	Metadata        map[string]string // Inbound message metadata, exposed to tools
+	UserMessage     string            // User message content (may include prefix)
+	DefaultResponse string            // Response when LLM returns empty
+	EnableSummary   bool              // Whether to trigger summarization
+	SendResponse    bool              // Whether to send response via bus
+	NoHistory       bool              // If true, don't load session history (for heartbeat)
 }
 
 // createToolRegistry creates a tool registry with common tools.
 // This is shared between main agent and subagents.
-func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msgBus *bus.MessageBus) *tools.ToolRegistry {
+func createToolRegistry(ws *tools.Workspace, cfg *config.Config, msgBus *bus.MessageBus) *tools.ToolRegistry {
 	registry := tools.NewToolRegistry()
+	workspace, restrict := ws.Root(), ws.Restricted()
 
 	// File system tools
-	registry.Register(tools.NewReadFileTool(workspace, restrict))
-	registry.Register(tools.NewWriteFileTool(workspace, restrict))
-	registry.Register(tools.NewListDirTool(workspace, restrict))
-	registry.Register(tools.NewEditFileTool(workspace, restrict))
-	registry.Register(tools.NewAppendFileTool(workspace, restrict))
+	registry.Register(tools.NewReadFileTool(ws))
+	registry.Register(tools.NewWriteFileTool(ws))
+	registry.Register(tools.NewListDirTool(ws))
+	registry.Register(tools.NewEditFileTool(ws))
+	registry.Register(tools.NewAppendFileTool(ws))
 
 	// Shell execution
 	registry.Register(tools.NewExecTool(workspace, restrict))
+	if cfg.Tools.Command.Enabled {
+		registry.Register(tools.NewCommandTool(tools.CommandToolOptions{
+			WorkingDir:      workspace,
+			Restrict:        restrict,
+			AllowedCommands: cfg.Tools.Command.AllowedCommands,
+			AllowShell:      cfg.Tools.Command.AllowShell,
+			Timeout:         time.Duration(cfg.Tools.Command.TimeoutSeconds) * time.Second,
+		}))
+	}
 
 	if searchTool := tools.NewWebSearchTool(tools.WebSearchToolOptions{
 		BraveAPIKey:          cfg.Tools.Web.Brave.APIKey,
@@ -82,9 +102,20 @@ func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msg
 	}
 	registry.Register(tools.NewWebFetchTool(50000))
 
-	// Hardware tools (I2C, SPI) - Linux only, returns error on other platforms
-	registry.Register(tools.NewI2CTool())
-	registry.Register(tools.NewSPITool())
+	// Hardware tools (I2C, SPI, kernel log, board info) - Linux only, returns error on other platforms
+	inventoryTTL := time.Duration(cfg.Tools.Hardware.InventoryTTLSeconds) * time.Second
+	registry.Register(tools.NewI2CTool(tools.I2CToolOptions{
+		DeviceNames:  cfg.Tools.Hardware.I2CDevices,
+		InventoryTTL: inventoryTTL,
+		AllowedBuses: cfg.Tools.Hardware.AllowedI2CBuses,
+	}))
+	registry.Register(tools.NewSPITool(tools.SPIToolOptions{
+		DeviceNames:    cfg.Tools.Hardware.SPIDevices,
+		InventoryTTL:   inventoryTTL,
+		AllowedDevices: cfg.Tools.Hardware.AllowedSPIDevices,
+	}))
+	registry.Register(tools.NewKernelLogTool())
+	registry.Register(tools.NewBoardInfoTool())
 
 	// Message tool - available to both agent and subagent
 	// Subagent uses it to communicate directly with user
@@ -99,21 +130,67 @@ func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msg
 	})
 	registry.Register(messageTool)
 
+	if imageTool := tools.NewImageGenTool(tools.ImageGenToolOptions{
+		Enabled: cfg.Tools.Image.Enabled,
+		APIKey:  cfg.Tools.Image.APIKey,
+		APIBase: cfg.Tools.Image.APIBase,
+		Model:   cfg.Tools.Image.Model,
+	}); imageTool != nil {
+		imageTool.SetSendCallback(func(channel, chatID, content string) error {
+			msgBus.PublishOutbound(bus.OutboundMessage{
+				Channel: channel,
+				ChatID:  chatID,
+				Content: content,
+			})
+			return nil
+		})
+		registry.Register(imageTool)
+	}
+
+	registry.SetOutputLimits(cfg.Tools.MaxOutputBytes, cfg.Tools.MaxOutputBytesPerTool)
+	registry.SetErrorPolicies(cfg.Tools.ErrorPolicyPerTool)
+	registry.SetPolicy(newToolPolicy(cfg.Tools.Permissions))
+
 	return registry
 }
 
+// restrictedTools are denied everywhere unless a chat's allow list names
+// them, e.g. group moderation that should only run from an admin's DM.
+var restrictedTools = []string{"onebot_admin"}
+
+func isRestrictedTool(entry string) bool {
+	name, _, _ := strings.Cut(entry, ":")
+	for _, tool := range restrictedTools {
+		if name == tool {
+			return true
+		}
+	}
+	return false
+}
+
+// newToolPolicy converts the tools.permissions config into a ToolPolicy.
+func newToolPolicy(perms config.ToolPermissionsConfig) *tools.ToolPolicy {
+	overrides := make(map[string]tools.ToolRule, len(perms.Chats))
+	for key, rule := range perms.Chats {
+		overrides[key] = tools.ToolRule{Allow: rule.Allow, Deny: rule.Deny}
+	}
+	deny := append(append([]string(nil), perms.Deny...), restrictedTools...)
+	return tools.NewToolPolicy(tools.ToolRule{Allow: perms.Allow, Deny: deny}, overrides)
+}
+
 func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers.LLMProvider) *AgentLoop {
 	workspace := cfg.WorkspacePath()
 	os.MkdirAll(workspace, 0755)
 
-	restrict := cfg.Agents.Defaults.RestrictToWorkspace
+	ws := tools.NewWorkspace(workspace, cfg.Agents.Defaults.RestrictToWorkspace)
+	workspace = ws.Root()
 
 	// Create tool registry for main agent
-	toolsRegistry := createToolRegistry(workspace, restrict, cfg, msgBus)
+	toolsRegistry := createToolRegistry(ws, cfg, msgBus)
 
 	// Create subagent manager with its own tool registry
 	subagentManager := tools.NewSubagentManager(provider, cfg.Agents.Defaults.Model, workspace, msgBus)
-	subagentTools := createToolRegistry(workspace, restrict, cfg, msgBus)
+	subagentTools := createToolRegistry(ws, cfg, msgBus)
 	// Subagent doesn't need spawn/subagent tools to avoid recursion
 	subagentManager.SetTools(subagentTools)
 
@@ -125,6 +202,16 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 	subagentTool := tools.NewSubagentTool(subagentManager)
 	toolsRegistry.Register(subagentTool)
 
+	for _, entry := range toolsRegistry.UnknownPolicyEntries() {
+		if isRestrictedTool(entry) {
+			continue // registered later by the gateway when its channel is enabled
+		}
+		logger.WarnCF("agent", "Unknown tool in tools.permissions; entry has no effect",
+			map[string]interface{}{
+				"entry": entry,
+			})
+	}
+
 	sessionsManager := session.NewSessionManager(filepath.Join(workspace, "sessions"))
 
 	// Create state manager for atomic state persistence
@@ -133,19 +220,27 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 	// Create context builder and set tools registry
 	contextBuilder := NewContextBuilder(workspace)
 	contextBuilder.SetToolsRegistry(toolsRegistry)
+	contextBuilder.SetGuardrailPrompt(cfg.Agents.Defaults.GuardrailPrompt)
 
 	return &AgentLoop{
 		bus:            msgBus,
 		provider:       provider,
 		workspace:      workspace,
 		model:          cfg.Agents.Defaults.Model,
+		sendUserID:     cfg.Providers.SendUserID,
+		noParallel:     cfg.Providers.DisableParallelToolCalls,
 		contextWindow:  cfg.Agents.Defaults.MaxTokens, // Restore context window for summarization
+		maxTokens:      cfg.Providers.DefaultMaxTokens,
 		maxIterations:  cfg.Agents.Defaults.MaxToolIterations,
 		sessions:       sessionsManager,
 		state:          stateManager,
 		contextBuilder: contextBuilder,
 		tools:          toolsRegistry,
 		summarizing:    sync.Map{},
+		loginsInUse: map[string]bool{
+			"openai":    cfg.Providers.OpenAI.AuthMethod != "",
+			"anthropic": cfg.Providers.Anthropic.AuthMethod != "",
+		},
 	}
 }
 
@@ -199,6 +294,28 @@ func (al *AgentLoop) RegisterTool(tool tools.Tool) {
 	al.tools.Register(tool)
 }
 
+// SetIndicator sets the callback used to show a typing indicator while the
+// model is generating a reply.
+func (al *AgentLoop) SetIndicator(fn IndicatorFunc) {
+	al.indicator = fn
+}
+
+// indicate forwards an activity indicator for user-facing chats; failures are
+// only logged since the indicator is cosmetic.
+func (al *AgentLoop) indicate(ctx context.Context, channel, chatID string, kind bus.IndicatorKind) {
+	if al.indicator == nil || channel == "" || chatID == "" || constants.IsInternalChannel(channel) {
+		return
+	}
+	if err := al.indicator(ctx, channel, chatID, kind); err != nil {
+		logger.DebugCF("agent", "Failed to set chat indicator",
+			map[string]interface{}{
+				"channel": channel,
+				"kind":    string(kind),
+				"error":   err.Error(),
+			})
+	}
+}
+
 // RecordLastChannel records the last active channel for this workspace.
 // This uses the atomic state save mechanism to prevent data loss on crash.
 func (al *AgentLoop) RecordLastChannel(channel string) error {
@@ -263,11 +380,17 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		return al.processSystemMessage(ctx, msg)
 	}
 
+	if reply, ok := al.handleCommand(ctx, msg); ok {
+		return reply, nil
+	}
+
 	// Process as user message
 	return al.runAgentLoop(ctx, processOptions{
 		SessionKey:      msg.SessionKey,
 		Channel:         msg.Channel,
 		ChatID:          msg.ChatID,
+		SenderID:        msg.SenderID,
+		Metadata:        msg.Metadata,
 		UserMessage:     msg.Content,
 		DefaultResponse: "I've completed processing but have no response to give.",
 		EnableSummary:   true,
@@ -407,11 +530,56 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 	return finalContent, nil
 }
 
+// contentFilteredResponse replaces an empty answer the provider withheld
+// under its content policy, so the user is not left with a blank reply.
+const contentFilteredResponse = "The request was filtered by the model provider's content policy, so no response was generated."
+
+// historyBudget returns the tokens left for the prompt, messages and tool
+// definitions, once room is reserved for the completion. The reserve never
+// takes more than a quarter of the window. Returns 0 (no trimming) when the
+// context window is unset.
+func (al *AgentLoop) historyBudget() int {
+	if al.contextWindow <= 0 {
+		return 0
+	}
+
+	outputReserve := al.maxTokens
+	if outputReserve <= 0 {
+		outputReserve = providers.DefaultMaxTokens
+	}
+	if quarter := al.contextWindow / 4; outputReserve > quarter {
+		outputReserve = quarter
+	}
+	return al.contextWindow - outputReserve
+}
+
+// chatOptions returns the provider options for a turn of the conversation.
+func (al *AgentLoop) chatOptions(opts processOptions) map[string]interface{} {
+	options := map[string]interface{}{}
+	if al.sendUserID && opts.SenderID != "" {
+		options["user"] = opts.Channel + ":" + opts.SenderID
+	}
+	if al.noParallel {
+		options["parallel_tool_calls"] = false
+	}
+	return options
+}
+
 // runLLMIteration executes the LLM call loop with tool handling.
 // Returns the final content, iteration count, and any error.
 func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.Message, opts processOptions) (string, int, error) {
 	iteration := 0
+	omitted := 0
 	var finalContent string
+	model := al.modelFor(opts.SessionKey)
+
+	// Expose the triggering message to tools
+	toolCtx := tools.WithMessageMetadata(ctx, tools.MessageMetadata{
+		Channel:  opts.Channel,
+		ChatID:   opts.ChatID,
+		SenderID: opts.SenderID,
+		Extra:    opts.Metadata,
+	})
 
 	for iteration < al.maxIterations {
 		iteration++
@@ -423,17 +591,29 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 			})
 
 		// Build tool definitions
-		providerToolDefs := al.tools.ToProviderDefs()
+		providerToolDefs := al.tools.ToProviderDefsFor(opts.Channel, opts.ChatID)
+
+		// Keep the request within the context window; older turns are
+		// covered by the session summary once summarization catches up.
+		// Tool results grow the history on every iteration, so this runs
+		// each time and the note in the system prompt is replaced, not added.
+		// Tokens are counted the way the provider does, including the tool
+		// definitions sent with the request.
+		countTokens := func(msgs []providers.Message) int {
+			return providers.CountPromptTokens(ctx, al.provider, msgs, providerToolDefs, model)
+		}
+		messages = TrimHistory(messages, al.historyBudget(), countTokens, func(dropped []providers.Message) string {
+			omitted += len(dropped)
+			return fmt.Sprintf("%d earlier messages were omitted to fit the context window.", omitted)
+		})
 
 		// Log LLM request details
 		logger.DebugCF("agent", "LLM request",
 			map[string]interface{}{
 				"iteration":         iteration,
-				"model":             al.model,
+				"model":             model,
 				"messages_count":    len(messages),
 				"tools_count":       len(providerToolDefs),
-				"max_tokens":        8192,
-				"temperature":       0.7,
 				"system_prompt_len": len(messages[0].Content),
 			})
 
@@ -446,10 +626,9 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 			})
 
 		// Call LLM
-		response, err := al.provider.Chat(ctx, messages, providerToolDefs, al.model, map[string]interface{}{
-			"max_tokens":  8192,
-			"temperature": 0.7,
-		})
+		al.indicate(ctx, opts.Channel, opts.ChatID, bus.IndicatorTyping)
+		response, err := al.provider.Chat(ctx, messages, providerToolDefs, model, al.chatOptions(opts))
+		al.indicate(ctx, opts.Channel, opts.ChatID, bus.IndicatorNone)
 
 		if err != nil {
 			logger.ErrorCF("agent", "LLM call failed",
@@ -460,9 +639,22 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 			return "", iteration, fmt.Errorf("LLM call failed: %w", err)
 		}
 
+		al.publishReasoning(opts, response.ReasoningContent)
+
 		// Check if no tool calls - we're done
 		if len(response.ToolCalls) == 0 {
 			finalContent = response.Content
+			if response.FinishReason == providers.FinishReasonContentFilter {
+				logger.WarnCF("agent", "LLM response blocked by content filter",
+					map[string]interface{}{
+						"iteration":     iteration,
+						"content_chars": len(finalContent),
+					})
+				if finalContent == "" {
+					finalContent = contentFilteredResponse
+				}
+				break
+			}
 			logger.InfoCF("agent", "LLM response without tool calls (direct answer)",
 				map[string]interface{}{
 					"iteration":     iteration,
@@ -531,10 +723,12 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 				}
 			}
 
-			toolResult := al.tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, asyncCallback)
+			toolResult := al.tools.ExecuteWithContext(toolCtx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, asyncCallback)
 
-			// Send ForUser content to user immediately if not Silent
-			if !toolResult.Silent && toolResult.ForUser != "" && opts.SendResponse {
+			// Send ForUser content to user immediately if not Silent.
+			// Errors of tools with the "surface" policy are always sent.
+			surfaced := toolResult.IsError && al.tools.ErrorPolicy(tc.Name) == tools.ErrorPolicySurface
+			if !toolResult.Silent && toolResult.ForUser != "" && (opts.SendResponse || surfaced) {
 				al.bus.PublishOutbound(bus.OutboundMessage{
 					Channel: opts.Channel,
 					ChatID:  opts.ChatID,
@@ -557,10 +751,13 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 				Role:       "tool",
 				Content:    contentForLLM,
 				ToolCallID: tc.ID,
+				Images:     toolResult.Images,
 			}
 			messages = append(messages, toolResultMsg)
 
-			// Save tool result message to session
+			// Save tool result message to session. Images are only sent
+			// during this turn; they would bloat every later request.
+			toolResultMsg.Images = nil
 			al.sessions.AddFullMessage(opts.SessionKey, toolResultMsg)
 		}
 	}
@@ -568,6 +765,22 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 	return finalContent, iteration, nil
 }
 
+// publishReasoning sends the model's reasoning to the chat ahead of its
+// answer. The channel manager drops it unless the channel has reasoning
+// output enabled.
+func (al *AgentLoop) publishReasoning(opts processOptions, reasoning string) {
+	reasoning = strings.TrimSpace(reasoning)
+	if reasoning == "" || opts.Channel == "" || opts.ChatID == "" || constants.IsInternalChannel(opts.Channel) {
+		return
+	}
+	al.bus.PublishOutbound(bus.OutboundMessage{
+		Channel:   opts.Channel,
+		ChatID:    opts.ChatID,
+		Content:   reasoning,
+		Reasoning: true,
+	})
+}
+
 // updateToolContexts updates the context for tools that need channel/chatID info.
 func (al *AgentLoop) updateToolContexts(channel, chatID string) {
 	// Use ContextualTool interface instead of type assertions
@@ -604,6 +817,12 @@ func (al *AgentLoop) maybeSummarize(sessionKey string) {
 	}
 }
 
+// DescribeTools returns the registered tools as JSON, in the same
+// ToolDefinition shape sent to providers.
+func (al *AgentLoop) DescribeTools() ([]byte, error) {
+	return al.tools.DescribeJSON()
+}
+
 // GetStartupInfo returns information about loaded tools and skills for logging.
 func (al *AgentLoop) GetStartupInfo() map[string]interface{} {
 	info := make(map[string]interface{})
@@ -769,13 +988,6 @@ func (al *AgentLoop) summarizeBatch(ctx context.Context, batch []providers.Messa
 }
 
 // estimateTokens estimates the number of tokens in a message list.
-// Uses rune count instead of byte length so that CJK and other multi-byte
-// characters are not over-counted (a Chinese character is 3 bytes but roughly
-// one token).
 func (al *AgentLoop) estimateTokens(messages []providers.Message) int {
-	total := 0
-	for _, m := range messages {
-		total += utf8.RuneCountInString(m.Content) / 3
-	}
-	return total
+	return estimateTokens(messages)
 }
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/tools"
@@ -359,13 +360,17 @@ func TestAgentLoop_Stop(t *testing.T) {
 // Mock implementations for testing
 
 type simpleMockProvider struct {
-	response string
+	response     string
+	finishReason string
+	reasoning    string
 }
 
 func (m *simpleMockProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
 	return &providers.LLMResponse{
-		Content:   m.response,
-		ToolCalls: []providers.ToolCall{},
+		Content:          m.response,
+		ReasoningContent: m.reasoning,
+		ToolCalls:        []providers.ToolCall{},
+		FinishReason:     m.finishReason,
 	}, nil
 }
 
@@ -486,6 +491,44 @@ func TestToolResult_SilentToolDoesNotSendUserMessage(t *testing.T) {
 	}
 }
 
+func TestAgentLoop_ContentFilteredResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"empty answer is explained", "", contentFilteredResponse},
+		{"refusal text is kept", "I can't help with that.", "I can't help with that."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Agents: config.AgentsConfig{
+					Defaults: config.AgentDefaults{
+						Workspace:         t.TempDir(),
+						Model:             "test-model",
+						MaxTokens:         4096,
+						MaxToolIterations: 10,
+					},
+				},
+			}
+			provider := &simpleMockProvider{response: tt.response, finishReason: providers.FinishReasonContentFilter}
+			al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+
+			response := testHelper{al: al}.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+				Channel:    "test",
+				SenderID:   "user1",
+				ChatID:     "chat1",
+				Content:    "hello",
+				SessionKey: "test-session",
+			})
+			if response != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, response)
+			}
+		})
+	}
+}
+
 // TestToolResult_UserFacingToolDoesSendMessage verifies user-facing tools trigger outbound
 func TestToolResult_UserFacingToolDoesSendMessage(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "agent-test-*")
@@ -527,3 +570,224 @@ func TestToolResult_UserFacingToolDoesSendMessage(t *testing.T) {
 		t.Errorf("Expected 'Command output: hello world', got: %s", response)
 	}
 }
+
+// TestAgentLoop_IndicatorWrapsProviderCall verifies typing is shown during the LLM call and cleared after
+func TestAgentLoop_IndicatorWrapsProviderCall(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "agent-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+
+	msgBus := bus.NewMessageBus()
+	provider := &simpleMockProvider{response: "hello"}
+	al := NewAgentLoop(cfg, msgBus, provider)
+
+	var kinds []bus.IndicatorKind
+	al.SetIndicator(func(ctx context.Context, channel, chatID string, kind bus.IndicatorKind) error {
+		if channel != "test" || chatID != "chat1" {
+			t.Errorf("indicator target = %s:%s, want test:chat1", channel, chatID)
+		}
+		kinds = append(kinds, kind)
+		return nil
+	})
+
+	helper := testHelper{al: al}
+	helper.executeAndGetResponse(t, context.Background(), bus.InboundMessage{
+		Channel:    "test",
+		SenderID:   "user1",
+		ChatID:     "chat1",
+		Content:    "hi",
+		SessionKey: "test-session",
+	})
+
+	if len(kinds) != 2 || kinds[0] != bus.IndicatorTyping || kinds[1] != bus.IndicatorNone {
+		t.Errorf("indicator calls = %q, want [typing, none]", kinds)
+	}
+}
+
+func TestAgentLoop_HistoryBudgetReservesOutput(t *testing.T) {
+	al := &AgentLoop{contextWindow: 100000}
+	if got := al.historyBudget(); got != 100000-providers.DefaultMaxTokens {
+		t.Errorf("Expected output reserve of %d, got budget %d", providers.DefaultMaxTokens, got)
+	}
+
+	configured := &AgentLoop{contextWindow: 100000, maxTokens: 2000}
+	if got := configured.historyBudget(); got != 98000 {
+		t.Errorf("Expected providers.default_max_tokens reserved, got budget %d", got)
+	}
+
+	small := &AgentLoop{contextWindow: 8192}
+	if got := small.historyBudget(); got != 8192-2048 {
+		t.Errorf("Expected output reserve capped at a quarter of the window, got %d", got)
+	}
+
+	if got := (&AgentLoop{}).historyBudget(); got != 0 {
+		t.Errorf("Expected trimming disabled without a context window, got %d", got)
+	}
+}
+
+func TestAgentLoop_EndToEndInMemoryChannel(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	msgBus := bus.NewMessageBus()
+	al := NewAgentLoop(cfg, msgBus, &simpleMockProvider{response: "pong"})
+
+	mgr, err := channels.NewManager(cfg, msgBus)
+	if err != nil {
+		t.Fatalf("NewManager() error: %v", err)
+	}
+	ch := channels.NewInMemoryChannel("mem", msgBus, nil)
+	mgr.RegisterChannel("mem", ch)
+
+	ctx, cancel := context.WithTimeout(context.Background(), responseTimeout)
+	defer cancel()
+	if err := mgr.StartAll(ctx); err != nil {
+		t.Fatalf("StartAll() error: %v", err)
+	}
+	defer mgr.StopAll(context.Background())
+	go al.Run(ctx)
+	defer al.Stop()
+
+	ch.Inject("user1", "chat1", "ping")
+
+	sent, err := ch.WaitForSent(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent[0].Channel != "mem" || sent[0].ChatID != "chat1" || sent[0].Content != "pong" {
+		t.Errorf("unexpected reply: %+v", sent[0])
+	}
+}
+
+func TestNewToolPolicy_RestrictedToolsNeedChatAllow(t *testing.T) {
+	policy := newToolPolicy(config.ToolPermissionsConfig{
+		Chats: map[string]config.ToolPermissionRule{
+			"onebot:private:10001": {Allow: []string{"message", "onebot_admin"}},
+		},
+	})
+
+	if policy.Allowed("onebot", "group:123456", "onebot_admin") {
+		t.Error("onebot_admin offered in a group without an allow rule")
+	}
+	if policy.Allowed("telegram", "42", "onebot_admin") {
+		t.Error("onebot_admin offered in an unrelated chat")
+	}
+	if !policy.Allowed("onebot", "private:10001", "onebot_admin") {
+		t.Error("onebot_admin not offered in the admin DM that allows it")
+	}
+	if !policy.Allowed("onebot", "group:123456", "message") {
+		t.Error("restricting onebot_admin affected other tools")
+	}
+}
+
+func TestAgentLoop_ReasoningSentOnlyWhenChannelOptsIn(t *testing.T) {
+	for name, show := range map[string]bool{"hidden": false, "shown": true} {
+		t.Run(name, func(t *testing.T) {
+			cfg := &config.Config{
+				Agents: config.AgentsConfig{
+					Defaults: config.AgentDefaults{
+						Workspace:         t.TempDir(),
+						Model:             "test-model",
+						MaxTokens:         4096,
+						MaxToolIterations: 10,
+					},
+				},
+			}
+			msgBus := bus.NewMessageBus()
+			al := NewAgentLoop(cfg, msgBus, &simpleMockProvider{response: "pong", reasoning: "The user said ping."})
+
+			mgr, err := channels.NewManager(cfg, msgBus)
+			if err != nil {
+				t.Fatalf("NewManager() error: %v", err)
+			}
+			ch := channels.NewInMemoryChannel("mem", msgBus, nil)
+			ch.SetShowReasoning(show)
+			mgr.RegisterChannel("mem", ch)
+
+			ctx, cancel := context.WithTimeout(context.Background(), responseTimeout)
+			defer cancel()
+			if err := mgr.StartAll(ctx); err != nil {
+				t.Fatalf("StartAll() error: %v", err)
+			}
+			defer mgr.StopAll(context.Background())
+			go al.Run(ctx)
+			defer al.Stop()
+
+			ch.Inject("user1", "chat1", "ping")
+
+			want := 1
+			if show {
+				want = 2
+			}
+			sent, err := ch.WaitForSent(ctx, want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if show {
+				if !sent[0].Reasoning || sent[0].Content != "The user said ping." {
+					t.Errorf("first message = %+v, want reasoning", sent[0])
+				}
+			}
+			last := sent[len(sent)-1]
+			if last.Reasoning || last.Content != "pong" {
+				t.Errorf("last message = %+v, want answer", last)
+			}
+			if !show {
+				// Give a dropped reasoning message time to show up if it was sent
+				time.Sleep(50 * time.Millisecond)
+				if n := len(ch.Sent()); n != 1 {
+					t.Errorf("sent %d messages, want only the answer", n)
+				}
+			}
+		})
+	}
+}
+
+func TestAgentLoop_ChatOptionsUser(t *testing.T) {
+	opts := processOptions{Channel: "telegram", SenderID: "123456"}
+
+	al := &AgentLoop{}
+	if _, ok := al.chatOptions(opts)["user"]; ok {
+		t.Error("user option set without send_user_id")
+	}
+
+	al.sendUserID = true
+	if got := al.chatOptions(opts)["user"]; got != "telegram:123456" {
+		t.Errorf("user option = %v, want telegram:123456", got)
+	}
+	if _, ok := al.chatOptions(processOptions{Channel: "system"})["user"]; ok {
+		t.Error("user option set without a sender")
+	}
+}
+
+func TestAgentLoop_ChatOptionsParallelToolCalls(t *testing.T) {
+	al := &AgentLoop{}
+	if _, ok := al.chatOptions(processOptions{})["parallel_tool_calls"]; ok {
+		t.Error("parallel_tool_calls set by default")
+	}
+
+	al.noParallel = true
+	if got, ok := al.chatOptions(processOptions{})["parallel_tool_calls"]; !ok || got != false {
+		t.Errorf("parallel_tool_calls = %v, want false", got)
+	}
+}
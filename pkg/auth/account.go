@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LoginProviders are the providers that support picoclaw auth login.
+var LoginProviders = []string{"openai", "anthropic"}
+
+// Account describes a stored login without exposing its tokens.
+type Account struct {
+	Provider   string
+	LoggedIn   bool
+	AccountID  string    // empty when the provider does not report one
+	AuthMethod string    // "oauth" or "token"
+	ExpiresAt  time.Time // zero when the token does not expire
+	Expired    bool
+}
+
+// AccountInfo returns the login stored for provider. A provider without a
+// stored credential yields an Account with LoggedIn false, not an error.
+func AccountInfo(provider string) (Account, error) {
+	info := Account{Provider: provider}
+	cred, err := GetCredential(provider)
+	if err != nil {
+		return info, err
+	}
+	if cred == nil || cred.AccessToken == "" {
+		return info, nil
+	}
+
+	info.LoggedIn = true
+	info.AccountID = cred.AccountID
+	info.AuthMethod = cred.AuthMethod
+	info.ExpiresAt = cred.ExpiresAt
+	info.Expired = cred.IsExpired()
+	return info, nil
+}
+
+// String renders the account on one line, e.g.
+// "openai: logged in via oauth, account acc-123, expires 2026-03-01 12:00".
+func (a Account) String() string {
+	if !a.LoggedIn {
+		return a.Provider + ": not logged in"
+	}
+
+	parts := []string{"logged in"}
+	if a.AuthMethod != "" {
+		parts[0] += " via " + a.AuthMethod
+	}
+	if a.AccountID != "" {
+		parts = append(parts, "account "+a.AccountID)
+	}
+	switch {
+	case a.ExpiresAt.IsZero():
+		parts = append(parts, "no expiry")
+	case a.Expired:
+		parts = append(parts, "expired "+a.ExpiresAt.Format("2006-01-02 15:04"))
+	default:
+		parts = append(parts, "expires "+a.ExpiresAt.Format("2006-01-02 15:04"))
+	}
+	return fmt.Sprintf("%s: %s", a.Provider, strings.Join(parts, ", "))
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccountInfo(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	info, err := AccountInfo("openai")
+	if err != nil {
+		t.Fatalf("AccountInfo() error: %v", err)
+	}
+	if info.LoggedIn {
+		t.Fatal("LoggedIn = true with no stored credential")
+	}
+	if got := info.String(); got != "openai: not logged in" {
+		t.Errorf("String() = %q", got)
+	}
+
+	expires := time.Now().Add(time.Hour)
+	if err := SetCredential("openai", &AuthCredential{
+		AccessToken: "secret-access-token",
+		AccountID:   "acct-123",
+		ExpiresAt:   expires,
+		Provider:    "openai",
+		AuthMethod:  "oauth",
+	}); err != nil {
+		t.Fatalf("SetCredential() error: %v", err)
+	}
+
+	info, err = AccountInfo("openai")
+	if err != nil {
+		t.Fatalf("AccountInfo() error: %v", err)
+	}
+	if !info.LoggedIn || info.AccountID != "acct-123" || info.AuthMethod != "oauth" || info.Expired {
+		t.Errorf("AccountInfo() = %+v", info)
+	}
+	got := info.String()
+	if !strings.Contains(got, "acct-123") || !strings.Contains(got, "expires "+expires.Format("2006-01-02 15:04")) {
+		t.Errorf("String() = %q", got)
+	}
+	if strings.Contains(got, "secret") {
+		t.Errorf("String() leaks the token: %q", got)
+	}
+}
+
+func TestAccountStringExpiredAndNoExpiry(t *testing.T) {
+	expired := Account{Provider: "openai", LoggedIn: true, AuthMethod: "oauth", ExpiresAt: time.Now().Add(-time.Hour), Expired: true}
+	if got := expired.String(); !strings.Contains(got, "expired") {
+		t.Errorf("String() = %q, want expired", got)
+	}
+	token := Account{Provider: "anthropic", LoggedIn: true, AuthMethod: "token"}
+	if got := token.String(); got != "anthropic: logged in via token, no expiry" {
+		t.Errorf("String() = %q", got)
+	}
+}
@@ -24,16 +24,53 @@ type OAuthProviderConfig struct {
 	Scopes     string
 	Originator string
 	Port       int
+
+	// Device-code flow. Empty endpoints fall back to OpenAI's layout under Issuer.
+	DeviceCodeURL      string        // Endpoint that issues the user code
+	DeviceTokenURL     string        // Endpoint polled until the user approves
+	DeviceVerifyURL    string        // Page shown to the user to enter the code
+	DeviceRedirectURI  string        // redirect_uri used when exchanging the device authorization code
+	DevicePollTimeout  time.Duration // Give up after this long (default 15m)
+	DevicePollInterval time.Duration // Used when the server does not specify an interval (default 5s)
 }
 
 func OpenAIOAuthConfig() OAuthProviderConfig {
 	return OAuthProviderConfig{
-		Issuer:     "https://auth.openai.com",
-		ClientID:   "app_EMoamEEZ73f0CkXaXp7hrann",
-		Scopes:     "openid profile email offline_access",
-		Originator: "codex_cli_rs",
-		Port:       1455,
+		Issuer:             "https://auth.openai.com",
+		ClientID:           "app_EMoamEEZ73f0CkXaXp7hrann",
+		Scopes:             "openid profile email offline_access",
+		Originator:         "codex_cli_rs",
+		Port:               1455,
+		DeviceCodeURL:      "https://auth.openai.com/api/accounts/deviceauth/usercode",
+		DeviceTokenURL:     "https://auth.openai.com/api/accounts/deviceauth/token",
+		DeviceVerifyURL:    "https://auth.openai.com/codex/device",
+		DeviceRedirectURI:  "https://auth.openai.com/deviceauth/callback",
+		DevicePollTimeout:  15 * time.Minute,
+		DevicePollInterval: 5 * time.Second,
+	}
+}
+
+// withDeviceDefaults fills unset device-code settings from Issuer.
+func (cfg OAuthProviderConfig) withDeviceDefaults() OAuthProviderConfig {
+	if cfg.DeviceCodeURL == "" {
+		cfg.DeviceCodeURL = cfg.Issuer + "/api/accounts/deviceauth/usercode"
+	}
+	if cfg.DeviceTokenURL == "" {
+		cfg.DeviceTokenURL = cfg.Issuer + "/api/accounts/deviceauth/token"
 	}
+	if cfg.DeviceVerifyURL == "" {
+		cfg.DeviceVerifyURL = cfg.Issuer + "/codex/device"
+	}
+	if cfg.DeviceRedirectURI == "" {
+		cfg.DeviceRedirectURI = cfg.Issuer + "/deviceauth/callback"
+	}
+	if cfg.DevicePollTimeout <= 0 {
+		cfg.DevicePollTimeout = 15 * time.Minute
+	}
+	if cfg.DevicePollInterval <= 0 {
+		cfg.DevicePollInterval = 5 * time.Second
+	}
+	return cfg
 }
 
 func generateState() (string, error) {
@@ -172,12 +209,14 @@ func parseFlexibleInt(raw json.RawMessage) (int, error) {
 }
 
 func LoginDeviceCode(cfg OAuthProviderConfig) (*AuthCredential, error) {
+	cfg = cfg.withDeviceDefaults()
+
 	reqBody, _ := json.Marshal(map[string]string{
 		"client_id": cfg.ClientID,
 	})
 
 	resp, err := http.Post(
-		cfg.Issuer+"/api/accounts/deviceauth/usercode",
+		cfg.DeviceCodeURL,
 		"application/json",
 		strings.NewReader(string(reqBody)),
 	)
@@ -196,21 +235,22 @@ func LoginDeviceCode(cfg OAuthProviderConfig) (*AuthCredential, error) {
 		return nil, fmt.Errorf("parsing device code response: %w", err)
 	}
 
-	if deviceResp.Interval < 1 {
-		deviceResp.Interval = 5
+	interval := time.Duration(deviceResp.Interval) * time.Second
+	if interval <= 0 {
+		interval = cfg.DevicePollInterval
 	}
 
-	fmt.Printf("\nTo authenticate, open this URL in your browser:\n\n  %s/codex/device\n\nThen enter this code: %s\n\nWaiting for authentication...\n",
-		cfg.Issuer, deviceResp.UserCode)
+	fmt.Printf("\nTo authenticate, open this URL in your browser:\n\n  %s\n\nThen enter this code: %s\n\nWaiting for authentication...\n",
+		cfg.DeviceVerifyURL, deviceResp.UserCode)
 
-	deadline := time.After(15 * time.Minute)
-	ticker := time.NewTicker(time.Duration(deviceResp.Interval) * time.Second)
+	deadline := time.After(cfg.DevicePollTimeout)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-deadline:
-			return nil, fmt.Errorf("device code authentication timed out after 15 minutes")
+			return nil, fmt.Errorf("device code authentication timed out after %v", cfg.DevicePollTimeout)
 		case <-ticker.C:
 			cred, err := pollDeviceCode(cfg, deviceResp.DeviceAuthID, deviceResp.UserCode)
 			if err != nil {
@@ -230,7 +270,7 @@ func pollDeviceCode(cfg OAuthProviderConfig, deviceAuthID, userCode string) (*Au
 	})
 
 	resp, err := http.Post(
-		cfg.Issuer+"/api/accounts/deviceauth/token",
+		cfg.DeviceTokenURL,
 		"application/json",
 		strings.NewReader(string(reqBody)),
 	)
@@ -254,8 +294,7 @@ func pollDeviceCode(cfg OAuthProviderConfig, deviceAuthID, userCode string) (*Au
 		return nil, err
 	}
 
-	redirectURI := cfg.Issuer + "/deviceauth/callback"
-	return exchangeCodeForTokens(cfg, tokenResp.AuthorizationCode, tokenResp.CodeVerifier, redirectURI)
+	return exchangeCodeForTokens(cfg, tokenResp.AuthorizationCode, tokenResp.CodeVerifier, cfg.DeviceRedirectURI)
 }
 
 func RefreshAccessToken(cred *AuthCredential, cfg OAuthProviderConfig) (*AuthCredential, error) {
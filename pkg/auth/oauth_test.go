@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestBuildAuthorizeURL(t *testing.T) {
@@ -274,3 +275,101 @@ func TestParseDeviceCodeResponseInvalidInterval(t *testing.T) {
 		t.Fatal("expected error for invalid interval")
 	}
 }
+
+func TestLoginDeviceCodeCustomEndpoints(t *testing.T) {
+	polls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device/code", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_auth_id": "dev-1",
+			"user_code":      "ABCD-1234",
+		})
+	})
+	mux.HandleFunc("/device/token", func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls < 2 {
+			http.Error(w, "authorization_pending", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"authorization_code": "auth-code",
+			"code_verifier":      "verifier",
+		})
+	})
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.FormValue("code") != "auth-code" || r.FormValue("redirect_uri") != "https://example.com/cb" {
+			http.Error(w, "bad exchange", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "device-access-token",
+			"expires_in":   3600,
+		})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cfg := OAuthProviderConfig{
+		Issuer:             server.URL,
+		ClientID:           "test-client",
+		DeviceCodeURL:      server.URL + "/device/code",
+		DeviceTokenURL:     server.URL + "/device/token",
+		DeviceVerifyURL:    "https://example.com/activate",
+		DeviceRedirectURI:  "https://example.com/cb",
+		DevicePollTimeout:  5 * time.Second,
+		DevicePollInterval: 10 * time.Millisecond,
+	}
+
+	cred, err := LoginDeviceCode(cfg)
+	if err != nil {
+		t.Fatalf("LoginDeviceCode() error: %v", err)
+	}
+	if cred.AccessToken != "device-access-token" {
+		t.Errorf("AccessToken = %q, want %q", cred.AccessToken, "device-access-token")
+	}
+	if polls < 2 {
+		t.Errorf("polls = %d, want at least 2", polls)
+	}
+}
+
+func TestLoginDeviceCodeTimeout(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/accounts/deviceauth/usercode", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"device_auth_id":"dev-1","user_code":"ABCD","interval":"0"}`))
+	})
+	mux.HandleFunc("/api/accounts/deviceauth/token", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "authorization_pending", http.StatusForbidden)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cfg := OAuthProviderConfig{
+		Issuer:             server.URL,
+		ClientID:           "test-client",
+		DevicePollTimeout:  50 * time.Millisecond,
+		DevicePollInterval: 10 * time.Millisecond,
+	}
+
+	if _, err := LoginDeviceCode(cfg); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("LoginDeviceCode() error = %v, want timeout", err)
+	}
+}
+
+func TestOpenAIOAuthConfigDeviceDefaults(t *testing.T) {
+	cfg := OpenAIOAuthConfig()
+	derived := OAuthProviderConfig{Issuer: cfg.Issuer}.withDeviceDefaults()
+
+	if cfg.DeviceCodeURL != derived.DeviceCodeURL ||
+		cfg.DeviceTokenURL != derived.DeviceTokenURL ||
+		cfg.DeviceVerifyURL != derived.DeviceVerifyURL ||
+		cfg.DeviceRedirectURI != derived.DeviceRedirectURI {
+		t.Errorf("OpenAI device endpoints %+v do not match issuer-derived defaults %+v", cfg, derived)
+	}
+	if cfg.DevicePollTimeout != 15*time.Minute || cfg.DevicePollInterval != 5*time.Second {
+		t.Errorf("unexpected OpenAI device polling settings: %v / %v", cfg.DevicePollTimeout, cfg.DevicePollInterval)
+	}
+}
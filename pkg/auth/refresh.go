@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"fmt"
+	"sync"
+)
+
+// refreshCall is an in-progress refresh whose result is shared with every
+// caller that arrives while it runs.
+type refreshCall struct {
+	done chan struct{}
+	cred *AuthCredential
+	err  error
+}
+
+var (
+	refreshMu    sync.Mutex
+	refreshCalls = map[string]*refreshCall{}
+)
+
+// RefreshCredential returns the stored credential for provider, first
+// refreshing it with refresh and saving the result when it is about to
+// expire. Concurrent callers for the same provider share a single refresh,
+// so parallel requests do not each spend the refresh token.
+func RefreshCredential(provider string, refresh func(cred *AuthCredential) (*AuthCredential, error)) (*AuthCredential, error) {
+	cred, err := GetCredential(provider)
+	if err != nil {
+		return nil, fmt.Errorf("loading auth credentials: %w", err)
+	}
+	if cred == nil || !cred.NeedsRefresh() || cred.RefreshToken == "" {
+		return cred, nil
+	}
+
+	refreshMu.Lock()
+	if call, ok := refreshCalls[provider]; ok {
+		refreshMu.Unlock()
+		<-call.done
+		return copyCredential(call.cred), call.err
+	}
+	call := &refreshCall{done: make(chan struct{})}
+	refreshCalls[provider] = call
+	refreshMu.Unlock()
+
+	call.cred, call.err = refreshStored(provider, refresh)
+
+	refreshMu.Lock()
+	delete(refreshCalls, provider)
+	refreshMu.Unlock()
+	close(call.done)
+
+	return copyCredential(call.cred), call.err
+}
+
+// refreshStored re-reads the credential, since a refresh that finished just
+// before this one started has already saved a fresh token, and refreshes it
+// only if still needed.
+func refreshStored(provider string, refresh func(cred *AuthCredential) (*AuthCredential, error)) (*AuthCredential, error) {
+	cred, err := GetCredential(provider)
+	if err != nil {
+		return nil, fmt.Errorf("loading auth credentials: %w", err)
+	}
+	if cred == nil || !cred.NeedsRefresh() || cred.RefreshToken == "" {
+		return cred, nil
+	}
+
+	refreshed, err := refresh(cred)
+	if err != nil {
+		return nil, fmt.Errorf("refreshing token: %w", err)
+	}
+	if err := SetCredential(provider, refreshed); err != nil {
+		return nil, fmt.Errorf("saving refreshed token: %w", err)
+	}
+	return refreshed, nil
+}
+
+func copyCredential(cred *AuthCredential) *AuthCredential {
+	if cred == nil {
+		return nil
+	}
+	copied := *cred
+	return &copied
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func storeExpiringCredential(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	invalidateCache()
+	t.Cleanup(invalidateCache)
+
+	err := SetCredential("openai", &AuthCredential{
+		AccessToken:  "old-token",
+		RefreshToken: "refresh-token",
+		ExpiresAt:    time.Now().Add(time.Minute),
+		Provider:     "openai",
+		AuthMethod:   "oauth",
+	})
+	if err != nil {
+		t.Fatalf("SetCredential() error: %v", err)
+	}
+}
+
+// refreshConcurrently calls RefreshCredential from n goroutines at once.
+func refreshConcurrently(n int, refresh func(*AuthCredential) (*AuthCredential, error)) ([]*AuthCredential, []error) {
+	creds := make([]*AuthCredential, n)
+	errs := make([]error, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			creds[i], errs[i] = RefreshCredential("openai", refresh)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return creds, errs
+}
+
+func TestRefreshCredentialSingleFlight(t *testing.T) {
+	storeExpiringCredential(t)
+
+	var refreshes atomic.Int32
+	creds, errs := refreshConcurrently(16, func(cred *AuthCredential) (*AuthCredential, error) {
+		refreshes.Add(1)
+		time.Sleep(50 * time.Millisecond) // keep the refresh in flight while others arrive
+		return &AuthCredential{
+			AccessToken:  "new-token",
+			RefreshToken: "new-refresh-token",
+			ExpiresAt:    time.Now().Add(time.Hour),
+			Provider:     "openai",
+			AuthMethod:   "oauth",
+		}, nil
+	})
+
+	if n := refreshes.Load(); n != 1 {
+		t.Errorf("refreshes = %d, want 1", n)
+	}
+	for i := range creds {
+		if errs[i] != nil || creds[i] == nil || creds[i].AccessToken != "new-token" {
+			t.Errorf("caller %d got %+v, %v; want new-token", i, creds[i], errs[i])
+		}
+	}
+
+	stored, err := GetCredential("openai")
+	if err != nil || stored.AccessToken != "new-token" {
+		t.Errorf("stored credential = %+v, %v; want new-token saved", stored, err)
+	}
+}
+
+func TestRefreshCredentialSharesError(t *testing.T) {
+	storeExpiringCredential(t)
+
+	var refreshes atomic.Int32
+	_, errs := refreshConcurrently(8, func(cred *AuthCredential) (*AuthCredential, error) {
+		refreshes.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return nil, errors.New("invalid_grant")
+	})
+
+	if n := refreshes.Load(); n != 1 {
+		t.Errorf("refreshes = %d, want 1", n)
+	}
+	for i, err := range errs {
+		if err == nil {
+			t.Errorf("caller %d got no error", i)
+		}
+	}
+}
+
+func TestRefreshCredentialFreshTokenNotRefreshed(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	invalidateCache()
+	t.Cleanup(invalidateCache)
+	if err := SetCredential("openai", &AuthCredential{AccessToken: "tok", RefreshToken: "r", ExpiresAt: time.Now().Add(time.Hour), AuthMethod: "oauth"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cred, err := RefreshCredential("openai", func(*AuthCredential) (*AuthCredential, error) {
+		t.Error("refresh called for a fresh token")
+		return nil, nil
+	})
+	if err != nil || cred.AccessToken != "tok" {
+		t.Errorf("RefreshCredential() = %+v, %v", cred, err)
+	}
+}
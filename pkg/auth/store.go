@@ -4,9 +4,29 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
+// credentialCacheTTL bounds how long GetCredential serves credentials from
+// memory before re-reading auth.json, so logins from another process are
+// picked up without hitting disk on every provider call.
+const credentialCacheTTL = 30 * time.Second
+
+type cachedStore struct {
+	path     string
+	store    *AuthStore
+	loadedAt time.Time
+}
+
+var (
+	// storeMu serializes read-modify-write cycles on auth.json
+	storeMu sync.Mutex
+
+	cacheMu sync.RWMutex
+	cache   *cachedStore
+)
+
 type AuthCredential struct {
 	AccessToken  string    `json:"access_token"`
 	RefreshToken string    `json:"refresh_token,omitempty"`
@@ -59,7 +79,15 @@ func LoadStore() (*AuthStore, error) {
 	return &store, nil
 }
 
+// SaveStore writes store to auth.json, replacing its contents.
 func SaveStore(store *AuthStore) error {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	return saveStoreLocked(store)
+}
+
+// saveStoreLocked is SaveStore for callers already holding storeMu.
+func saveStoreLocked(store *AuthStore) error {
 	path := authFilePath()
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -70,40 +98,89 @@ func SaveStore(store *AuthStore) error {
 	if err != nil {
 		return err
 	}
+	defer invalidateCache()
 	return os.WriteFile(path, data, 0600)
 }
 
+// GetCredential returns a copy of the stored credential for provider, or nil
+// if none exists. Results are cached in memory for credentialCacheTTL.
 func GetCredential(provider string) (*AuthCredential, error) {
-	store, err := LoadStore()
-	if err != nil {
-		return nil, err
+	path := authFilePath()
+
+	c := cachedFor(path)
+	if c == nil {
+		// Load and install under storeMu so a concurrent write cannot land
+		// between the read and the cache update and leave a stale store cached
+		storeMu.Lock()
+		if c = cachedFor(path); c == nil {
+			store, err := LoadStore()
+			if err != nil {
+				storeMu.Unlock()
+				return nil, err
+			}
+			c = &cachedStore{path: path, store: store, loadedAt: time.Now()}
+			cacheMu.Lock()
+			cache = c
+			cacheMu.Unlock()
+		}
+		storeMu.Unlock()
 	}
-	cred, ok := store.Credentials[provider]
-	if !ok {
+
+	cred, ok := c.store.Credentials[provider]
+	if !ok || cred == nil {
 		return nil, nil
 	}
-	return cred, nil
+	copied := *cred
+	return &copied, nil
+}
+
+// cachedFor returns the cached store for path if it is still fresh.
+func cachedFor(path string) *cachedStore {
+	cacheMu.RLock()
+	defer cacheMu.RUnlock()
+	if cache == nil || cache.path != path || time.Since(cache.loadedAt) >= credentialCacheTTL {
+		return nil
+	}
+	return cache
 }
 
 func SetCredential(provider string, cred *AuthCredential) error {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
 	store, err := LoadStore()
 	if err != nil {
 		return err
 	}
 	store.Credentials[provider] = cred
-	return SaveStore(store)
+	return saveStoreLocked(store)
 }
 
 func DeleteCredential(provider string) error {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
 	store, err := LoadStore()
 	if err != nil {
 		return err
 	}
 	delete(store.Credentials, provider)
-	return SaveStore(store)
+	return saveStoreLocked(store)
+}
+
+// invalidateCache drops the in-memory credential cache so the next
+// GetCredential re-reads auth.json.
+func invalidateCache() {
+	cacheMu.Lock()
+	cache = nil
+	cacheMu.Unlock()
 }
 
 func DeleteAllCredentials() error {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	defer invalidateCache()
+
 	path := authFilePath()
 	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
 		return err
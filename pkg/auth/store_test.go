@@ -1,8 +1,10 @@
 package auth
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 )
@@ -187,3 +189,125 @@ func TestLoadStoreEmpty(t *testing.T) {
 		t.Errorf("expected empty credentials, got %d", len(store.Credentials))
 	}
 }
+
+func TestGetCredentialCached(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+
+	if err := SetCredential("openai", &AuthCredential{AccessToken: "first", Provider: "openai"}); err != nil {
+		t.Fatalf("SetCredential() error: %v", err)
+	}
+	if _, err := GetCredential("openai"); err != nil {
+		t.Fatalf("GetCredential() error: %v", err)
+	}
+
+	// Remove the file behind the cache's back; cached reads must not hit disk
+	if err := os.Remove(filepath.Join(tmpDir, ".picoclaw", "auth.json")); err != nil {
+		t.Fatalf("removing auth.json: %v", err)
+	}
+	cred, err := GetCredential("openai")
+	if err != nil || cred == nil || cred.AccessToken != "first" {
+		t.Fatalf("GetCredential() = %v, %v; want cached credential", cred, err)
+	}
+
+	// Mutating the returned copy must not affect the cache
+	cred.AccessToken = "mutated"
+	again, _ := GetCredential("openai")
+	if again.AccessToken != "first" {
+		t.Errorf("AccessToken = %q, want cached value unaffected by caller mutation", again.AccessToken)
+	}
+}
+
+func TestSetCredentialInvalidatesCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if err := SetCredential("openai", &AuthCredential{AccessToken: "old", Provider: "openai"}); err != nil {
+		t.Fatalf("SetCredential() error: %v", err)
+	}
+	if cred, _ := GetCredential("openai"); cred == nil || cred.AccessToken != "old" {
+		t.Fatalf("GetCredential() = %v, want old token", cred)
+	}
+
+	if err := SetCredential("openai", &AuthCredential{AccessToken: "new", Provider: "openai"}); err != nil {
+		t.Fatalf("SetCredential() error: %v", err)
+	}
+	if cred, _ := GetCredential("openai"); cred == nil || cred.AccessToken != "new" {
+		t.Errorf("GetCredential() = %v, want refreshed token", cred)
+	}
+
+	if err := DeleteCredential("openai"); err != nil {
+		t.Fatalf("DeleteCredential() error: %v", err)
+	}
+	if cred, _ := GetCredential("openai"); cred != nil {
+		t.Errorf("GetCredential() = %v, want nil after delete", cred)
+	}
+}
+
+func TestCredentialStoreConcurrentAccess(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			provider := fmt.Sprintf("provider-%d", i)
+			if err := SetCredential(provider, &AuthCredential{AccessToken: provider, Provider: provider}); err != nil {
+				t.Errorf("SetCredential(%s) error: %v", provider, err)
+			}
+			for j := 0; j < 10; j++ {
+				if _, err := GetCredential(provider); err != nil {
+					t.Errorf("GetCredential(%s) error: %v", provider, err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Serialized read-modify-write must not lose any provider
+	for i := 0; i < 8; i++ {
+		provider := fmt.Sprintf("provider-%d", i)
+		if cred, _ := GetCredential(provider); cred == nil {
+			t.Errorf("credential for %s was lost", provider)
+		}
+	}
+}
+
+func TestGetCredentialNeverCachesStaleStoreDuringRefresh(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if err := SetCredential("openai", &AuthCredential{AccessToken: "token-0", Provider: "openai"}); err != nil {
+		t.Fatalf("SetCredential() error: %v", err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					GetCredential("openai")
+				}
+			}
+		}()
+	}
+
+	// Each completed refresh must be visible to the next read, as a rotated
+	// refresh token would be unusable otherwise
+	for i := 1; i <= 50; i++ {
+		want := fmt.Sprintf("token-%d", i)
+		if err := SetCredential("openai", &AuthCredential{AccessToken: want, Provider: "openai"}); err != nil {
+			t.Fatalf("SetCredential() error: %v", err)
+		}
+		if cred, _ := GetCredential("openai"); cred == nil || cred.AccessToken != want {
+			t.Fatalf("GetCredential() = %v after refresh, want %s", cred, want)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -14,6 +14,18 @@ type OutboundMessage struct {
 	Channel string `json:"channel"`
 	ChatID  string `json:"chat_id"`
 	Content string `json:"content"`
+	// Reasoning marks Content as the model's reasoning rather than a reply.
+	// Only channels configured to show reasoning send it.
+	Reasoning bool `json:"reasoning,omitempty"`
 }
 
 type MessageHandler func(InboundMessage) error
+
+// IndicatorKind is a chat activity indicator shown while the agent works.
+type IndicatorKind string
+
+const (
+	IndicatorNone      IndicatorKind = ""          // Clear any active indicator
+	IndicatorTyping    IndicatorKind = "typing"    // Bot is composing a reply
+	IndicatorRecording IndicatorKind = "recording" // Bot is recording audio
+)
@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
 type Channel interface {
@@ -15,16 +18,44 @@ type Channel interface {
 	Send(ctx context.Context, msg bus.OutboundMessage) error
 	IsRunning() bool
 	IsAllowed(senderID string) bool
+	// Indicator shows or clears an activity indicator in chatID. Channels
+	// that cannot display the requested kind ignore it.
+	Indicator(ctx context.Context, chatID string, kind bus.IndicatorKind) error
 }
 
 type BaseChannel struct {
-	config    interface{}
-	bus       *bus.MessageBus
-	running   bool
-	name      string
-	allowList []string
+	config        interface{}
+	bus           *bus.MessageBus
+	running       bool
+	name          string
+	allowList     []string
+	formatter     Formatter
+	showReasoning bool
+
+	// Think time (SetThinkTime): rapid messages from one sender are merged
+	// into pending, and replies wait for lastInbound of their chat. Entries
+	// of lastInbound are dropped once the think time has passed. Replies
+	// that have to wait are queued in replyQueues, one per chat, each
+	// drained by its own goroutine.
+	thinkTime   time.Duration
+	thinkMu     sync.Mutex
+	pending     map[string]*pendingInbound
+	lastInbound map[string]time.Time
+	replyQueues map[string][]func() error
+}
+
+// pendingInbound is a message held back while its sender may still be
+// typing.
+type pendingInbound struct {
+	msg   bus.InboundMessage
+	first time.Time
+	timer *time.Timer
 }
 
+// maxCoalesceWaits bounds how many think times a sender who keeps typing
+// can hold back their merged message.
+const maxCoalesceWaits = 5
+
 func NewBaseChannel(name string, config interface{}, bus *bus.MessageBus, allowList []string) *BaseChannel {
 	return &BaseChannel{
 		config:    config,
@@ -82,6 +113,152 @@ func (c *BaseChannel) IsAllowed(senderID string) bool {
 	return false
 }
 
+// SetFormatter sets the formatter applied to outbound text. nil restores
+// passthrough.
+func (c *BaseChannel) SetFormatter(f Formatter) {
+	c.formatter = f
+}
+
+// FormatOutbound renders outbound text with the channel's formatter. Without
+// one the Markdown produced by the model is sent unchanged.
+func (c *BaseChannel) FormatOutbound(content string) string {
+	if c.formatter == nil {
+		return content
+	}
+	return c.formatter.Format(content)
+}
+
+// SetShowReasoning controls whether reasoning messages from the model are
+// sent to chats. Off by default.
+func (c *BaseChannel) SetShowReasoning(show bool) {
+	c.showReasoning = show
+}
+
+// ShowsReasoning reports whether the channel sends reasoning messages.
+func (c *BaseChannel) ShowsReasoning() bool {
+	return c.showReasoning
+}
+
+// SetThinkTime makes the channel wait d after a sender's last message before
+// passing it on, merging messages sent in the meantime into one turn, and
+// lets WaitThinkTime hold replies until a chat has been quiet for d. 0, the
+// default, passes messages on immediately.
+func (c *BaseChannel) SetThinkTime(d time.Duration) {
+	c.thinkMu.Lock()
+	defer c.thinkMu.Unlock()
+	c.thinkTime = d
+	if c.pending == nil {
+		c.pending = make(map[string]*pendingInbound)
+		c.lastInbound = make(map[string]time.Time)
+		c.replyQueues = make(map[string][]func() error)
+	}
+}
+
+// WaitThinkTime blocks until the think time has passed since the last
+// message received in chatID, so a reply does not land while users are
+// still typing. It returns ctx's error if ctx ends first.
+func (c *BaseChannel) WaitThinkTime(ctx context.Context, chatID string) error {
+	c.thinkMu.Lock()
+	wait := c.thinkWait(chatID)
+	c.thinkMu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// thinkWait returns how long a reply to chatID still has to wait, dropping
+// the chat's entry from lastInbound once nothing is left to wait for. The
+// caller must hold thinkMu.
+func (c *BaseChannel) thinkWait(chatID string) time.Duration {
+	last, ok := c.lastInbound[chatID]
+	if !ok {
+		return 0
+	}
+	wait := time.Until(last.Add(c.thinkTime))
+	if wait <= 0 {
+		delete(c.lastInbound, chatID)
+	}
+	return wait
+}
+
+// SendAfterThinkTime runs send once the think time has passed since the last
+// message received in chatID. When the chat is quiet and no earlier reply is
+// waiting, send runs at once and its error is returned. Otherwise send is
+// queued behind the chat's earlier replies and nil is returned; the queue is
+// delivered in order by a goroutine of its own, so the caller, normally the
+// outbound dispatcher shared by all chats and channels, is not held up.
+// Errors of queued sends are logged.
+func (c *BaseChannel) SendAfterThinkTime(ctx context.Context, chatID string, send func() error) error {
+	c.thinkMu.Lock()
+	if c.thinkTime <= 0 {
+		c.thinkMu.Unlock()
+		return send()
+	}
+	queue, busy := c.replyQueues[chatID]
+	if !busy && c.thinkWait(chatID) <= 0 {
+		c.thinkMu.Unlock()
+		return send()
+	}
+	c.replyQueues[chatID] = append(queue, send)
+	c.thinkMu.Unlock()
+
+	if !busy {
+		go c.drainReplies(ctx, chatID)
+	}
+	return nil
+}
+
+// drainReplies delivers the replies queued for chatID, waiting for the think
+// time before each. If ctx ends, the replies still queued are dropped.
+func (c *BaseChannel) drainReplies(ctx context.Context, chatID string) {
+	for {
+		if err := c.WaitThinkTime(ctx, chatID); err != nil {
+			c.thinkMu.Lock()
+			dropped := len(c.replyQueues[chatID])
+			delete(c.replyQueues, chatID)
+			c.thinkMu.Unlock()
+			logger.WarnCF(c.name, "Dropped replies waiting for think time", map[string]interface{}{
+				"chat_id": chatID,
+				"dropped": dropped,
+				"error":   err.Error(),
+			})
+			return
+		}
+
+		c.thinkMu.Lock()
+		queue := c.replyQueues[chatID]
+		if len(queue) == 0 {
+			delete(c.replyQueues, chatID)
+			c.thinkMu.Unlock()
+			return
+		}
+		send := queue[0]
+		c.replyQueues[chatID] = queue[1:]
+		c.thinkMu.Unlock()
+
+		if err := send(); err != nil {
+			logger.ErrorCF(c.name, "Failed to send delayed reply", map[string]interface{}{
+				"chat_id": chatID,
+				"error":   err.Error(),
+			})
+		}
+	}
+}
+
+// Indicator is a no-op; channels with native typing support override it.
+func (c *BaseChannel) Indicator(ctx context.Context, chatID string, kind bus.IndicatorKind) error {
+	return nil
+}
+
 func (c *BaseChannel) HandleMessage(senderID, chatID, content string, media []string, metadata map[string]string) {
 	if !c.IsAllowed(senderID) {
 		return
@@ -100,6 +277,64 @@ func (c *BaseChannel) HandleMessage(senderID, chatID, content string, media []st
 		Metadata:   metadata,
 	}
 
+	if !c.coalesce(msg) {
+		c.bus.PublishInbound(msg)
+	}
+}
+
+// coalesce holds msg back for the think time, merging it into a message
+// already held for the same sender and chat. It reports false when no
+// think time is set and msg should be published right away.
+func (c *BaseChannel) coalesce(msg bus.InboundMessage) bool {
+	c.thinkMu.Lock()
+	defer c.thinkMu.Unlock()
+	if c.thinkTime <= 0 {
+		return false
+	}
+
+	now := time.Now()
+	for chatID, last := range c.lastInbound {
+		if now.Sub(last) >= c.thinkTime {
+			delete(c.lastInbound, chatID)
+		}
+	}
+	c.lastInbound[msg.ChatID] = now
+	key := msg.ChatID + "\x00" + msg.SenderID
+
+	p, ok := c.pending[key]
+	if !ok {
+		p = &pendingInbound{msg: msg, first: now}
+		c.pending[key] = p
+		p.timer = time.AfterFunc(c.thinkTime, func() { c.flushInbound(key, p) })
+		return true
+	}
+
+	p.msg.Content += "\n" + msg.Content
+	p.msg.Media = append(p.msg.Media, msg.Media...)
+	if len(msg.Metadata) > 0 && p.msg.Metadata == nil {
+		p.msg.Metadata = make(map[string]string, len(msg.Metadata))
+	}
+	for k, v := range msg.Metadata {
+		p.msg.Metadata[k] = v
+	}
+	if now.Sub(p.first) < maxCoalesceWaits*c.thinkTime {
+		p.timer.Reset(c.thinkTime)
+	}
+	return true
+}
+
+// flushInbound publishes the held message p. A timer that fires again after
+// p was already published finds p gone and does nothing.
+func (c *BaseChannel) flushInbound(key string, p *pendingInbound) {
+	c.thinkMu.Lock()
+	if c.pending[key] != p {
+		c.thinkMu.Unlock()
+		return
+	}
+	delete(c.pending, key)
+	msg := p.msg
+	c.thinkMu.Unlock()
+
 	c.bus.PublishInbound(msg)
 }
 
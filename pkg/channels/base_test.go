@@ -1,6 +1,13 @@
 package channels
 
-import "testing"
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
 
 func TestBaseChannelIsAllowed(t *testing.T) {
 	tests := []struct {
@@ -50,3 +57,143 @@ func TestBaseChannelIsAllowed(t *testing.T) {
 		})
 	}
 }
+
+func TestBaseChannelIndicatorIsNoop(t *testing.T) {
+	ch := NewBaseChannel("test", nil, nil, nil)
+	if err := ch.Indicator(context.Background(), "chat1", bus.IndicatorTyping); err != nil {
+		t.Fatalf("Indicator() error = %v, want nil", err)
+	}
+}
+
+func TestBaseChannelThinkTimeCoalescesSender(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	ch := NewBaseChannel("test", nil, msgBus, nil)
+	ch.SetThinkTime(50 * time.Millisecond)
+
+	ch.HandleMessage("alice", "group:1", "wait", nil, map[string]string{"message_id": "1"})
+	ch.HandleMessage("bob", "group:1", "hi", nil, nil)
+	time.Sleep(20 * time.Millisecond)
+	ch.HandleMessage("alice", "group:1", "one more thing", nil, map[string]string{"message_id": "2"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	got := map[string]bus.InboundMessage{}
+	for i := 0; i < 2; i++ {
+		msg, ok := msgBus.ConsumeInbound(ctx)
+		if !ok {
+			t.Fatalf("got %d inbound messages, want 2", i)
+		}
+		got[msg.SenderID] = msg
+	}
+
+	if got["alice"].Content != "wait\none more thing" || got["alice"].Metadata["message_id"] != "2" {
+		t.Errorf("alice's messages were not merged: %+v", got["alice"])
+	}
+	if got["bob"].Content != "hi" {
+		t.Errorf("bob's message = %+v", got["bob"])
+	}
+
+	short, cancelShort := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelShort()
+	if _, ok := msgBus.ConsumeInbound(short); ok {
+		t.Error("merged message was published twice")
+	}
+}
+
+func TestBaseChannelWaitThinkTime(t *testing.T) {
+	ch := NewBaseChannel("test", nil, bus.NewMessageBus(), nil)
+	if err := ch.WaitThinkTime(context.Background(), "group:1"); err != nil {
+		t.Fatalf("WaitThinkTime() without think time error = %v", err)
+	}
+
+	ch.SetThinkTime(200 * time.Millisecond)
+	ch.HandleMessage("alice", "group:1", "hello", nil, nil)
+
+	start := time.Now()
+	if err := ch.WaitThinkTime(context.Background(), "group:1"); err != nil {
+		t.Fatalf("WaitThinkTime() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("WaitThinkTime returned after %s, want about 200ms", elapsed)
+	}
+	if err := ch.WaitThinkTime(context.Background(), "group:2"); err != nil {
+		t.Errorf("WaitThinkTime() for a quiet chat error = %v", err)
+	}
+
+	ch.HandleMessage("alice", "group:1", "again", nil, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := ch.WaitThinkTime(ctx, "group:1"); err != context.DeadlineExceeded {
+		t.Errorf("WaitThinkTime() error = %v, want context deadline", err)
+	}
+}
+
+func TestBaseChannelSendAfterThinkTime(t *testing.T) {
+	ch := NewBaseChannel("test", nil, bus.NewMessageBus(), nil)
+	ch.SetThinkTime(200 * time.Millisecond)
+	ch.HandleMessage("alice", "group:1", "hello", nil, nil)
+
+	sent := make(chan string, 3)
+	send := func(s string) func() error {
+		return func() error { sent <- s; return nil }
+	}
+
+	// A reply to the busy chat is queued without blocking the caller
+	start := time.Now()
+	if err := ch.SendAfterThinkTime(context.Background(), "group:1", send("first")); err != nil {
+		t.Fatalf("SendAfterThinkTime() error = %v", err)
+	}
+	if err := ch.SendAfterThinkTime(context.Background(), "group:1", send("second")); err != nil {
+		t.Fatalf("SendAfterThinkTime() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("SendAfterThinkTime blocked for %s", elapsed)
+	}
+
+	// A quiet chat is sent at once, ahead of the queued replies
+	if err := ch.SendAfterThinkTime(context.Background(), "group:2", send("other")); err != nil {
+		t.Fatalf("SendAfterThinkTime() error = %v", err)
+	}
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		select {
+		case s := <-sent:
+			got = append(got, s)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("got %v, want 3 sends", got)
+		}
+	}
+	if strings.Join(got, ",") != "other,first,second" {
+		t.Errorf("send order = %v, want [other first second]", got)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("queued replies sent after %s, want about 200ms", elapsed)
+	}
+}
+
+func TestBaseChannelThinkTimeExpiresLastInbound(t *testing.T) {
+	ch := NewBaseChannel("test", nil, bus.NewMessageBus(), nil)
+	ch.SetThinkTime(20 * time.Millisecond)
+	ch.HandleMessage("alice", "group:1", "hello", nil, nil)
+	time.Sleep(40 * time.Millisecond)
+	ch.HandleMessage("bob", "group:2", "hi", nil, nil)
+
+	ch.thinkMu.Lock()
+	_, stale := ch.lastInbound["group:1"]
+	ch.thinkMu.Unlock()
+	if stale {
+		t.Error("expired lastInbound entry for group:1 was kept")
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if err := ch.SendAfterThinkTime(context.Background(), "group:2", func() error { return nil }); err != nil {
+		t.Fatalf("SendAfterThinkTime() error = %v", err)
+	}
+	ch.thinkMu.Lock()
+	n := len(ch.lastInbound)
+	ch.thinkMu.Unlock()
+	if n != 0 {
+		t.Errorf("lastInbound has %d entries after the replies, want 0", n)
+	}
+}
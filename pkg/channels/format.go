@@ -0,0 +1,106 @@
+package channels
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Formatter rewrites model output (Markdown) into what a channel can display.
+type Formatter interface {
+	Format(content string) string
+}
+
+// PassthroughFormatter sends content unchanged, for channels that render
+// Markdown themselves.
+type PassthroughFormatter struct{}
+
+func (PassthroughFormatter) Format(content string) string {
+	return content
+}
+
+var (
+	mdHeadingRe    = regexp.MustCompile(`(?m)^#{1,6}\s+`)
+	mdQuoteRe      = regexp.MustCompile(`(?m)^>\s?`)
+	mdListRe       = regexp.MustCompile(`(?m)^(\s*)[-*+]\s+`)
+	mdImageRe      = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)`)
+	mdLinkRe       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdBoldRe       = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	mdBoldUnderRe  = regexp.MustCompile(`__(.+?)__`)
+	mdStrikeRe     = regexp.MustCompile(`~~(.+?)~~`)
+	mdItalicRe     = regexp.MustCompile(`\*([^*\s](?:[^*]*[^*\s])?)\*`)
+	mdItalicUndRe  = regexp.MustCompile(`(^|[^\w])_([^_\s](?:[^_]*[^_\s])?)_($|[^\w])`)
+	mdPlaceholders = regexp.MustCompile("\x00(CB|IC|IM)(\\d+)\x00")
+)
+
+// PlainTextFormatter strips Markdown for channels that show it literally:
+// emphasis markers and headings are dropped, code keeps its text, links
+// become "text (url)" and list bullets become "•".
+type PlainTextFormatter struct {
+	// Escape, if set, is applied to all text before markup is inserted,
+	// e.g. to neutralize a channel's own control syntax.
+	Escape func(string) string
+	// Image renders ![alt](url). Defaults to "alt (url)".
+	Image func(alt, url string) string
+}
+
+func (f PlainTextFormatter) Format(content string) string {
+	if content == "" {
+		return ""
+	}
+
+	codeBlocks := extractCodeBlocks(content)
+	text := codeBlocks.text
+	inlineCodes := extractInlineCodes(text)
+	text = inlineCodes.text
+
+	var images [][2]string
+	text = mdImageRe.ReplaceAllStringFunc(text, func(m string) string {
+		sm := mdImageRe.FindStringSubmatch(m)
+		images = append(images, [2]string{sm[1], sm[2]})
+		return fmt.Sprintf("\x00IM%d\x00", len(images)-1)
+	})
+
+	text = mdHeadingRe.ReplaceAllString(text, "")
+	text = mdQuoteRe.ReplaceAllString(text, "")
+	text = mdListRe.ReplaceAllString(text, "$1• ")
+	text = mdLinkRe.ReplaceAllStringFunc(text, func(m string) string {
+		sm := mdLinkRe.FindStringSubmatch(m)
+		if sm[1] == sm[2] {
+			return sm[2]
+		}
+		return sm[1] + " (" + sm[2] + ")"
+	})
+	text = mdBoldRe.ReplaceAllString(text, "$1")
+	text = mdBoldUnderRe.ReplaceAllString(text, "$1")
+	text = mdStrikeRe.ReplaceAllString(text, "$1")
+	text = mdItalicRe.ReplaceAllString(text, "$1")
+	text = mdItalicUndRe.ReplaceAllString(text, "$1$2$3")
+
+	escape := f.Escape
+	if escape == nil {
+		escape = func(s string) string { return s }
+	}
+	text = escape(text)
+
+	return mdPlaceholders.ReplaceAllStringFunc(text, func(m string) string {
+		sm := mdPlaceholders.FindStringSubmatch(m)
+		i, _ := strconv.Atoi(sm[2])
+		switch sm[1] {
+		case "CB":
+			return escape(strings.TrimRight(codeBlocks.codes[i], "\n"))
+		case "IC":
+			return escape(inlineCodes.codes[i])
+		default:
+			alt, url := images[i][0], images[i][1]
+			if f.Image != nil {
+				return f.Image(alt, url)
+			}
+			if alt == "" {
+				return escape(url)
+			}
+			return escape(alt + " (" + url + ")")
+		}
+	})
+}
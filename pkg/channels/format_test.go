@@ -0,0 +1,67 @@
+package channels
+
+import "testing"
+
+func TestPlainTextFormatter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"bold and italic", "**bold**, __strong__, *em* and _under_", "bold, strong, em and under"},
+		{"strikethrough", "~~old~~ new", "old new"},
+		{"heading and quote", "## Title\n> quoted", "Title\nquoted"},
+		{"list bullets", "- one\n  * two\n+ three", "• one\n  • two\n• three"},
+		{"link", "see [docs](https://example.com)", "see docs (https://example.com)"},
+		{"bare link", "[https://example.com](https://example.com)", "https://example.com"},
+		{"image", "![chart](https://x/c.png)", "chart (https://x/c.png)"},
+		{"inline code kept verbatim", "run `**not bold**` now", "run **not bold** now"},
+		{"code fence", "```go\nfmt.Println(\"*hi*\")\n```", "fmt.Println(\"*hi*\")"},
+		{"arithmetic untouched", "2 * 3 * 4", "2 * 3 * 4"},
+		{"snake_case untouched", "use snake_case_name here", "use snake_case_name here"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (PlainTextFormatter{}).Format(tt.in); got != tt.want {
+				t.Errorf("Format(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOneBotFormatter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"strips markdown", "**Done**: `ok`", "Done: ok"},
+		{"escapes cq syntax", "[CQ:at,qq=all] & more", "&#91;CQ:at,qq=all&#93; &amp; more"},
+		{"escapes code", "`[x]`", "&#91;x&#93;"},
+		{"image becomes cq segment", "look ![cat](https://x/cat.png?a=1,b=2)", "look [CQ:image,file=https://x/cat.png?a=1&#44;b=2]"},
+		{"base64 image becomes cq segment", "![image](base64://aGVsbG8=)", "[CQ:image,file=base64://aGVsbG8=]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := oneBotFormatter.Format(tt.in); got != tt.want {
+				t.Errorf("Format(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBaseChannel_FormatOutbound(t *testing.T) {
+	c := NewBaseChannel("test", nil, nil, nil)
+	if got := c.FormatOutbound("**hi**"); got != "**hi**" {
+		t.Errorf("Default formatter should pass through, got %q", got)
+	}
+	c.SetFormatter(PlainTextFormatter{})
+	if got := c.FormatOutbound("**hi**"); got != "hi" {
+		t.Errorf("Expected plain text, got %q", got)
+	}
+	c.SetFormatter(nil)
+	if got := c.FormatOutbound("**hi**"); got != "**hi**" {
+		t.Errorf("nil formatter should restore passthrough, got %q", got)
+	}
+}
@@ -272,6 +272,13 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 				continue
 			}
 
+			// Reasoning is a debugging aid; drop it unless the channel opted in
+			if msg.Reasoning {
+				if rc, ok := channel.(interface{ ShowsReasoning() bool }); !ok || !rc.ShowsReasoning() {
+					continue
+				}
+			}
+
 			if err := channel.Send(ctx, msg); err != nil {
 				logger.ErrorCF("channels", "Error sending message to channel", map[string]interface{}{
 					"channel": msg.Channel,
@@ -326,6 +333,19 @@ func (m *Manager) UnregisterChannel(name string) {
 	delete(m.channels, name)
 }
 
+// SetIndicator shows or clears an activity indicator on the named channel.
+func (m *Manager) SetIndicator(ctx context.Context, channelName, chatID string, kind bus.IndicatorKind) error {
+	m.mu.RLock()
+	channel, exists := m.channels[channelName]
+	m.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("channel %s not found", channelName)
+	}
+
+	return channel.Indicator(ctx, chatID, kind)
+}
+
 func (m *Manager) SendToChannel(ctx context.Context, channelName, chatID, content string) error {
 	m.mu.RLock()
 	channel, exists := m.channels[channelName]
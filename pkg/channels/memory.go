@@ -0,0 +1,110 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+// IndicatorEvent is an Indicator call recorded by InMemoryChannel.
+type IndicatorEvent struct {
+	ChatID string
+	Kind   bus.IndicatorKind
+}
+
+// InMemoryChannel is a Channel that keeps outbound traffic in memory instead
+// of talking to a chat service, so the agent can be tested end to end
+// without a network. Inject delivers an inbound message through the same
+// HandleMessage path a real channel uses; Sent and WaitForSent return what
+// was sent back.
+type InMemoryChannel struct {
+	*BaseChannel
+
+	mu         sync.Mutex
+	sent       []bus.OutboundMessage
+	indicators []IndicatorEvent
+	changed    chan struct{} // closed and replaced on every Send
+}
+
+func NewInMemoryChannel(name string, messageBus *bus.MessageBus, allowList []string) *InMemoryChannel {
+	return &InMemoryChannel{
+		BaseChannel: NewBaseChannel(name, nil, messageBus, allowList),
+		changed:     make(chan struct{}),
+	}
+}
+
+func (c *InMemoryChannel) Start(ctx context.Context) error {
+	c.setRunning(true)
+	return nil
+}
+
+func (c *InMemoryChannel) Stop(ctx context.Context) error {
+	c.setRunning(false)
+	return nil
+}
+
+// Send records msg with its content passed through the channel formatter.
+func (c *InMemoryChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("%s channel not running", c.Name())
+	}
+	msg.Content = c.FormatOutbound(msg.Content)
+
+	c.mu.Lock()
+	c.sent = append(c.sent, msg)
+	close(c.changed)
+	c.changed = make(chan struct{})
+	c.mu.Unlock()
+	return nil
+}
+
+// Indicator records the call; it never fails.
+func (c *InMemoryChannel) Indicator(ctx context.Context, chatID string, kind bus.IndicatorKind) error {
+	c.mu.Lock()
+	c.indicators = append(c.indicators, IndicatorEvent{ChatID: chatID, Kind: kind})
+	c.mu.Unlock()
+	return nil
+}
+
+// Inject delivers an inbound message as if senderID wrote content in chatID.
+// Senders outside the allow list are dropped, as on a real channel.
+func (c *InMemoryChannel) Inject(senderID, chatID, content string) {
+	c.HandleMessage(senderID, chatID, content, nil, nil)
+}
+
+// Sent returns a copy of the messages sent so far.
+func (c *InMemoryChannel) Sent() []bus.OutboundMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]bus.OutboundMessage(nil), c.sent...)
+}
+
+// Indicators returns a copy of the indicator calls made so far.
+func (c *InMemoryChannel) Indicators() []IndicatorEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]IndicatorEvent(nil), c.indicators...)
+}
+
+// WaitForSent blocks until at least n messages have been sent or ctx is
+// done, and returns the messages sent so far.
+func (c *InMemoryChannel) WaitForSent(ctx context.Context, n int) ([]bus.OutboundMessage, error) {
+	for {
+		c.mu.Lock()
+		if len(c.sent) >= n {
+			sent := append([]bus.OutboundMessage(nil), c.sent...)
+			c.mu.Unlock()
+			return sent, nil
+		}
+		changed := c.changed
+		c.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return c.Sent(), fmt.Errorf("waiting for %d sent message(s): %w", n, ctx.Err())
+		}
+	}
+}
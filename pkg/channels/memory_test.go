@@ -0,0 +1,84 @@
+package channels
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// Compile-time check that InMemoryChannel satisfies the channel contract.
+var _ Channel = (*InMemoryChannel)(nil)
+
+func TestInMemoryChannel_InjectPublishesInbound(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	ch := NewInMemoryChannel("mem", msgBus, []string{"alice"})
+
+	ch.Inject("mallory", "chat1", "ignored")
+	ch.Inject("alice", "chat1", "hello")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no inbound message published")
+	}
+	if msg.Channel != "mem" || msg.SenderID != "alice" || msg.ChatID != "chat1" || msg.Content != "hello" {
+		t.Errorf("unexpected inbound message: %+v", msg)
+	}
+	if msg.SessionKey != "mem:chat1" {
+		t.Errorf("SessionKey = %q, want mem:chat1", msg.SessionKey)
+	}
+}
+
+func TestInMemoryChannel_SendRequiresRunning(t *testing.T) {
+	ch := NewInMemoryChannel("mem", bus.NewMessageBus(), nil)
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "c", Content: "x"}); err == nil {
+		t.Fatal("Send() before Start succeeded")
+	}
+}
+
+func TestInMemoryChannel_ManagerDispatch(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	mgr, err := NewManager(&config.Config{}, msgBus)
+	if err != nil {
+		t.Fatalf("NewManager() error: %v", err)
+	}
+	ch := NewInMemoryChannel("mem", msgBus, nil)
+	ch.SetFormatter(PlainTextFormatter{})
+	mgr.RegisterChannel("mem", ch)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := mgr.StartAll(ctx); err != nil {
+		t.Fatalf("StartAll() error: %v", err)
+	}
+	defer mgr.StopAll(context.Background())
+
+	if err := mgr.SetIndicator(ctx, "mem", "chat1", bus.IndicatorTyping); err != nil {
+		t.Fatalf("SetIndicator() error: %v", err)
+	}
+	msgBus.PublishOutbound(bus.OutboundMessage{Channel: "mem", ChatID: "chat1", Content: "**done**"})
+
+	sent, err := ch.WaitForSent(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent[0].ChatID != "chat1" || sent[0].Content != "done" {
+		t.Errorf("unexpected sent message: %+v", sent[0])
+	}
+	if got := ch.Indicators(); len(got) != 1 || got[0] != (IndicatorEvent{ChatID: "chat1", Kind: bus.IndicatorTyping}) {
+		t.Errorf("unexpected indicators: %+v", got)
+	}
+}
+
+func TestInMemoryChannel_WaitForSentTimeout(t *testing.T) {
+	ch := NewInMemoryChannel("mem", bus.NewMessageBus(), nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := ch.WaitForSent(ctx, 1); err == nil {
+		t.Fatal("WaitForSent() returned without messages or error")
+	}
+}
@@ -2,11 +2,16 @@ package channels
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -14,20 +19,61 @@ import (
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
+// oneBotConn is the part of *websocket.Conn the channel uses, so tests can
+// substitute an in-memory connection.
+type oneBotConn interface {
+	ReadMessage() (messageType int, p []byte, err error)
+	WriteMessage(messageType int, data []byte) error
+	SetPongHandler(h func(appData string) error)
+	Close() error
+}
+
+// oneBotDialer opens a connection to the OneBot implementation.
+type oneBotDialer func(url string, header http.Header) (oneBotConn, error)
+
+// dialOneBotWebSocket is the default oneBotDialer.
+func dialOneBotWebSocket(url string, header http.Header) (oneBotConn, error) {
+	dialer := *websocket.DefaultDialer
+	dialer.HandshakeTimeout = 10 * time.Second
+	conn, _, err := dialer.Dial(url, header)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
 type OneBotChannel struct {
 	*BaseChannel
-	config      config.OneBotConfig
-	conn        *websocket.Conn
-	ctx         context.Context
-	cancel      context.CancelFunc
-	dedup       map[string]struct{}
-	dedupRing   []string
-	dedupIdx    int
-	mu          sync.Mutex
-	writeMu     sync.Mutex
-	echoCounter int64
+	config config.OneBotConfig
+	dial   oneBotDialer
+	conn   oneBotConn
+	// reconnectInterval is the wait between reconnect attempts; at least 5s
+	// outside tests.
+	reconnectInterval time.Duration
+	// broadcastInterval spaces the sends of SendBroadcast
+	broadcastInterval time.Duration
+	ctx               context.Context
+	cancel            context.CancelFunc
+	dedup             map[string]struct{}
+	dedupRing         []string
+	dedupIdx          int
+	mu                sync.Mutex
+	writeMu           sync.Mutex
+	echoCounter       int64
+	// Idle disconnect (config.IdleTimeout): idle is set, under mu, while the
+	// connection is closed on purpose; the next Send reconnects.
+	idle         bool
+	wakeMu       sync.Mutex   // serializes on-demand reconnects
+	lastActivity atomic.Int64 // unix nanoseconds of the last message in or out
+	// pending holds CallAction waiters keyed by echo
+	pendingMu sync.Mutex
+	pending   map[string]chan oneBotAPIResponse
+	// Keep-alive pings (config.PingInterval), 0 = none
+	pingInterval    time.Duration
+	unansweredPings atomic.Int32 // pings sent on the current connection since the last pong
 }
 
 type oneBotRawEvent struct {
@@ -37,10 +83,13 @@ type oneBotRawEvent struct {
 	MessageID     json.RawMessage `json:"message_id"`
 	UserID        json.RawMessage `json:"user_id"`
 	GroupID       json.RawMessage `json:"group_id"`
+	GuildID       json.RawMessage `json:"guild_id"`
+	ChannelID     json.RawMessage `json:"channel_id"`
 	RawMessage    string          `json:"raw_message"`
 	Message       json.RawMessage `json:"message"`
 	Sender        json.RawMessage `json:"sender"`
 	SelfID        json.RawMessage `json:"self_id"`
+	SelfTinyID    json.RawMessage `json:"self_tiny_id"` // the bot's ID inside guilds
 	Time          json.RawMessage `json:"time"`
 	MetaEventType string          `json:"meta_event_type"`
 	Echo          string          `json:"echo"`
@@ -57,8 +106,18 @@ type oneBotSender struct {
 	UserID   json.RawMessage `json:"user_id"`
 	Nickname string          `json:"nickname"`
 	Card     string          `json:"card"`
+	Role     string          `json:"role"`  // group messages: "owner", "admin" or "member"
+	Title    string          `json:"title"` // group messages: special title granted by the owner
 }
 
+// Private chat trigger modes for OneBotConfig.PrivateTrigger. Group messages
+// always behave like oneBotTriggerPrefix.
+const (
+	oneBotTriggerAlways  = "always"  // every message
+	oneBotTriggerPrefix  = "prefix"  // an @mention or a group_trigger_prefix
+	oneBotTriggerMention = "mention" // an @mention only
+)
+
 type oneBotEvent struct {
 	PostType       string
 	MessageType    string
@@ -66,9 +125,12 @@ type oneBotEvent struct {
 	MessageID      string
 	UserID         int64
 	GroupID        int64
+	GuildID        string
+	ChannelID      string
 	Content        string
 	RawContent     string
 	IsBotMentioned bool
+	ReplyToID      string
 	Sender         oneBotSender
 	SelfID         int64
 	Time           int64
@@ -81,6 +143,27 @@ type oneBotAPIRequest struct {
 	Echo   string      `json:"echo,omitempty"`
 }
 
+// oneBotAPIResponse is the reply to an action request. Status is "ok" or
+// "failed" here, unlike the status object in heartbeat events.
+type oneBotAPIResponse struct {
+	Status  json.RawMessage `json:"status"`
+	RetCode int             `json:"retcode"`
+	Data    json.RawMessage `json:"data"`
+	Message string          `json:"message"`
+	Wording string          `json:"wording"`
+	Echo    string          `json:"echo"`
+}
+
+// Reasoning messages are headed so they read apart from the answer, and
+// capped since reasoning often runs far longer than a chat message should.
+const (
+	oneBotReasoningHeader   = "💭 Reasoning:\n"
+	oneBotMaxReasoningRunes = 3000
+)
+
+// oneBotActionTimeout bounds how long CallAction waits for a response.
+const oneBotActionTimeout = 10 * time.Second
+
 type oneBotSendPrivateMsgParams struct {
 	UserID  int64  `json:"user_id"`
 	Message string `json:"message"`
@@ -91,16 +174,65 @@ type oneBotSendGroupMsgParams struct {
 	Message string `json:"message"`
 }
 
+type oneBotSendGuildChannelMsgParams struct {
+	GuildID   string `json:"guild_id"`
+	ChannelID string `json:"channel_id"`
+	Message   string `json:"message"`
+}
+
+// set_input_status event types
+const (
+	oneBotInputSpeaking = 0
+	oneBotInputTyping   = 1
+)
+
+type oneBotSetInputStatusParams struct {
+	UserID    int64 `json:"user_id"`
+	EventType int   `json:"event_type"`
+}
+
+// oneBotGetMsgTimeout bounds how long an inbound reply waits for the message
+// it quotes. Backends without get_msg may never answer, so it is kept short.
+const oneBotGetMsgTimeout = 3 * time.Second
+
+type oneBotGetMsgParams struct {
+	MessageID interface{} `json:"message_id"`
+}
+
+type oneBotGetMsgData struct {
+	Sender     oneBotSender    `json:"sender"`
+	Message    json.RawMessage `json:"message"`
+	RawMessage string          `json:"raw_message"`
+}
+
 func NewOneBotChannel(cfg config.OneBotConfig, messageBus *bus.MessageBus) (*OneBotChannel, error) {
+	switch cfg.PrivateTrigger {
+	case "", oneBotTriggerAlways, oneBotTriggerPrefix, oneBotTriggerMention:
+	default:
+		return nil, fmt.Errorf("invalid OneBot private_trigger %q (valid: always, prefix, mention)", cfg.PrivateTrigger)
+	}
+
 	base := NewBaseChannel("onebot", cfg, messageBus, cfg.AllowFrom)
+	base.SetFormatter(newOneBotFormatter(cfg.StripImageMetadata))
+	base.SetShowReasoning(cfg.ShowReasoning)
+	base.SetThinkTime(time.Duration(cfg.ThinkTimeMs) * time.Millisecond)
+
+	reconnectInterval := time.Duration(cfg.ReconnectInterval) * time.Second
+	if reconnectInterval < 5*time.Second {
+		reconnectInterval = 5 * time.Second
+	}
 
 	const dedupSize = 1024
 	return &OneBotChannel{
-		BaseChannel: base,
-		config:      cfg,
-		dedup:       make(map[string]struct{}, dedupSize),
-		dedupRing:   make([]string, dedupSize),
-		dedupIdx:    0,
+		BaseChannel:       base,
+		config:            cfg,
+		dial:              dialOneBotWebSocket,
+		reconnectInterval: reconnectInterval,
+		broadcastInterval: oneBotBroadcastInterval,
+		pingInterval:      time.Duration(cfg.PingInterval) * time.Second,
+		dedup:             make(map[string]struct{}, dedupSize),
+		dedupRing:         make([]string, dedupSize),
+		dedupIdx:          0,
 	}, nil
 }
 
@@ -123,6 +255,14 @@ func (c *OneBotChannel) Start(ctx context.Context) error {
 		go c.listen()
 	}
 
+	if c.config.IdleTimeout > 0 {
+		go c.idleLoop(time.Duration(c.config.IdleTimeout) * time.Minute)
+	}
+
+	if c.pingInterval > 0 {
+		go c.pingLoop(c.pingInterval)
+	}
+
 	if c.config.ReconnectInterval > 0 {
 		go c.reconnectLoop()
 	} else {
@@ -139,43 +279,184 @@ func (c *OneBotChannel) Start(ctx context.Context) error {
 }
 
 func (c *OneBotChannel) connect() error {
-	dialer := websocket.DefaultDialer
-	dialer.HandshakeTimeout = 10 * time.Second
-
-	header := make(map[string][]string)
+	header := make(http.Header)
 	if c.config.AccessToken != "" {
 		header["Authorization"] = []string{"Bearer " + c.config.AccessToken}
 	}
 
-	conn, _, err := dialer.Dial(c.config.WSUrl, header)
+	conn, err := c.dial(c.config.WSUrl, header)
 	if err != nil {
 		return err
 	}
 
+	// The handler runs inside ReadMessage, on the listener goroutine
+	c.unansweredPings.Store(0)
+	conn.SetPongHandler(func(string) error {
+		c.unansweredPings.Store(0)
+		return nil
+	})
+
 	c.mu.Lock()
 	c.conn = conn
+	c.idle = false
 	c.mu.Unlock()
+	c.markActivity()
 
 	logger.InfoC("onebot", "WebSocket connected")
 	return nil
 }
 
-func (c *OneBotChannel) reconnectLoop() {
-	interval := time.Duration(c.config.ReconnectInterval) * time.Second
-	if interval < 5*time.Second {
-		interval = 5 * time.Second
+// markActivity records inbound or outbound traffic for the idle timeout.
+func (c *OneBotChannel) markActivity() {
+	c.lastActivity.Store(time.Now().UnixNano())
+}
+
+// idleLoop closes the connection once nothing has been sent or received for
+// timeout. Heartbeats do not count as activity. The reconnect loop leaves an
+// idle channel alone; the next Send reconnects it.
+func (c *OneBotChannel) idleLoop(timeout time.Duration) {
+	check := timeout / 4
+	if check > time.Minute {
+		check = time.Minute
+	}
+	ticker := time.NewTicker(check)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			c.disconnectIfIdle(timeout)
+		}
+	}
+}
+
+// disconnectIfIdle closes the connection if it has been quiet for timeout.
+func (c *OneBotChannel) disconnectIfIdle(timeout time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil || time.Since(time.Unix(0, c.lastActivity.Load())) < timeout {
+		return false
+	}
+	logger.InfoCF("onebot", "Idle timeout reached, disconnecting until the next send", map[string]interface{}{
+		"idle_timeout": timeout.String(),
+	})
+	c.idle = true
+	c.conn.Close()
+	c.conn = nil
+	return true
+}
+
+// oneBotMissedPongs is how many pings may go unanswered before the
+// connection is considered dead.
+const oneBotMissedPongs = 2
+
+// pingLoop writes a ping frame every interval so proxies that drop idle
+// WebSockets see traffic. Pings do not count as activity for the idle
+// timeout, and while the channel is disconnected nothing is sent.
+func (c *OneBotChannel) pingLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			c.ping(interval)
+		}
+	}
+}
+
+// ping sends one ping frame, or drops the connection for the reconnect loop
+// to replace when earlier pings went unanswered.
+func (c *OneBotChannel) ping(interval time.Duration) {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn == nil {
+		return
+	}
+
+	if missed := c.unansweredPings.Load(); missed >= oneBotMissedPongs {
+		logger.WarnCF("onebot", "No pong received, reconnecting", map[string]interface{}{
+			"unanswered_pings": missed,
+			"ping_interval":    interval.String(),
+		})
+		c.dropConn(conn)
+		return
+	}
+
+	c.unansweredPings.Add(1)
+	c.writeMu.Lock()
+	err := conn.WriteMessage(websocket.PingMessage, nil)
+	c.writeMu.Unlock()
+	if err != nil {
+		logger.WarnCF("onebot", "Failed to send ping", map[string]interface{}{
+			"error": err.Error(),
+		})
+		c.dropConn(conn)
+	}
+}
+
+// isIdle reports whether the connection was closed by the idle timeout.
+func (c *OneBotChannel) isIdle() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.idle
+}
+
+// activeConn returns the current connection, reconnecting first if the idle
+// timeout closed it.
+func (c *OneBotChannel) activeConn() (oneBotConn, error) {
+	c.mu.Lock()
+	conn, idle := c.conn, c.idle
+	c.mu.Unlock()
+	if conn != nil {
+		return conn, nil
+	}
+	if !idle {
+		return nil, fmt.Errorf("OneBot WebSocket not connected")
+	}
+
+	c.wakeMu.Lock()
+	defer c.wakeMu.Unlock()
+
+	// Another sender may have reconnected while we waited
+	c.mu.Lock()
+	conn = c.conn
+	c.mu.Unlock()
+	if conn != nil {
+		return conn, nil
 	}
 
+	logger.InfoC("onebot", "Reconnecting after idle disconnect")
+	if err := c.connect(); err != nil {
+		return nil, fmt.Errorf("OneBot reconnect after idle failed: %w", err)
+	}
+	go c.listen()
+
+	c.mu.Lock()
+	conn = c.conn
+	c.mu.Unlock()
+	if conn == nil {
+		return nil, fmt.Errorf("OneBot WebSocket not connected")
+	}
+	return conn, nil
+}
+
+func (c *OneBotChannel) reconnectLoop() {
 	for {
 		select {
 		case <-c.ctx.Done():
 			return
-		case <-time.After(interval):
+		case <-time.After(c.reconnectInterval):
 			c.mu.Lock()
-			conn := c.conn
+			conn, idle := c.conn, c.idle
 			c.mu.Unlock()
 
-			if conn == nil {
+			if conn == nil && !idle {
 				logger.InfoC("onebot", "Attempting to reconnect...")
 				if err := c.connect(); err != nil {
 					logger.ErrorCF("onebot", "Reconnect failed", map[string]interface{}{
@@ -212,24 +493,224 @@ func (c *OneBotChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		return fmt.Errorf("OneBot channel not running")
 	}
 
-	c.mu.Lock()
-	conn := c.conn
-	c.mu.Unlock()
+	action, params, err := c.buildSendRequest(msg)
+	if err != nil {
+		return err
+	}
 
-	if conn == nil {
-		return fmt.Errorf("OneBot WebSocket not connected")
+	// Replies held by the think time are queued per chat, so one busy chat
+	// does not hold up the outbound dispatcher for everyone else.
+	return c.SendAfterThinkTime(ctx, msg.ChatID, func() error {
+		c.markActivity()
+		conn, err := c.activeConn()
+		if err != nil {
+			return err
+		}
+
+		if err := c.sendAPIRequest(conn, action, params, "send"); err != nil {
+			logger.ErrorCF("onebot", "Failed to send message", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return err
+		}
+		return nil
+	})
+}
+
+// oneBotBroadcastInterval is the pause between targets of SendBroadcast, so
+// an announcement to many chats does not trip QQ's spam detection.
+const oneBotBroadcastInterval = 500 * time.Millisecond
+
+// BroadcastResult reports which targets of SendBroadcast received the
+// message and why the others did not.
+type BroadcastResult struct {
+	Sent   []string
+	Failed map[string]error
+}
+
+// SendBroadcast sends content to each chat ID ("group:<id>", "private:<id>"
+// or "guild:<guild>:<channel>") in turn through Send. A failed target does
+// not stop the others; once ctx ends, the remaining targets fail with its
+// error.
+func (c *OneBotChannel) SendBroadcast(ctx context.Context, chatIDs []string, content string) BroadcastResult {
+	result := BroadcastResult{Failed: make(map[string]error)}
+	for i, chatID := range chatIDs {
+		if i > 0 && c.broadcastInterval > 0 {
+			select {
+			case <-time.After(c.broadcastInterval):
+			case <-ctx.Done():
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			result.Failed[chatID] = err
+			continue
+		}
+
+		err := c.Send(ctx, bus.OutboundMessage{Channel: c.Name(), ChatID: chatID, Content: content})
+		if err != nil {
+			result.Failed[chatID] = err
+			continue
+		}
+		result.Sent = append(result.Sent, chatID)
 	}
 
-	action, params, err := c.buildSendRequest(msg)
+	logger.InfoCF("onebot", "Broadcast finished", map[string]interface{}{
+		"targets": len(chatIDs),
+		"sent":    len(result.Sent),
+		"failed":  len(result.Failed),
+	})
+	return result
+}
+
+// Indicator shows the "typing" or "speaking" status in private chats through
+// the set_input_status extension (NapCat, LLOneBot). QQ has no group typing
+// status and clears the indicator by itself once a reply arrives, so groups
+// and IndicatorNone are no-ops. Guild channels have no input status either.
+func (c *OneBotChannel) Indicator(ctx context.Context, chatID string, kind bus.IndicatorKind) error {
+	var eventType int
+	switch kind {
+	case bus.IndicatorTyping:
+		eventType = oneBotInputTyping
+	case bus.IndicatorRecording:
+		eventType = oneBotInputSpeaking
+	default:
+		return nil
+	}
+
+	if !c.IsRunning() || strings.HasPrefix(chatID, "group:") || strings.HasPrefix(chatID, "guild:") {
+		return nil
+	}
+
+	userID, err := strconv.ParseInt(strings.TrimPrefix(chatID, "private:"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid chatID for OneBot: %s", chatID)
+	}
+
+	conn, err := c.activeConn()
 	if err != nil {
 		return err
 	}
 
+	return c.sendAPIRequest(conn, "set_input_status", oneBotSetInputStatusParams{
+		UserID:    userID,
+		EventType: eventType,
+	}, "status")
+}
+
+// CallAction runs a OneBot API action, such as set_group_ban, and waits for
+// its response. It returns the response data, or an error if the action
+// failed or no response arrived in time.
+func (c *OneBotChannel) CallAction(ctx context.Context, action string, params interface{}) (json.RawMessage, error) {
+	if !c.IsRunning() {
+		return nil, fmt.Errorf("OneBot channel not running")
+	}
+
+	c.markActivity()
+	conn, err := c.activeConn()
+	if err != nil {
+		return nil, err
+	}
+
+	echo := c.nextEcho("call")
+	respCh := make(chan oneBotAPIResponse, 1)
+	c.pendingMu.Lock()
+	if c.pending == nil {
+		c.pending = make(map[string]chan oneBotAPIResponse)
+	}
+	c.pending[echo] = respCh
+	c.pendingMu.Unlock()
+	defer func() {
+		c.pendingMu.Lock()
+		delete(c.pending, echo)
+		c.pendingMu.Unlock()
+	}()
+
+	if err := c.writeAPIRequest(conn, action, params, echo); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(oneBotActionTimeout)
+	defer timer.Stop()
+
+	select {
+	case resp := <-respCh:
+		if resp.RetCode != 0 {
+			reason := resp.Wording
+			if reason == "" {
+				reason = resp.Message
+			}
+			return nil, fmt.Errorf("OneBot %s failed (retcode %d): %s", action, resp.RetCode, reason)
+		}
+		return resp.Data, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("OneBot %s: no response after %s", action, oneBotActionTimeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// GetMessage fetches a message by ID with the get_msg action and returns its
+// sender's display name and its text, rendered like inbound messages.
+func (c *OneBotChannel) GetMessage(ctx context.Context, messageID string) (sender, content string, err error) {
+	// message_id is numeric in OneBot 11; some implementations use strings
+	var id interface{} = messageID
+	if n, err := strconv.ParseInt(messageID, 10, 64); err == nil {
+		id = n
+	}
+
+	data, err := c.CallAction(ctx, "get_msg", oneBotGetMsgParams{MessageID: id})
+	if err != nil {
+		return "", "", err
+	}
+
+	var msg oneBotGetMsgData
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return "", "", fmt.Errorf("parse get_msg response: %w", err)
+	}
+
+	rendered := parseMessageContentEx(msg.Message, 0)
+	if msg.RawMessage != "" {
+		rendered = renderCQSegments(parseCQString(msg.RawMessage), 0)
+	}
+
+	sender = msg.Sender.Card
+	if sender == "" {
+		sender = msg.Sender.Nickname
+	}
+	return sender, rendered.Text, nil
+}
+
+// deliverResponse hands an action response to the CallAction waiting for
+// its echo. It reports false when nobody is waiting.
+func (c *OneBotChannel) deliverResponse(resp oneBotAPIResponse) bool {
+	if resp.Echo == "" {
+		return false
+	}
+	c.pendingMu.Lock()
+	respCh, ok := c.pending[resp.Echo]
+	c.pendingMu.Unlock()
+	if ok {
+		respCh <- resp
+	}
+	return ok
+}
+
+// nextEcho returns a unique echo value; prefix tags it so responses can be
+// told apart in logs.
+func (c *OneBotChannel) nextEcho(prefix string) string {
 	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
 	c.echoCounter++
-	echo := fmt.Sprintf("send_%d", c.echoCounter)
-	c.writeMu.Unlock()
+	return fmt.Sprintf("%s_%d", prefix, c.echoCounter)
+}
 
+// sendAPIRequest writes an action request to the connection without waiting
+// for the response.
+func (c *OneBotChannel) sendAPIRequest(conn oneBotConn, action string, params interface{}, echoPrefix string) error {
+	return c.writeAPIRequest(conn, action, params, c.nextEcho(echoPrefix))
+}
+
+func (c *OneBotChannel) writeAPIRequest(conn oneBotConn, action string, params interface{}, echo string) error {
 	req := oneBotAPIRequest{
 		Action: action,
 		Params: params,
@@ -246,17 +727,103 @@ func (c *OneBotChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 	c.writeMu.Unlock()
 
 	if err != nil {
-		logger.ErrorCF("onebot", "Failed to send message", map[string]interface{}{
-			"error": err.Error(),
-		})
+		// A failed write means the socket is unusable; drop it so the
+		// reconnect loop replaces it instead of waiting for a read error
+		c.dropConn(conn)
 		return err
 	}
 
 	return nil
 }
 
+// dropConn closes conn and clears it if it is still the active connection.
+// A connection that was already replaced by a reconnect is left alone.
+func (c *OneBotChannel) dropConn(conn oneBotConn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	conn.Close()
+	if c.conn == conn {
+		c.conn = nil
+	}
+}
+
+// oneBotFormatter strips Markdown, which QQ clients show literally, escapes
+// CQ code syntax so model output cannot inject segments such as @all, and
+// sends Markdown images as CQ image segments.
+var oneBotFormatter = PlainTextFormatter{
+	Escape: escapeCQText,
+	Image: func(alt, url string) string {
+		return "[CQ:image,file=" + escapeCQParam(url) + "]"
+	},
+}
+
+// newOneBotFormatter returns oneBotFormatter, with local image files sent as
+// copies without EXIF and other metadata when stripMetadata is set. Remote
+// image URLs are fetched by the OneBot implementation and left alone. An
+// image whose metadata cannot be removed is not sent.
+func newOneBotFormatter(stripMetadata bool) PlainTextFormatter {
+	if !stripMetadata {
+		return oneBotFormatter
+	}
+	f := oneBotFormatter
+	f.Image = func(alt, url string) string {
+		path, ok := oneBotLocalFile(url)
+		if !ok {
+			return oneBotFormatter.Image(alt, url)
+		}
+		clean, err := utils.StripImageFileMetadata(path)
+		if err != nil {
+			logger.WarnCF("onebot", "Could not strip image metadata, not sending image", map[string]interface{}{
+				"path":  path,
+				"error": err.Error(),
+			})
+			if alt == "" {
+				alt = "image"
+			}
+			return escapeCQText("[" + alt + "]")
+		}
+		if clean == path {
+			return oneBotFormatter.Image(alt, url)
+		}
+		return oneBotFormatter.Image(alt, "file://"+clean)
+	}
+	return f
+}
+
+// oneBotLocalFile returns the path of a file:// URL or absolute path.
+func oneBotLocalFile(url string) (string, bool) {
+	if path, ok := strings.CutPrefix(url, "file://"); ok {
+		return path, true
+	}
+	if filepath.IsAbs(url) {
+		return url, true
+	}
+	return "", false
+}
+
+// escapeCQText escapes the characters OneBot treats as CQ code syntax in
+// plain message text.
+func escapeCQText(s string) string {
+	return cqTextEscaper.Replace(s)
+}
+
+// escapeCQParam escapes a CQ code parameter value, which also may not
+// contain commas.
+func escapeCQParam(s string) string {
+	return cqParamEscaper.Replace(s)
+}
+
+var (
+	cqTextEscaper  = strings.NewReplacer("&", "&amp;", "[", "&#91;", "]", "&#93;")
+	cqParamEscaper = strings.NewReplacer("&", "&amp;", "[", "&#91;", "]", "&#93;", ",", "&#44;")
+)
+
 func (c *OneBotChannel) buildSendRequest(msg bus.OutboundMessage) (string, interface{}, error) {
 	chatID := msg.ChatID
+	content := c.FormatOutbound(msg.Content)
+	if msg.Reasoning {
+		content = oneBotReasoningHeader + utils.Truncate(content, oneBotMaxReasoningRunes)
+	}
 
 	if len(chatID) > 6 && chatID[:6] == "group:" {
 		groupID, err := strconv.ParseInt(chatID[6:], 10, 64)
@@ -265,7 +832,19 @@ func (c *OneBotChannel) buildSendRequest(msg bus.OutboundMessage) (string, inter
 		}
 		return "send_group_msg", oneBotSendGroupMsgParams{
 			GroupID: groupID,
-			Message: msg.Content,
+			Message: content,
+		}, nil
+	}
+
+	if rest, ok := strings.CutPrefix(chatID, "guild:"); ok {
+		guildID, channelID, _ := strings.Cut(rest, ":")
+		if guildID == "" || channelID == "" {
+			return "", nil, fmt.Errorf("invalid guild chatID (want guild:<guild_id>:<channel_id>): %s", chatID)
+		}
+		return "send_guild_channel_msg", oneBotSendGuildChannelMsgParams{
+			GuildID:   guildID,
+			ChannelID: channelID,
+			Message:   content,
 		}, nil
 	}
 
@@ -276,7 +855,7 @@ func (c *OneBotChannel) buildSendRequest(msg bus.OutboundMessage) (string, inter
 		}
 		return "send_private_msg", oneBotSendPrivateMsgParams{
 			UserID:  userID,
-			Message: msg.Content,
+			Message: content,
 		}, nil
 	}
 
@@ -287,7 +866,7 @@ func (c *OneBotChannel) buildSendRequest(msg bus.OutboundMessage) (string, inter
 
 	return "send_private_msg", oneBotSendPrivateMsgParams{
 		UserID:  userID,
-		Message: msg.Content,
+		Message: content,
 	}, nil
 }
 
@@ -308,15 +887,14 @@ func (c *OneBotChannel) listen() {
 
 			_, message, err := conn.ReadMessage()
 			if err != nil {
+				if c.isIdle() {
+					logger.DebugC("onebot", "Listener exiting after idle disconnect")
+					return
+				}
 				logger.ErrorCF("onebot", "WebSocket read error", map[string]interface{}{
 					"error": err.Error(),
 				})
-				c.mu.Lock()
-				if c.conn != nil {
-					c.conn.Close()
-					c.conn = nil
-				}
-				c.mu.Unlock()
+				c.dropConn(conn)
 				return
 			}
 
@@ -325,6 +903,11 @@ func (c *OneBotChannel) listen() {
 				"payload": string(message),
 			})
 
+			var resp oneBotAPIResponse
+			if json.Unmarshal(message, &resp) == nil && c.deliverResponse(resp) {
+				continue
+			}
+
 			var raw oneBotRawEvent
 			if err := json.Unmarshal(message, &raw); err != nil {
 				logger.WarnCF("onebot", "Failed to unmarshal raw event", map[string]interface{}{
@@ -386,6 +969,7 @@ func parseJSONString(raw json.RawMessage) string {
 type parseMessageResult struct {
 	Text           string
 	IsBotMentioned bool
+	ReplyToID      string
 }
 
 func parseMessageContentEx(raw json.RawMessage, selfID int64) parseMessageResult {
@@ -395,48 +979,21 @@ func parseMessageContentEx(raw json.RawMessage, selfID int64) parseMessageResult
 
 	var s string
 	if err := json.Unmarshal(raw, &s); err == nil {
-		mentioned := false
-		if selfID > 0 {
-			cqAt := fmt.Sprintf("[CQ:at,qq=%d]", selfID)
-			if strings.Contains(s, cqAt) {
-				mentioned = true
-				s = strings.ReplaceAll(s, cqAt, "")
-				s = strings.TrimSpace(s)
-			}
-		}
-		return parseMessageResult{Text: s, IsBotMentioned: mentioned}
+		return renderCQSegments(parseCQString(s), selfID)
 	}
 
 	var segments []map[string]interface{}
 	if err := json.Unmarshal(raw, &segments); err == nil {
-		var text string
-		mentioned := false
-		selfIDStr := strconv.FormatInt(selfID, 10)
-		for _, seg := range segments {
-			segType, _ := seg["type"].(string)
-			data, _ := seg["data"].(map[string]interface{})
-			switch segType {
-			case "text":
-				if data != nil {
-					if t, ok := data["text"].(string); ok {
-						text += t
-					}
-				}
-			case "at":
-				if data != nil && selfID > 0 {
-					qqVal := fmt.Sprintf("%v", data["qq"])
-					if qqVal == selfIDStr || qqVal == "all" {
-						mentioned = true
-					}
-				}
-			}
-		}
-		return parseMessageResult{Text: strings.TrimSpace(text), IsBotMentioned: mentioned}
+		return renderCQSegments(cqSegmentsFromArray(segments), selfID)
 	}
 	return parseMessageResult{}
 }
 
 func (c *OneBotChannel) handleRawEvent(raw *oneBotRawEvent) {
+	if raw.PostType != "meta_event" && raw.PostType != "" {
+		c.markActivity()
+	}
+
 	switch raw.PostType {
 	case "message":
 		evt, err := c.normalizeMessageEvent(raw)
@@ -479,19 +1036,28 @@ func (c *OneBotChannel) normalizeMessageEvent(raw *oneBotRawEvent) (*oneBotEvent
 	selfID, _ := parseJSONInt64(raw.SelfID)
 	ts, _ := parseJSONInt64(raw.Time)
 	messageID := parseJSONString(raw.MessageID)
+	guildID := parseJSONString(raw.GuildID)
+	channelID := parseJSONString(raw.ChannelID)
+
+	// Inside guilds the bot is mentioned by its tiny ID, not its QQ number
+	mentionID := selfID
+	if raw.MessageType == "guild" {
+		if tinyID, _ := parseJSONInt64(raw.SelfTinyID); tinyID > 0 {
+			mentionID = tinyID
+		}
+	}
 
-	parsed := parseMessageContentEx(raw.Message, selfID)
+	parsed := parseMessageContentEx(raw.Message, mentionID)
 	isBotMentioned := parsed.IsBotMentioned
-
-	content := raw.RawMessage
-	if content == "" {
-		content = parsed.Text
-	} else if selfID > 0 {
-		cqAt := fmt.Sprintf("[CQ:at,qq=%d]", selfID)
-		if strings.Contains(content, cqAt) {
-			isBotMentioned = true
-			content = strings.ReplaceAll(content, cqAt, "")
-			content = strings.TrimSpace(content)
+	replyTo := parsed.ReplyToID
+
+	content := parsed.Text
+	if raw.RawMessage != "" {
+		rendered := renderCQSegments(parseCQString(raw.RawMessage), mentionID)
+		content = rendered.Text
+		isBotMentioned = isBotMentioned || rendered.IsBotMentioned
+		if replyTo == "" {
+			replyTo = rendered.ReplyToID
 		}
 	}
 
@@ -509,6 +1075,7 @@ func (c *OneBotChannel) normalizeMessageEvent(raw *oneBotRawEvent) (*oneBotEvent
 		"message_type": raw.MessageType,
 		"user_id":      userID,
 		"group_id":     groupID,
+		"guild_id":     guildID,
 		"message_id":   messageID,
 		"content_len":  len(content),
 		"nickname":     sender.Nickname,
@@ -521,9 +1088,12 @@ func (c *OneBotChannel) normalizeMessageEvent(raw *oneBotRawEvent) (*oneBotEvent
 		MessageID:      messageID,
 		UserID:         userID,
 		GroupID:        groupID,
+		GuildID:        guildID,
+		ChannelID:      channelID,
 		Content:        content,
 		RawContent:     raw.RawMessage,
 		IsBotMentioned: isBotMentioned,
+		ReplyToID:      replyTo,
 		Sender:         sender,
 		SelfID:         selfID,
 		Time:           ts,
@@ -547,9 +1117,10 @@ func (c *OneBotChannel) handleMetaEvent(raw *oneBotRawEvent) {
 }
 
 func (c *OneBotChannel) handleMessage(evt *oneBotEvent) {
-	if c.isDuplicate(evt.MessageID) {
+	if c.isDuplicate(oneBotDedupKey(evt, time.Now())) {
 		logger.DebugCF("onebot", "Duplicate message, skipping", map[string]interface{}{
 			"message_id": evt.MessageID,
+			"user_id":    evt.UserID,
 		})
 		return
 	}
@@ -572,11 +1143,23 @@ func (c *OneBotChannel) handleMessage(evt *oneBotEvent) {
 	switch evt.MessageType {
 	case "private":
 		chatID = "private:" + senderID
+
+		triggered, strippedContent := c.checkTrigger(evt.MessageType, content, evt.IsBotMentioned)
+		if !triggered {
+			logger.DebugCF("onebot", "Private message ignored (no trigger)", map[string]interface{}{
+				"sender":       senderID,
+				"is_mentioned": evt.IsBotMentioned,
+				"content":      utils.Truncate(content, 100),
+			})
+			return
+		}
+		content = strippedContent
+
 		logger.InfoCF("onebot", "Received private message", map[string]interface{}{
 			"sender":     senderID,
 			"message_id": evt.MessageID,
 			"length":     len(content),
-			"content":    truncate(content, 100),
+			"content":    utils.Truncate(content, 100),
 		})
 
 	case "group":
@@ -594,14 +1177,20 @@ func (c *OneBotChannel) handleMessage(evt *oneBotEvent) {
 		} else if evt.Sender.Nickname != "" {
 			metadata["sender_name"] = evt.Sender.Nickname
 		}
+		if evt.Sender.Role != "" {
+			metadata["sender_role"] = evt.Sender.Role
+		}
+		if evt.Sender.Title != "" {
+			metadata["sender_title"] = evt.Sender.Title
+		}
 
-		triggered, strippedContent := c.checkGroupTrigger(content, evt.IsBotMentioned)
+		triggered, strippedContent := c.checkTrigger(evt.MessageType, content, evt.IsBotMentioned)
 		if !triggered {
 			logger.DebugCF("onebot", "Group message ignored (no trigger)", map[string]interface{}{
 				"sender":       senderID,
 				"group":        groupIDStr,
 				"is_mentioned": evt.IsBotMentioned,
-				"content":      truncate(content, 100),
+				"content":      utils.Truncate(content, 100),
 			})
 			return
 		}
@@ -613,7 +1202,47 @@ func (c *OneBotChannel) handleMessage(evt *oneBotEvent) {
 			"message_id":   evt.MessageID,
 			"is_mentioned": evt.IsBotMentioned,
 			"length":       len(content),
-			"content":      truncate(content, 100),
+			"content":      utils.Truncate(content, 100),
+		})
+
+	case "guild":
+		if evt.GuildID == "" || evt.ChannelID == "" {
+			logger.WarnCF("onebot", "Guild message without guild_id or channel_id, cannot route", map[string]interface{}{
+				"message_id": evt.MessageID,
+				"guild_id":   evt.GuildID,
+				"channel_id": evt.ChannelID,
+			})
+			return
+		}
+		chatID = "guild:" + evt.GuildID + ":" + evt.ChannelID
+		metadata["guild_id"] = evt.GuildID
+		metadata["channel_id"] = evt.ChannelID
+		if evt.Sender.Nickname != "" {
+			metadata["sender_name"] = evt.Sender.Nickname
+		}
+
+		// Guild messages are triggered like group messages
+		triggered, strippedContent := c.checkTrigger(evt.MessageType, content, evt.IsBotMentioned)
+		if !triggered {
+			logger.DebugCF("onebot", "Guild message ignored (no trigger)", map[string]interface{}{
+				"sender":       senderID,
+				"guild":        evt.GuildID,
+				"channel":      evt.ChannelID,
+				"is_mentioned": evt.IsBotMentioned,
+				"content":      utils.Truncate(content, 100),
+			})
+			return
+		}
+		content = strippedContent
+
+		logger.InfoCF("onebot", "Received guild message", map[string]interface{}{
+			"sender":       senderID,
+			"guild":        evt.GuildID,
+			"channel":      evt.ChannelID,
+			"message_id":   evt.MessageID,
+			"is_mentioned": evt.IsBotMentioned,
+			"length":       len(content),
+			"content":      utils.Truncate(content, 100),
 		})
 
 	default:
@@ -629,49 +1258,118 @@ func (c *OneBotChannel) handleMessage(evt *oneBotEvent) {
 		metadata["nickname"] = evt.Sender.Nickname
 	}
 
+	if evt.ReplyToID != "" {
+		metadata["reply_to_message_id"] = evt.ReplyToID
+		// get_msg is answered on the connection this goroutine reads, so the
+		// quoted message is fetched without blocking the listener
+		go c.forwardWithQuote(senderID, chatID, content, evt.ReplyToID, metadata)
+		return
+	}
+
 	logger.DebugCF("onebot", "Forwarding message to bus", map[string]interface{}{
 		"sender_id": senderID,
 		"chat_id":   chatID,
-		"content":   truncate(content, 100),
+		"content":   utils.Truncate(content, 100),
 	})
 
 	c.HandleMessage(senderID, chatID, content, []string{}, metadata)
 }
 
-func (c *OneBotChannel) isDuplicate(messageID string) bool {
-	if messageID == "" || messageID == "0" {
-		return false
+// forwardWithQuote prefixes content with the text of the message it replies
+// to, then forwards it. If the quoted message cannot be fetched in time the
+// content is forwarded unchanged.
+func (c *OneBotChannel) forwardWithQuote(senderID, chatID, content, replyTo string, metadata map[string]string) {
+	ctx, cancel := context.WithTimeout(c.ctx, oneBotGetMsgTimeout)
+	defer cancel()
+
+	quotedSender, quoted, err := c.GetMessage(ctx, replyTo)
+	switch {
+	case err != nil:
+		logger.DebugCF("onebot", "Could not fetch quoted message", map[string]interface{}{
+			"message_id": replyTo,
+			"error":      err.Error(),
+		})
+	case quoted != "":
+		if quotedSender != "" {
+			content = fmt.Sprintf("[Quoted message from %s: %s]\n%s", quotedSender, quoted, content)
+		} else {
+			content = fmt.Sprintf("[Quoted message: %s]\n%s", quoted, content)
+		}
 	}
 
+	logger.DebugCF("onebot", "Forwarding message to bus", map[string]interface{}{
+		"sender_id": senderID,
+		"chat_id":   chatID,
+		"content":   utils.Truncate(content, 100),
+	})
+
+	c.HandleMessage(senderID, chatID, content, []string{}, metadata)
+}
+
+// oneBotDedupBucket is the time window within which identical messages
+// without a message_id are treated as one.
+const oneBotDedupBucket = 10 * time.Second
+
+// oneBotDedupKey returns the key isDuplicate remembers evt by: its
+// message_id, or, for implementations that send an empty or zero ID, a hash
+// of sender, chat, content and time bucket. The event's own timestamp is
+// used so a replay after reconnect lands in the same bucket; now stands in
+// when the event has none.
+func oneBotDedupKey(evt *oneBotEvent, now time.Time) string {
+	if evt.MessageID != "" && evt.MessageID != "0" {
+		return "id:" + evt.MessageID
+	}
+
+	ts := evt.Time
+	if ts <= 0 {
+		ts = now.Unix()
+	}
+	bucket := ts / int64(oneBotDedupBucket/time.Second)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00%s\x00%d\x00%s\x00%s\x00%d\x00%s", evt.UserID, evt.MessageType, evt.GroupID, evt.GuildID, evt.ChannelID, bucket, evt.Content)
+	return "hash:" + hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+func (c *OneBotChannel) isDuplicate(key string) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if _, exists := c.dedup[messageID]; exists {
+	if _, exists := c.dedup[key]; exists {
 		return true
 	}
 
 	if old := c.dedupRing[c.dedupIdx]; old != "" {
 		delete(c.dedup, old)
 	}
-	c.dedupRing[c.dedupIdx] = messageID
-	c.dedup[messageID] = struct{}{}
+	c.dedupRing[c.dedupIdx] = key
+	c.dedup[key] = struct{}{}
 	c.dedupIdx = (c.dedupIdx + 1) % len(c.dedupRing)
 
 	return false
 }
 
-func truncate(s string, n int) string {
-	runes := []rune(s)
-	if len(runes) <= n {
-		return s
+// checkTrigger reports whether a message is addressed to the bot and returns
+// its content without the trigger. Group and guild messages need an
+// @mention or a group_trigger_prefix; private messages follow
+// private_trigger.
+func (c *OneBotChannel) checkTrigger(messageType, content string, isBotMentioned bool) (triggered bool, strippedContent string) {
+	mode := oneBotTriggerPrefix
+	if messageType == "private" {
+		mode = c.config.PrivateTrigger
+	}
+	switch mode {
+	case oneBotTriggerPrefix, oneBotTriggerMention:
+	default:
+		return true, content
 	}
-	return string(runes[:n]) + "..."
-}
 
-func (c *OneBotChannel) checkGroupTrigger(content string, isBotMentioned bool) (triggered bool, strippedContent string) {
 	if isBotMentioned {
 		return true, strings.TrimSpace(content)
 	}
+	if mode == oneBotTriggerMention {
+		return false, content
+	}
 
 	for _, prefix := range c.config.GroupTriggerPrefix {
 		if prefix == "" {
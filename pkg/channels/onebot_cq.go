@@ -0,0 +1,150 @@
+package channels
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// cqSegment is one segment of a OneBot message: plain text, or a CQ code
+// such as [CQ:image,file=...] with its parameters.
+type cqSegment struct {
+	Type string
+	Data map[string]string
+}
+
+var cqCodePattern = regexp.MustCompile(`\[CQ:([A-Za-z0-9_.-]+)((?:,[^\]]*)?)\]`)
+
+var (
+	cqTextUnescaper  = strings.NewReplacer("&#91;", "[", "&#93;", "]", "&amp;", "&")
+	cqParamUnescaper = strings.NewReplacer("&#91;", "[", "&#93;", "]", "&#44;", ",", "&amp;", "&")
+)
+
+// parseCQString splits a raw CQ-code message ("hi [CQ:face,id=1]") into
+// segments, unescaping text and parameter values.
+func parseCQString(s string) []cqSegment {
+	var segments []cqSegment
+	addText := func(text string) {
+		if text != "" {
+			segments = append(segments, cqSegment{Type: "text", Data: map[string]string{"text": cqTextUnescaper.Replace(text)}})
+		}
+	}
+
+	last := 0
+	for _, m := range cqCodePattern.FindAllStringSubmatchIndex(s, -1) {
+		addText(s[last:m[0]])
+		seg := cqSegment{Type: s[m[2]:m[3]], Data: map[string]string{}}
+		for _, param := range strings.Split(s[m[4]:m[5]], ",") {
+			if key, value, ok := strings.Cut(param, "="); ok {
+				seg.Data[key] = cqParamUnescaper.Replace(value)
+			}
+		}
+		segments = append(segments, seg)
+		last = m[1]
+	}
+	addText(s[last:])
+	return segments
+}
+
+// cqSegmentsFromArray converts the array message form
+// ([{"type":"text","data":{"text":"hi"}}, ...]) to segments.
+func cqSegmentsFromArray(raw []map[string]interface{}) []cqSegment {
+	segments := make([]cqSegment, 0, len(raw))
+	for _, r := range raw {
+		segType, _ := r["type"].(string)
+		seg := cqSegment{Type: segType, Data: map[string]string{}}
+		if data, ok := r["data"].(map[string]interface{}); ok {
+			for k, v := range data {
+				switch v := v.(type) {
+				case string:
+					seg.Data[k] = v
+				case float64:
+					seg.Data[k] = strconv.FormatFloat(v, 'f', -1, 64)
+				case nil:
+				default:
+					seg.Data[k] = fmt.Sprint(v)
+				}
+			}
+		}
+		segments = append(segments, seg)
+	}
+	return segments
+}
+
+// renderCQSegments turns segments into the text the model sees: text as is,
+// mentions of the bot removed (and reported), other media and codes as short
+// placeholders such as "[image]". Replies are dropped from the text; the
+// quoted message ID is reported in ReplyToID.
+func renderCQSegments(segments []cqSegment, selfID int64) parseMessageResult {
+	var sb strings.Builder
+	mentioned := false
+	replyTo := ""
+	selfIDStr := strconv.FormatInt(selfID, 10)
+	for _, seg := range segments {
+		switch seg.Type {
+		case "text":
+			sb.WriteString(seg.Data["text"])
+		case "at":
+			qq := seg.Data["qq"]
+			switch {
+			case selfID > 0 && qq == selfIDStr:
+				mentioned = true
+			case qq == "all":
+				if selfID > 0 {
+					mentioned = true
+				}
+				sb.WriteString("@all")
+			case seg.Data["name"] != "":
+				sb.WriteString("@" + seg.Data["name"])
+			default:
+				sb.WriteString("@" + qq)
+			}
+		case "reply":
+			if replyTo == "" {
+				replyTo = seg.Data["id"]
+			}
+		default:
+			sb.WriteString(cqPlaceholder(seg))
+		}
+	}
+	return parseMessageResult{Text: strings.TrimSpace(sb.String()), IsBotMentioned: mentioned, ReplyToID: replyTo}
+}
+
+// cqPlaceholders names the non-text segments the model is told about.
+var cqPlaceholders = map[string]string{
+	"face":     "[face]",
+	"mface":    "[sticker]",
+	"image":    "[image]",
+	"record":   "[voice]",
+	"video":    "[video]",
+	"file":     "[file]",
+	"location": "[location]",
+	"contact":  "[contact]",
+	"music":    "[music]",
+	"json":     "[card]",
+	"xml":      "[card]",
+	"forward":  "[forwarded messages]",
+	"poke":     "[poke]",
+	"dice":     "[dice]",
+	"rps":      "[rock-paper-scissors]",
+	"markdown": "[markdown]",
+}
+
+func cqPlaceholder(seg cqSegment) string {
+	switch seg.Type {
+	case "share":
+		if title := seg.Data["title"]; title != "" {
+			return "[link: " + title + "]"
+		}
+		return "[link]"
+	case "file":
+		if name := seg.Data["name"]; name != "" {
+			return "[file: " + name + "]"
+		}
+	}
+	if p, ok := cqPlaceholders[seg.Type]; ok {
+		return p
+	}
+	return "[" + seg.Type + "]"
+}
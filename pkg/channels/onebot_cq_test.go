@@ -0,0 +1,64 @@
+package channels
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseCQString(t *testing.T) {
+	got := parseCQString("a &#91;x&#93; [CQ:image,file=abc.jpg,url=https://x/y?a=1&#44;b=2]b")
+	want := []cqSegment{
+		{Type: "text", Data: map[string]string{"text": "a [x] "}},
+		{Type: "image", Data: map[string]string{"file": "abc.jpg", "url": "https://x/y?a=1,b=2"}},
+		{Type: "text", Data: map[string]string{"text": "b"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCQString() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseMessageContentEx_CQCodes(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		want      string
+		mentioned bool
+	}{
+		{"bot mention removed", `"[CQ:at,qq=999] hello"`, "hello", true},
+		{"other mention kept", `"[CQ:at,qq=123,name=bob] hi"`, "@bob hi", false},
+		{"media placeholders", `"[CQ:reply,id=5][CQ:face,id=14]look [CQ:image,file=a.jpg] and [CQ:record,file=b.amr]"`, "[face]look [image] and [voice]", false},
+		{"share title", `"[CQ:share,url=https://x,title=News]"`, "[link: News]", false},
+		{"unknown code", `"[CQ:gift,qq=1]"`, "[gift]", false},
+		{"escaped text", `"1 &amp; 2 &#91;ok&#93;"`, "1 & 2 [ok]", false},
+		{"array form", `[{"type":"reply","data":{"id":"5"}},{"type":"at","data":{"qq":999}},{"type":"text","data":{"text":" look "}},{"type":"image","data":{"file":"a.jpg"}}]`, "look [image]", true},
+		{"array at all", `[{"type":"at","data":{"qq":"all"}},{"type":"text","data":{"text":" meeting"}}]`, "@all meeting", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseMessageContentEx(json.RawMessage(tt.raw), 999)
+			if got.Text != tt.want || got.IsBotMentioned != tt.mentioned {
+				t.Errorf("parseMessageContentEx() = %q (mentioned %v), want %q (mentioned %v)", got.Text, got.IsBotMentioned, tt.want, tt.mentioned)
+			}
+		})
+	}
+}
+
+func TestParseMessageContentEx_FormsAgree(t *testing.T) {
+	str := parseMessageContentEx(json.RawMessage(`"[CQ:at,qq=999] see [CQ:image,file=a.jpg][CQ:face,id=1]"`), 999)
+	arr := parseMessageContentEx(json.RawMessage(`[{"type":"at","data":{"qq":"999"}},{"type":"text","data":{"text":" see "}},{"type":"image","data":{"file":"a.jpg"}},{"type":"face","data":{"id":"1"}}]`), 999)
+	if str != arr {
+		t.Errorf("string form %+v and array form %+v differ", str, arr)
+	}
+}
+
+func TestParseMessageContentEx_ReplyID(t *testing.T) {
+	str := parseMessageContentEx(json.RawMessage(`"[CQ:reply,id=-2147][CQ:at,qq=999] why?"`), 999)
+	arr := parseMessageContentEx(json.RawMessage(`[{"type":"reply","data":{"id":-2147}},{"type":"text","data":{"text":"why?"}}]`), 999)
+	if str.ReplyToID != "-2147" || arr.ReplyToID != "-2147" {
+		t.Errorf("ReplyToID = %q (string form), %q (array form), want -2147", str.ReplyToID, arr.ReplyToID)
+	}
+	if plain := parseMessageContentEx(json.RawMessage(`"hi"`), 999); plain.ReplyToID != "" {
+		t.Errorf("ReplyToID = %q for a message without a reply", plain.ReplyToID)
+	}
+}
@@ -0,0 +1,797 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func newOneBotTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func TestOneBotSendWriteFailureDropsConnection(t *testing.T) {
+	server := newOneBotTestServer(t)
+	defer server.Close()
+
+	cfg := config.OneBotConfig{WSUrl: "ws" + strings.TrimPrefix(server.URL, "http")}
+	ch, err := NewOneBotChannel(cfg, bus.NewMessageBus())
+	if err != nil {
+		t.Fatalf("NewOneBotChannel() error: %v", err)
+	}
+	if err := ch.connect(); err != nil {
+		t.Fatalf("connect() error: %v", err)
+	}
+	ch.setRunning(true)
+
+	msg := bus.OutboundMessage{Channel: "onebot", ChatID: "private:12345", Content: "hi"}
+	if err := ch.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() on healthy connection error: %v", err)
+	}
+
+	// Break the socket underneath the websocket so the next write fails
+	ch.mu.Lock()
+	ch.conn.(*websocket.Conn).UnderlyingConn().Close()
+	ch.mu.Unlock()
+
+	if err := ch.Send(context.Background(), msg); err == nil {
+		t.Fatal("Send() on broken connection succeeded, want error")
+	}
+
+	ch.mu.Lock()
+	conn := ch.conn
+	ch.mu.Unlock()
+	if conn != nil {
+		t.Error("connection still set after write failure, want nil so reconnect picks it up")
+	}
+
+	if err := ch.Send(context.Background(), msg); err == nil || !strings.Contains(err.Error(), "not connected") {
+		t.Errorf("Send() after drop error = %v, want not connected", err)
+	}
+}
+
+func TestOneBotDropConnKeepsReplacement(t *testing.T) {
+	server := newOneBotTestServer(t)
+	defer server.Close()
+
+	cfg := config.OneBotConfig{WSUrl: "ws" + strings.TrimPrefix(server.URL, "http")}
+	ch, _ := NewOneBotChannel(cfg, bus.NewMessageBus())
+	if err := ch.connect(); err != nil {
+		t.Fatalf("connect() error: %v", err)
+	}
+	ch.mu.Lock()
+	stale := ch.conn
+	ch.mu.Unlock()
+
+	if err := ch.connect(); err != nil {
+		t.Fatalf("reconnect error: %v", err)
+	}
+	ch.dropConn(stale)
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.conn == nil || ch.conn == stale {
+		t.Error("dropping a stale connection cleared the active one")
+	}
+	ch.conn.Close()
+}
+
+func TestOneBotBuildSendRequestFormatsContent(t *testing.T) {
+	ch, err := NewOneBotChannel(config.OneBotConfig{}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatalf("NewOneBotChannel() error: %v", err)
+	}
+
+	action, params, err := ch.buildSendRequest(bus.OutboundMessage{ChatID: "group:42", Content: "**Done** [x]"})
+	if err != nil {
+		t.Fatalf("buildSendRequest() error: %v", err)
+	}
+	if action != "send_group_msg" {
+		t.Fatalf("action = %q, want send_group_msg", action)
+	}
+	if got := params.(oneBotSendGroupMsgParams).Message; got != "Done &#91;x&#93;" {
+		t.Errorf("Message = %q, want %q", got, "Done &#91;x&#93;")
+	}
+
+	// A bare numeric chatID is a private chat and is formatted the same way
+	_, params, err = ch.buildSendRequest(bus.OutboundMessage{ChatID: "42", Content: "**Done** [x]"})
+	if err != nil {
+		t.Fatalf("buildSendRequest() error: %v", err)
+	}
+	if got := params.(oneBotSendPrivateMsgParams).Message; got != "Done &#91;x&#93;" {
+		t.Errorf("private Message = %q, want %q", got, "Done &#91;x&#93;")
+	}
+}
+
+func TestOneBotStripsImageMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2)), nil); err != nil {
+		t.Fatal(err)
+	}
+	exif := []byte("Exif\x00\x00GPS 52.52N")
+	data := append([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0, byte(len(exif) + 2)}, exif...)
+	data = append(data, buf.Bytes()[2:]...)
+	photo := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(photo, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	content := "![](file://" + photo + ") ![](https://example.com/a.jpg)"
+	if got := newOneBotFormatter(false).Format(content); !strings.Contains(got, "file="+escapeCQParam("file://"+photo)) {
+		t.Errorf("without stripping the original file should be sent, got %q", got)
+	}
+
+	got := newOneBotFormatter(true).Format(content)
+	if strings.Contains(got, photo) {
+		t.Fatalf("original file still referenced: %q", got)
+	}
+	if !strings.Contains(got, "file=https://example.com/a.jpg") {
+		t.Errorf("remote image should be untouched, got %q", got)
+	}
+	start := strings.Index(got, "file://")
+	end := strings.Index(got[start:], "]")
+	clean := strings.TrimPrefix(got[start:start+end], "file://")
+	defer os.Remove(clean)
+	if cleaned, err := os.ReadFile(clean); err != nil || bytes.Contains(cleaned, []byte("GPS")) {
+		t.Errorf("sent copy %q still has metadata (err %v)", clean, err)
+	}
+
+	if got := newOneBotFormatter(true).Format("![map](/nonexistent/x.jpg)"); got != "&#91;map&#93;" {
+		t.Errorf("unreadable image should be withheld, got %q", got)
+	}
+}
+
+func TestOneBotBuildSendRequestGuild(t *testing.T) {
+	ch, err := NewOneBotChannel(config.OneBotConfig{}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatalf("NewOneBotChannel() error: %v", err)
+	}
+
+	action, params, err := ch.buildSendRequest(bus.OutboundMessage{ChatID: "guild:6001:1234", Content: "hi"})
+	if err != nil {
+		t.Fatalf("buildSendRequest() error: %v", err)
+	}
+	if action != "send_guild_channel_msg" {
+		t.Fatalf("action = %q, want send_guild_channel_msg", action)
+	}
+	want := oneBotSendGuildChannelMsgParams{GuildID: "6001", ChannelID: "1234", Message: "hi"}
+	if got := params.(oneBotSendGuildChannelMsgParams); got != want {
+		t.Errorf("params = %+v, want %+v", got, want)
+	}
+
+	for _, chatID := range []string{"guild:6001", "guild::1234", "guild:6001:"} {
+		if _, _, err := ch.buildSendRequest(bus.OutboundMessage{ChatID: chatID, Content: "hi"}); err == nil {
+			t.Errorf("buildSendRequest(%q) succeeded, want error", chatID)
+		}
+	}
+}
+
+func TestOneBotIdleDisconnectAndReconnectOnSend(t *testing.T) {
+	server := newOneBotTestServer(t)
+	defer server.Close()
+
+	cfg := config.OneBotConfig{WSUrl: "ws" + strings.TrimPrefix(server.URL, "http"), IdleTimeout: 1}
+	ch, _ := NewOneBotChannel(cfg, bus.NewMessageBus())
+	ch.ctx, ch.cancel = context.WithCancel(context.Background())
+	defer ch.Stop(context.Background())
+	if err := ch.connect(); err != nil {
+		t.Fatalf("connect() error: %v", err)
+	}
+	ch.setRunning(true)
+
+	if ch.disconnectIfIdle(time.Minute) {
+		t.Fatal("disconnected right after connecting")
+	}
+
+	ch.lastActivity.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	if !ch.disconnectIfIdle(time.Minute) {
+		t.Fatal("expected idle disconnect")
+	}
+	ch.mu.Lock()
+	conn, idle := ch.conn, ch.idle
+	ch.mu.Unlock()
+	if conn != nil || !idle {
+		t.Fatalf("after idle disconnect: conn=%v idle=%v", conn, idle)
+	}
+
+	msg := bus.OutboundMessage{Channel: "onebot", ChatID: "private:12345", Content: "hi"}
+	if err := ch.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() after idle disconnect error: %v", err)
+	}
+	ch.mu.Lock()
+	conn, idle = ch.conn, ch.idle
+	ch.mu.Unlock()
+	if conn == nil || idle {
+		t.Fatalf("after Send: conn=%v idle=%v, want reconnected", conn, idle)
+	}
+}
+
+func TestOneBotSendWithoutIdleDoesNotReconnect(t *testing.T) {
+	ch, _ := NewOneBotChannel(config.OneBotConfig{WSUrl: "ws://127.0.0.1:1"}, bus.NewMessageBus())
+	ch.setRunning(true)
+
+	msg := bus.OutboundMessage{Channel: "onebot", ChatID: "private:12345", Content: "hi"}
+	if err := ch.Send(context.Background(), msg); err == nil || !strings.Contains(err.Error(), "not connected") {
+		t.Fatalf("Send() error = %v, want not connected", err)
+	}
+}
+
+func TestOneBotCallActionReturnsResponseData(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var req oneBotAPIRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			resp := map[string]interface{}{"status": "ok", "retcode": 0, "echo": req.Echo,
+				"data": map[string]interface{}{"role": "admin"}}
+			if req.Action == "set_group_kick" {
+				resp = map[string]interface{}{"status": "failed", "retcode": 102, "wording": "no permission", "echo": req.Echo}
+			}
+			conn.WriteJSON(resp)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.OneBotConfig{WSUrl: "ws" + strings.TrimPrefix(server.URL, "http")}
+	ch, _ := NewOneBotChannel(cfg, bus.NewMessageBus())
+	ch.ctx, ch.cancel = context.WithCancel(context.Background())
+	defer ch.Stop(context.Background())
+	if err := ch.connect(); err != nil {
+		t.Fatalf("connect() error: %v", err)
+	}
+	ch.setRunning(true)
+	go ch.listen()
+
+	data, err := ch.CallAction(context.Background(), "get_group_member_info", map[string]interface{}{"group_id": 1, "user_id": 2})
+	if err != nil {
+		t.Fatalf("CallAction() error: %v", err)
+	}
+	if string(data) != `{"role":"admin"}` {
+		t.Errorf("data = %s", data)
+	}
+
+	_, err = ch.CallAction(context.Background(), "set_group_kick", map[string]interface{}{"group_id": 1, "user_id": 2})
+	if err == nil || !strings.Contains(err.Error(), "retcode 102") || !strings.Contains(err.Error(), "no permission") {
+		t.Errorf("CallAction() error = %v, want retcode 102 failure", err)
+	}
+}
+
+func TestOneBotRepliesIncludeQuotedMessage(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	gotID := make(chan interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"post_type":"message","message_type":"private","message_id":2,"user_id":12345,"self_id":999,"raw_message":"[CQ:reply,id=41]what does this mean?"}`))
+		for {
+			var req struct {
+				Action string             `json:"action"`
+				Params oneBotGetMsgParams `json:"params"`
+				Echo   string             `json:"echo"`
+			}
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			if req.Action != "get_msg" {
+				continue
+			}
+			gotID <- req.Params.MessageID
+			conn.WriteJSON(map[string]interface{}{"status": "ok", "retcode": 0, "echo": req.Echo,
+				"data": map[string]interface{}{
+					"sender":      map[string]interface{}{"user_id": 777, "nickname": "alice"},
+					"raw_message": "the build is [CQ:face,id=1] green",
+				}})
+		}
+	}))
+	defer server.Close()
+
+	msgBus := bus.NewMessageBus()
+	cfg := config.OneBotConfig{WSUrl: "ws" + strings.TrimPrefix(server.URL, "http")}
+	ch, _ := NewOneBotChannel(cfg, msgBus)
+	ch.ctx, ch.cancel = context.WithCancel(context.Background())
+	defer ch.Stop(context.Background())
+	if err := ch.connect(); err != nil {
+		t.Fatalf("connect() error: %v", err)
+	}
+	ch.setRunning(true)
+	go ch.listen()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	msg, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no inbound message")
+	}
+	if id := <-gotID; id != float64(41) {
+		t.Errorf("get_msg message_id = %v, want 41", id)
+	}
+	want := "[Quoted message from alice: the build is [face] green]\nwhat does this mean?"
+	if msg.Content != want {
+		t.Errorf("inbound content = %q, want %q", msg.Content, want)
+	}
+	if msg.Metadata["reply_to_message_id"] != "41" {
+		t.Errorf("reply_to_message_id = %q, want 41", msg.Metadata["reply_to_message_id"])
+	}
+}
+
+func TestOneBotBuildSendRequestReasoning(t *testing.T) {
+	ch, _ := NewOneBotChannel(config.OneBotConfig{ShowReasoning: true}, bus.NewMessageBus())
+	if !ch.ShowsReasoning() {
+		t.Fatal("ShowsReasoning() = false with show_reasoning enabled")
+	}
+
+	_, params, err := ch.buildSendRequest(bus.OutboundMessage{ChatID: "private:42", Content: "**Check** the docs", Reasoning: true})
+	if err != nil {
+		t.Fatalf("buildSendRequest() error: %v", err)
+	}
+	if got := params.(oneBotSendPrivateMsgParams).Message; got != oneBotReasoningHeader+"Check the docs" {
+		t.Errorf("Message = %q", got)
+	}
+}
+
+func TestOneBotCheckTrigger(t *testing.T) {
+	tests := []struct {
+		name        string
+		privateMode string
+		msgType     string
+		content     string
+		mentioned   bool
+		want        bool
+		wantContent string
+	}{
+		{"private default", "", "private", "hello", false, true, "hello"},
+		{"private always", "always", "private", "hello", false, true, "hello"},
+		{"private prefix without prefix", "prefix", "private", "hello", false, false, "hello"},
+		{"private prefix with prefix", "prefix", "private", "/ask hello", false, true, "hello"},
+		{"private prefix with mention", "prefix", "private", " hello", true, true, "hello"},
+		{"private mention ignores prefix", "mention", "private", "/ask hello", false, false, "/ask hello"},
+		{"private mention with mention", "mention", "private", "hello", true, true, "hello"},
+		{"group ignores private mode", "always", "group", "hello", false, false, "hello"},
+		{"group with prefix", "mention", "group", "/ask hello", false, true, "hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch, err := NewOneBotChannel(config.OneBotConfig{
+				GroupTriggerPrefix: []string{"/ask"},
+				PrivateTrigger:     tt.privateMode,
+			}, bus.NewMessageBus())
+			if err != nil {
+				t.Fatalf("NewOneBotChannel() error: %v", err)
+			}
+			got, content := ch.checkTrigger(tt.msgType, tt.content, tt.mentioned)
+			if got != tt.want || content != tt.wantContent {
+				t.Errorf("checkTrigger() = %v, %q; want %v, %q", got, content, tt.want, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestNewOneBotChannelRejectsUnknownPrivateTrigger(t *testing.T) {
+	_, err := NewOneBotChannel(config.OneBotConfig{PrivateTrigger: "sometimes"}, bus.NewMessageBus())
+	if err == nil || !strings.Contains(err.Error(), "private_trigger") {
+		t.Fatalf("NewOneBotChannel() error = %v, want private_trigger error", err)
+	}
+}
+
+// fakeOneBotConn is an in-memory oneBotConn. Frames sent on in are read by
+// the channel; a value on readErr fails the pending read. Ping frames are
+// counted rather than recorded, and answered when answerPings is set.
+type fakeOneBotConn struct {
+	in          chan []byte
+	readErr     chan error
+	closed      chan struct{}
+	closeOnce   sync.Once
+	answerPings bool
+
+	mu          sync.Mutex
+	written     [][]byte
+	pings       int
+	pongHandler func(string) error
+}
+
+func newFakeOneBotConn() *fakeOneBotConn {
+	return &fakeOneBotConn{
+		in:      make(chan []byte, 8),
+		readErr: make(chan error, 1),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (f *fakeOneBotConn) ReadMessage() (int, []byte, error) {
+	select {
+	case data := <-f.in:
+		return websocket.TextMessage, data, nil
+	case err := <-f.readErr:
+		return 0, nil, err
+	case <-f.closed:
+		return 0, nil, errors.New("use of closed connection")
+	}
+}
+
+func (f *fakeOneBotConn) WriteMessage(messageType int, data []byte) error {
+	select {
+	case <-f.closed:
+		return errors.New("use of closed connection")
+	default:
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if messageType == websocket.PingMessage {
+			f.pings++
+			if f.answerPings && f.pongHandler != nil {
+				return f.pongHandler("")
+			}
+			return nil
+		}
+		f.written = append(f.written, data)
+		return nil
+	}
+}
+
+func (f *fakeOneBotConn) SetPongHandler(h func(string) error) {
+	f.mu.Lock()
+	f.pongHandler = h
+	f.mu.Unlock()
+}
+
+func (f *fakeOneBotConn) pingCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pings
+}
+
+func (f *fakeOneBotConn) Close() error {
+	f.closeOnce.Do(func() { close(f.closed) })
+	return nil
+}
+
+// fakeOneBotDialer hands out a new fakeOneBotConn per dial and reports each
+// one on conns.
+type fakeOneBotDialer struct {
+	conns       chan *fakeOneBotConn
+	answerPings bool
+}
+
+func (d *fakeOneBotDialer) dial(url string, header http.Header) (oneBotConn, error) {
+	conn := newFakeOneBotConn()
+	conn.answerPings = d.answerPings
+	d.conns <- conn
+	return conn, nil
+}
+
+func newFakeOneBotChannel(t *testing.T, msgBus *bus.MessageBus) (*OneBotChannel, *fakeOneBotDialer) {
+	t.Helper()
+	ch, err := NewOneBotChannel(config.OneBotConfig{WSUrl: "ws://onebot.test", ReconnectInterval: 5}, msgBus)
+	if err != nil {
+		t.Fatalf("NewOneBotChannel() error: %v", err)
+	}
+	dialer := &fakeOneBotDialer{conns: make(chan *fakeOneBotConn, 4)}
+	ch.dial = dialer.dial
+	ch.reconnectInterval = 10 * time.Millisecond
+	return ch, dialer
+}
+
+func waitFakeDial(t *testing.T, d *fakeOneBotDialer) *fakeOneBotConn {
+	t.Helper()
+	select {
+	case conn := <-d.conns:
+		return conn
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for dial")
+		return nil
+	}
+}
+
+func TestOneBotReconnectsAfterReadError(t *testing.T) {
+	ch, dialer := newFakeOneBotChannel(t, bus.NewMessageBus())
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer ch.Stop(context.Background())
+
+	first := waitFakeDial(t, dialer)
+	first.readErr <- errors.New("connection reset by peer")
+
+	second := waitFakeDial(t, dialer)
+	select {
+	case <-first.closed:
+	default:
+		t.Error("failed connection was not closed")
+	}
+
+	ch.mu.Lock()
+	current := ch.conn
+	ch.mu.Unlock()
+	if current != oneBotConn(second) {
+		t.Errorf("active connection = %v, want the reconnected one", current)
+	}
+}
+
+func TestOneBotDropsDuplicateMessages(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	ch, dialer := newFakeOneBotChannel(t, msgBus)
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer ch.Stop(context.Background())
+	conn := waitFakeDial(t, dialer)
+
+	event := func(id int) []byte {
+		return []byte(fmt.Sprintf(`{"post_type":"message","message_type":"private","message_id":%d,"user_id":12345,"self_id":999,"message":"hello %d"}`, id, id))
+	}
+	conn.in <- event(1)
+	conn.in <- event(1)
+	conn.in <- event(2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	for _, want := range []string{"hello 1", "hello 2"} {
+		msg, ok := msgBus.ConsumeInbound(ctx)
+		if !ok {
+			t.Fatalf("no inbound message, want %q", want)
+		}
+		if msg.Content != want {
+			t.Errorf("inbound content = %q, want %q (duplicate not dropped?)", msg.Content, want)
+		}
+	}
+}
+
+func TestOneBotDedupKey(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	base := oneBotEvent{MessageType: "group", UserID: 1, GroupID: 2, Content: "hi", Time: 1700000001}
+	key := func(mod func(*oneBotEvent)) string {
+		evt := base
+		if mod != nil {
+			mod(&evt)
+		}
+		return oneBotDedupKey(&evt, now)
+	}
+
+	if got := key(func(e *oneBotEvent) { e.MessageID = "42" }); got != "id:42" {
+		t.Errorf("key with message_id = %q, want id:42", got)
+	}
+	if key(nil) != key(func(e *oneBotEvent) { e.MessageID = "0" }) {
+		t.Error("zero message_id should fall back to the content hash")
+	}
+	if key(nil) != key(func(e *oneBotEvent) { e.Time = 1700000003 }) {
+		t.Error("same bucket should give the same key")
+	}
+	for name, mod := range map[string]func(*oneBotEvent){
+		"content": func(e *oneBotEvent) { e.Content = "hello" },
+		"sender":  func(e *oneBotEvent) { e.UserID = 3 },
+		"chat":    func(e *oneBotEvent) { e.GroupID = 4 },
+		"bucket":  func(e *oneBotEvent) { e.Time += 60 },
+	} {
+		if key(nil) == key(mod) {
+			t.Errorf("different %s should give a different key", name)
+		}
+	}
+}
+
+func TestOneBotDropsDuplicatesWithoutMessageID(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	ch, dialer := newFakeOneBotChannel(t, msgBus)
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer ch.Stop(context.Background())
+	conn := waitFakeDial(t, dialer)
+
+	event := func(text string) []byte {
+		return []byte(fmt.Sprintf(`{"post_type":"message","message_type":"private","message_id":0,"user_id":12345,"self_id":999,"time":1700000000,"message":%q}`, text))
+	}
+	conn.in <- event("hello")
+	conn.in <- event("hello")
+	conn.in <- event("again")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	for _, want := range []string{"hello", "again"} {
+		msg, ok := msgBus.ConsumeInbound(ctx)
+		if !ok {
+			t.Fatalf("no inbound message, want %q", want)
+		}
+		if msg.Content != want {
+			t.Errorf("inbound content = %q, want %q (duplicate not dropped?)", msg.Content, want)
+		}
+	}
+}
+
+func TestOneBotRoutesGuildMessages(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	ch, dialer := newFakeOneBotChannel(t, msgBus)
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer ch.Stop(context.Background())
+	conn := waitFakeDial(t, dialer)
+
+	// Not addressed to the bot: ignored like a group message
+	conn.in <- []byte(`{"post_type":"message","message_type":"guild","sub_type":"channel","message_id":"m1","guild_id":"6001","channel_id":"1234","user_id":"144115218678093982","self_id":999,"self_tiny_id":"144115218677000001","message":"chatter","sender":{"nickname":"alice"}}`)
+	conn.in <- []byte(`{"post_type":"message","message_type":"guild","sub_type":"channel","message_id":"m2","guild_id":"6001","channel_id":"1234","user_id":"144115218678093982","self_id":999,"self_tiny_id":"144115218677000001","raw_message":"[CQ:at,qq=144115218677000001] status?","message":"","sender":{"nickname":"alice"}}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	msg, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no inbound message")
+	}
+	if msg.ChatID != "guild:6001:1234" || msg.Content != "status?" || msg.SenderID != "144115218678093982" {
+		t.Errorf("inbound = chat %q, content %q, sender %q", msg.ChatID, msg.Content, msg.SenderID)
+	}
+	if msg.Metadata["guild_id"] != "6001" || msg.Metadata["channel_id"] != "1234" || msg.Metadata["sender_name"] != "alice" {
+		t.Errorf("metadata = %v", msg.Metadata)
+	}
+}
+
+func TestOneBotSendBroadcast(t *testing.T) {
+	ch, dialer := newFakeOneBotChannel(t, bus.NewMessageBus())
+	ch.broadcastInterval = 0
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer ch.Stop(context.Background())
+	conn := waitFakeDial(t, dialer)
+
+	result := ch.SendBroadcast(context.Background(), []string{"group:1", "bogus", "private:2"}, "maintenance at 10pm")
+
+	if len(result.Sent) != 2 || result.Sent[0] != "group:1" || result.Sent[1] != "private:2" {
+		t.Errorf("Sent = %v, want group:1 and private:2", result.Sent)
+	}
+	if len(result.Failed) != 1 || result.Failed["bogus"] == nil {
+		t.Errorf("Failed = %v, want only bogus", result.Failed)
+	}
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	var actions []string
+	for _, data := range conn.written {
+		var req oneBotAPIRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			t.Fatalf("written request is not JSON: %s", data)
+		}
+		actions = append(actions, req.Action)
+	}
+	if len(actions) != 2 || actions[0] != "send_group_msg" || actions[1] != "send_private_msg" {
+		t.Errorf("actions = %v, want send_group_msg then send_private_msg", actions)
+	}
+}
+
+func TestOneBotSendBroadcastStopsOnCancel(t *testing.T) {
+	ch, dialer := newFakeOneBotChannel(t, bus.NewMessageBus())
+	ch.broadcastInterval = time.Hour
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer ch.Stop(context.Background())
+	waitFakeDial(t, dialer)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result := ch.SendBroadcast(ctx, []string{"group:1", "group:2", "group:3"}, "hi")
+
+	if len(result.Sent) != 1 {
+		t.Errorf("Sent = %v, want only the first target", result.Sent)
+	}
+	if !errors.Is(result.Failed["group:2"], context.DeadlineExceeded) || !errors.Is(result.Failed["group:3"], context.DeadlineExceeded) {
+		t.Errorf("Failed = %v, want remaining targets to fail with the context error", result.Failed)
+	}
+}
+
+func TestOneBotGroupSenderRoleMetadata(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	ch, dialer := newFakeOneBotChannel(t, msgBus)
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer ch.Stop(context.Background())
+	conn := waitFakeDial(t, dialer)
+
+	conn.in <- []byte(`{"post_type":"message","message_type":"group","message_id":1,"group_id":42,"user_id":12345,"self_id":999,"raw_message":"[CQ:at,qq=999] mute bob","sender":{"user_id":12345,"nickname":"alice","card":"Alice","role":"admin","title":"Keeper"}}`)
+	conn.in <- []byte(`{"post_type":"message","message_type":"group","message_id":2,"group_id":42,"user_id":678,"self_id":999,"raw_message":"[CQ:at,qq=999] hi","sender":{"user_id":678,"nickname":"bob"}}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	admin, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no inbound message")
+	}
+	if admin.Metadata["sender_role"] != "admin" || admin.Metadata["sender_title"] != "Keeper" || admin.Metadata["sender_name"] != "Alice" {
+		t.Errorf("metadata = %v, want role admin, title Keeper, name Alice", admin.Metadata)
+	}
+
+	member, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no second inbound message")
+	}
+	if _, ok := member.Metadata["sender_role"]; ok {
+		t.Errorf("sender without role got metadata %v", member.Metadata)
+	}
+}
+
+func TestOneBotPingKeepsAnsweredConnection(t *testing.T) {
+	ch, dialer := newFakeOneBotChannel(t, bus.NewMessageBus())
+	ch.pingInterval = 10 * time.Millisecond
+	dialer.answerPings = true
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer ch.Stop(context.Background())
+	conn := waitFakeDial(t, dialer)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for conn.pingCount() < 5 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := conn.pingCount(); n < 5 {
+		t.Fatalf("pings sent = %d, want at least 5", n)
+	}
+	select {
+	case <-dialer.conns:
+		t.Error("connection replaced although every ping was answered")
+	default:
+	}
+}
+
+func TestOneBotPingReconnectsWithoutPong(t *testing.T) {
+	ch, dialer := newFakeOneBotChannel(t, bus.NewMessageBus())
+	ch.pingInterval = 10 * time.Millisecond
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer ch.Stop(context.Background())
+	first := waitFakeDial(t, dialer)
+
+	second := waitFakeDial(t, dialer)
+	if second == first {
+		t.Fatal("expected a new connection")
+	}
+	select {
+	case <-first.closed:
+	default:
+		t.Error("unanswered connection was not closed")
+	}
+	if n := first.pingCount(); n < oneBotMissedPongs {
+		t.Errorf("pings before reconnect = %d, want at least %d", n, oneBotMissedPongs)
+	}
+}
@@ -51,6 +51,8 @@ type Config struct {
 	Tools     ToolsConfig     `json:"tools"`
 	Heartbeat HeartbeatConfig `json:"heartbeat"`
 	Devices   DevicesConfig   `json:"devices"`
+	Voice     VoiceConfig     `json:"voice"`
+	Network   NetworkConfig   `json:"network"`
 	mu        sync.RWMutex
 }
 
@@ -64,8 +66,12 @@ type AgentDefaults struct {
 	Provider            string  `json:"provider" env:"PICOCLAW_AGENTS_DEFAULTS_PROVIDER"`
 	Model               string  `json:"model" env:"PICOCLAW_AGENTS_DEFAULTS_MODEL"`
 	MaxTokens           int     `json:"max_tokens" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOKENS"`
-	Temperature         float64 `json:"temperature" env:"PICOCLAW_AGENTS_DEFAULTS_TEMPERATURE"`
+	Temperature         float64 `json:"temperature" env:"PICOCLAW_AGENTS_DEFAULTS_TEMPERATURE"` // sampling temperature for LLM calls that set none, across all providers
 	MaxToolIterations   int     `json:"max_tool_iterations" env:"PICOCLAW_AGENTS_DEFAULTS_MAX_TOOL_ITERATIONS"`
+	// GuardrailPrompt is an operator-controlled instruction sent as the first
+	// system message of every conversation, ahead of the regular system
+	// prompt and anything skills add. Empty disables it.
+	GuardrailPrompt string `json:"guardrail_prompt,omitempty" env:"PICOCLAW_AGENTS_DEFAULTS_GUARDRAIL_PROMPT"`
 }
 
 type ChannelsConfig struct {
@@ -153,7 +159,22 @@ type OneBotConfig struct {
 	AccessToken        string              `json:"access_token" env:"PICOCLAW_CHANNELS_ONEBOT_ACCESS_TOKEN"`
 	ReconnectInterval  int                 `json:"reconnect_interval" env:"PICOCLAW_CHANNELS_ONEBOT_RECONNECT_INTERVAL"`
 	GroupTriggerPrefix []string            `json:"group_trigger_prefix" env:"PICOCLAW_CHANNELS_ONEBOT_GROUP_TRIGGER_PREFIX"`
+	PrivateTrigger     string              `json:"private_trigger,omitempty" env:"PICOCLAW_CHANNELS_ONEBOT_PRIVATE_TRIGGER"` // "always" (default), "prefix" (@mention or group_trigger_prefix, like groups) or "mention"
 	AllowFrom          FlexibleStringSlice `json:"allow_from" env:"PICOCLAW_CHANNELS_ONEBOT_ALLOW_FROM"`
+	IdleTimeout        int                 `json:"idle_timeout,omitempty" env:"PICOCLAW_CHANNELS_ONEBOT_IDLE_TIMEOUT"`                 // minutes without messages before disconnecting until the next send, 0 = stay connected
+	ShowReasoning      bool                `json:"show_reasoning,omitempty" env:"PICOCLAW_CHANNELS_ONEBOT_SHOW_REASONING"`             // send the model's reasoning before each answer, for debugging
+	ThinkTimeMs        int                 `json:"think_time_ms,omitempty" env:"PICOCLAW_CHANNELS_ONEBOT_THINK_TIME_MS"`               // wait this long after a sender's last message, merging rapid messages into one turn and holding replies while the chat is active, 0 = reply at once
+	StripImageMetadata bool                `json:"strip_image_metadata,omitempty" env:"PICOCLAW_CHANNELS_ONEBOT_STRIP_IMAGE_METADATA"` // remove EXIF (GPS etc.) from local images before sending them
+	PingInterval       int                 `json:"ping_interval,omitempty" env:"PICOCLAW_CHANNELS_ONEBOT_PING_INTERVAL"`               // seconds between WebSocket ping frames, reconnecting after two go unanswered, 0 = off
+}
+
+// NetworkConfig restricts the hosts that tools, providers, voice
+// transcription and downloads may contact. Rules are hostnames
+// ("api.openai.com"), subdomain wildcards ("*.github.com"), IPs or CIDR
+// ranges ("10.0.0.0/8"). Channel connections are not affected.
+type NetworkConfig struct {
+	EgressAllow FlexibleStringSlice `json:"egress_allow" env:"PICOCLAW_NETWORK_EGRESS_ALLOW"` // when set, only these hosts may be contacted
+	EgressDeny  FlexibleStringSlice `json:"egress_deny" env:"PICOCLAW_NETWORK_EGRESS_DENY"`   // hosts that are always refused
 }
 
 type HeartbeatConfig struct {
@@ -161,32 +182,83 @@ type HeartbeatConfig struct {
 	Interval int  `json:"interval" env:"PICOCLAW_HEARTBEAT_INTERVAL"` // minutes, min 5
 }
 
+type VoiceConfig struct {
+	Chunked      bool `json:"chunked" env:"PICOCLAW_VOICE_CHUNKED"`             // split recordings over max_file_mb instead of rejecting them
+	ChunkSeconds int  `json:"chunk_seconds" env:"PICOCLAW_VOICE_CHUNK_SECONDS"` // chunk length when splitting with ffmpeg
+	MaxFileMB    int  `json:"max_file_mb" env:"PICOCLAW_VOICE_MAX_FILE_MB"`     // upload limit of the transcription API
+}
+
 type DevicesConfig struct {
 	Enabled    bool `json:"enabled" env:"PICOCLAW_DEVICES_ENABLED"`
 	MonitorUSB bool `json:"monitor_usb" env:"PICOCLAW_DEVICES_MONITOR_USB"`
 }
 
 type ProvidersConfig struct {
-	Anthropic     ProviderConfig `json:"anthropic"`
-	OpenAI        ProviderConfig `json:"openai"`
-	OpenRouter    ProviderConfig `json:"openrouter"`
-	Groq          ProviderConfig `json:"groq"`
-	Zhipu         ProviderConfig `json:"zhipu"`
-	VLLM          ProviderConfig `json:"vllm"`
-	Gemini        ProviderConfig `json:"gemini"`
-	Nvidia        ProviderConfig `json:"nvidia"`
-	Moonshot      ProviderConfig `json:"moonshot"`
-	ShengSuanYun  ProviderConfig `json:"shengsuanyun"`
-	DeepSeek      ProviderConfig `json:"deepseek"`
-	GitHubCopilot ProviderConfig `json:"github_copilot"`
+	Anthropic                ProviderConfig `json:"anthropic"`
+	OpenAI                   ProviderConfig `json:"openai"`
+	OpenRouter               ProviderConfig `json:"openrouter"`
+	Groq                     ProviderConfig `json:"groq"`
+	Zhipu                    ProviderConfig `json:"zhipu"`
+	VLLM                     ProviderConfig `json:"vllm"`
+	Gemini                   ProviderConfig `json:"gemini"`
+	Nvidia                   ProviderConfig `json:"nvidia"`
+	Moonshot                 ProviderConfig `json:"moonshot"`
+	ShengSuanYun             ProviderConfig `json:"shengsuanyun"`
+	DeepSeek                 ProviderConfig `json:"deepseek"`
+	Ollama                   ProviderConfig `json:"ollama"`
+	GitHubCopilot            ProviderConfig `json:"github_copilot"`
+	Azure                    ProviderConfig `json:"azure"`
+	Debug                    bool           `json:"debug,omitempty" env:"PICOCLAW_PROVIDERS_DEBUG"`                                             // log raw provider requests/responses at DEBUG level
+	MaxInFlight              int            `json:"max_in_flight,omitempty" env:"PICOCLAW_PROVIDERS_MAX_IN_FLIGHT"`                             // concurrent LLM requests, 0 = unlimited
+	MaxQueued                int            `json:"max_queued,omitempty" env:"PICOCLAW_PROVIDERS_MAX_QUEUED"`                                   // requests waiting for a slot before rejecting, 0 = unlimited
+	RequestsPerMinute        int            `json:"requests_per_minute,omitempty" env:"PICOCLAW_PROVIDERS_REQUESTS_PER_MINUTE"`                 // Chat calls per minute allowed for each model, 0 = unlimited
+	ResponseCacheTTLSeconds  int            `json:"response_cache_ttl_seconds,omitempty" env:"PICOCLAW_PROVIDERS_RESPONSE_CACHE_TTL_SECONDS"`   // reuse the response to an identical request for this long, 0 = no caching
+	DefaultMaxTokens         int            `json:"default_max_tokens" env:"PICOCLAW_PROVIDERS_DEFAULT_MAX_TOKENS"`                             // completion budget when a call sets no max_tokens, 0 = 4096
+	SendUserID               bool           `json:"send_user_id,omitempty" env:"PICOCLAW_PROVIDERS_SEND_USER_ID"`                               // send a hashed sender ID as the OpenAI "user" field for abuse monitoring
+	DisableParallelToolCalls bool           `json:"disable_parallel_tool_calls,omitempty" env:"PICOCLAW_PROVIDERS_DISABLE_PARALLEL_TOOL_CALLS"` // ask for at most one tool call per response
+	RequestTimeoutSeconds    int            `json:"request_timeout_seconds" env:"PICOCLAW_PROVIDERS_REQUEST_TIMEOUT_SECONDS"`                   // per-request timeout, 0 = 120
+	MaxRetries               int            `json:"max_retries" env:"PICOCLAW_PROVIDERS_MAX_RETRIES"`                                           // retries on rate limits and server errors, 0 = none
+	MaxIdleConns             int            `json:"max_idle_conns" env:"PICOCLAW_PROVIDERS_MAX_IDLE_CONNS"`                                     // pooled keep-alive connections, 0 = 10
+	IdleConnTimeoutSeconds   int            `json:"idle_conn_timeout_seconds" env:"PICOCLAW_PROVIDERS_IDLE_CONN_TIMEOUT_SECONDS"`               // close pooled connections idle this long, 0 = 30
+	ConnMaxLifetimeSeconds   int            `json:"conn_max_lifetime_seconds" env:"PICOCLAW_PROVIDERS_CONN_MAX_LIFETIME_SECONDS"`               // stop reusing connections this old, 0 = no limit
+	// ModelRequestsPerMinute overrides requests_per_minute for the named
+	// models ("gpt-4o": 20). A value <= 0 leaves that model unlimited.
+	ModelRequestsPerMinute map[string]int `json:"model_requests_per_minute,omitempty"`
+	// ModelMaxTokens caps max_tokens per model name prefix ("gpt-4o": 16384),
+	// adding to or overriding the built-in output limits. A value <= 0 turns
+	// the cap off for that prefix.
+	ModelMaxTokens map[string]int `json:"model_max_tokens,omitempty"`
+	// ModelPrefixes routes models named "<prefix>/<model>" to OpenAI-compatible
+	// endpoints, keyed by prefix ("together/"). Entries override the built-in
+	// prefixes such as "groq/" and "ollama/".
+	ModelPrefixes map[string]ModelPrefixConfig `json:"model_prefixes,omitempty"`
+}
+
+// ModelPrefixConfig is one providers.model_prefixes entry. Without api_base
+// the entry adjusts the built-in backend that owns the prefix.
+type ModelPrefixConfig struct {
+	APIBase    string `json:"api_base,omitempty"`
+	APIKey     string `json:"api_key,omitempty"`
+	Proxy      string `json:"proxy,omitempty"`
+	KeepPrefix bool   `json:"keep_prefix,omitempty"` // send the model name with the prefix instead of stripping it
+	// ExtraBody adds backend-specific fields ("enable_thinking": false) to
+	// every request. Standard fields such as "model" cannot be overridden.
+	ExtraBody map[string]interface{} `json:"extra_body,omitempty"`
 }
 
 type ProviderConfig struct {
-	APIKey      string `json:"api_key" env:"PICOCLAW_PROVIDERS_{{.Name}}_API_KEY"`
-	APIBase     string `json:"api_base" env:"PICOCLAW_PROVIDERS_{{.Name}}_API_BASE"`
-	Proxy       string `json:"proxy,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_PROXY"`
-	AuthMethod  string `json:"auth_method,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_AUTH_METHOD"`
-	ConnectMode string `json:"connect_mode,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_CONNECT_MODE"` //only for Github Copilot, `stdio` or `grpc`
+	APIKey       string `json:"api_key" env:"PICOCLAW_PROVIDERS_{{.Name}}_API_KEY"`
+	APIBase      string `json:"api_base" env:"PICOCLAW_PROVIDERS_{{.Name}}_API_BASE"`
+	Proxy        string `json:"proxy,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_PROXY"`
+	AuthMethod   string `json:"auth_method,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_AUTH_METHOD"`
+	ConnectMode  string `json:"connect_mode,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_CONNECT_MODE"` //only for Github Copilot, `stdio` or `grpc`
+	Deployment   string `json:"deployment,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_DEPLOYMENT"`     //only for Azure OpenAI, defaults to the model name
+	APIVersion   string `json:"api_version,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_API_VERSION"`   //only for Azure OpenAI
+	Instructions string `json:"instructions,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_INSTRUCTIONS"` //only for OpenAI oauth/token (Codex), used when no system prompt is sent
+	// ExtraBody adds backend-specific fields ("repetition_penalty": 1.1) to
+	// every OpenAI-compatible request. Standard fields such as "model" cannot
+	// be overridden.
+	ExtraBody map[string]interface{} `json:"extra_body,omitempty"`
 }
 
 type GatewayConfig struct {
@@ -210,8 +282,55 @@ type WebToolsConfig struct {
 	DuckDuckGo DuckDuckGoConfig `json:"duckduckgo"`
 }
 
+type CommandToolConfig struct {
+	Enabled         bool     `json:"enabled" env:"PICOCLAW_TOOLS_COMMAND_ENABLED"`
+	AllowedCommands []string `json:"allowed_commands" env:"PICOCLAW_TOOLS_COMMAND_ALLOWED_COMMANDS"`
+	AllowShell      bool     `json:"allow_shell" env:"PICOCLAW_TOOLS_COMMAND_ALLOW_SHELL"`
+	TimeoutSeconds  int      `json:"timeout_seconds" env:"PICOCLAW_TOOLS_COMMAND_TIMEOUT_SECONDS"`
+}
+
+type ImageGenConfig struct {
+	Enabled bool   `json:"enabled" env:"PICOCLAW_TOOLS_IMAGE_ENABLED"`
+	APIKey  string `json:"api_key" env:"PICOCLAW_TOOLS_IMAGE_API_KEY"`
+	APIBase string `json:"api_base" env:"PICOCLAW_TOOLS_IMAGE_API_BASE"`
+	Model   string `json:"model" env:"PICOCLAW_TOOLS_IMAGE_MODEL"`
+}
+
+// HardwareToolsConfig configures the I2C and SPI tools.
+type HardwareToolsConfig struct {
+	I2CDevices          map[string]string   `json:"i2c_devices,omitempty"`                                                           // address ("0x38") -> name shown in scan results
+	SPIDevices          map[string]string   `json:"spi_devices,omitempty"`                                                           // device ("0.0") -> name shown in list results
+	InventoryTTLSeconds int                 `json:"inventory_ttl_seconds" env:"PICOCLAW_TOOLS_HARDWARE_INVENTORY_TTL_SECONDS"`       // how long bus/device lists are cached
+	AllowedI2CBuses     FlexibleStringSlice `json:"allowed_i2c_buses,omitempty" env:"PICOCLAW_TOOLS_HARDWARE_ALLOWED_I2C_BUSES"`     // bus numbers ("1") the i2c tool may use; empty allows all
+	AllowedSPIDevices   FlexibleStringSlice `json:"allowed_spi_devices,omitempty" env:"PICOCLAW_TOOLS_HARDWARE_ALLOWED_SPI_DEVICES"` // spidev identifiers ("0.0") the spi tool may use; empty allows all
+}
+
+// ToolPermissionRule selects tools by name ("i2c") or by tool action
+// ("i2c:write"). An empty allow list permits every tool; deny always wins.
+type ToolPermissionRule struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// ToolPermissionsConfig controls which tools the model is offered. Entries in
+// chats are keyed by "channel:chat_id" or "channel". A chat's allow list
+// replaces the global one, but globally denied tools stay denied unless the
+// chat's allow list names them.
+type ToolPermissionsConfig struct {
+	Allow []string                      `json:"allow,omitempty"`
+	Deny  []string                      `json:"deny,omitempty"`
+	Chats map[string]ToolPermissionRule `json:"chats,omitempty"`
+}
+
 type ToolsConfig struct {
-	Web WebToolsConfig `json:"web"`
+	Web                   WebToolsConfig        `json:"web"`
+	Command               CommandToolConfig     `json:"command"`
+	Image                 ImageGenConfig        `json:"image"`
+	Hardware              HardwareToolsConfig   `json:"hardware"`
+	Permissions           ToolPermissionsConfig `json:"permissions"`
+	MaxOutputBytes        int                   `json:"max_output_bytes" env:"PICOCLAW_TOOLS_MAX_OUTPUT_BYTES"` // cap on tool output sent to the LLM, 0 disables
+	MaxOutputBytesPerTool map[string]int        `json:"max_output_bytes_per_tool,omitempty"`                    // per-tool overrides keyed by tool name
+	ErrorPolicyPerTool    map[string]string     `json:"error_policy_per_tool,omitempty"`                        // "surface" also shows a tool's errors to the user, "silent" never does
 }
 
 func DefaultConfig() *Config {
@@ -290,26 +409,49 @@ func DefaultConfig() *Config {
 				AccessToken:        "",
 				ReconnectInterval:  5,
 				GroupTriggerPrefix: []string{},
+				PrivateTrigger:     "always",
 				AllowFrom:          FlexibleStringSlice{},
 			},
 		},
 		Providers: ProvidersConfig{
-			Anthropic:    ProviderConfig{},
-			OpenAI:       ProviderConfig{},
-			OpenRouter:   ProviderConfig{},
-			Groq:         ProviderConfig{},
-			Zhipu:        ProviderConfig{},
-			VLLM:         ProviderConfig{},
-			Gemini:       ProviderConfig{},
-			Nvidia:       ProviderConfig{},
-			Moonshot:     ProviderConfig{},
-			ShengSuanYun: ProviderConfig{},
+			Anthropic:              ProviderConfig{},
+			OpenAI:                 ProviderConfig{},
+			OpenRouter:             ProviderConfig{},
+			Groq:                   ProviderConfig{},
+			Zhipu:                  ProviderConfig{},
+			VLLM:                   ProviderConfig{},
+			Gemini:                 ProviderConfig{},
+			Nvidia:                 ProviderConfig{},
+			Moonshot:               ProviderConfig{},
+			ShengSuanYun:           ProviderConfig{},
+			Azure:                  ProviderConfig{},
+			DefaultMaxTokens:       4096,
+			RequestTimeoutSeconds:  120,
+			MaxRetries:             2,
+			MaxIdleConns:           10,
+			IdleConnTimeoutSeconds: 30,
+			ConnMaxLifetimeSeconds: 300,
 		},
 		Gateway: GatewayConfig{
 			Host: "0.0.0.0",
 			Port: 18790,
 		},
 		Tools: ToolsConfig{
+			MaxOutputBytes: 64000,
+			Command: CommandToolConfig{
+				Enabled:         false,
+				AllowedCommands: []string{"dmesg", "uname", "uptime", "df", "free", "lsusb", "lsmod"},
+				AllowShell:      false,
+				TimeoutSeconds:  60,
+			},
+			Image: ImageGenConfig{
+				Enabled: false,
+				APIBase: "https://api.openai.com/v1",
+				Model:   "dall-e-3",
+			},
+			Hardware: HardwareToolsConfig{
+				InventoryTTLSeconds: 10,
+			},
 			Web: WebToolsConfig{
 				Brave: BraveConfig{
 					Enabled:    false,
@@ -330,6 +472,15 @@ func DefaultConfig() *Config {
 			Enabled:    false,
 			MonitorUSB: true,
 		},
+		Voice: VoiceConfig{
+			Chunked:      false,
+			ChunkSeconds: 600,
+			MaxFileMB:    25,
+		},
+		Network: NetworkConfig{
+			EgressAllow: FlexibleStringSlice{},
+			EgressDeny:  FlexibleStringSlice{},
+		},
 	}
 }
 
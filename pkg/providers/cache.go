@@ -0,0 +1,228 @@
+package providers
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// defaultResponseCacheEntries bounds a response cache when no size is given.
+const defaultResponseCacheEntries = 256
+
+// CachedProvider answers repeated identical Chat calls from memory. Calls are
+// keyed by a hash of the model, messages, tools and options; a stored
+// response is reused until its TTL passes. Responses with tool calls are
+// never stored, since replaying them would repeat their side effects, and
+// neither are errors.
+type CachedProvider struct {
+	inner      LLMProvider
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // of *cacheEntry, most recently used first
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	key     string
+	resp    LLMResponse
+	expires time.Time
+}
+
+// NewCachedProvider wraps inner with a cache keeping responses for ttl. At
+// most maxEntries responses are kept, evicting the least recently used;
+// maxEntries <= 0 uses a default of 256.
+func NewCachedProvider(inner LLMProvider, ttl time.Duration, maxEntries int) *CachedProvider {
+	if maxEntries <= 0 {
+		maxEntries = defaultResponseCacheEntries
+	}
+	return &CachedProvider{
+		inner:      inner,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+// CacheProvider wraps inner like NewCachedProvider but keeps its optional
+// capabilities: when inner is a StreamingProvider the result is one too. A
+// cache hit is streamed as a single delta.
+func CacheProvider(inner LLMProvider, ttl time.Duration, maxEntries int) LLMProvider {
+	cached := NewCachedProvider(inner, ttl, maxEntries)
+	if stream, ok := inner.(StreamingProvider); ok {
+		return &cachedStreamingProvider{CachedProvider: cached, stream: stream}
+	}
+	return cached
+}
+
+func (p *CachedProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	key, ok := p.key(messages, tools, model, options)
+	if ok {
+		if resp := p.get(key, model); resp != nil {
+			return resp, nil
+		}
+	}
+	resp, err := p.inner.Chat(ctx, messages, tools, model, options)
+	if err == nil && ok {
+		p.put(key, resp)
+	}
+	return resp, err
+}
+
+func (p *CachedProvider) GetDefaultModel() string {
+	return p.inner.GetDefaultModel()
+}
+
+// Ping forwards the health check to the wrapped provider, bypassing the
+// cache.
+func (p *CachedProvider) Ping(ctx context.Context) error {
+	return Ping(ctx, p.inner)
+}
+
+// ListModels forwards to the wrapped provider, bypassing the cache.
+func (p *CachedProvider) ListModels(ctx context.Context) ([]string, error) {
+	return ListModels(ctx, p.inner)
+}
+
+// CountTokens forwards to the wrapped provider's token counting, or returns
+// the local estimate when it has none.
+func (p *CachedProvider) CountTokens(ctx context.Context, messages []Message, tools []ToolDefinition, model string) (int, error) {
+	tc, ok := p.inner.(TokenCounter)
+	if !ok {
+		return CountTokens(messages, model) + countToolTokens(tools, model), nil
+	}
+	return tc.CountTokens(ctx, messages, tools, model)
+}
+
+// Close drops the cached responses and releases the wrapped provider.
+func (p *CachedProvider) Close() error {
+	p.mu.Lock()
+	p.entries = make(map[string]*list.Element)
+	p.order.Init()
+	p.mu.Unlock()
+	return Close(p.inner)
+}
+
+// SetUsageObserver forwards the observer to the wrapped provider when
+// supported. Cache hits use no tokens and are not reported.
+func (p *CachedProvider) SetUsageObserver(observer UsageObserver) {
+	if u, ok := p.inner.(UsageObservableProvider); ok {
+		u.SetUsageObserver(observer)
+	}
+}
+
+// SetDebugHook forwards the hook to the wrapped provider when supported.
+func (p *CachedProvider) SetDebugHook(hook DebugHook) {
+	if d, ok := p.inner.(DebuggableProvider); ok {
+		d.SetDebugHook(hook)
+	}
+}
+
+// key hashes everything that determines the response. ok is false when the
+// request cannot be encoded, in which case it bypasses the cache.
+func (p *CachedProvider) key(messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (string, bool) {
+	if model == "" {
+		model = p.inner.GetDefaultModel()
+	}
+	data, err := json.Marshal(struct {
+		Model    string                 `json:"model"`
+		Messages []Message              `json:"messages"`
+		Tools    []ToolDefinition       `json:"tools"`
+		Options  map[string]interface{} `json:"options"`
+	}{model, messages, tools, options})
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), true
+}
+
+// get returns a copy of the live response stored under key, or nil.
+func (p *CachedProvider) get(key, model string) *LLMResponse {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	el, ok := p.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := el.Value.(*cacheEntry)
+	if !p.now().Before(entry.expires) {
+		p.order.Remove(el)
+		delete(p.entries, key)
+		return nil
+	}
+	p.order.MoveToFront(el)
+
+	logger.DebugCF("provider", "Response cache hit", map[string]interface{}{
+		"model": model,
+	})
+	return copyLLMResponse(&entry.resp)
+}
+
+// put stores resp under key unless it must not be replayed.
+func (p *CachedProvider) put(key string, resp *LLMResponse) {
+	if resp == nil || len(resp.ToolCalls) > 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry := &cacheEntry{key: key, resp: *copyLLMResponse(resp), expires: p.now().Add(p.ttl)}
+	if el, ok := p.entries[key]; ok {
+		el.Value = entry
+		p.order.MoveToFront(el)
+		return
+	}
+	p.entries[key] = p.order.PushFront(entry)
+	for p.order.Len() > p.maxEntries {
+		oldest := p.order.Back()
+		p.order.Remove(oldest)
+		delete(p.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// copyLLMResponse copies resp so callers cannot modify a cached response.
+// Cached responses carry no tool calls, so the copy does not cover them.
+func copyLLMResponse(resp *LLMResponse) *LLMResponse {
+	c := *resp
+	if resp.Usage != nil {
+		usage := *resp.Usage
+		c.Usage = &usage
+	}
+	return &c
+}
+
+// cachedStreamingProvider is a CachedProvider over a StreamingProvider.
+type cachedStreamingProvider struct {
+	*CachedProvider
+	stream StreamingProvider
+}
+
+func (p *cachedStreamingProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta StreamCallback) (*LLMResponse, error) {
+	key, ok := p.key(messages, tools, model, options)
+	if ok {
+		if resp := p.get(key, model); resp != nil {
+			if onDelta != nil && resp.Content != "" {
+				onDelta(resp.Content)
+			}
+			return resp, nil
+		}
+	}
+	resp, err := p.stream.ChatStream(ctx, messages, tools, model, options, onDelta)
+	if err == nil && ok {
+		p.put(key, resp)
+	}
+	return resp, err
+}
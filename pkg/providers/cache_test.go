@@ -0,0 +1,202 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// scriptedProvider returns resp and err on every call and counts the calls.
+type scriptedProvider struct {
+	resp  LLMResponse
+	err   error
+	calls int
+}
+
+func (p *scriptedProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	resp := p.resp
+	return &resp, nil
+}
+
+func (p *scriptedProvider) GetDefaultModel() string {
+	return "scripted"
+}
+
+func TestCachedProvider_ReusesIdenticalRequests(t *testing.T) {
+	inner := &scriptedProvider{resp: LLMResponse{Content: "hi", FinishReason: "stop", Usage: &UsageInfo{TotalTokens: 5}}}
+	p := NewCachedProvider(inner, time.Minute, 0)
+	messages := []Message{{Role: "user", Content: "hello"}}
+	options := map[string]interface{}{"temperature": 0.2}
+
+	first, err := p.Chat(t.Context(), messages, nil, "m", options)
+	if err != nil {
+		t.Fatalf("Chat error = %v", err)
+	}
+	first.Content = "modified by caller"
+	first.Usage.TotalTokens = 0
+
+	second, err := p.Chat(t.Context(), messages, nil, "m", map[string]interface{}{"temperature": 0.2})
+	if err != nil {
+		t.Fatalf("Chat error = %v", err)
+	}
+	if inner.calls != 1 {
+		t.Errorf("backend calls = %d, want 1", inner.calls)
+	}
+	if second.Content != "hi" || second.Usage.TotalTokens != 5 {
+		t.Errorf("cached response = %+v, want the original", second)
+	}
+
+	// Anything that changes the request misses the cache
+	misses := []func() (*LLMResponse, error){
+		func() (*LLMResponse, error) { return p.Chat(t.Context(), messages, nil, "other", options) },
+		func() (*LLMResponse, error) {
+			return p.Chat(t.Context(), messages, nil, "m", map[string]interface{}{"temperature": 0.3})
+		},
+		func() (*LLMResponse, error) {
+			return p.Chat(t.Context(), []Message{{Role: "user", Content: "hello!"}}, nil, "m", options)
+		},
+		func() (*LLMResponse, error) {
+			return p.Chat(t.Context(), messages, []ToolDefinition{{Type: "function", Function: ToolFunctionDefinition{Name: "x"}}}, "m", options)
+		},
+	}
+	for i, miss := range misses {
+		if _, err := miss(); err != nil {
+			t.Fatalf("Chat error = %v", err)
+		}
+		if inner.calls != i+2 {
+			t.Errorf("request %d: backend calls = %d, want %d", i, inner.calls, i+2)
+		}
+	}
+}
+
+func TestCachedProvider_Expires(t *testing.T) {
+	inner := &scriptedProvider{resp: LLMResponse{Content: "hi"}}
+	p := NewCachedProvider(inner, time.Minute, 0)
+	now := time.Unix(1000, 0)
+	p.now = func() time.Time { return now }
+	messages := []Message{{Role: "user", Content: "hello"}}
+
+	p.Chat(t.Context(), messages, nil, "", nil)
+	now = now.Add(59 * time.Second)
+	p.Chat(t.Context(), messages, nil, "", nil)
+	if inner.calls != 1 {
+		t.Errorf("backend calls before expiry = %d, want 1", inner.calls)
+	}
+	now = now.Add(time.Second)
+	p.Chat(t.Context(), messages, nil, "", nil)
+	if inner.calls != 2 {
+		t.Errorf("backend calls after expiry = %d, want 2", inner.calls)
+	}
+}
+
+func TestCachedProvider_SkipsToolCallsAndErrors(t *testing.T) {
+	messages := []Message{{Role: "user", Content: "run it"}}
+
+	inner := &scriptedProvider{resp: LLMResponse{
+		ToolCalls:    []ToolCall{{ID: "call_1", Name: "exec", Arguments: map[string]interface{}{"cmd": "reboot"}}},
+		FinishReason: "tool_calls",
+	}}
+	p := NewCachedProvider(inner, time.Minute, 0)
+	p.Chat(t.Context(), messages, nil, "m", nil)
+	p.Chat(t.Context(), messages, nil, "m", nil)
+	if inner.calls != 2 {
+		t.Errorf("tool call responses: backend calls = %d, want 2", inner.calls)
+	}
+
+	inner = &scriptedProvider{err: errors.New("boom")}
+	p = NewCachedProvider(inner, time.Minute, 0)
+	p.Chat(t.Context(), messages, nil, "m", nil)
+	p.Chat(t.Context(), messages, nil, "m", nil)
+	if inner.calls != 2 {
+		t.Errorf("errors: backend calls = %d, want 2", inner.calls)
+	}
+}
+
+func TestCachedProvider_EvictsLeastRecentlyUsed(t *testing.T) {
+	inner := &scriptedProvider{resp: LLMResponse{Content: "hi"}}
+	p := NewCachedProvider(inner, time.Minute, 2)
+	ask := func(content string) {
+		p.Chat(t.Context(), []Message{{Role: "user", Content: content}}, nil, "m", nil)
+	}
+
+	ask("a")
+	ask("b")
+	ask("a") // hit; b is now the least recently used
+	ask("c") // evicts b
+	if inner.calls != 3 {
+		t.Fatalf("backend calls = %d, want 3", inner.calls)
+	}
+	ask("a")
+	if inner.calls != 3 {
+		t.Errorf("a was evicted; backend calls = %d, want 3", inner.calls)
+	}
+	ask("b")
+	if inner.calls != 4 {
+		t.Errorf("b was kept; backend calls = %d, want 4", inner.calls)
+	}
+}
+
+func TestCacheProvider_StreamsHitsAsOneDelta(t *testing.T) {
+	inner := &streamingBlockingProvider{blockingProvider: newBlockingProvider()}
+	close(inner.release)
+	p := CacheProvider(inner, time.Minute, 0)
+	stream, ok := p.(StreamingProvider)
+	if !ok {
+		t.Fatalf("CacheProvider = %T, want a StreamingProvider", p)
+	}
+	messages := []Message{{Role: "user", Content: "hello"}}
+
+	first, err := stream.ChatStream(t.Context(), messages, nil, "m", nil, nil)
+	if err != nil {
+		t.Fatalf("ChatStream error = %v", err)
+	}
+	var deltas []string
+	second, err := stream.ChatStream(t.Context(), messages, nil, "m", nil, func(d string) { deltas = append(deltas, d) })
+	if err != nil {
+		t.Fatalf("ChatStream error = %v", err)
+	}
+	if second.Content != first.Content || len(deltas) != 1 || deltas[0] != first.Content {
+		t.Errorf("hit = %+v with deltas %q, want %q as one delta", second, deltas, first.Content)
+	}
+	if n := len(inner.started); n != 1 {
+		t.Errorf("backend calls = %d, want 1", n)
+	}
+}
+
+func TestCreateProvider_AppliesResponseCache(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Model = "gpt-4o"
+	cfg.Providers.OpenAI.APIKey = "sk"
+	cfg.Providers.RequestsPerMinute = 30
+
+	provider, err := CreateProvider(cfg)
+	if err != nil {
+		t.Fatalf("CreateProvider error = %v", err)
+	}
+	if _, ok := provider.(*CachedProvider); ok {
+		t.Error("responses cached without configuration")
+	}
+
+	cfg.Providers.ResponseCacheTTLSeconds = 60
+	provider, err = CreateProvider(cfg)
+	if err != nil {
+		t.Fatalf("CreateProvider error = %v", err)
+	}
+	cached, ok := provider.(*CachedProvider)
+	if !ok {
+		t.Fatalf("provider = %T, want *CachedProvider", provider)
+	}
+	if cached.ttl != time.Minute {
+		t.Errorf("ttl = %v, want 1m", cached.ttl)
+	}
+	if _, ok := cached.inner.(*RateLimitedProvider); !ok {
+		t.Errorf("cache wraps %T, want the rate limiter", cached.inner)
+	}
+}
@@ -11,8 +11,9 @@ import (
 
 // ClaudeCliProvider implements LLMProvider using the claude CLI as a subprocess.
 type ClaudeCliProvider struct {
-	command   string
-	workspace string
+	command       string
+	workspace     string
+	usageObserver UsageObserver
 }
 
 // NewClaudeCliProvider creates a new Claude CLI provider.
@@ -54,7 +55,27 @@ func (p *ClaudeCliProvider) Chat(ctx context.Context, messages []Message, tools
 		return nil, fmt.Errorf("claude cli error: %w", err)
 	}
 
-	return p.parseClaudeCliResponse(stdout.String())
+	resp, err := p.parseClaudeCliResponse(stdout.String())
+	if err != nil {
+		return nil, err
+	}
+	reportUsage(p.usageObserver, "claude-cli", model, resp)
+	return resp, nil
+}
+
+// SetUsageObserver reports the usage of each successful Chat. Pass nil to
+// disable.
+func (p *ClaudeCliProvider) SetUsageObserver(observer UsageObserver) {
+	p.usageObserver = observer
+}
+
+// Ping checks that the claude CLI is installed. Login state is managed by the
+// CLI itself and is only verified by a real request.
+func (p *ClaudeCliProvider) Ping(ctx context.Context) error {
+	if _, err := exec.LookPath(p.command); err != nil {
+		return fmt.Errorf("claude cli not found: %w", err)
+	}
+	return nil
 }
 
 // GetDefaultModel returns the default model identifier.
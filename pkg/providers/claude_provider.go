@@ -4,23 +4,34 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"sort"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
 type ClaudeProvider struct {
-	client      *anthropic.Client
-	tokenSource func() (string, error)
+	client             *anthropic.Client
+	tokenSource        func() (string, error)
+	defaultMaxTokens   int            // used when a call passes no max_tokens
+	defaultTemperature *float64       // used when a call passes no temperature, nil = none
+	modelMaxTokens     map[string]int // overrides modelOutputCaps by model name prefix
+	timeout            time.Duration  // per-request timeout, 0 = DefaultRequestTimeout
+	retry              RetryOptions
+	usageObserver      UsageObserver
 }
 
 func NewClaudeProvider(token string) *ClaudeProvider {
 	client := anthropic.NewClient(
 		option.WithAuthToken(token),
 		option.WithBaseURL("https://api.anthropic.com"),
+		option.WithHTTPClient(&http.Client{Transport: utils.EgressTransport(nil)}),
 	)
-	return &ClaudeProvider{client: &client}
+	return &ClaudeProvider{client: &client, retry: DefaultRetryOptions()}
 }
 
 func NewClaudeProviderWithTokenSource(token string, tokenSource func() (string, error)) *ClaudeProvider {
@@ -30,32 +41,172 @@ func NewClaudeProviderWithTokenSource(token string, tokenSource func() (string,
 }
 
 func (p *ClaudeProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
-	var opts []option.RequestOption
-	if p.tokenSource != nil {
-		tok, err := p.tokenSource()
-		if err != nil {
-			return nil, fmt.Errorf("refreshing token: %w", err)
-		}
-		opts = append(opts, option.WithAuthToken(tok))
+	opts, err := p.requestOptions()
+	if err != nil {
+		return nil, err
 	}
 
-	params, err := buildClaudeParams(messages, tools, model, options)
+	params, err := buildClaudeParams(messages, tools, model, withMaxTokensCap(withDefaultMaxTokens(withDefaultTemperature(options, p.defaultTemperature), p.defaultMaxTokens), model, p.modelMaxTokens))
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := p.client.Messages.New(ctx, params, opts...)
+	// The SDK's own retries are disabled so only p.retry applies.
+	opts = append(opts, option.WithMaxRetries(0))
+	resp, err := withRetry(ctx, p.retry, func() (*anthropic.Message, error) {
+		resp, err := p.client.Messages.New(ctx, params, opts...)
+		return resp, classifySDKError(err)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("claude API call: %w", err)
 	}
 
-	return parseClaudeResponse(resp), nil
+	result := parseClaudeResponse(resp)
+	reportUsage(p.usageObserver, "anthropic", model, result)
+	return result, nil
+}
+
+// Ping sends a one-token message to verify the API is reachable and the
+// credentials are accepted.
+func (p *ClaudeProvider) Ping(ctx context.Context) error {
+	opts, err := p.requestOptions()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+
+	_, err = p.client.Messages.New(ctx, anthropic.MessageNewParams{
+		Model:     anthropic.Model(p.GetDefaultModel()),
+		MaxTokens: 1,
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock("ping")),
+		},
+	}, opts...)
+	if err != nil {
+		return fmt.Errorf("claude ping: %w", classifySDKError(err))
+	}
+	return nil
+}
+
+// CountTokens returns the exact prompt tokens of a request using the
+// count_tokens endpoint, which is free and not billed as a message.
+func (p *ClaudeProvider) CountTokens(ctx context.Context, messages []Message, tools []ToolDefinition, model string) (int, error) {
+	opts, err := p.requestOptions()
+	if err != nil {
+		return 0, err
+	}
+
+	params, err := buildClaudeParams(messages, tools, model, nil)
+	if err != nil {
+		return 0, err
+	}
+	countParams := anthropic.MessageCountTokensParams{
+		Model:    params.Model,
+		Messages: params.Messages,
+	}
+	if len(params.System) > 0 {
+		countParams.System.OfTextBlockArray = params.System
+	}
+	for _, t := range params.Tools {
+		countParams.Tools = append(countParams.Tools, anthropic.MessageCountTokensToolUnionParam{OfTool: t.OfTool})
+	}
+
+	resp, err := p.client.Messages.CountTokens(ctx, countParams, opts...)
+	if err != nil {
+		return 0, fmt.Errorf("claude count tokens: %w", classifySDKError(err))
+	}
+	return int(resp.InputTokens), nil
+}
+
+// ListModels returns the IDs of the models available to the account.
+func (p *ClaudeProvider) ListModels(ctx context.Context) ([]string, error) {
+	opts, err := p.requestOptions()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+
+	var models []string
+	pager := p.client.Models.ListAutoPaging(ctx, anthropic.ModelListParams{Limit: anthropic.Int(1000)}, opts...)
+	for pager.Next() {
+		models = append(models, pager.Current().ID)
+	}
+	if err := pager.Err(); err != nil {
+		return nil, fmt.Errorf("claude list models: %w", classifySDKError(err))
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// requestOptions returns per-request options: the request timeout, and a
+// freshly resolved token when the provider was built with a token source.
+func (p *ClaudeProvider) requestOptions() ([]option.RequestOption, error) {
+	opts := []option.RequestOption{option.WithRequestTimeout(requestTimeout(p.timeout))}
+	if p.tokenSource == nil {
+		return opts, nil
+	}
+	tok, err := p.tokenSource()
+	if err != nil {
+		return nil, fmt.Errorf("refreshing token: %w", err)
+	}
+	return append(opts, option.WithAuthToken(tok)), nil
+}
+
+// SetUsageObserver reports the usage of each successful Chat. Pass nil to
+// disable.
+func (p *ClaudeProvider) SetUsageObserver(observer UsageObserver) {
+	p.usageObserver = observer
+}
+
+// SetDefaultMaxTokens sets the completion budget for calls without
+// max_tokens. n <= 0 restores DefaultMaxTokens.
+func (p *ClaudeProvider) SetDefaultMaxTokens(n int) {
+	p.defaultMaxTokens = n
+}
+
+// SetDefaultTemperature sets the sampling temperature for calls without
+// temperature. Until it is called no temperature is sent for them.
+func (p *ClaudeProvider) SetDefaultTemperature(t float64) {
+	p.defaultTemperature = &t
+}
+
+// SetModelMaxTokens sets per-model output caps on top of modelOutputCaps.
+func (p *ClaudeProvider) SetModelMaxTokens(caps map[string]int) {
+	p.modelMaxTokens = caps
+}
+
+// SetRequestTimeout bounds each request attempt. d <= 0 restores
+// DefaultRequestTimeout.
+func (p *ClaudeProvider) SetRequestTimeout(d time.Duration) {
+	p.timeout = d
+}
+
+// SetRetryOptions sets how failed Chat calls are retried.
+func (p *ClaudeProvider) SetRetryOptions(opts RetryOptions) {
+	p.retry = opts
 }
 
 func (p *ClaudeProvider) GetDefaultModel() string {
 	return "claude-sonnet-4-5-20250929"
 }
 
+// claudeToolResultBlock returns the tool_result block for a tool message,
+// including any images the tool returned.
+func claudeToolResultBlock(msg Message) anthropic.ContentBlockParamUnion {
+	block := anthropic.NewToolResultBlock(msg.ToolCallID, msg.Content, false)
+	for _, img := range msg.Images {
+		block.OfToolResult.Content = append(block.OfToolResult.Content, anthropic.ToolResultBlockParamContentUnion{
+			OfImage: &anthropic.ImageBlockParam{
+				Source: anthropic.ImageBlockParamSourceUnion{
+					OfBase64: &anthropic.Base64ImageSourceParam{
+						Data:      img.Base64(),
+						MediaType: anthropic.Base64ImageSourceMediaType(img.MediaType),
+					},
+				},
+			},
+		})
+	}
+	return block
+}
+
 func buildClaudeParams(messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (anthropic.MessageNewParams, error) {
 	var system []anthropic.TextBlockParam
 	var anthropicMessages []anthropic.MessageParam
@@ -90,13 +241,11 @@ func buildClaudeParams(messages []Message, tools []ToolDefinition, model string,
 				)
 			}
 		case "tool":
-			anthropicMessages = append(anthropicMessages,
-				anthropic.NewUserMessage(anthropic.NewToolResultBlock(msg.ToolCallID, msg.Content, false)),
-			)
+			anthropicMessages = append(anthropicMessages, anthropic.NewUserMessage(claudeToolResultBlock(msg)))
 		}
 	}
 
-	maxTokens := int64(4096)
+	maxTokens := int64(DefaultMaxTokens)
 	if mt, ok := options["max_tokens"].(int); ok {
 		maxTokens = int64(mt)
 	}
@@ -112,7 +261,7 @@ func buildClaudeParams(messages []Message, tools []ToolDefinition, model string,
 	}
 
 	if temp, ok := options["temperature"].(float64); ok {
-		params.Temperature = anthropic.Float(temp)
+		params.Temperature = anthropic.Float(clampTemperature(model, temp))
 	}
 
 	if len(tools) > 0 {
@@ -149,7 +298,7 @@ func translateToolsForClaude(tools []ToolDefinition) []anthropic.ToolUnionParam
 }
 
 func parseClaudeResponse(resp *anthropic.Message) *LLMResponse {
-	var content string
+	var content, reasoning string
 	var toolCalls []ToolCall
 
 	for _, block := range resp.Content {
@@ -157,6 +306,8 @@ func parseClaudeResponse(resp *anthropic.Message) *LLMResponse {
 		case "text":
 			tb := block.AsText()
 			content += tb.Text
+		case "thinking":
+			reasoning += block.AsThinking().Thinking
 		case "tool_use":
 			tu := block.AsToolUse()
 			var args map[string]interface{}
@@ -179,12 +330,15 @@ func parseClaudeResponse(resp *anthropic.Message) *LLMResponse {
 		finishReason = "length"
 	case anthropic.StopReasonEndTurn:
 		finishReason = "stop"
+	case anthropic.StopReasonRefusal:
+		finishReason = FinishReasonContentFilter
 	}
 
 	return &LLMResponse{
-		Content:      content,
-		ToolCalls:    toolCalls,
-		FinishReason: finishReason,
+		Content:          content,
+		ReasoningContent: reasoning,
+		ToolCalls:        toolCalls,
+		FinishReason:     finishReason,
 		Usage: &UsageInfo{
 			PromptTokens:     int(resp.Usage.InputTokens),
 			CompletionTokens: int(resp.Usage.OutputTokens),
@@ -122,6 +122,28 @@ func TestParseClaudeResponse_TextOnly(t *testing.T) {
 	}
 }
 
+func TestParseClaudeResponse_Thinking(t *testing.T) {
+	var resp anthropic.Message
+	if err := json.Unmarshal([]byte(`{
+		"content": [
+			{"type": "thinking", "thinking": "The user wants a greeting.", "signature": "sig"},
+			{"type": "text", "text": "Hello!"}
+		],
+		"stop_reason": "end_turn",
+		"usage": {"input_tokens": 5, "output_tokens": 7}
+	}`), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	result := parseClaudeResponse(&resp)
+	if result.Content != "Hello!" {
+		t.Errorf("Content = %q, want %q", result.Content, "Hello!")
+	}
+	if result.ReasoningContent != "The user wants a greeting." {
+		t.Errorf("ReasoningContent = %q", result.ReasoningContent)
+	}
+}
+
 func TestParseClaudeResponse_StopReasons(t *testing.T) {
 	tests := []struct {
 		stopReason anthropic.StopReason
@@ -130,6 +152,7 @@ func TestParseClaudeResponse_StopReasons(t *testing.T) {
 		{anthropic.StopReasonEndTurn, "stop"},
 		{anthropic.StopReasonMaxTokens, "length"},
 		{anthropic.StopReasonToolUse, "tool_calls"},
+		{anthropic.StopReasonRefusal, FinishReasonContentFilter},
 	}
 	for _, tt := range tests {
 		resp := &anthropic.Message{
@@ -175,8 +198,10 @@ func TestClaudeProvider_ChatRoundTrip(t *testing.T) {
 	}))
 	defer server.Close()
 
+	var records []usageRecord
 	provider := NewClaudeProvider("test-token")
 	provider.client = createAnthropicTestClient(server.URL, "test-token")
+	provider.SetUsageObserver(recordUsage(&records))
 
 	messages := []Message{{Role: "user", Content: "Hello"}}
 	resp, err := provider.Chat(t.Context(), messages, nil, "claude-sonnet-4-5-20250929", map[string]interface{}{"max_tokens": 1024})
@@ -192,6 +217,10 @@ func TestClaudeProvider_ChatRoundTrip(t *testing.T) {
 	if resp.Usage.PromptTokens != 15 {
 		t.Errorf("PromptTokens = %d, want 15", resp.Usage.PromptTokens)
 	}
+	want := usageRecord{"anthropic", "claude-sonnet-4-5-20250929", UsageInfo{PromptTokens: 15, CompletionTokens: 8, TotalTokens: 23}}
+	if len(records) != 1 || records[0] != want {
+		t.Errorf("usage records = %+v, want [%+v]", records, want)
+	}
 }
 
 func TestClaudeProvider_GetDefaultModel(t *testing.T) {
@@ -0,0 +1,13 @@
+package providers
+
+import "io"
+
+// Close releases the connections held by providers that keep them open for
+// their lifetime, such as the GitHub Copilot client. Other providers need no
+// cleanup and Close returns nil.
+func Close(provider LLMProvider) error {
+	if c, ok := provider.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
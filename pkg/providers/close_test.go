@@ -0,0 +1,44 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type closingProvider struct {
+	blockingProvider
+	closed int
+}
+
+func (p *closingProvider) Close() error {
+	p.closed++
+	return nil
+}
+
+func TestClose(t *testing.T) {
+	if err := Close(NewHTTPProvider("key", "http://localhost", "")); err != nil {
+		t.Errorf("Close(HTTPProvider) = %v, want nil", err)
+	}
+
+	inner := &closingProvider{}
+	if err := Close(LimitProvider(inner, 1, 0)); err != nil {
+		t.Fatalf("Close(limited) = %v", err)
+	}
+	if inner.closed != 1 {
+		t.Errorf("closed = %d, want 1", inner.closed)
+	}
+}
+
+func TestGitHubCopilotProvider_ChatWithoutSession(t *testing.T) {
+	p, err := NewGitHubCopilotProvider("", "stdio", "gpt-4.1")
+	if err != nil {
+		t.Fatalf("NewGitHubCopilotProvider error = %v", err)
+	}
+	defer p.Close()
+
+	_, err = p.Chat(context.Background(), []Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4.1", nil)
+	if err == nil || !strings.Contains(err.Error(), "no session") {
+		t.Fatalf("Chat error = %v, want no session error", err)
+	}
+}
@@ -4,94 +4,335 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
 	"github.com/openai/openai-go/v3/responses"
+	"github.com/openai/openai-go/v3/shared"
 	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
 type CodexProvider struct {
-	client      *openai.Client
-	accountID   string
-	tokenSource func() (string, string, error)
+	client       *openai.Client
+	accountID    string
+	tokenSource  func() (string, string, error)
+	instructions string // Used when a request carries no system message
+	// defaultMaxTokens is used when a call passes no max_tokens
+	defaultMaxTokens int
+	// defaultTemperature is used when a call passes no temperature, nil = none
+	defaultTemperature *float64
+	modelMaxTokens     map[string]int // overrides modelOutputCaps by model name prefix
+	timeout            time.Duration  // per-request timeout, 0 = DefaultRequestTimeout
+	retry              RetryOptions
+	usageObserver      UsageObserver
 }
 
 const defaultCodexInstructions = "You are Codex, a coding assistant."
 
-func NewCodexProvider(token, accountID string) *CodexProvider {
-	opts := []option.RequestOption{
+// CodexOption customizes a CodexProvider at construction.
+type CodexOption func(*CodexProvider)
+
+// WithCodexInstructions sets the instructions sent when a request has no
+// system message. Empty keeps the built-in default.
+func WithCodexInstructions(instructions string) CodexOption {
+	return func(p *CodexProvider) {
+		p.instructions = instructions
+	}
+}
+
+func NewCodexProvider(token, accountID string, opts ...CodexOption) *CodexProvider {
+	reqOpts := []option.RequestOption{
 		option.WithBaseURL("https://chatgpt.com/backend-api/codex"),
 		option.WithAPIKey(token),
+		option.WithHTTPClient(&http.Client{Transport: utils.EgressTransport(nil)}),
 	}
 	if accountID != "" {
-		opts = append(opts, option.WithHeader("Chatgpt-Account-Id", accountID))
+		reqOpts = append(reqOpts, option.WithHeader("Chatgpt-Account-Id", accountID))
 	}
-	client := openai.NewClient(opts...)
-	return &CodexProvider{
+	client := openai.NewClient(reqOpts...)
+	p := &CodexProvider{
 		client:    &client,
 		accountID: accountID,
+		retry:     DefaultRetryOptions(),
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
-func NewCodexProviderWithTokenSource(token, accountID string, tokenSource func() (string, string, error)) *CodexProvider {
-	p := NewCodexProvider(token, accountID)
+func NewCodexProviderWithTokenSource(token, accountID string, tokenSource func() (string, string, error), opts ...CodexOption) *CodexProvider {
+	p := NewCodexProvider(token, accountID, opts...)
 	p.tokenSource = tokenSource
 	return p
 }
 
 func (p *CodexProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
-	var opts []option.RequestOption
-	if p.tokenSource != nil {
-		tok, accID, err := p.tokenSource()
-		if err != nil {
-			return nil, fmt.Errorf("refreshing token: %w", err)
+	opts, err := p.requestOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	responseFormat, err := responseFormatOption(options)
+	if err != nil {
+		return nil, err
+	}
+
+	params := buildCodexParams(messages, tools, model, withMaxTokensCap(withDefaultMaxTokens(withDefaultTemperature(options, p.defaultTemperature), p.defaultMaxTokens), model, p.modelMaxTokens), p.instructions)
+
+	// The SDK's own retries are disabled so only p.retry applies.
+	opts = append(opts, option.WithMaxRetries(0))
+	resp, err := withRetry(ctx, p.retry, func() (*responses.Response, error) {
+		resp, err := p.client.Responses.New(ctx, params, opts...)
+		return resp, classifySDKError(err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("codex API call: %w", err)
+	}
+
+	result := parseCodexResponse(resp)
+	if len(result.ToolCalls) == 0 {
+		if err := validateStructuredOutput(result.Content, responseFormat); err != nil {
+			return nil, err
 		}
-		opts = append(opts, option.WithAPIKey(tok))
-		if accID != "" {
-			opts = append(opts, option.WithHeader("Chatgpt-Account-Id", accID))
+	}
+
+	reportUsage(p.usageObserver, "codex", model, result)
+	return result, nil
+}
+
+// ChatStream behaves like Chat but uses the streaming Responses API, passing
+// each output_text delta to onDelta as it arrives. The returned response is
+// the same aggregate Chat would produce.
+func (p *CodexProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta StreamCallback) (*LLMResponse, error) {
+	opts, err := p.requestOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	responseFormat, err := responseFormatOption(options)
+	if err != nil {
+		return nil, err
+	}
+
+	params := buildCodexParams(messages, tools, model, withMaxTokensCap(withDefaultMaxTokens(withDefaultTemperature(options, p.defaultTemperature), p.defaultMaxTokens), model, p.modelMaxTokens), p.instructions)
+
+	stream := p.client.Responses.NewStreaming(ctx, params, opts...)
+	defer stream.Close()
+
+	acc := newCodexStreamAccumulator()
+	var final *responses.Response
+	for stream.Next() {
+		event := stream.Current()
+		switch event.Type {
+		case "response.output_text.delta":
+			if onDelta != nil && event.Delta != "" {
+				onDelta(event.Delta)
+			}
+		case "response.output_item.added":
+			acc.addItem(event.Item)
+		case "response.function_call_arguments.delta":
+			acc.appendArguments(event.ItemID, event.Delta)
+		case "response.function_call_arguments.done":
+			acc.setArguments(event.ItemID, event.Arguments)
+		case "response.output_item.done":
+			acc.finishItem(event.Item)
+		case "response.completed", "response.incomplete":
+			resp := event.Response
+			final = &resp
+		case "response.failed":
+			msg := event.Response.Error.Message
+			if msg == "" {
+				msg = "response failed"
+			}
+			return nil, fmt.Errorf("codex API call: %s", msg)
+		case "error":
+			return nil, fmt.Errorf("codex API call: %s", event.Message)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("codex API call: %w", classifySDKError(err))
+	}
+	if final == nil {
+		return nil, fmt.Errorf("codex API call: stream ended before the response completed")
+	}
+
+	// The terminal event does not always repeat the output items, so fall back
+	// to what was assembled from the stream.
+	if len(final.Output) == 0 {
+		final.Output = acc.items
+	}
+
+	result := parseCodexResponse(final)
+	if len(result.ToolCalls) == 0 {
+		if err := validateStructuredOutput(result.Content, responseFormat); err != nil {
+			return nil, err
 		}
 	}
 
-	params := buildCodexParams(messages, tools, model, options)
+	reportUsage(p.usageObserver, "codex", model, result)
+	return result, nil
+}
+
+// SetUsageObserver reports the usage of each successful Chat or ChatStream.
+// Pass nil to disable.
+func (p *CodexProvider) SetUsageObserver(observer UsageObserver) {
+	p.usageObserver = observer
+}
+
+// SetDefaultMaxTokens sets the completion budget for calls without
+// max_tokens. n <= 0 restores DefaultMaxTokens.
+func (p *CodexProvider) SetDefaultMaxTokens(n int) {
+	p.defaultMaxTokens = n
+}
+
+// SetDefaultTemperature sets the sampling temperature for calls without
+// temperature. Until it is called no temperature is sent for them.
+func (p *CodexProvider) SetDefaultTemperature(t float64) {
+	p.defaultTemperature = &t
+}
+
+// SetModelMaxTokens sets per-model output caps on top of modelOutputCaps.
+func (p *CodexProvider) SetModelMaxTokens(caps map[string]int) {
+	p.modelMaxTokens = caps
+}
+
+// SetRequestTimeout bounds each request attempt. d <= 0 restores
+// DefaultRequestTimeout.
+func (p *CodexProvider) SetRequestTimeout(d time.Duration) {
+	p.timeout = d
+}
+
+// SetRetryOptions sets how failed Chat calls are retried. Streaming calls
+// keep the SDK's retries, since a stream cannot be replayed once output has
+// been delivered.
+func (p *CodexProvider) SetRetryOptions(opts RetryOptions) {
+	p.retry = opts
+}
 
-	resp, err := p.client.Responses.New(ctx, params, opts...)
+// Ping sends a minimal responses request to verify the backend is reachable
+// and the credentials are accepted.
+func (p *CodexProvider) Ping(ctx context.Context) error {
+	opts, err := p.requestOptions()
 	if err != nil {
-		return nil, fmt.Errorf("codex API call: %w", err)
+		return fmt.Errorf("%w: %v", ErrUnauthorized, err)
 	}
 
-	return parseCodexResponse(resp), nil
+	params := buildCodexParams([]Message{{Role: "user", Content: "ping"}}, nil, p.GetDefaultModel(), nil, p.instructions)
+	if _, err := p.client.Responses.New(ctx, params, opts...); err != nil {
+		return fmt.Errorf("codex ping: %w", classifySDKError(err))
+	}
+	return nil
+}
+
+// requestOptions returns per-request options: the request timeout, and a
+// freshly resolved token when the provider was built with a token source.
+func (p *CodexProvider) requestOptions() ([]option.RequestOption, error) {
+	opts := []option.RequestOption{option.WithRequestTimeout(requestTimeout(p.timeout))}
+	if p.tokenSource == nil {
+		return opts, nil
+	}
+	tok, accID, err := p.tokenSource()
+	if err != nil {
+		return nil, fmt.Errorf("refreshing token: %w", err)
+	}
+	opts = append(opts, option.WithAPIKey(tok))
+	if accID != "" {
+		opts = append(opts, option.WithHeader("Chatgpt-Account-Id", accID))
+	}
+	return opts, nil
+}
+
+// codexStreamAccumulator rebuilds output items from streaming events.
+type codexStreamAccumulator struct {
+	items []responses.ResponseOutputItemUnion
+	index map[string]int
+}
+
+func newCodexStreamAccumulator() *codexStreamAccumulator {
+	return &codexStreamAccumulator{index: make(map[string]int)}
+}
+
+func (a *codexStreamAccumulator) addItem(item responses.ResponseOutputItemUnion) {
+	if _, ok := a.index[item.ID]; ok || item.ID == "" {
+		return
+	}
+	a.index[item.ID] = len(a.items)
+	a.items = append(a.items, item)
+}
+
+func (a *codexStreamAccumulator) appendArguments(itemID, delta string) {
+	if i, ok := a.index[itemID]; ok {
+		a.items[i].Arguments += delta
+	}
+}
+
+func (a *codexStreamAccumulator) setArguments(itemID, arguments string) {
+	if i, ok := a.index[itemID]; ok && arguments != "" {
+		a.items[i].Arguments = arguments
+	}
+}
+
+func (a *codexStreamAccumulator) finishItem(item responses.ResponseOutputItemUnion) {
+	i, ok := a.index[item.ID]
+	if !ok {
+		a.addItem(item)
+		return
+	}
+	if item.Type == "function_call" && item.Arguments == "" {
+		item.Arguments = a.items[i].Arguments
+	}
+	a.items[i] = item
 }
 
 func (p *CodexProvider) GetDefaultModel() string {
 	return "gpt-4o"
 }
 
-func buildCodexParams(messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) responses.ResponseNewParams {
+// codexFunctionCall returns the name and JSON arguments of a tool call.
+// Calls from session history carry them in Function; calls parsed from a
+// response carry Name and Arguments.
+func codexFunctionCall(tc ToolCall) (string, string) {
+	if tc.Function != nil && tc.Function.Name != "" {
+		arguments := tc.Function.Arguments
+		if arguments == "" {
+			arguments = "{}"
+		}
+		return tc.Function.Name, arguments
+	}
+	argsJSON, _ := json.Marshal(tc.Arguments)
+	return tc.Name, string(argsJSON)
+}
+
+// buildCodexParams converts a chat request to Responses API params. A system
+// message becomes the instructions; otherwise defaultInstructions is used, or
+// the built-in default when that is empty.
+func buildCodexParams(messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, defaultInstructions string) responses.ResponseNewParams {
 	var inputItems responses.ResponseInputParam
 	var instructions string
 
 	for _, msg := range messages {
 		switch msg.Role {
 		case "system":
-			instructions = msg.Content
-		case "user":
-			if msg.ToolCallID != "" {
-				inputItems = append(inputItems, responses.ResponseInputItemUnionParam{
-					OfFunctionCallOutput: &responses.ResponseInputItemFunctionCallOutputParam{
-						CallID: msg.ToolCallID,
-						Output: responses.ResponseInputItemFunctionCallOutputOutputUnionParam{OfString: openai.Opt(msg.Content)},
-					},
-				})
-			} else {
-				inputItems = append(inputItems, responses.ResponseInputItemUnionParam{
-					OfMessage: &responses.EasyInputMessageParam{
-						Role:    responses.EasyInputMessageRoleUser,
-						Content: responses.EasyInputMessageContentUnionParam{OfString: openai.Opt(msg.Content)},
-					},
-				})
+			// Several system messages (a guardrail, then the system prompt)
+			// are joined in order
+			if instructions != "" {
+				instructions += "\n\n"
 			}
+			instructions += msg.Content
+		case "user":
+			// Tool outputs come only from "tool" messages; a stray
+			// ToolCallID on a user message is ignored.
+			inputItems = append(inputItems, responses.ResponseInputItemUnionParam{
+				OfMessage: &responses.EasyInputMessageParam{
+					Role:    responses.EasyInputMessageRoleUser,
+					Content: responses.EasyInputMessageContentUnionParam{OfString: openai.Opt(msg.Content)},
+				},
+			})
 		case "assistant":
 			if len(msg.ToolCalls) > 0 {
 				if msg.Content != "" {
@@ -103,12 +344,12 @@ func buildCodexParams(messages []Message, tools []ToolDefinition, model string,
 					})
 				}
 				for _, tc := range msg.ToolCalls {
-					argsJSON, _ := json.Marshal(tc.Arguments)
+					name, arguments := codexFunctionCall(tc)
 					inputItems = append(inputItems, responses.ResponseInputItemUnionParam{
 						OfFunctionCall: &responses.ResponseFunctionToolCallParam{
 							CallID:    tc.ID,
-							Name:      tc.Name,
-							Arguments: string(argsJSON),
+							Name:      name,
+							Arguments: arguments,
 						},
 					})
 				}
@@ -124,7 +365,7 @@ func buildCodexParams(messages []Message, tools []ToolDefinition, model string,
 			inputItems = append(inputItems, responses.ResponseInputItemUnionParam{
 				OfFunctionCallOutput: &responses.ResponseInputItemFunctionCallOutputParam{
 					CallID: msg.ToolCallID,
-					Output: responses.ResponseInputItemFunctionCallOutputOutputUnionParam{OfString: openai.Opt(msg.Content)},
+					Output: codexToolOutput(msg),
 				},
 			})
 		}
@@ -138,28 +379,54 @@ func buildCodexParams(messages []Message, tools []ToolDefinition, model string,
 		Store: openai.Opt(false),
 	}
 
-	if instructions != "" {
-		params.Instructions = openai.Opt(instructions)
-	} else {
+	if instructions == "" {
+		instructions = defaultInstructions
+	}
+	if instructions == "" {
 		// ChatGPT Codex backend requires instructions to be present.
-		params.Instructions = openai.Opt(defaultCodexInstructions)
+		instructions = defaultCodexInstructions
 	}
+	params.Instructions = openai.Opt(instructions)
 
 	if maxTokens, ok := options["max_tokens"].(int); ok {
 		params.MaxOutputTokens = openai.Opt(int64(maxTokens))
 	}
 
 	if temp, ok := options["temperature"].(float64); ok {
-		params.Temperature = openai.Opt(temp)
+		params.Temperature = openai.Opt(clampTemperature(model, temp))
 	}
 
 	if len(tools) > 0 {
 		params.Tools = translateToolsForCodex(tools)
+		if parallel, ok := options["parallel_tool_calls"].(bool); ok {
+			params.ParallelToolCalls = openai.Opt(parallel)
+		}
+	}
+
+	if rf, err := responseFormatOption(options); err == nil && rf != nil {
+		params.Text = translateResponseFormatForCodex(rf)
 	}
 
 	return params
 }
 
+// codexToolOutput returns the function_call_output for a tool message. Text
+// stays a plain string unless the tool also returned images.
+func codexToolOutput(msg Message) responses.ResponseInputItemFunctionCallOutputOutputUnionParam {
+	if len(msg.Images) == 0 {
+		return responses.ResponseInputItemFunctionCallOutputOutputUnionParam{OfString: openai.Opt(msg.Content)}
+	}
+	items := responses.ResponseFunctionCallOutputItemListParam{
+		{OfInputText: &responses.ResponseInputTextContentParam{Text: msg.Content}},
+	}
+	for _, img := range msg.Images {
+		items = append(items, responses.ResponseFunctionCallOutputItemUnionParam{
+			OfInputImage: &responses.ResponseInputImageContentParam{ImageURL: openai.Opt(img.DataURL())},
+		})
+	}
+	return responses.ResponseInputItemFunctionCallOutputOutputUnionParam{OfResponseFunctionCallOutputItemArray: items}
+}
+
 func translateToolsForCodex(tools []ToolDefinition) []responses.ToolUnionParam {
 	result := make([]responses.ToolUnionParam, 0, len(tools))
 	for _, t := range tools {
@@ -176,27 +443,51 @@ func translateToolsForCodex(tools []ToolDefinition) []responses.ToolUnionParam {
 	return result
 }
 
+// translateResponseFormatForCodex maps a chat-completions style response_format
+// onto the Responses API text.format setting.
+func translateResponseFormatForCodex(rf *ResponseFormat) responses.ResponseTextConfigParam {
+	var format responses.ResponseFormatTextConfigUnionParam
+	if rf.Type == "json_schema" {
+		format.OfJSONSchema = &responses.ResponseFormatTextJSONSchemaConfigParam{
+			Name:   rf.JSONSchema.Name,
+			Schema: rf.JSONSchema.Schema,
+			Strict: openai.Opt(rf.JSONSchema.Strict),
+		}
+	} else {
+		format.OfJSONObject = &shared.ResponseFormatJSONObjectParam{}
+	}
+	return responses.ResponseTextConfigParam{Format: format}
+}
+
 func parseCodexResponse(resp *responses.Response) *LLMResponse {
-	var content strings.Builder
+	var content, reasoning strings.Builder
 	var toolCalls []ToolCall
+	refused := false
 
 	for _, item := range resp.Output {
 		switch item.Type {
+		case "reasoning":
+			for _, s := range item.Summary {
+				if reasoning.Len() > 0 {
+					reasoning.WriteString("\n\n")
+				}
+				reasoning.WriteString(s.Text)
+			}
 		case "message":
 			for _, c := range item.Content {
-				if c.Type == "output_text" {
+				switch c.Type {
+				case "output_text":
 					content.WriteString(c.Text)
+				case "refusal":
+					content.WriteString(c.Refusal)
+					refused = true
 				}
 			}
 		case "function_call":
-			var args map[string]interface{}
-			if err := json.Unmarshal([]byte(item.Arguments), &args); err != nil {
-				args = map[string]interface{}{"raw": item.Arguments}
-			}
 			toolCalls = append(toolCalls, ToolCall{
 				ID:        item.CallID,
 				Name:      item.Name,
-				Arguments: args,
+				Arguments: parseToolArguments(item.Arguments),
 			})
 		}
 	}
@@ -207,6 +498,12 @@ func parseCodexResponse(resp *responses.Response) *LLMResponse {
 	}
 	if resp.Status == "incomplete" {
 		finishReason = "length"
+		if resp.IncompleteDetails.Reason == "content_filter" {
+			finishReason = FinishReasonContentFilter
+		}
+	}
+	if refused {
+		finishReason = FinishReasonContentFilter
 	}
 
 	var usage *UsageInfo
@@ -219,35 +516,29 @@ func parseCodexResponse(resp *responses.Response) *LLMResponse {
 	}
 
 	return &LLMResponse{
-		Content:      content.String(),
-		ToolCalls:    toolCalls,
-		FinishReason: finishReason,
-		Usage:        usage,
+		Content:          content.String(),
+		ReasoningContent: reasoning.String(),
+		ToolCalls:        toolCalls,
+		FinishReason:     finishReason,
+		Usage:            usage,
 	}
 }
 
 func createCodexTokenSource() func() (string, string, error) {
 	return func() (string, string, error) {
-		cred, err := auth.GetCredential("openai")
+		// Concurrent calls on an expiring token share one refresh
+		cred, err := auth.RefreshCredential("openai", func(cred *auth.AuthCredential) (*auth.AuthCredential, error) {
+			if cred.AuthMethod != "oauth" {
+				return cred, nil
+			}
+			return auth.RefreshAccessToken(cred, auth.OpenAIOAuthConfig())
+		})
 		if err != nil {
-			return "", "", fmt.Errorf("loading auth credentials: %w", err)
+			return "", "", err
 		}
 		if cred == nil {
 			return "", "", fmt.Errorf("no credentials for openai. Run: picoclaw auth login --provider openai")
 		}
-
-		if cred.AuthMethod == "oauth" && cred.NeedsRefresh() && cred.RefreshToken != "" {
-			oauthCfg := auth.OpenAIOAuthConfig()
-			refreshed, err := auth.RefreshAccessToken(cred, oauthCfg)
-			if err != nil {
-				return "", "", fmt.Errorf("refreshing token: %w", err)
-			}
-			if err := auth.SetCredential("openai", refreshed); err != nil {
-				return "", "", fmt.Errorf("saving refreshed token: %w", err)
-			}
-			return refreshed.AccessToken, refreshed.AccountID, nil
-		}
-
 		return cred.AccessToken, cred.AccountID, nil
 	}
 }
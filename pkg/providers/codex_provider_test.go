@@ -2,8 +2,10 @@ package providers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/openai/openai-go/v3"
@@ -17,7 +19,7 @@ func TestBuildCodexParams_BasicMessage(t *testing.T) {
 	}
 	params := buildCodexParams(messages, nil, "gpt-4o", map[string]interface{}{
 		"max_tokens": 2048,
-	})
+	}, "")
 	if params.Model != "gpt-4o" {
 		t.Errorf("Model = %q, want %q", params.Model, "gpt-4o")
 	}
@@ -34,7 +36,7 @@ func TestBuildCodexParams_SystemAsInstructions(t *testing.T) {
 		{Role: "system", Content: "You are helpful"},
 		{Role: "user", Content: "Hi"},
 	}
-	params := buildCodexParams(messages, nil, "gpt-4o", map[string]interface{}{})
+	params := buildCodexParams(messages, nil, "gpt-4o", map[string]interface{}{}, "")
 	if !params.Instructions.Valid() {
 		t.Fatal("Instructions should be set")
 	}
@@ -43,6 +45,44 @@ func TestBuildCodexParams_SystemAsInstructions(t *testing.T) {
 	}
 }
 
+func TestBuildCodexParams_JoinsSystemMessages(t *testing.T) {
+	messages := []Message{
+		{Role: "system", Content: "Guardrail"},
+		{Role: "system", Content: "You are helpful"},
+		{Role: "user", Content: "Hi"},
+	}
+	params := buildCodexParams(messages, nil, "gpt-4o", map[string]interface{}{}, "")
+	if got := params.Instructions.Or(""); got != "Guardrail\n\nYou are helpful" {
+		t.Errorf("Instructions = %q, want both system messages in order", got)
+	}
+}
+
+func TestBuildCodexParams_ConfiguredInstructions(t *testing.T) {
+	params := buildCodexParams([]Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4o", map[string]interface{}{}, "You are a friendly assistant")
+	if params.Instructions.Or("") != "You are a friendly assistant" {
+		t.Errorf("Instructions = %q, want configured default", params.Instructions.Or(""))
+	}
+
+	messages := []Message{
+		{Role: "system", Content: "You are helpful"},
+		{Role: "user", Content: "Hi"},
+	}
+	params = buildCodexParams(messages, nil, "gpt-4o", map[string]interface{}{}, "You are a friendly assistant")
+	if params.Instructions.Or("") != "You are helpful" {
+		t.Errorf("Instructions = %q, want system message to override", params.Instructions.Or(""))
+	}
+}
+
+func TestNewCodexProvider_WithInstructions(t *testing.T) {
+	p := NewCodexProvider("tok", "", WithCodexInstructions("Be brief"))
+	if p.instructions != "Be brief" {
+		t.Errorf("instructions = %q, want %q", p.instructions, "Be brief")
+	}
+	if p := NewCodexProvider("tok", ""); p.instructions != "" {
+		t.Errorf("instructions = %q, want empty", p.instructions)
+	}
+}
+
 func TestBuildCodexParams_ToolCallConversation(t *testing.T) {
 	messages := []Message{
 		{Role: "user", Content: "What's the weather?"},
@@ -54,7 +94,7 @@ func TestBuildCodexParams_ToolCallConversation(t *testing.T) {
 		},
 		{Role: "tool", Content: `{"temp": 72}`, ToolCallID: "call_1"},
 	}
-	params := buildCodexParams(messages, nil, "gpt-4o", map[string]interface{}{})
+	params := buildCodexParams(messages, nil, "gpt-4o", map[string]interface{}{}, "")
 	if params.Input.OfInputItemList == nil {
 		t.Fatal("Input.OfInputItemList should not be nil")
 	}
@@ -63,6 +103,57 @@ func TestBuildCodexParams_ToolCallConversation(t *testing.T) {
 	}
 }
 
+func TestBuildCodexParams_MixedConversationCallIDs(t *testing.T) {
+	messages := []Message{
+		{Role: "user", Content: "Weather in SF and NYC?"},
+		{
+			Role: "assistant",
+			ToolCalls: []ToolCall{
+				{ID: "call_sf", Name: "get_weather", Arguments: map[string]interface{}{"city": "SF"}},
+				// Shape stored in session history by the agent loop
+				{ID: "call_nyc", Type: "function", Function: &FunctionCall{Name: "get_weather", Arguments: `{"city":"NYC"}`}},
+			},
+		},
+		{Role: "tool", Content: `{"temp": 72}`, ToolCallID: "call_sf"},
+		{Role: "tool", Content: `{"temp": 60}`, ToolCallID: "call_nyc"},
+		// A stray ToolCallID must not turn user text into a tool output
+		{Role: "user", Content: "Thanks! And tomorrow?", ToolCallID: "call_sf"},
+	}
+	items := buildCodexParams(messages, nil, "gpt-4o", nil, "").Input.OfInputItemList
+	if len(items) != 6 {
+		t.Fatalf("len(Input items) = %d, want 6", len(items))
+	}
+
+	calls := map[string]*responses.ResponseFunctionToolCallParam{}
+	for _, item := range items[1:3] {
+		if item.OfFunctionCall == nil {
+			t.Fatalf("expected function call item, got %+v", item)
+		}
+		calls[item.OfFunctionCall.CallID] = item.OfFunctionCall
+	}
+	if c := calls["call_nyc"]; c == nil || c.Name != "get_weather" || c.Arguments != `{"city":"NYC"}` {
+		t.Errorf("call_nyc = %+v", c)
+	}
+	if c := calls["call_sf"]; c == nil || c.Name != "get_weather" || c.Arguments != `{"city":"SF"}` {
+		t.Errorf("call_sf = %+v", c)
+	}
+
+	for _, item := range items[3:5] {
+		out := item.OfFunctionCallOutput
+		if out == nil {
+			t.Fatalf("expected function call output item, got %+v", item)
+		}
+		if calls[out.CallID] == nil {
+			t.Errorf("output for %q has no matching call", out.CallID)
+		}
+	}
+
+	last := items[5]
+	if last.OfFunctionCallOutput != nil || last.OfMessage == nil || last.OfMessage.Role != responses.EasyInputMessageRoleUser {
+		t.Errorf("user message with ToolCallID mapped to %+v, want a user message", last)
+	}
+}
+
 func TestBuildCodexParams_WithTools(t *testing.T) {
 	tools := []ToolDefinition{
 		{
@@ -79,7 +170,7 @@ func TestBuildCodexParams_WithTools(t *testing.T) {
 			},
 		},
 	}
-	params := buildCodexParams([]Message{{Role: "user", Content: "Hi"}}, tools, "gpt-4o", map[string]interface{}{})
+	params := buildCodexParams([]Message{{Role: "user", Content: "Hi"}}, tools, "gpt-4o", map[string]interface{}{}, "")
 	if len(params.Tools) != 1 {
 		t.Fatalf("len(Tools) = %d, want 1", len(params.Tools))
 	}
@@ -91,13 +182,66 @@ func TestBuildCodexParams_WithTools(t *testing.T) {
 	}
 }
 
+func TestBuildCodexParams_ParallelToolCalls(t *testing.T) {
+	tools := []ToolDefinition{{Type: "function", Function: ToolFunctionDefinition{Name: "read_file"}}}
+	messages := []Message{{Role: "user", Content: "Hi"}}
+
+	params := buildCodexParams(messages, tools, "gpt-4o", map[string]interface{}{}, "")
+	if params.ParallelToolCalls.Valid() {
+		t.Error("ParallelToolCalls should be unset by default")
+	}
+
+	params = buildCodexParams(messages, tools, "gpt-4o", map[string]interface{}{"parallel_tool_calls": false}, "")
+	if !params.ParallelToolCalls.Valid() || params.ParallelToolCalls.Or(true) != false {
+		t.Error("ParallelToolCalls should be explicitly set to false")
+	}
+}
+
 func TestBuildCodexParams_StoreIsFalse(t *testing.T) {
-	params := buildCodexParams([]Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4o", map[string]interface{}{})
+	params := buildCodexParams([]Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4o", map[string]interface{}{}, "")
 	if !params.Store.Valid() || params.Store.Or(true) != false {
 		t.Error("Store should be explicitly set to false")
 	}
 }
 
+func TestBuildCodexParams_ResponseFormatJSONSchema(t *testing.T) {
+	rf := &ResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &JSONSchemaSpec{
+			Name:   "weather",
+			Schema: map[string]interface{}{"type": "object"},
+			Strict: true,
+		},
+	}
+	params := buildCodexParams([]Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4o", map[string]interface{}{
+		"response_format": rf,
+	}, "")
+	if params.Text.Format.OfJSONSchema == nil {
+		t.Fatal("Text.Format should be a json_schema format")
+	}
+
+	data, err := json.Marshal(params.Text)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	var text map[string]map[string]interface{}
+	if err := json.Unmarshal(data, &text); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	if text["format"]["type"] != "json_schema" || text["format"]["name"] != "weather" {
+		t.Errorf("text.format = %v, want json_schema named weather", text["format"])
+	}
+}
+
+func TestBuildCodexParams_ResponseFormatJSONObject(t *testing.T) {
+	params := buildCodexParams([]Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4o", map[string]interface{}{
+		"response_format": ResponseFormat{Type: "json_object"},
+	}, "")
+	if params.Text.Format.OfJSONObject == nil {
+		t.Fatal("Text.Format should be a json_object format")
+	}
+}
+
 func TestParseCodexResponse_TextOutput(t *testing.T) {
 	respJSON := `{
 		"id": "resp_test",
@@ -140,6 +284,98 @@ func TestParseCodexResponse_TextOutput(t *testing.T) {
 	}
 }
 
+func TestParseCodexResponse_ReasoningSummary(t *testing.T) {
+	respJSON := `{
+		"id": "resp_test",
+		"object": "response",
+		"status": "completed",
+		"output": [
+			{
+				"id": "rs_1",
+				"type": "reasoning",
+				"summary": [
+					{"type": "summary_text", "text": "Looking up the capital."},
+					{"type": "summary_text", "text": "It is Paris."}
+				]
+			},
+			{
+				"id": "msg_1",
+				"type": "message",
+				"role": "assistant",
+				"status": "completed",
+				"content": [{"type": "output_text", "text": "Paris"}]
+			}
+		]
+	}`
+
+	var resp responses.Response
+	if err := json.Unmarshal([]byte(respJSON), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	result := parseCodexResponse(&resp)
+	if result.Content != "Paris" {
+		t.Errorf("Content = %q, want Paris", result.Content)
+	}
+	if want := "Looking up the capital.\n\nIt is Paris."; result.ReasoningContent != want {
+		t.Errorf("ReasoningContent = %q, want %q", result.ReasoningContent, want)
+	}
+}
+
+func TestParseCodexResponse_ContentFilter(t *testing.T) {
+	tests := []struct {
+		name        string
+		respJSON    string
+		wantContent string
+	}{
+		{
+			name: "incomplete due to content filter",
+			respJSON: `{
+				"id": "resp_test", "object": "response", "status": "incomplete",
+				"incomplete_details": {"reason": "content_filter"},
+				"output": []
+			}`,
+		},
+		{
+			name: "refusal output",
+			respJSON: `{
+				"id": "resp_test", "object": "response", "status": "completed",
+				"output": [{
+					"id": "msg_1", "type": "message", "role": "assistant", "status": "completed",
+					"content": [{"type": "refusal", "refusal": "I can't help with that."}]
+				}]
+			}`,
+			wantContent: "I can't help with that.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp responses.Response
+			if err := json.Unmarshal([]byte(tt.respJSON), &resp); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			result := parseCodexResponse(&resp)
+			if result.FinishReason != FinishReasonContentFilter {
+				t.Errorf("FinishReason = %q, want %q", result.FinishReason, FinishReasonContentFilter)
+			}
+			if result.Content != tt.wantContent {
+				t.Errorf("Content = %q, want %q", result.Content, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestParseCodexResponse_IncompleteMaxTokens(t *testing.T) {
+	var resp responses.Response
+	respJSON := `{"id": "resp_test", "object": "response", "status": "incomplete", "incomplete_details": {"reason": "max_output_tokens"}, "output": []}`
+	if err := json.Unmarshal([]byte(respJSON), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got := parseCodexResponse(&resp).FinishReason; got != "length" {
+		t.Errorf("FinishReason = %q, want length", got)
+	}
+}
+
 func TestParseCodexResponse_FunctionCall(t *testing.T) {
 	respJSON := `{
 		"id": "resp_test",
@@ -257,6 +493,142 @@ func TestCodexProvider_GetDefaultModel(t *testing.T) {
 	}
 }
 
+func newCodexSSEServer(t *testing.T, wantToken string, events []map[string]interface{}) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/responses" {
+			http.Error(w, "not found: "+r.URL.Path, http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+wantToken {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["stream"] != true {
+			http.Error(w, "expected stream: true", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range events {
+			data, _ := json.Marshal(ev)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev["type"], data)
+		}
+	}))
+}
+
+func TestCodexProvider_ChatStreamText(t *testing.T) {
+	events := []map[string]interface{}{
+		{"type": "response.output_item.added", "output_index": 0, "item": map[string]interface{}{
+			"id": "msg_1", "type": "message", "role": "assistant", "status": "in_progress", "content": []interface{}{},
+		}},
+		{"type": "response.output_text.delta", "item_id": "msg_1", "output_index": 0, "content_index": 0, "delta": "Hi "},
+		{"type": "response.output_text.delta", "item_id": "msg_1", "output_index": 0, "content_index": 0, "delta": "there"},
+		{"type": "response.completed", "response": map[string]interface{}{
+			"id": "resp_1", "object": "response", "status": "completed",
+			"output": []map[string]interface{}{
+				{
+					"id": "msg_1", "type": "message", "role": "assistant", "status": "completed",
+					"content": []map[string]interface{}{{"type": "output_text", "text": "Hi there"}},
+				},
+			},
+			"usage": map[string]interface{}{
+				"input_tokens": 5, "output_tokens": 2, "total_tokens": 7,
+				"input_tokens_details":  map[string]interface{}{"cached_tokens": 0},
+				"output_tokens_details": map[string]interface{}{"reasoning_tokens": 0},
+			},
+		}},
+	}
+	server := newCodexSSEServer(t, "test-token", events)
+	defer server.Close()
+
+	provider := NewCodexProvider("test-token", "")
+	provider.client = createOpenAITestClient(server.URL, "test-token", "")
+
+	var deltas []string
+	resp, err := provider.ChatStream(t.Context(), []Message{{Role: "user", Content: "Hello"}}, nil, "gpt-4o", nil, func(d string) {
+		deltas = append(deltas, d)
+	})
+	if err != nil {
+		t.Fatalf("ChatStream() error: %v", err)
+	}
+	if len(deltas) != 2 || deltas[0] != "Hi " || deltas[1] != "there" {
+		t.Errorf("deltas = %q, want [\"Hi \" \"there\"]", deltas)
+	}
+	if resp.Content != "Hi there" {
+		t.Errorf("Content = %q, want %q", resp.Content, "Hi there")
+	}
+	if resp.FinishReason != "stop" {
+		t.Errorf("FinishReason = %q, want %q", resp.FinishReason, "stop")
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 7 {
+		t.Errorf("Usage = %+v, want TotalTokens 7", resp.Usage)
+	}
+}
+
+func TestCodexProvider_ChatStreamFunctionCallUsesTokenSource(t *testing.T) {
+	events := []map[string]interface{}{
+		{"type": "response.output_item.added", "output_index": 0, "item": map[string]interface{}{
+			"id": "fc_1", "type": "function_call", "call_id": "call_1", "name": "get_weather", "arguments": "", "status": "in_progress",
+		}},
+		{"type": "response.function_call_arguments.delta", "item_id": "fc_1", "output_index": 0, "delta": `{"city":`},
+		{"type": "response.function_call_arguments.delta", "item_id": "fc_1", "output_index": 0, "delta": `"SF"}`},
+		{"type": "response.completed", "response": map[string]interface{}{
+			"id": "resp_2", "object": "response", "status": "completed", "output": []interface{}{},
+		}},
+	}
+	server := newCodexSSEServer(t, "fresh-token", events)
+	defer server.Close()
+
+	calls := 0
+	provider := NewCodexProviderWithTokenSource("stale-token", "", func() (string, string, error) {
+		calls++
+		return "fresh-token", "", nil
+	})
+	provider.client = createOpenAITestClient(server.URL, "stale-token", "")
+
+	resp, err := provider.ChatStream(t.Context(), []Message{{Role: "user", Content: "Weather?"}}, nil, "gpt-4o", nil, nil)
+	if err != nil {
+		t.Fatalf("ChatStream() error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("token source called %d times, want 1", calls)
+	}
+	if len(resp.ToolCalls) != 1 {
+		t.Fatalf("len(ToolCalls) = %d, want 1", len(resp.ToolCalls))
+	}
+	tc := resp.ToolCalls[0]
+	if tc.ID != "call_1" || tc.Name != "get_weather" {
+		t.Errorf("ToolCall = %+v, want call_1/get_weather", tc)
+	}
+	if tc.Arguments["city"] != "SF" {
+		t.Errorf("Arguments[city] = %v, want SF", tc.Arguments["city"])
+	}
+	if resp.FinishReason != "tool_calls" {
+		t.Errorf("FinishReason = %q, want %q", resp.FinishReason, "tool_calls")
+	}
+}
+
+func TestCodexProvider_ChatStreamFailed(t *testing.T) {
+	events := []map[string]interface{}{
+		{"type": "response.failed", "response": map[string]interface{}{
+			"id": "resp_3", "object": "response", "status": "failed",
+			"error": map[string]interface{}{"code": "server_error", "message": "boom"},
+		}},
+	}
+	server := newCodexSSEServer(t, "test-token", events)
+	defer server.Close()
+
+	provider := NewCodexProvider("test-token", "")
+	provider.client = createOpenAITestClient(server.URL, "test-token", "")
+
+	_, err := provider.ChatStream(t.Context(), []Message{{Role: "user", Content: "Hello"}}, nil, "gpt-4o", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("ChatStream() error = %v, want failure containing %q", err, "boom")
+	}
+}
+
 func createOpenAITestClient(baseURL, token, accountID string) *openai.Client {
 	opts := []openaiopt.RequestOption{
 		openaiopt.WithBaseURL(baseURL),
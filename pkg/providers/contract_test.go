@@ -0,0 +1,362 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// Provider contract tests: every LLMProvider backend must map its wire format
+// onto the same LLMResponse. A backend joins the suite by adding a
+// providerContract to providerContracts that renders a contractReply the way
+// its API would and locates the tool round-trip in the requests it sends.
+//
+// GitHubCopilotProvider talks to a Copilot CLI session and ClaudeCliProvider
+// runs the claude binary, so neither can be pointed at a fake server; they
+// are not part of the suite.
+
+// contractReply is the backend-neutral response a fake server must render.
+type contractReply struct {
+	kind string // "text", "tool_call" or "length"
+}
+
+// Values every fake server returns, so the contract can check the mapping.
+const (
+	contractText             = "The weather is sunny."
+	contractToolCallID       = "call_contract_1"
+	contractToolName         = "get_weather"
+	contractToolArgs         = `{"city":"Paris"}`
+	contractToolOutput       = "18C and sunny"
+	contractPromptTokens     = 11
+	contractCompletionTokens = 7
+)
+
+type providerContract struct {
+	name  string
+	model string
+	// defaultModel is what GetDefaultModel must return; empty for backends
+	// that serve whatever model the caller names.
+	defaultModel string
+	// newProvider returns the provider under test talking to baseURL.
+	newProvider func(baseURL string) LLMProvider
+	// reply renders reply in the backend's response format.
+	reply func(reply contractReply) map[string]interface{}
+	// toolRoundTrip finds, in a captured request body, the ID of the
+	// assistant's tool call and the ID and output of the tool result.
+	toolRoundTrip func(body map[string]interface{}) (callID, resultID, output string)
+}
+
+var providerContracts = []providerContract{
+	{
+		name:  "openai_compat",
+		model: "gpt-4o-mini",
+		newProvider: func(baseURL string) LLMProvider {
+			return NewHTTPProvider("test-key", baseURL, "")
+		},
+		reply: func(reply contractReply) map[string]interface{} {
+			message := map[string]interface{}{"role": "assistant", "content": contractText}
+			finish := "stop"
+			switch reply.kind {
+			case "tool_call":
+				message = map[string]interface{}{
+					"role": "assistant",
+					"tool_calls": []map[string]interface{}{{
+						"id":       contractToolCallID,
+						"type":     "function",
+						"function": map[string]interface{}{"name": contractToolName, "arguments": contractToolArgs},
+					}},
+				}
+				finish = "tool_calls"
+			case "length":
+				finish = "length"
+			}
+			return map[string]interface{}{
+				"choices": []map[string]interface{}{{"message": message, "finish_reason": finish}},
+				"usage": map[string]interface{}{
+					"prompt_tokens":     contractPromptTokens,
+					"completion_tokens": contractCompletionTokens,
+					"total_tokens":      contractPromptTokens + contractCompletionTokens,
+				},
+			}
+		},
+		toolRoundTrip: func(body map[string]interface{}) (callID, resultID, output string) {
+			for _, m := range contractList(body["messages"]) {
+				switch m["role"] {
+				case "assistant":
+					for _, tc := range contractList(m["tool_calls"]) {
+						callID, _ = tc["id"].(string)
+					}
+				case "tool":
+					resultID, _ = m["tool_call_id"].(string)
+					output, _ = m["content"].(string)
+				}
+			}
+			return callID, resultID, output
+		},
+	},
+	{
+		name:         "anthropic",
+		model:        "claude-sonnet-4-5-20250929",
+		defaultModel: "claude-sonnet-4-5-20250929",
+		newProvider: func(baseURL string) LLMProvider {
+			p := NewClaudeProvider("test-token")
+			p.client = createAnthropicTestClient(baseURL, "test-token")
+			return p
+		},
+		reply: func(reply contractReply) map[string]interface{} {
+			content := []map[string]interface{}{{"type": "text", "text": contractText}}
+			stop := "end_turn"
+			switch reply.kind {
+			case "tool_call":
+				var input map[string]interface{}
+				json.Unmarshal([]byte(contractToolArgs), &input)
+				content = []map[string]interface{}{{"type": "tool_use", "id": contractToolCallID, "name": contractToolName, "input": input}}
+				stop = "tool_use"
+			case "length":
+				stop = "max_tokens"
+			}
+			return map[string]interface{}{
+				"id":          "msg_contract",
+				"type":        "message",
+				"role":        "assistant",
+				"model":       "claude-sonnet-4-5-20250929",
+				"stop_reason": stop,
+				"content":     content,
+				"usage":       map[string]interface{}{"input_tokens": contractPromptTokens, "output_tokens": contractCompletionTokens},
+			}
+		},
+		toolRoundTrip: func(body map[string]interface{}) (callID, resultID, output string) {
+			for _, m := range contractList(body["messages"]) {
+				for _, block := range contractList(m["content"]) {
+					switch block["type"] {
+					case "tool_use":
+						callID, _ = block["id"].(string)
+					case "tool_result":
+						resultID, _ = block["tool_use_id"].(string)
+						output = contractBlockText(block["content"])
+					}
+				}
+			}
+			return callID, resultID, output
+		},
+	},
+	{
+		name:         "codex",
+		model:        "gpt-4o",
+		defaultModel: "gpt-4o",
+		newProvider: func(baseURL string) LLMProvider {
+			p := NewCodexProvider("test-token", "")
+			p.client = createOpenAITestClient(baseURL, "test-token", "")
+			return p
+		},
+		reply: func(reply contractReply) map[string]interface{} {
+			item := map[string]interface{}{
+				"id": "msg_contract", "type": "message", "role": "assistant", "status": "completed",
+				"content": []map[string]interface{}{{"type": "output_text", "text": contractText}},
+			}
+			resp := map[string]interface{}{"id": "resp_contract", "object": "response", "status": "completed"}
+			switch reply.kind {
+			case "tool_call":
+				item = map[string]interface{}{
+					"id": "fc_contract", "type": "function_call", "status": "completed",
+					"call_id": contractToolCallID, "name": contractToolName, "arguments": contractToolArgs,
+				}
+			case "length":
+				resp["status"] = "incomplete"
+				resp["incomplete_details"] = map[string]interface{}{"reason": "max_output_tokens"}
+			}
+			resp["output"] = []map[string]interface{}{item}
+			resp["usage"] = map[string]interface{}{
+				"input_tokens":          contractPromptTokens,
+				"output_tokens":         contractCompletionTokens,
+				"total_tokens":          contractPromptTokens + contractCompletionTokens,
+				"input_tokens_details":  map[string]interface{}{"cached_tokens": 0},
+				"output_tokens_details": map[string]interface{}{"reasoning_tokens": 0},
+			}
+			return resp
+		},
+		toolRoundTrip: func(body map[string]interface{}) (callID, resultID, output string) {
+			for _, item := range contractList(body["input"]) {
+				switch item["type"] {
+				case "function_call":
+					callID, _ = item["call_id"].(string)
+				case "function_call_output":
+					resultID, _ = item["call_id"].(string)
+					output, _ = item["output"].(string)
+				}
+			}
+			return callID, resultID, output
+		},
+	},
+}
+
+func TestProviderContract(t *testing.T) {
+	for _, c := range providerContracts {
+		t.Run(c.name, func(t *testing.T) {
+			runProviderContract(t, c)
+		})
+	}
+}
+
+// runProviderContract checks one backend against the contract.
+func runProviderContract(t *testing.T, c providerContract) {
+	var (
+		mu      sync.Mutex
+		reply   contractReply
+		request map[string]interface{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		request = nil
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c.reply(reply))
+	}))
+	defer server.Close()
+
+	provider := c.newProvider(server.URL)
+	options := map[string]interface{}{"max_tokens": 256}
+	chat := func(t *testing.T, kind string, messages []Message, tools []ToolDefinition) *LLMResponse {
+		t.Helper()
+		mu.Lock()
+		reply = contractReply{kind: kind}
+		mu.Unlock()
+		resp, err := provider.Chat(t.Context(), messages, tools, c.model, options)
+		if err != nil {
+			t.Fatalf("Chat() error: %v", err)
+		}
+		return resp
+	}
+	weatherTool := ToolDefinition{
+		Type: "function",
+		Function: ToolFunctionDefinition{
+			Name:        contractToolName,
+			Description: "Get the weather for a city",
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+				"required":   []string{"city"},
+			},
+		},
+	}
+	question := []Message{{Role: "user", Content: "What's the weather in Paris?"}}
+
+	t.Run("default model", func(t *testing.T) {
+		if got := provider.GetDefaultModel(); got != c.defaultModel {
+			t.Errorf("GetDefaultModel() = %q, want %q", got, c.defaultModel)
+		}
+	})
+
+	t.Run("text reply", func(t *testing.T) {
+		resp := chat(t, "text", question, nil)
+		if resp.Content != contractText {
+			t.Errorf("Content = %q, want %q", resp.Content, contractText)
+		}
+		if resp.FinishReason != "stop" {
+			t.Errorf("FinishReason = %q, want stop", resp.FinishReason)
+		}
+		if len(resp.ToolCalls) != 0 {
+			t.Errorf("ToolCalls = %+v, want none", resp.ToolCalls)
+		}
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		resp := chat(t, "text", question, nil)
+		want := UsageInfo{
+			PromptTokens:     contractPromptTokens,
+			CompletionTokens: contractCompletionTokens,
+			TotalTokens:      contractPromptTokens + contractCompletionTokens,
+		}
+		if resp.Usage == nil || *resp.Usage != want {
+			t.Errorf("Usage = %+v, want %+v", resp.Usage, want)
+		}
+	})
+
+	t.Run("tool call", func(t *testing.T) {
+		resp := chat(t, "tool_call", question, []ToolDefinition{weatherTool})
+		if resp.FinishReason != "tool_calls" {
+			t.Errorf("FinishReason = %q, want tool_calls", resp.FinishReason)
+		}
+		if len(resp.ToolCalls) != 1 {
+			t.Fatalf("ToolCalls = %+v, want one call", resp.ToolCalls)
+		}
+		tc := resp.ToolCalls[0]
+		if tc.ID != contractToolCallID || tc.Name != contractToolName {
+			t.Errorf("ToolCall = %q %q, want %q %q", tc.ID, tc.Name, contractToolCallID, contractToolName)
+		}
+		if tc.Arguments["city"] != "Paris" {
+			t.Errorf("ToolCall.Arguments = %v, want city Paris", tc.Arguments)
+		}
+	})
+
+	t.Run("tool result round trip", func(t *testing.T) {
+		messages := append(append([]Message{}, question...),
+			Message{Role: "assistant", ToolCalls: []ToolCall{{
+				ID:        contractToolCallID,
+				Type:      "function",
+				Function:  &FunctionCall{Name: contractToolName, Arguments: contractToolArgs},
+				Name:      contractToolName,
+				Arguments: map[string]interface{}{"city": "Paris"},
+			}}},
+			Message{Role: "tool", ToolCallID: contractToolCallID, Content: contractToolOutput},
+		)
+		chat(t, "text", messages, []ToolDefinition{weatherTool})
+
+		mu.Lock()
+		callID, resultID, output := c.toolRoundTrip(request)
+		mu.Unlock()
+		if callID != contractToolCallID || resultID != contractToolCallID {
+			t.Errorf("sent tool call %q and result %q, want both %q", callID, resultID, contractToolCallID)
+		}
+		if output != contractToolOutput {
+			t.Errorf("sent tool output %q, want %q", output, contractToolOutput)
+		}
+	})
+
+	t.Run("truncated reply", func(t *testing.T) {
+		if resp := chat(t, "length", question, nil); resp.FinishReason != "length" {
+			t.Errorf("FinishReason = %q, want length", resp.FinishReason)
+		}
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		if _, err := provider.Chat(ctx, question, nil, c.model, options); err == nil {
+			t.Error("Chat() with a canceled context succeeded")
+		}
+	})
+}
+
+// contractList returns v as a list of JSON objects, skipping other values.
+func contractList(v interface{}) []map[string]interface{} {
+	items, _ := v.([]interface{})
+	out := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// contractBlockText returns the text of a content value that is either a string
+// or a list of text blocks.
+func contractBlockText(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	var text string
+	for _, block := range contractList(v) {
+		if s, ok := block["text"].(string); ok {
+			text += s
+		}
+	}
+	return text
+}
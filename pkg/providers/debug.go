@@ -0,0 +1,70 @@
+package providers
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// DebugHook receives the raw traffic of a provider for troubleshooting.
+// Bodies passed to the hook have already had known secrets redacted.
+type DebugHook interface {
+	OnRequest(provider, url string, body []byte)
+	OnResponse(provider string, status int, body []byte)
+}
+
+// DebuggableProvider is implemented by providers that can report raw
+// request/response bodies to a DebugHook.
+type DebuggableProvider interface {
+	SetDebugHook(hook DebugHook)
+}
+
+// LogDebugHook logs provider traffic at DEBUG level.
+type LogDebugHook struct{}
+
+func NewLogDebugHook() *LogDebugHook {
+	return &LogDebugHook{}
+}
+
+func (h *LogDebugHook) OnRequest(provider, url string, body []byte) {
+	logger.DebugCF("provider", "Raw request",
+		map[string]interface{}{
+			"provider": provider,
+			"url":      redactSecrets(url),
+			"body":     redactSecrets(string(body)),
+		})
+}
+
+func (h *LogDebugHook) OnResponse(provider string, status int, body []byte) {
+	logger.DebugCF("provider", "Raw response",
+		map[string]interface{}{
+			"provider": provider,
+			"status":   status,
+			"body":     redactSecrets(string(body)),
+		})
+}
+
+var secretPatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`(?i)("(?:api[_-]?key|access_token|refresh_token|id_token|token|authorization|password|secret)"\s*:\s*")[^"]*(")`), `${1}[REDACTED]${2}`},
+	{regexp.MustCompile(`(?i)([?&](?:api[_-]?key|key|token|access_token)=)[^&\s"]*`), `${1}[REDACTED]`},
+	{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`), `${1}[REDACTED]`},
+	{regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{8,}`), `[REDACTED]`},
+}
+
+// redactSecrets masks API keys and tokens in s. Any extra literal secrets
+// (such as the provider's configured API key) are masked as well.
+func redactSecrets(s string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, "[REDACTED]")
+		}
+	}
+	for _, p := range secretPatterns {
+		s = p.re.ReplaceAllString(s, p.repl)
+	}
+	return s
+}
@@ -0,0 +1,87 @@
+package providers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type recordingDebugHook struct {
+	requestURL   string
+	requestBody  string
+	status       int
+	responseBody string
+}
+
+func (h *recordingDebugHook) OnRequest(provider, url string, body []byte) {
+	h.requestURL = url
+	h.requestBody = string(body)
+}
+
+func (h *recordingDebugHook) OnResponse(provider string, status int, body []byte) {
+	h.status = status
+	h.responseBody = string(body)
+}
+
+func TestRedactSecrets(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		leak string
+	}{
+		{"json api key", `{"api_key":"abc123secret"}`, "abc123secret"},
+		{"json token", `{"access_token": "tok-xyz"}`, "tok-xyz"},
+		{"bearer", `Authorization: Bearer eyJhbGciOi.payload`, "eyJhbGciOi"},
+		{"openai key", `invalid key sk-proj-AbCdEf123456`, "sk-proj-AbCdEf123456"},
+		{"query param", `https://example.com/v1?key=AIzaSecret&alt=json`, "AIzaSecret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactSecrets(tt.in)
+			if strings.Contains(got, tt.leak) {
+				t.Errorf("redactSecrets(%q) = %q, still contains secret", tt.in, got)
+			}
+			if !strings.Contains(got, "[REDACTED]") {
+				t.Errorf("redactSecrets(%q) = %q, want [REDACTED] marker", tt.in, got)
+			}
+		})
+	}
+
+	if got := redactSecrets("echo my-custom-key", "my-custom-key"); got != "echo [REDACTED]" {
+		t.Errorf("literal secret not redacted: %q", got)
+	}
+	if got := redactSecrets(`{"content":"hello"}`); got != `{"content":"hello"}` {
+		t.Errorf("non-secret content modified: %q", got)
+	}
+}
+
+func TestHTTPProvider_DebugHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"content":"echo custom-secret-key"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	hook := &recordingDebugHook{}
+	p := NewHTTPProvider("custom-secret-key", server.URL, "")
+	p.SetDebugHook(hook)
+
+	_, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "my key is custom-secret-key"}}, nil, "gpt-4o", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+
+	if hook.requestURL != server.URL+"/chat/completions" {
+		t.Errorf("request URL = %q", hook.requestURL)
+	}
+	if !strings.Contains(hook.requestBody, `"model":"gpt-4o"`) {
+		t.Errorf("request body = %q, want model field", hook.requestBody)
+	}
+	if hook.status != http.StatusOK {
+		t.Errorf("status = %d, want 200", hook.status)
+	}
+	if strings.Contains(hook.requestBody, "custom-secret-key") || strings.Contains(hook.responseBody, "custom-secret-key") {
+		t.Error("API key leaked to debug hook")
+	}
+}
@@ -0,0 +1,127 @@
+package providers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go/v3"
+)
+
+// Sentinel errors for classifying failed provider calls with errors.Is.
+var (
+	ErrRateLimited  = errors.New("rate limited")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrBadRequest   = errors.New("bad request")
+	ErrServer       = errors.New("server error")
+)
+
+// APIError is returned by Chat when the provider responds with a non-200 status.
+// It unwraps to one of the sentinel errors above based on the status code.
+type APIError struct {
+	StatusCode int
+	Message    string        // Error message parsed from the response body, if any
+	Type       string        // Provider error type/code, if any
+	Body       string        // Raw response body
+	RetryAfter time.Duration // Wait requested by the Retry-After header, 0 if none
+	kind       error
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = e.Body
+	}
+	if e.kind != nil {
+		return fmt.Sprintf("API request failed (%v):\n  Status: %d\n  Error:  %s", e.kind, e.StatusCode, msg)
+	}
+	return fmt.Sprintf("API request failed:\n  Status: %d\n  Error:  %s", e.StatusCode, msg)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+// newAPIError classifies a failed response and extracts the error message from
+// the common OpenAI-style shapes: {"error":{"message":...}}, {"error":"..."}
+// and {"message":"..."}.
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Body:       string(body),
+		kind:       classifyStatus(statusCode),
+	}
+
+	var parsed struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return apiErr
+	}
+
+	if len(parsed.Error) > 0 {
+		var detail struct {
+			Message string      `json:"message"`
+			Type    string      `json:"type"`
+			Code    interface{} `json:"code"`
+		}
+		var text string
+		if err := json.Unmarshal(parsed.Error, &detail); err == nil {
+			apiErr.Message = detail.Message
+			apiErr.Type = detail.Type
+			if apiErr.Type == "" && detail.Code != nil {
+				apiErr.Type = fmt.Sprint(detail.Code)
+			}
+		} else if err := json.Unmarshal(parsed.Error, &text); err == nil {
+			apiErr.Message = text
+		}
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = parsed.Message
+	}
+	apiErr.Message = strings.TrimSpace(apiErr.Message)
+
+	return apiErr
+}
+
+// classifySDKError converts HTTP errors from the Anthropic and OpenAI SDKs into
+// an *APIError so callers can match them with the sentinel errors. Other
+// errors are returned unchanged.
+func classifySDKError(err error) error {
+	var claudeErr *anthropic.Error
+	if errors.As(err, &claudeErr) {
+		apiErr := newAPIError(claudeErr.StatusCode, []byte(claudeErr.RawJSON()))
+		if claudeErr.Response != nil {
+			apiErr.RetryAfter = parseRetryAfter(claudeErr.Response.Header)
+		}
+		return apiErr
+	}
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		apiErr := newAPIError(openaiErr.StatusCode, []byte(openaiErr.RawJSON()))
+		if openaiErr.Response != nil {
+			apiErr.RetryAfter = parseRetryAfter(openaiErr.Response.Header)
+		}
+		return apiErr
+	}
+	return err
+}
+
+func classifyStatus(statusCode int) error {
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case statusCode >= 400 && statusCode < 500:
+		return ErrBadRequest
+	case statusCode >= 500:
+		return ErrServer
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package providers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewAPIError_Classification(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrUnauthorized},
+		{http.StatusBadRequest, ErrBadRequest},
+		{http.StatusNotFound, ErrBadRequest},
+		{http.StatusInternalServerError, ErrServer},
+		{http.StatusServiceUnavailable, ErrServer},
+	}
+	for _, tt := range tests {
+		err := newAPIError(tt.status, nil)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("status %d: errors.Is(%v) = false", tt.status, tt.want)
+		}
+	}
+}
+
+func TestNewAPIError_ParsesMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantMsg  string
+		wantType string
+	}{
+		{"openai", `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`, "Rate limit reached", "requests"},
+		{"code only", `{"error":{"message":"bad key","code":401}}`, "bad key", "401"},
+		{"string error", `{"error":"invalid model"}`, "invalid model", ""},
+		{"top-level message", `{"message":"upstream timeout"}`, "upstream timeout", ""},
+		{"not json", `<html>502 Bad Gateway</html>`, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newAPIError(http.StatusBadRequest, []byte(tt.body))
+			if err.Message != tt.wantMsg {
+				t.Errorf("Message = %q, want %q", err.Message, tt.wantMsg)
+			}
+			if err.Type != tt.wantType {
+				t.Errorf("Type = %q, want %q", err.Type, tt.wantType)
+			}
+			if err.Body != tt.body {
+				t.Errorf("Body = %q, want %q", err.Body, tt.body)
+			}
+		})
+	}
+}
+
+func TestHTTPProvider_ReturnsTypedError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"message":"slow down","type":"rate_limit"}}`))
+	}))
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	p.SetRetryOptions(RetryOptions{})
+	_, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4o", map[string]interface{}{})
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("errors.Is(err, ErrRateLimited) = false, err = %v", err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("errors.As(*APIError) = false")
+	}
+	if apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Message != "slow down" {
+		t.Errorf("APIError = %+v", apiErr)
+	}
+	if !strings.Contains(err.Error(), "slow down") {
+		t.Errorf("Error() = %q, want message included", err.Error())
+	}
+}
@@ -0,0 +1,43 @@
+package providers
+
+import (
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// reservedBodyFields are the chat completion fields the HTTP provider sets
+// itself. extra_body entries may not replace them.
+var reservedBodyFields = map[string]bool{
+	"model":                 true,
+	"messages":              true,
+	"tools":                 true,
+	"tool_choice":           true,
+	"parallel_tool_calls":   true,
+	"stream":                true,
+	"max_tokens":            true,
+	"max_completion_tokens": true,
+	"temperature":           true,
+	"response_format":       true,
+	"user":                  true,
+}
+
+// extraBodyOption returns the "extra_body" call option: vendor-specific
+// request fields such as "enable_thinking" or "repetition_penalty".
+func extraBodyOption(options map[string]interface{}) map[string]interface{} {
+	extra, _ := options["extra_body"].(map[string]interface{})
+	return extra
+}
+
+// mergeExtraBody copies extra into body, skipping reserved fields with a
+// warning so a backend option cannot silently replace a standard one.
+func mergeExtraBody(body, extra map[string]interface{}) {
+	for key, value := range extra {
+		if reservedBodyFields[key] {
+			logger.WarnCF("provider", "Ignoring extra_body field that would override a standard request field",
+				map[string]interface{}{
+					"field": key,
+				})
+			continue
+		}
+		body[key] = value
+	}
+}
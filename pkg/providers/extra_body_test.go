@@ -0,0 +1,72 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestHTTPProvider_ExtraBody(t *testing.T) {
+	var body map[string]interface{}
+	server := newChatCompletionServer(t, "ok", &body)
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	p.SetExtraBody(map[string]interface{}{
+		"enable_thinking":    false,
+		"repetition_penalty": 1.1,
+		"model":              "hijacked",
+	})
+	messages := []Message{{Role: "user", Content: "Hi"}}
+
+	options := map[string]interface{}{
+		"temperature": 0.3,
+		"extra_body": map[string]interface{}{
+			"repetition_penalty": 1.3,
+			"logit_bias":         map[string]interface{}{"50256": -100},
+			"temperature":        1.5,
+		},
+	}
+	if _, err := p.Chat(t.Context(), messages, nil, "qwen3", options); err != nil {
+		t.Fatalf("Chat error = %v", err)
+	}
+
+	if body["model"] != "qwen3" {
+		t.Errorf("model = %v, want qwen3 (extra_body must not override it)", body["model"])
+	}
+	if body["temperature"] != 0.3 {
+		t.Errorf("temperature = %v, want 0.3", body["temperature"])
+	}
+	if body["enable_thinking"] != false {
+		t.Errorf("enable_thinking = %v, want false from config", body["enable_thinking"])
+	}
+	if body["repetition_penalty"] != 1.3 {
+		t.Errorf("repetition_penalty = %v, want the call's 1.3", body["repetition_penalty"])
+	}
+	if bias, _ := body["logit_bias"].(map[string]interface{}); bias["50256"] != float64(-100) {
+		t.Errorf("logit_bias = %v", body["logit_bias"])
+	}
+}
+
+func TestProviderFactory_ExtraBodyFromConfig(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Providers.Ollama.ExtraBody = map[string]interface{}{"enable_thinking": false}
+	cfg.Providers.ModelPrefixes = map[string]config.ModelPrefixConfig{
+		"together": {APIBase: "https://api.together.xyz/v1", APIKey: "k", ExtraBody: map[string]interface{}{"top_k": 40}},
+	}
+	f := NewProviderFactory(cfg)
+
+	for model, key := range map[string]string{"ollama/qwen3": "enable_thinking", "together/llama": "top_k"} {
+		provider, err := f.Create(model)
+		if err != nil {
+			t.Fatalf("Create(%q) error = %v", model, err)
+		}
+		hp, ok := provider.(*HTTPProvider)
+		if !ok {
+			t.Fatalf("Create(%q) = %T, want *HTTPProvider", model, provider)
+		}
+		if _, ok := hp.extraBody[key]; !ok {
+			t.Errorf("Create(%q) extra body = %v, want %q", model, hp.extraBody, key)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// Inspired by and based on nanobot: https://github.com/HKUDS/nanobot
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// providerRoute describes one backend and how models are routed to it.
+type providerRoute struct {
+	name    string
+	aliases []string
+	// prefixes are model prefixes ("groq/") served natively by this backend.
+	// The prefix is stripped before the request is sent.
+	prefixes []string
+	// keywords are model-name substrings that suggest this backend when no
+	// prefix matches.
+	keywords []string
+	apiBase  string // default endpoint
+	keyless  bool   // local servers that need no API key
+	settings func(p *config.ProvidersConfig) config.ProviderConfig
+	// ready overrides the default "has an API key" check.
+	ready func(pc config.ProviderConfig) bool
+	// build overrides the default OpenAI-compatible HTTP provider, e.g. for
+	// OAuth logins. It returns nil to fall back to the HTTP provider.
+	build func(f *ProviderFactory, pc config.ProviderConfig, model string) (LLMProvider, error)
+}
+
+// openRouterNamespaces are model prefixes that name an OpenRouter model
+// ("anthropic/claude-3.5-sonnet") rather than a backend.
+var openRouterNamespaces = []string{"openrouter/", "anthropic/", "openai/", "meta-llama/", "deepseek/", "google/"}
+
+// providerRoutes lists backends in model-detection order: the first route
+// whose keyword matches and which is configured wins.
+var providerRoutes = []providerRoute{
+	{
+		name:     "moonshot",
+		prefixes: []string{"moonshot/"},
+		keywords: []string{"kimi", "moonshot"},
+		apiBase:  "https://api.moonshot.cn/v1",
+		settings: func(p *config.ProvidersConfig) config.ProviderConfig { return p.Moonshot },
+	},
+	{
+		name:     "anthropic",
+		aliases:  []string{"claude"},
+		keywords: []string{"claude"},
+		apiBase:  "https://api.anthropic.com/v1",
+		settings: func(p *config.ProvidersConfig) config.ProviderConfig { return p.Anthropic },
+		build: func(f *ProviderFactory, pc config.ProviderConfig, model string) (LLMProvider, error) {
+			if isOAuthMethod(pc.AuthMethod) {
+				return createClaudeAuthProvider()
+			}
+			return nil, nil
+		},
+	},
+	{
+		name:     "openai",
+		aliases:  []string{"gpt"},
+		keywords: []string{"gpt"},
+		apiBase:  "https://api.openai.com/v1",
+		settings: func(p *config.ProvidersConfig) config.ProviderConfig { return p.OpenAI },
+		build: func(f *ProviderFactory, pc config.ProviderConfig, model string) (LLMProvider, error) {
+			if isOAuthMethod(pc.AuthMethod) {
+				return createCodexAuthProvider(pc.Instructions)
+			}
+			return nil, nil
+		},
+	},
+	{
+		name:     "gemini",
+		aliases:  []string{"google"},
+		keywords: []string{"gemini"},
+		apiBase:  "https://generativelanguage.googleapis.com/v1beta",
+		settings: func(p *config.ProvidersConfig) config.ProviderConfig { return p.Gemini },
+	},
+	{
+		name:     "zhipu",
+		aliases:  []string{"glm"},
+		prefixes: []string{"zhipu/"},
+		keywords: []string{"glm", "zhipu", "zai"},
+		apiBase:  "https://open.bigmodel.cn/api/paas/v4",
+		settings: func(p *config.ProvidersConfig) config.ProviderConfig { return p.Zhipu },
+	},
+	{
+		name:     "groq",
+		prefixes: []string{"groq/"},
+		keywords: []string{"groq"},
+		apiBase:  "https://api.groq.com/openai/v1",
+		settings: func(p *config.ProvidersConfig) config.ProviderConfig { return p.Groq },
+	},
+	{
+		name:     "nvidia",
+		prefixes: []string{"nvidia/"},
+		keywords: []string{"nvidia"},
+		apiBase:  "https://integrate.api.nvidia.com/v1",
+		settings: func(p *config.ProvidersConfig) config.ProviderConfig { return p.Nvidia },
+	},
+	{
+		name:     "deepseek",
+		prefixes: []string{"deepseek/"},
+		apiBase:  "https://api.deepseek.com/v1",
+		settings: func(p *config.ProvidersConfig) config.ProviderConfig { return p.DeepSeek },
+	},
+	{
+		name:     "ollama",
+		prefixes: []string{"ollama/"},
+		apiBase:  "http://localhost:11434/v1",
+		keyless:  true,
+		settings: func(p *config.ProvidersConfig) config.ProviderConfig { return p.Ollama },
+	},
+	{
+		name:     "vllm",
+		prefixes: []string{"vllm/"},
+		keyless:  true,
+		settings: func(p *config.ProvidersConfig) config.ProviderConfig { return p.VLLM },
+	},
+	{
+		name:     "shengsuanyun",
+		apiBase:  "https://router.shengsuanyun.com/api/v1",
+		settings: func(p *config.ProvidersConfig) config.ProviderConfig { return p.ShengSuanYun },
+	},
+	{
+		name:     "openrouter",
+		apiBase:  "https://openrouter.ai/api/v1",
+		settings: func(p *config.ProvidersConfig) config.ProviderConfig { return p.OpenRouter },
+	},
+	{
+		name:     "azure",
+		aliases:  []string{"azure_openai"},
+		settings: func(p *config.ProvidersConfig) config.ProviderConfig { return p.Azure },
+		ready:    func(pc config.ProviderConfig) bool { return pc.APIKey != "" && pc.APIBase != "" },
+		build: func(f *ProviderFactory, pc config.ProviderConfig, model string) (LLMProvider, error) {
+			return NewAzureHTTPProvider(pc.APIKey, pc.APIBase, pc.Deployment, pc.APIVersion, pc.Proxy), nil
+		},
+	},
+	{
+		name:     "github_copilot",
+		aliases:  []string{"copilot"},
+		settings: func(p *config.ProvidersConfig) config.ProviderConfig { return p.GitHubCopilot },
+		ready:    func(config.ProviderConfig) bool { return true },
+		build: func(f *ProviderFactory, pc config.ProviderConfig, model string) (LLMProvider, error) {
+			apiBase := pc.APIBase
+			if apiBase == "" {
+				apiBase = "localhost:4321"
+			}
+			return NewGitHubCopilotProvider(apiBase, pc.ConnectMode, model)
+		},
+	},
+	{
+		name:    "claude-cli",
+		aliases: []string{"claudecode", "claude-code"},
+		ready:   func(config.ProviderConfig) bool { return true },
+		build: func(f *ProviderFactory, pc config.ProviderConfig, model string) (LLMProvider, error) {
+			workspace := f.cfg.Agents.Defaults.Workspace
+			if workspace == "" {
+				workspace = "."
+			}
+			return NewClaudeCliProvider(workspace), nil
+		},
+	},
+}
+
+func isOAuthMethod(method string) bool {
+	return method == "oauth" || method == "token"
+}
+
+func findProviderRoute(name string) *providerRoute {
+	name = strings.ToLower(name)
+	for i := range providerRoutes {
+		r := &providerRoutes[i]
+		if r.name == name {
+			return r
+		}
+		for _, alias := range r.aliases {
+			if alias == name {
+				return r
+			}
+		}
+	}
+	return nil
+}
+
+// ProviderFactory maps a model string to the LLMProvider that serves it,
+// using the provider sections of the config:
+//   - an explicit agents.defaults.provider wins when that provider is set up;
+//   - a prefix listed in providers.model_prefixes routes to its endpoint;
+//   - a backend prefix ("groq/llama-3.3-70b", "ollama/qwen2.5") routes to
+//     that backend with the prefix stripped;
+//   - OpenRouter namespaces ("anthropic/...", "meta-llama/...") go to
+//     OpenRouter;
+//   - otherwise the model family decides (claude → Anthropic, gpt → OpenAI,
+//     with OAuth logins served by the Claude and Codex providers), falling
+//     back to vLLM and then OpenRouter.
+type ProviderFactory struct {
+	cfg *config.Config
+}
+
+// NewProviderFactory returns a factory that reads provider settings from cfg.
+func NewProviderFactory(cfg *config.Config) *ProviderFactory {
+	return &ProviderFactory{cfg: cfg}
+}
+
+// Create returns the provider for model.
+func (f *ProviderFactory) Create(model string) (LLMProvider, error) {
+	if name := f.cfg.Agents.Defaults.Provider; name != "" {
+		if route := findProviderRoute(name); route != nil {
+			provider, err := f.createExplicit(route, model)
+			if provider != nil || err != nil {
+				return provider, err
+			}
+		}
+	}
+
+	if prefix, pc, ok := f.configuredPrefix(model); ok {
+		return f.buildConfiguredPrefix(prefix, pc, model)
+	}
+
+	if route, prefix := f.routeByPrefix(model); route != nil {
+		return f.build(route, model, prefix)
+	}
+
+	for _, ns := range openRouterNamespaces {
+		if strings.HasPrefix(model, ns) {
+			return f.build(findProviderRoute("openrouter"), model, "")
+		}
+	}
+
+	lowerModel := strings.ToLower(model)
+	for i := range providerRoutes {
+		route := &providerRoutes[i]
+		if f.configured(route) && containsAny(lowerModel, route.keywords) {
+			return f.build(route, model, "")
+		}
+	}
+
+	for _, name := range []string{"vllm", "openrouter"} {
+		if route := findProviderRoute(name); f.configured(route) {
+			return f.build(route, model, "")
+		}
+	}
+	return nil, fmt.Errorf("no API key configured for model: %s", model)
+}
+
+// createExplicit builds the provider named in the config. It returns nil,
+// nil when that provider has no credentials, so model detection can take
+// over.
+func (f *ProviderFactory) createExplicit(route *providerRoute, model string) (LLMProvider, error) {
+	if !f.configured(route) {
+		return nil, nil
+	}
+	prefix := ""
+	for _, p := range route.prefixes {
+		if strings.HasPrefix(model, p) {
+			prefix = p
+			break
+		}
+	}
+	return f.build(route, model, prefix)
+}
+
+// routeByPrefix returns the configured backend whose prefix starts model.
+func (f *ProviderFactory) routeByPrefix(model string) (*providerRoute, string) {
+	for i := range providerRoutes {
+		route := &providerRoutes[i]
+		for _, prefix := range route.prefixes {
+			if strings.HasPrefix(model, prefix) && f.configured(route) {
+				return route, prefix
+			}
+		}
+	}
+	return nil, ""
+}
+
+// configuredPrefix returns the longest providers.model_prefixes entry that
+// starts model. Keys may omit the trailing "/".
+func (f *ProviderFactory) configuredPrefix(model string) (string, config.ModelPrefixConfig, bool) {
+	var (
+		best   string
+		bestPC config.ModelPrefixConfig
+	)
+	for key, pc := range f.cfg.Providers.ModelPrefixes {
+		prefix := strings.TrimSuffix(key, "/") + "/"
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best, bestPC = prefix, pc
+		}
+	}
+	return best, bestPC, best != ""
+}
+
+// buildConfiguredPrefix builds the provider for a providers.model_prefixes
+// entry: its own endpoint when api_base is set, otherwise the built-in
+// backend owning the prefix.
+func (f *ProviderFactory) buildConfiguredPrefix(prefix string, pc config.ModelPrefixConfig, model string) (LLMProvider, error) {
+	strip := prefix
+	if pc.KeepPrefix {
+		strip = ""
+	}
+
+	if pc.APIBase == "" {
+		for i := range providerRoutes {
+			route := &providerRoutes[i]
+			for _, p := range route.prefixes {
+				if p == prefix {
+					return f.build(route, model, strip)
+				}
+			}
+		}
+		return nil, fmt.Errorf("model prefix %q has no api_base configured (model: %s)", prefix, model)
+	}
+
+	p := NewHTTPProvider(pc.APIKey, pc.APIBase, pc.Proxy)
+	p.name = strings.TrimSuffix(prefix, "/")
+	p.modelPrefix = strip
+	p.SetExtraBody(pc.ExtraBody)
+	return p, nil
+}
+
+// configured reports whether the route has the credentials it needs.
+func (f *ProviderFactory) configured(route *providerRoute) bool {
+	var pc config.ProviderConfig
+	if route.settings != nil {
+		pc = route.settings(&f.cfg.Providers)
+	}
+	if route.ready != nil {
+		return route.ready(pc)
+	}
+	if route.keyless {
+		return pc.APIBase != "" || route.apiBase != ""
+	}
+	return pc.APIKey != "" || (route.build != nil && pc.AuthMethod != "")
+}
+
+// build constructs the provider for route; prefix is stripped from model
+// names before they are sent.
+func (f *ProviderFactory) build(route *providerRoute, model, prefix string) (LLMProvider, error) {
+	var pc config.ProviderConfig
+	if route.settings != nil {
+		pc = route.settings(&f.cfg.Providers)
+	}
+	if route.build != nil {
+		provider, err := route.build(f, pc, model)
+		if provider != nil || err != nil {
+			return provider, err
+		}
+	}
+
+	apiBase := pc.APIBase
+	if apiBase == "" {
+		apiBase = route.apiBase
+	}
+	if pc.APIKey == "" && !route.keyless && !strings.HasPrefix(model, "bedrock/") {
+		return nil, fmt.Errorf("no API key configured for provider %s (model: %s)", route.name, model)
+	}
+	if apiBase == "" {
+		return nil, fmt.Errorf("no API base configured for provider %s (model: %s)", route.name, model)
+	}
+
+	p := NewHTTPProvider(pc.APIKey, apiBase, pc.Proxy)
+	p.name = route.name
+	p.modelPrefix = prefix
+	p.SetExtraBody(pc.ExtraBody)
+	return p, nil
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package providers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestProviderFactory_Routing(t *testing.T) {
+	tests := []struct {
+		name       string
+		provider   string
+		model      string
+		setup      func(p *config.ProvidersConfig)
+		wantBase   string
+		wantPrefix string
+	}{
+		{
+			name:       "groq prefix",
+			model:      "groq/llama-3.3-70b",
+			setup:      func(p *config.ProvidersConfig) { p.Groq.APIKey = "gsk" },
+			wantBase:   "https://api.groq.com/openai/v1",
+			wantPrefix: "groq/",
+		},
+		{
+			name:       "ollama prefix needs no key",
+			model:      "ollama/qwen2.5",
+			wantBase:   "http://localhost:11434/v1",
+			wantPrefix: "ollama/",
+		},
+		{
+			name:  "ollama custom base",
+			model: "ollama/qwen2.5",
+			setup: func(p *config.ProvidersConfig) {
+				p.Ollama.APIBase = "http://pi.local:11434/v1"
+			},
+			wantBase:   "http://pi.local:11434/v1",
+			wantPrefix: "ollama/",
+		},
+		{
+			name:       "deepseek prefix with deepseek key",
+			model:      "deepseek/deepseek-chat",
+			setup:      func(p *config.ProvidersConfig) { p.DeepSeek.APIKey = "sk"; p.OpenRouter.APIKey = "or" },
+			wantBase:   "https://api.deepseek.com/v1",
+			wantPrefix: "deepseek/",
+		},
+		{
+			name:     "deepseek namespace without deepseek key goes to openrouter",
+			model:    "deepseek/deepseek-chat",
+			setup:    func(p *config.ProvidersConfig) { p.OpenRouter.APIKey = "or" },
+			wantBase: "https://openrouter.ai/api/v1",
+		},
+		{
+			name:     "openrouter namespace",
+			model:    "anthropic/claude-3.5-sonnet",
+			setup:    func(p *config.ProvidersConfig) { p.Anthropic.APIKey = "ak"; p.OpenRouter.APIKey = "or" },
+			wantBase: "https://openrouter.ai/api/v1",
+		},
+		{
+			name:     "claude model with api key",
+			model:    "claude-sonnet-4",
+			setup:    func(p *config.ProvidersConfig) { p.Anthropic.APIKey = "ak" },
+			wantBase: "https://api.anthropic.com/v1",
+		},
+		{
+			name:     "gpt model",
+			model:    "gpt-4o",
+			setup:    func(p *config.ProvidersConfig) { p.OpenAI.APIKey = "sk"; p.OpenAI.APIBase = "https://proxy.example/v1" },
+			wantBase: "https://proxy.example/v1",
+		},
+		{
+			name:     "kimi keyword",
+			model:    "kimi-k2.5",
+			setup:    func(p *config.ProvidersConfig) { p.Moonshot.APIKey = "sk" },
+			wantBase: "https://api.moonshot.cn/v1",
+		},
+		{
+			name:     "explicit provider wins over model family",
+			provider: "groq",
+			model:    "gpt-oss-120b",
+			setup:    func(p *config.ProvidersConfig) { p.Groq.APIKey = "gsk"; p.OpenAI.APIKey = "sk" },
+			wantBase: "https://api.groq.com/openai/v1",
+		},
+		{
+			name:     "unconfigured explicit provider falls back to detection",
+			provider: "groq",
+			model:    "gpt-4o",
+			setup:    func(p *config.ProvidersConfig) { p.OpenAI.APIKey = "sk" },
+			wantBase: "https://api.openai.com/v1",
+		},
+		{
+			name:  "configured prefix",
+			model: "together/llama-3-70b",
+			setup: func(p *config.ProvidersConfig) {
+				p.ModelPrefixes = map[string]config.ModelPrefixConfig{
+					"together/": {APIBase: "https://api.together.xyz/v1", APIKey: "tk"},
+				}
+			},
+			wantBase:   "https://api.together.xyz/v1",
+			wantPrefix: "together/",
+		},
+		{
+			name:  "configured prefix kept and longest wins",
+			model: "lab/big/model",
+			setup: func(p *config.ProvidersConfig) {
+				p.ModelPrefixes = map[string]config.ModelPrefixConfig{
+					"lab":     {APIBase: "http://lab:8000/v1"},
+					"lab/big": {APIBase: "http://big:8000/v1", KeepPrefix: true},
+				}
+			},
+			wantBase: "http://big:8000/v1",
+		},
+		{
+			name:  "configured prefix adjusts built-in backend",
+			model: "groq/llama-3.3-70b",
+			setup: func(p *config.ProvidersConfig) {
+				p.Groq.APIKey = "gsk"
+				p.ModelPrefixes = map[string]config.ModelPrefixConfig{"groq/": {KeepPrefix: true}}
+			},
+			wantBase: "https://api.groq.com/openai/v1",
+		},
+		{
+			name:     "vllm fallback",
+			model:    "my-local-model",
+			setup:    func(p *config.ProvidersConfig) { p.VLLM.APIBase = "http://localhost:8000/v1" },
+			wantBase: "http://localhost:8000/v1",
+		},
+		{
+			name:     "openrouter default",
+			model:    "mistral-large",
+			setup:    func(p *config.ProvidersConfig) { p.OpenRouter.APIKey = "or" },
+			wantBase: "https://openrouter.ai/api/v1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Agents.Defaults.Provider = tt.provider
+			if tt.setup != nil {
+				tt.setup(&cfg.Providers)
+			}
+
+			provider, err := NewProviderFactory(cfg).Create(tt.model)
+			if err != nil {
+				t.Fatalf("Create(%q) error = %v", tt.model, err)
+			}
+			hp, ok := provider.(*HTTPProvider)
+			if !ok {
+				t.Fatalf("Create(%q) returned %T, want *HTTPProvider", tt.model, provider)
+			}
+			if hp.apiBase != tt.wantBase {
+				t.Errorf("apiBase = %q, want %q", hp.apiBase, tt.wantBase)
+			}
+			if hp.modelPrefix != tt.wantPrefix {
+				t.Errorf("modelPrefix = %q, want %q", hp.modelPrefix, tt.wantPrefix)
+			}
+		})
+	}
+}
+
+func TestProviderFactory_SpecialProviders(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Provider = "azure"
+	cfg.Providers.Azure.APIKey = "az"
+	cfg.Providers.Azure.APIBase = "https://res.openai.azure.com"
+	cfg.Providers.Azure.Deployment = "prod"
+
+	provider, err := NewProviderFactory(cfg).Create("gpt-4o")
+	if err != nil {
+		t.Fatalf("Create error = %v", err)
+	}
+	hp, ok := provider.(*HTTPProvider)
+	if !ok || hp.azure == nil || hp.azure.deployment != "prod" {
+		t.Fatalf("expected Azure HTTP provider, got %#v", provider)
+	}
+}
+
+func TestProviderFactory_NoCredentials(t *testing.T) {
+	cfg := config.DefaultConfig()
+
+	_, err := NewProviderFactory(cfg).Create("claude-sonnet-4")
+	if err == nil || !strings.Contains(err.Error(), "no API key configured") {
+		t.Fatalf("expected missing key error, got %v", err)
+	}
+
+	_, err = NewProviderFactory(cfg).Create("meta-llama/llama-3-70b")
+	if err == nil || !strings.Contains(err.Error(), "openrouter") {
+		t.Fatalf("expected openrouter key error, got %v", err)
+	}
+
+	cfg.Providers.ModelPrefixes = map[string]config.ModelPrefixConfig{"lab/": {APIKey: "k"}}
+	_, err = NewProviderFactory(cfg).Create("lab/model")
+	if err == nil || !strings.Contains(err.Error(), "no api_base") {
+		t.Fatalf("expected missing api_base error, got %v", err)
+	}
+}
+
+func TestHTTPProvider_StripsRoutingPrefix(t *testing.T) {
+	var body map[string]interface{}
+	server := newChatCompletionServer(t, "ok", &body)
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	p.modelPrefix = "groq/"
+	if _, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "groq/llama-3.3-70b", nil); err != nil {
+		t.Fatalf("Chat error = %v", err)
+	}
+	if body["model"] != "llama-3.3-70b" {
+		t.Errorf("model = %v, want llama-3.3-70b", body["model"])
+	}
+}
@@ -13,16 +13,22 @@ type GitHubCopilotProvider struct {
 	uri         string
 	connectMode string // `stdio` or `grpc``
 
+	// client stays connected for the provider's lifetime; Close stops it.
+	client  *copilot.Client
 	session *copilot.Session
+
+	usageObserver UsageObserver
 }
 
 func NewGitHubCopilotProvider(uri string, connectMode string, model string) (*GitHubCopilotProvider, error) {
-
-	var session *copilot.Session
-	if connectMode == "" {
-		connectMode = "grpc"
+	p := &GitHubCopilotProvider{
+		uri:         uri,
+		connectMode: connectMode,
+	}
+	if p.connectMode == "" {
+		p.connectMode = "grpc"
 	}
-	switch connectMode {
+	switch p.connectMode {
 
 	case "stdio":
 		//todo
@@ -33,23 +39,27 @@ func NewGitHubCopilotProvider(uri string, connectMode string, model string) (*Gi
 		if err := client.Start(context.Background()); err != nil {
 			return nil, fmt.Errorf("Can't connect to Github Copilot, https://github.com/github/copilot-sdk/blob/main/docs/getting-started.md#connecting-to-an-external-cli-server for details")
 		}
-		defer client.Stop()
-		session, _ = client.CreateSession(context.Background(), &copilot.SessionConfig{
+		session, err := client.CreateSession(context.Background(), &copilot.SessionConfig{
 			Model: model,
 			Hooks: &copilot.SessionHooks{},
 		})
-
+		if err != nil {
+			client.Stop()
+			return nil, fmt.Errorf("failed to create Github Copilot session: %w", err)
+		}
+		p.client = client
+		p.session = session
 	}
 
-	return &GitHubCopilotProvider{
-		uri:         uri,
-		connectMode: connectMode,
-		session:     session,
-	}, nil
+	return p, nil
 }
 
 // Chat sends a chat request to GitHub Copilot
 func (p *GitHubCopilotProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	if p.session == nil {
+		return nil, fmt.Errorf("github copilot: no session for connect mode %q", p.connectMode)
+	}
+
 	type tempMessage struct {
 		Role    string `json:"role"`
 		Content string `json:"content"`
@@ -65,15 +75,42 @@ func (p *GitHubCopilotProvider) Chat(ctx context.Context, messages []Message, to
 
 	fullcontent, _ := json.Marshal(out)
 
-	content, _ := p.session.Send(ctx, copilot.MessageOptions{
+	// Send only returns the message ID; wait for the assistant's reply.
+	event, err := p.session.SendAndWait(ctx, copilot.MessageOptions{
 		Prompt: string(fullcontent),
 	})
+	if err != nil {
+		return nil, fmt.Errorf("github copilot: %w", err)
+	}
 
-	return &LLMResponse{
+	var content string
+	if event != nil && event.Data.Content != nil {
+		content = *event.Data.Content
+	}
+
+	resp := &LLMResponse{
 		FinishReason: "stop",
 		Content:      content,
-	}, nil
+	}
+	reportUsage(p.usageObserver, "github_copilot", model, resp)
+	return resp, nil
+}
+
+// SetUsageObserver reports each successful Chat. Copilot does not report
+// token counts, so usage is always zero.
+func (p *GitHubCopilotProvider) SetUsageObserver(observer UsageObserver) {
+	p.usageObserver = observer
+}
 
+// Close destroys the session and stops the Copilot client.
+func (p *GitHubCopilotProvider) Close() error {
+	if p.client == nil {
+		return nil
+	}
+	err := p.client.Stop()
+	p.client = nil
+	p.session = nil
+	return err
 }
 
 func (p *GitHubCopilotProvider) GetDefaultModel() string {
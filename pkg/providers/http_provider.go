@@ -14,65 +14,117 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/auth"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
 type HTTPProvider struct {
+	// name identifies the backend to usage observers ("groq"); set by
+	// ProviderFactory.
+	name       string
 	apiKey     string
 	apiBase    string
 	httpClient *http.Client
+	azure      *azureConfig
+	debugHook  DebugHook
+	// modelPrefix is a routing prefix ("groq/") stripped from model names
+	// before they are sent; set by ProviderFactory.
+	modelPrefix string
+	// defaultMaxTokens is used when a call passes no max_tokens
+	defaultMaxTokens int
+	// defaultTemperature is used when a call passes no temperature, nil = none
+	defaultTemperature *float64
+	// modelMaxTokens overrides modelOutputCaps by model name prefix
+	modelMaxTokens map[string]int
+	retry          RetryOptions
+	proxy          *url.URL // nil routes via the environment's proxy settings
+	usageObserver  UsageObserver
+	// extraBody holds backend-specific request fields from the config
+	extraBody map[string]interface{}
 }
 
-func NewHTTPProvider(apiKey, apiBase, proxy string) *HTTPProvider {
-	client := &http.Client{
-		Timeout: 120 * time.Second,
-	}
+// azureConfig switches the provider to Azure OpenAI's URL layout and auth header.
+type azureConfig struct {
+	deployment string
+	apiVersion string
+}
+
+const defaultAzureAPIVersion = "2024-10-21"
 
+func NewHTTPProvider(apiKey, apiBase, proxy string) *HTTPProvider {
+	var proxyURL *url.URL
 	if proxy != "" {
-		proxyURL, err := url.Parse(proxy)
-		if err == nil {
-			client.Transport = &http.Transport{
-				Proxy: http.ProxyURL(proxyURL),
-			}
+		if u, err := url.Parse(proxy); err == nil {
+			proxyURL = u
 		}
 	}
 
+	client := &http.Client{
+		Timeout:   DefaultRequestTimeout,
+		Transport: utils.EgressTransport(newProviderTransport(proxyURL, DefaultTransportOptions())),
+	}
+
 	return &HTTPProvider{
+		name:       "openai_compat",
 		apiKey:     apiKey,
 		apiBase:    strings.TrimRight(apiBase, "/"),
 		httpClient: client,
+		retry:      DefaultRetryOptions(),
+		proxy:      proxyURL,
 	}
 }
 
+// NewAzureHTTPProvider creates a provider for an Azure OpenAI resource.
+// Requests go to {apiBase}/openai/deployments/{deployment}/chat/completions
+// and authenticate with the api-key header.
+func NewAzureHTTPProvider(apiKey, apiBase, deployment, apiVersion, proxy string) *HTTPProvider {
+	if apiVersion == "" {
+		apiVersion = defaultAzureAPIVersion
+	}
+	p := NewHTTPProvider(apiKey, apiBase, proxy)
+	p.name = "azure"
+	p.azure = &azureConfig{
+		deployment: deployment,
+		apiVersion: apiVersion,
+	}
+	return p
+}
+
 func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
 	if p.apiBase == "" {
 		return nil, fmt.Errorf("API base not configured")
 	}
+	requestedModel := model
 
-	// Strip provider prefix from model name (e.g., moonshot/kimi-k2.5 -> kimi-k2.5)
-	if idx := strings.Index(model, "/"); idx != -1 {
-		prefix := model[:idx]
-		if prefix == "moonshot" || prefix == "nvidia" {
-			model = model[idx+1:]
-		}
+	// Azure model names are deployment names and must be used verbatim
+	if p.azure == nil {
+		model = normalizeModel(strings.TrimPrefix(model, p.modelPrefix))
 	}
 
 	requestBody := map[string]interface{}{
 		"model":    model,
-		"messages": messages,
+		"messages": openAIMessages(messages),
 	}
 
 	if len(tools) > 0 {
 		requestBody["tools"] = tools
 		requestBody["tool_choice"] = "auto"
+		// Set to false to get at most one tool call per response
+		if parallel, ok := options["parallel_tool_calls"].(bool); ok {
+			requestBody["parallel_tool_calls"] = parallel
+		}
 	}
 
+	options = withDefaultMaxTokens(options, p.defaultMaxTokens)
+	options = withDefaultTemperature(options, p.defaultTemperature)
 	if maxTokens, ok := options["max_tokens"].(int); ok {
 		lowerModel := strings.ToLower(model)
+		maxTokens = clampMaxTokens(model, maxTokens, p.modelMaxTokens)
 		if strings.Contains(lowerModel, "glm") || strings.Contains(lowerModel, "o1") {
 			requestBody["max_completion_tokens"] = maxTokens
 		} else {
@@ -86,23 +138,73 @@ func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []Too
 		if strings.Contains(lowerModel, "kimi") && strings.Contains(lowerModel, "k2") {
 			requestBody["temperature"] = 1.0
 		} else {
-			requestBody["temperature"] = temperature
+			requestBody["temperature"] = clampTemperature(model, temperature)
 		}
 	}
 
+	responseFormat, err := responseFormatOption(options)
+	if err != nil {
+		return nil, err
+	}
+	if responseFormat != nil {
+		requestBody["response_format"] = responseFormat
+	}
+
+	if user := userOption(options); user != "" {
+		requestBody["user"] = user
+	}
+
+	// Fields passed with the call win over the configured ones
+	mergeExtraBody(requestBody, p.extraBody)
+	mergeExtraBody(requestBody, extraBodyOption(options))
+
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", p.apiBase+"/chat/completions", bytes.NewReader(jsonData))
+	endpoint := p.chatCompletionsURL(model)
+	body, err := withRetry(ctx, p.retry, func() ([]byte, error) {
+		return p.post(ctx, endpoint, jsonData)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.parseResponse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.ToolCalls) == 0 {
+		if err := validateStructuredOutput(result.Content, responseFormat); err != nil {
+			return nil, err
+		}
+	}
+
+	reportUsage(p.usageObserver, p.name, requestedModel, result)
+	return result, nil
+}
+
+// post sends one chat completion request and returns the body of a
+// successful response.
+func (p *HTTPProvider) post(ctx context.Context, endpoint string, jsonData []byte) ([]byte, error) {
+	if p.debugHook != nil {
+		p.debugHook.OnRequest("openai_compat", redactSecrets(endpoint, p.apiKey), []byte(redactSecrets(string(jsonData), p.apiKey)))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	if p.apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+		if p.azure != nil {
+			req.Header.Set("api-key", p.apiKey)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+p.apiKey)
+		}
 	}
 
 	resp, err := p.httpClient.Do(req)
@@ -116,19 +218,189 @@ func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []Too
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
+	if p.debugHook != nil {
+		p.debugHook.OnResponse("openai_compat", resp.StatusCode, []byte(redactSecrets(string(body), p.apiKey)))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := newAPIError(resp.StatusCode, body)
+		apiErr.RetryAfter = parseRetryAfter(resp.Header)
+		return nil, apiErr
+	}
+
+	return body, nil
+}
+
+// Ping lists the available models, which is free and fails fast on a bad key.
+func (p *HTTPProvider) Ping(ctx context.Context) error {
+	resp, err := p.getModels(ctx)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// ListModels returns the model IDs from the endpoint's /models listing. IDs
+// carry the routing prefix ("groq/") when the provider was built with one, so
+// they match the names used in the config.
+func (p *HTTPProvider) ListModels(ctx context.Context) ([]string, error) {
+	resp, err := p.getModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var listing struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4*1024*1024)).Decode(&listing); err != nil {
+		return nil, fmt.Errorf("failed to parse model list: %w", err)
+	}
+
+	models := make([]string, 0, len(listing.Data))
+	for _, m := range listing.Data {
+		if m.ID != "" {
+			models = append(models, p.modelPrefix+m.ID)
+		}
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// getModels requests the /models listing. The caller closes the body of a
+// successful response.
+func (p *HTTPProvider) getModels(ctx context.Context) (*http.Response, error) {
+	if p.apiBase == "" {
+		return nil, fmt.Errorf("API base not configured")
+	}
+
+	endpoint := p.apiBase + "/models"
+	if p.azure != nil {
+		endpoint = fmt.Sprintf("%s/openai/models?api-version=%s", p.apiBase, url.QueryEscape(p.azure.apiVersion))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if p.apiKey != "" {
+		if p.azure != nil {
+			req.Header.Set("api-key", p.apiKey)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+p.apiKey)
+		}
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(body))
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+	return resp, nil
+}
+
+// SetDefaultMaxTokens sets the completion budget for calls without
+// max_tokens. n <= 0 restores DefaultMaxTokens.
+func (p *HTTPProvider) SetDefaultMaxTokens(n int) {
+	p.defaultMaxTokens = n
+}
+
+// SetDefaultTemperature sets the sampling temperature for calls without
+// temperature. Until it is called no temperature is sent for them.
+func (p *HTTPProvider) SetDefaultTemperature(t float64) {
+	p.defaultTemperature = &t
+}
+
+// SetExtraBody sets backend-specific fields, such as "enable_thinking",
+// added to every request. Standard fields cannot be overridden; a call's
+// "extra_body" option is merged on top.
+func (p *HTTPProvider) SetExtraBody(extra map[string]interface{}) {
+	// Filter once here so a misconfiguration is reported once, not per call
+	p.extraBody = nil
+	if len(extra) > 0 {
+		p.extraBody = make(map[string]interface{}, len(extra))
+		mergeExtraBody(p.extraBody, extra)
 	}
+}
 
-	return p.parseResponse(body)
+// SetModelMaxTokens sets per-model output caps on top of modelOutputCaps.
+func (p *HTTPProvider) SetModelMaxTokens(caps map[string]int) {
+	p.modelMaxTokens = caps
+}
+
+// SetRequestTimeout bounds each request, including reading the response.
+// d <= 0 restores DefaultRequestTimeout.
+func (p *HTTPProvider) SetRequestTimeout(d time.Duration) {
+	p.httpClient.Timeout = requestTimeout(d)
+}
+
+// SetTransportOptions replaces the connection pool with one using opts.
+// Zero fields fall back to DefaultTransportOptions, except MaxConnLifetime
+// where 0 means no limit.
+func (p *HTTPProvider) SetTransportOptions(opts TransportOptions) {
+	if old, ok := p.httpClient.Transport.(interface{ CloseIdleConnections() }); ok {
+		old.CloseIdleConnections()
+	}
+	p.httpClient.Transport = utils.EgressTransport(newProviderTransport(p.proxy, opts))
+}
+
+// SetRetryOptions sets how failed Chat calls are retried.
+func (p *HTTPProvider) SetRetryOptions(opts RetryOptions) {
+	p.retry = opts
+}
+
+// SetUsageObserver reports the usage of each successful Chat. Pass nil to
+// disable.
+func (p *HTTPProvider) SetUsageObserver(observer UsageObserver) {
+	p.usageObserver = observer
+}
+
+// SetDebugHook enables raw request/response reporting. Pass nil to disable.
+func (p *HTTPProvider) SetDebugHook(hook DebugHook) {
+	p.debugHook = hook
+}
+
+// normalizeModel strips provider prefixes the upstream API does not expect
+// (e.g., moonshot/kimi-k2.5 -> kimi-k2.5).
+func normalizeModel(model string) string {
+	if idx := strings.Index(model, "/"); idx != -1 {
+		prefix := model[:idx]
+		if prefix == "moonshot" || prefix == "nvidia" {
+			return model[idx+1:]
+		}
+	}
+	return model
+}
+
+// chatCompletionsURL returns the chat completions endpoint for the configured API.
+func (p *HTTPProvider) chatCompletionsURL(model string) string {
+	if p.azure == nil {
+		return p.apiBase + "/chat/completions"
+	}
+	deployment := p.azure.deployment
+	if deployment == "" {
+		deployment = model
+	}
+	return fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+		p.apiBase, url.PathEscape(deployment), url.QueryEscape(p.azure.apiVersion))
 }
 
 func (p *HTTPProvider) parseResponse(body []byte) (*LLMResponse, error) {
 	var apiResponse struct {
 		Choices []struct {
 			Message struct {
-				Content   string `json:"content"`
-				ToolCalls []struct {
+				Content          string `json:"content"`
+				Refusal          string `json:"refusal"`
+				ReasoningContent string `json:"reasoning_content"` // DeepSeek, Moonshot, vLLM
+				Reasoning        string `json:"reasoning"`         // OpenRouter
+				ToolCalls        []struct {
 					ID       string `json:"id"`
 					Type     string `json:"type"`
 					Function *struct {
@@ -160,22 +432,11 @@ func (p *HTTPProvider) parseResponse(body []byte) (*LLMResponse, error) {
 		arguments := make(map[string]interface{})
 		name := ""
 
-		// Handle OpenAI format with nested function object
-		if tc.Type == "function" && tc.Function != nil {
+		// OpenAI format with a nested function object; the type field is
+		// missing in the legacy format.
+		if tc.Function != nil {
 			name = tc.Function.Name
-			if tc.Function.Arguments != "" {
-				if err := json.Unmarshal([]byte(tc.Function.Arguments), &arguments); err != nil {
-					arguments["raw"] = tc.Function.Arguments
-				}
-			}
-		} else if tc.Function != nil {
-			// Legacy format without type field
-			name = tc.Function.Name
-			if tc.Function.Arguments != "" {
-				if err := json.Unmarshal([]byte(tc.Function.Arguments), &arguments); err != nil {
-					arguments["raw"] = tc.Function.Arguments
-				}
-			}
+			arguments = parseToolArguments(tc.Function.Arguments)
 		}
 
 		toolCalls = append(toolCalls, ToolCall{
@@ -185,14 +446,40 @@ func (p *HTTPProvider) parseResponse(body []byte) (*LLMResponse, error) {
 		})
 	}
 
+	content := choice.Message.Content
+	finishReason := normalizeFinishReason(choice.FinishReason)
+	if choice.Message.Refusal != "" {
+		if content == "" {
+			content = choice.Message.Refusal
+		}
+		finishReason = FinishReasonContentFilter
+	}
+
+	reasoning := choice.Message.ReasoningContent
+	if reasoning == "" {
+		reasoning = choice.Message.Reasoning
+	}
+
 	return &LLMResponse{
-		Content:      choice.Message.Content,
-		ToolCalls:    toolCalls,
-		FinishReason: choice.FinishReason,
-		Usage:        apiResponse.Usage,
+		Content:          content,
+		ReasoningContent: reasoning,
+		ToolCalls:        toolCalls,
+		FinishReason:     finishReason,
+		Usage:            apiResponse.Usage,
 	}, nil
 }
 
+// normalizeFinishReason maps the content-policy stop reasons that
+// OpenAI-compatible backends report under different names onto
+// FinishReasonContentFilter. Other reasons pass through unchanged.
+func normalizeFinishReason(reason string) string {
+	switch strings.ToLower(reason) {
+	case "content_filter", "content_filtered", "safety", "recitation", "prohibited_content", "blocklist", "spii", "refusal", "sensitive":
+		return FinishReasonContentFilter
+	}
+	return reason
+}
+
 func (p *HTTPProvider) GetDefaultModel() string {
 	return ""
 }
@@ -208,7 +495,7 @@ func createClaudeAuthProvider() (LLMProvider, error) {
 	return NewClaudeProviderWithTokenSource(cred.AccessToken, createClaudeTokenSource()), nil
 }
 
-func createCodexAuthProvider() (LLMProvider, error) {
+func createCodexAuthProvider(instructions string) (LLMProvider, error) {
 	cred, err := auth.GetCredential("openai")
 	if err != nil {
 		return nil, fmt.Errorf("loading auth credentials: %w", err)
@@ -216,218 +503,70 @@ func createCodexAuthProvider() (LLMProvider, error) {
 	if cred == nil {
 		return nil, fmt.Errorf("no credentials for openai. Run: picoclaw auth login --provider openai")
 	}
-	return NewCodexProviderWithTokenSource(cred.AccessToken, cred.AccountID, createCodexTokenSource()), nil
+	return NewCodexProviderWithTokenSource(cred.AccessToken, cred.AccountID, createCodexTokenSource(), WithCodexInstructions(instructions)), nil
 }
 
+// CreateProvider builds the LLM provider selected by cfg and applies the
+// providers and agents.defaults settings in two steps. First the settings the
+// provider supports are set on it directly: debug logging, default
+// max_tokens and temperature for calls that pass none, per-model output
+// caps, request timeout, transport tuning and retries. Then it is wrapped,
+// innermost first, in the concurrency limit, the per-model rate limit and
+// the response cache, each only when configured, so the cache is outermost.
 func CreateProvider(cfg *config.Config) (LLMProvider, error) {
-	model := cfg.Agents.Defaults.Model
-	providerName := strings.ToLower(cfg.Agents.Defaults.Provider)
-
-	var apiKey, apiBase, proxy string
-
-	lowerModel := strings.ToLower(model)
-
-	// First, try to use explicitly configured provider
-	if providerName != "" {
-		switch providerName {
-		case "groq":
-			if cfg.Providers.Groq.APIKey != "" {
-				apiKey = cfg.Providers.Groq.APIKey
-				apiBase = cfg.Providers.Groq.APIBase
-				if apiBase == "" {
-					apiBase = "https://api.groq.com/openai/v1"
-				}
-			}
-		case "openai", "gpt":
-			if cfg.Providers.OpenAI.APIKey != "" || cfg.Providers.OpenAI.AuthMethod != "" {
-				if cfg.Providers.OpenAI.AuthMethod == "oauth" || cfg.Providers.OpenAI.AuthMethod == "token" {
-					return createCodexAuthProvider()
-				}
-				apiKey = cfg.Providers.OpenAI.APIKey
-				apiBase = cfg.Providers.OpenAI.APIBase
-				if apiBase == "" {
-					apiBase = "https://api.openai.com/v1"
-				}
-			}
-		case "anthropic", "claude":
-			if cfg.Providers.Anthropic.APIKey != "" || cfg.Providers.Anthropic.AuthMethod != "" {
-				if cfg.Providers.Anthropic.AuthMethod == "oauth" || cfg.Providers.Anthropic.AuthMethod == "token" {
-					return createClaudeAuthProvider()
-				}
-				apiKey = cfg.Providers.Anthropic.APIKey
-				apiBase = cfg.Providers.Anthropic.APIBase
-				if apiBase == "" {
-					apiBase = "https://api.anthropic.com/v1"
-				}
-			}
-		case "openrouter":
-			if cfg.Providers.OpenRouter.APIKey != "" {
-				apiKey = cfg.Providers.OpenRouter.APIKey
-				if cfg.Providers.OpenRouter.APIBase != "" {
-					apiBase = cfg.Providers.OpenRouter.APIBase
-				} else {
-					apiBase = "https://openrouter.ai/api/v1"
-				}
-			}
-		case "zhipu", "glm":
-			if cfg.Providers.Zhipu.APIKey != "" {
-				apiKey = cfg.Providers.Zhipu.APIKey
-				apiBase = cfg.Providers.Zhipu.APIBase
-				if apiBase == "" {
-					apiBase = "https://open.bigmodel.cn/api/paas/v4"
-				}
-			}
-		case "gemini", "google":
-			if cfg.Providers.Gemini.APIKey != "" {
-				apiKey = cfg.Providers.Gemini.APIKey
-				apiBase = cfg.Providers.Gemini.APIBase
-				if apiBase == "" {
-					apiBase = "https://generativelanguage.googleapis.com/v1beta"
-				}
-			}
-		case "vllm":
-			if cfg.Providers.VLLM.APIBase != "" {
-				apiKey = cfg.Providers.VLLM.APIKey
-				apiBase = cfg.Providers.VLLM.APIBase
-			}
-		case "shengsuanyun":
-			if cfg.Providers.ShengSuanYun.APIKey != "" {
-				apiKey = cfg.Providers.ShengSuanYun.APIKey
-				apiBase = cfg.Providers.ShengSuanYun.APIBase
-				if apiBase == "" {
-					apiBase = "https://router.shengsuanyun.com/api/v1"
-				}
-			}
-		case "claude-cli", "claudecode", "claude-code":
-			workspace := cfg.Agents.Defaults.Workspace
-			if workspace == "" {
-				workspace = "."
-			}
-			return NewClaudeCliProvider(workspace), nil
-		case "deepseek":
-			if cfg.Providers.DeepSeek.APIKey != "" {
-				apiKey = cfg.Providers.DeepSeek.APIKey
-				apiBase = cfg.Providers.DeepSeek.APIBase
-				if apiBase == "" {
-					apiBase = "https://api.deepseek.com/v1"
-				}
-				if model != "deepseek-chat" && model != "deepseek-reasoner" {
-					model = "deepseek-chat"
-				}
-			}
-		case "github_copilot", "copilot":
-			if cfg.Providers.GitHubCopilot.APIBase != "" {
-				apiBase = cfg.Providers.GitHubCopilot.APIBase
-			} else {
-				apiBase = "localhost:4321"
-			}
-			return NewGitHubCopilotProvider(apiBase, cfg.Providers.GitHubCopilot.ConnectMode, model)
-
+	provider, err := createProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Providers.Debug {
+		if d, ok := provider.(DebuggableProvider); ok {
+			d.SetDebugHook(NewLogDebugHook())
 		}
-
 	}
-
-	// Fallback: detect provider from model name
-	if apiKey == "" && apiBase == "" {
-		switch {
-		case (strings.Contains(lowerModel, "kimi") || strings.Contains(lowerModel, "moonshot") || strings.HasPrefix(model, "moonshot/")) && cfg.Providers.Moonshot.APIKey != "":
-			apiKey = cfg.Providers.Moonshot.APIKey
-			apiBase = cfg.Providers.Moonshot.APIBase
-			proxy = cfg.Providers.Moonshot.Proxy
-			if apiBase == "" {
-				apiBase = "https://api.moonshot.cn/v1"
-			}
-
-		case strings.HasPrefix(model, "openrouter/") || strings.HasPrefix(model, "anthropic/") || strings.HasPrefix(model, "openai/") || strings.HasPrefix(model, "meta-llama/") || strings.HasPrefix(model, "deepseek/") || strings.HasPrefix(model, "google/"):
-			apiKey = cfg.Providers.OpenRouter.APIKey
-			proxy = cfg.Providers.OpenRouter.Proxy
-			if cfg.Providers.OpenRouter.APIBase != "" {
-				apiBase = cfg.Providers.OpenRouter.APIBase
-			} else {
-				apiBase = "https://openrouter.ai/api/v1"
-			}
-
-		case (strings.Contains(lowerModel, "claude") || strings.HasPrefix(model, "anthropic/")) && (cfg.Providers.Anthropic.APIKey != "" || cfg.Providers.Anthropic.AuthMethod != ""):
-			if cfg.Providers.Anthropic.AuthMethod == "oauth" || cfg.Providers.Anthropic.AuthMethod == "token" {
-				return createClaudeAuthProvider()
-			}
-			apiKey = cfg.Providers.Anthropic.APIKey
-			apiBase = cfg.Providers.Anthropic.APIBase
-			proxy = cfg.Providers.Anthropic.Proxy
-			if apiBase == "" {
-				apiBase = "https://api.anthropic.com/v1"
-			}
-
-		case (strings.Contains(lowerModel, "gpt") || strings.HasPrefix(model, "openai/")) && (cfg.Providers.OpenAI.APIKey != "" || cfg.Providers.OpenAI.AuthMethod != ""):
-			if cfg.Providers.OpenAI.AuthMethod == "oauth" || cfg.Providers.OpenAI.AuthMethod == "token" {
-				return createCodexAuthProvider()
-			}
-			apiKey = cfg.Providers.OpenAI.APIKey
-			apiBase = cfg.Providers.OpenAI.APIBase
-			proxy = cfg.Providers.OpenAI.Proxy
-			if apiBase == "" {
-				apiBase = "https://api.openai.com/v1"
-			}
-
-		case (strings.Contains(lowerModel, "gemini") || strings.HasPrefix(model, "google/")) && cfg.Providers.Gemini.APIKey != "":
-			apiKey = cfg.Providers.Gemini.APIKey
-			apiBase = cfg.Providers.Gemini.APIBase
-			proxy = cfg.Providers.Gemini.Proxy
-			if apiBase == "" {
-				apiBase = "https://generativelanguage.googleapis.com/v1beta"
-			}
-
-		case (strings.Contains(lowerModel, "glm") || strings.Contains(lowerModel, "zhipu") || strings.Contains(lowerModel, "zai")) && cfg.Providers.Zhipu.APIKey != "":
-			apiKey = cfg.Providers.Zhipu.APIKey
-			apiBase = cfg.Providers.Zhipu.APIBase
-			proxy = cfg.Providers.Zhipu.Proxy
-			if apiBase == "" {
-				apiBase = "https://open.bigmodel.cn/api/paas/v4"
-			}
-
-		case (strings.Contains(lowerModel, "groq") || strings.HasPrefix(model, "groq/")) && cfg.Providers.Groq.APIKey != "":
-			apiKey = cfg.Providers.Groq.APIKey
-			apiBase = cfg.Providers.Groq.APIBase
-			proxy = cfg.Providers.Groq.Proxy
-			if apiBase == "" {
-				apiBase = "https://api.groq.com/openai/v1"
-			}
-
-		case (strings.Contains(lowerModel, "nvidia") || strings.HasPrefix(model, "nvidia/")) && cfg.Providers.Nvidia.APIKey != "":
-			apiKey = cfg.Providers.Nvidia.APIKey
-			apiBase = cfg.Providers.Nvidia.APIBase
-			proxy = cfg.Providers.Nvidia.Proxy
-			if apiBase == "" {
-				apiBase = "https://integrate.api.nvidia.com/v1"
-			}
-
-		case cfg.Providers.VLLM.APIBase != "":
-			apiKey = cfg.Providers.VLLM.APIKey
-			apiBase = cfg.Providers.VLLM.APIBase
-			proxy = cfg.Providers.VLLM.Proxy
-
-		default:
-			if cfg.Providers.OpenRouter.APIKey != "" {
-				apiKey = cfg.Providers.OpenRouter.APIKey
-				proxy = cfg.Providers.OpenRouter.Proxy
-				if cfg.Providers.OpenRouter.APIBase != "" {
-					apiBase = cfg.Providers.OpenRouter.APIBase
-				} else {
-					apiBase = "https://openrouter.ai/api/v1"
-				}
-			} else {
-				return nil, fmt.Errorf("no API key configured for model: %s", model)
-			}
+	if m, ok := provider.(MaxTokensDefaulter); ok {
+		m.SetDefaultMaxTokens(cfg.Providers.DefaultMaxTokens)
+	}
+	if d, ok := provider.(TemperatureDefaulter); ok {
+		d.SetDefaultTemperature(cfg.Agents.Defaults.Temperature)
+	}
+	if m, ok := provider.(MaxTokensCapper); ok {
+		m.SetModelMaxTokens(cfg.Providers.ModelMaxTokens)
+	}
+	if t, ok := provider.(RequestTimeouter); ok {
+		t.SetRequestTimeout(time.Duration(cfg.Providers.RequestTimeoutSeconds) * time.Second)
+	}
+	if t, ok := provider.(TransportTuner); ok {
+		opts := DefaultTransportOptions()
+		if cfg.Providers.MaxIdleConns > 0 {
+			opts.MaxIdleConns = cfg.Providers.MaxIdleConns
 		}
+		if cfg.Providers.IdleConnTimeoutSeconds > 0 {
+			opts.IdleConnTimeout = time.Duration(cfg.Providers.IdleConnTimeoutSeconds) * time.Second
+		}
+		opts.MaxConnLifetime = time.Duration(max(cfg.Providers.ConnMaxLifetimeSeconds, 0)) * time.Second
+		t.SetTransportOptions(opts)
 	}
-
-	if apiKey == "" && !strings.HasPrefix(model, "bedrock/") {
-		return nil, fmt.Errorf("no API key configured for provider (model: %s)", model)
+	if r, ok := provider.(Retrier); ok {
+		opts := DefaultRetryOptions()
+		opts.MaxRetries = cfg.Providers.MaxRetries
+		r.SetRetryOptions(opts)
 	}
-
-	if apiBase == "" {
-		return nil, fmt.Errorf("no API base configured for provider (model: %s)", model)
+	if cfg.Providers.MaxInFlight > 0 {
+		provider = LimitProvider(provider, cfg.Providers.MaxInFlight, cfg.Providers.MaxQueued)
+	}
+	// Rate limiting wraps the concurrency limit so calls waiting for their
+	// turn do not hold an in-flight slot.
+	if cfg.Providers.RequestsPerMinute > 0 || len(cfg.Providers.ModelRequestsPerMinute) > 0 {
+		provider = RateLimitProvider(provider, cfg.Providers.RequestsPerMinute, cfg.Providers.ModelRequestsPerMinute)
 	}
+	// The cache is outermost so hits neither wait for a rate limit slot nor
+	// take an in-flight one.
+	if cfg.Providers.ResponseCacheTTLSeconds > 0 {
+		provider = CacheProvider(provider, time.Duration(cfg.Providers.ResponseCacheTTLSeconds)*time.Second, 0)
+	}
+	return provider, nil
+}
 
-	return NewHTTPProvider(apiKey, apiBase, proxy), nil
+func createProvider(cfg *config.Config) (LLMProvider, error) {
+	return NewProviderFactory(cfg).Create(cfg.Agents.Defaults.Model)
 }
@@ -0,0 +1,226 @@
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newChatCompletionServer(t *testing.T, content string, capture *map[string]interface{}) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if capture != nil {
+			if err := json.NewDecoder(r.Body).Decode(capture); err != nil {
+				t.Errorf("decode request: %v", err)
+			}
+		}
+		resp := map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{
+					"message":       map[string]interface{}{"content": content},
+					"finish_reason": "stop",
+				},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestHTTPProvider_ResponseFormatSent(t *testing.T) {
+	var body map[string]interface{}
+	server := newChatCompletionServer(t, `{"city":"SF","temp":72}`, &body)
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	resp, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4o", map[string]interface{}{
+		"response_format": testWeatherFormat,
+	})
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if resp.Content != `{"city":"SF","temp":72}` {
+		t.Errorf("Content = %q", resp.Content)
+	}
+
+	rf, ok := body["response_format"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("response_format missing from request: %v", body)
+	}
+	if rf["type"] != "json_schema" {
+		t.Errorf("response_format.type = %v, want json_schema", rf["type"])
+	}
+	if schema, ok := rf["json_schema"].(map[string]interface{}); !ok || schema["name"] != "weather" {
+		t.Errorf("response_format.json_schema = %v, want name weather", rf["json_schema"])
+	}
+}
+
+func TestHTTPProvider_ResponseFormatValidationError(t *testing.T) {
+	server := newChatCompletionServer(t, `{"city":"SF"}`, nil)
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	_, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4o", map[string]interface{}{
+		"response_format": testWeatherFormat,
+	})
+	if err == nil {
+		t.Fatal("expected schema validation error")
+	}
+}
+
+func TestHTTPProvider_NoResponseFormat(t *testing.T) {
+	var body map[string]interface{}
+	server := newChatCompletionServer(t, "plain text", &body)
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	resp, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4o", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if resp.Content != "plain text" {
+		t.Errorf("Content = %q, want %q", resp.Content, "plain text")
+	}
+	if _, ok := body["response_format"]; ok {
+		t.Error("response_format should not be sent when not requested")
+	}
+}
+
+func TestHTTPProvider_ParallelToolCalls(t *testing.T) {
+	var body map[string]interface{}
+	server := newChatCompletionServer(t, "ok", &body)
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	messages := []Message{{Role: "user", Content: "Hi"}}
+	tools := []ToolDefinition{{Type: "function", Function: ToolFunctionDefinition{Name: "read_file"}}}
+
+	if _, err := p.Chat(t.Context(), messages, tools, "gpt-4o", map[string]interface{}{}); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if _, ok := body["parallel_tool_calls"]; ok {
+		t.Error("parallel_tool_calls should not be sent by default")
+	}
+
+	if _, err := p.Chat(t.Context(), messages, tools, "gpt-4o", map[string]interface{}{"parallel_tool_calls": false}); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if body["parallel_tool_calls"] != false {
+		t.Errorf("parallel_tool_calls = %v, want false", body["parallel_tool_calls"])
+	}
+}
+
+func TestHTTPProvider_AzureURLAndAuth(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/deployments/my-gpt4o/chat/completions" {
+			http.Error(w, "not found: "+r.URL.Path, http.StatusNotFound)
+			return
+		}
+		if got := r.URL.Query().Get("api-version"); got != "2024-06-01" {
+			http.Error(w, "bad api-version: "+got, http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("api-key") != "azure-key" || r.Header.Get("Authorization") != "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"content":"hi from azure"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	p := NewAzureHTTPProvider("azure-key", server.URL+"/", "my-gpt4o", "2024-06-01", "")
+	resp, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "nvidia/my-model", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if resp.Content != "hi from azure" {
+		t.Errorf("Content = %q, want %q", resp.Content, "hi from azure")
+	}
+	if body["model"] != "nvidia/my-model" {
+		t.Errorf("model = %v, want prefix preserved in Azure mode", body["model"])
+	}
+}
+
+func TestHTTPProvider_AzureDeploymentDefaultsToModel(t *testing.T) {
+	p := NewAzureHTTPProvider("key", "https://res.openai.azure.com", "", "", "")
+	got := p.chatCompletionsURL("gpt-4o")
+	want := "https://res.openai.azure.com/openai/deployments/gpt-4o/chat/completions?api-version=" + defaultAzureAPIVersion
+	if got != want {
+		t.Errorf("chatCompletionsURL() = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeModel(t *testing.T) {
+	tests := map[string]string{
+		"moonshot/kimi-k2.5": "kimi-k2.5",
+		"nvidia/llama-3":     "llama-3",
+		"openai/gpt-4o":      "openai/gpt-4o",
+		"gpt-4o":             "gpt-4o",
+	}
+	for in, want := range tests {
+		if got := normalizeModel(in); got != want {
+			t.Errorf("normalizeModel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestHTTPProvider_ParseResponseContentFilter(t *testing.T) {
+	p := NewHTTPProvider("key", "http://unused", "")
+	tests := []struct {
+		name        string
+		body        string
+		wantReason  string
+		wantContent string
+	}{
+		{"openai content_filter", `{"choices":[{"message":{"content":""},"finish_reason":"content_filter"}]}`, FinishReasonContentFilter, ""},
+		{"gemini safety", `{"choices":[{"message":{"content":""},"finish_reason":"SAFETY"}]}`, FinishReasonContentFilter, ""},
+		{"refusal message", `{"choices":[{"message":{"content":null,"refusal":"I can't help with that."},"finish_reason":"stop"}]}`, FinishReasonContentFilter, "I can't help with that."},
+		{"normal stop", `{"choices":[{"message":{"content":"hi"},"finish_reason":"stop"}]}`, "stop", "hi"},
+		{"length", `{"choices":[{"message":{"content":"hi"},"finish_reason":"length"}]}`, "length", "hi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := p.parseResponse([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("parseResponse error: %v", err)
+			}
+			if resp.FinishReason != tt.wantReason {
+				t.Errorf("FinishReason = %q, want %q", resp.FinishReason, tt.wantReason)
+			}
+			if resp.Content != tt.wantContent {
+				t.Errorf("Content = %q, want %q", resp.Content, tt.wantContent)
+			}
+		})
+	}
+}
+
+func TestHTTPProvider_ParseResponseReasoning(t *testing.T) {
+	p := NewHTTPProvider("key", "http://unused", "")
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"reasoning_content", `{"choices":[{"message":{"content":"4","reasoning_content":"2+2 is 4"},"finish_reason":"stop"}]}`, "2+2 is 4"},
+		{"openrouter reasoning", `{"choices":[{"message":{"content":"4","reasoning":"adding"},"finish_reason":"stop"}]}`, "adding"},
+		{"none", `{"choices":[{"message":{"content":"4"},"finish_reason":"stop"}]}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := p.parseResponse([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("parseResponse error: %v", err)
+			}
+			if resp.ReasoningContent != tt.want {
+				t.Errorf("ReasoningContent = %q, want %q", resp.ReasoningContent, tt.want)
+			}
+			if resp.Content != "4" {
+				t.Errorf("Content = %q, want 4", resp.Content)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package providers
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// Image is image data attached to a message, such as a screenshot returned by
+// a tool. Providers without image support send only the message text.
+type Image struct {
+	MediaType string // "image/png", "image/jpeg", "image/gif" or "image/webp"
+	Data      []byte
+}
+
+// Base64 returns the image data base64-encoded.
+func (img Image) Base64() string {
+	return base64.StdEncoding.EncodeToString(img.Data)
+}
+
+// DataURL returns the image as a data: URL.
+func (img Image) DataURL() string {
+	return "data:" + img.MediaType + ";base64," + img.Base64()
+}
+
+// openAIMessages converts messages to the chat completions format. Tool
+// messages only carry text there, so images returned by tools are sent in a
+// user message after the run of tool results, which must directly follow the
+// assistant's tool calls. Without images the messages are sent unchanged.
+func openAIMessages(messages []Message) []interface{} {
+	out := make([]interface{}, 0, len(messages))
+	var parts []map[string]interface{}
+	flush := func() {
+		if len(parts) > 0 {
+			out = append(out, map[string]interface{}{"role": "user", "content": parts})
+			parts = nil
+		}
+	}
+
+	for _, msg := range messages {
+		if msg.Role != "tool" {
+			flush()
+		}
+		out = append(out, msg)
+		if msg.Role != "tool" || len(msg.Images) == 0 {
+			continue
+		}
+		parts = append(parts, map[string]interface{}{
+			"type": "text",
+			"text": fmt.Sprintf("Image output of tool call %s:", msg.ToolCallID),
+		})
+		for _, img := range msg.Images {
+			parts = append(parts, map[string]interface{}{
+				"type":      "image_url",
+				"image_url": map[string]interface{}{"url": img.DataURL()},
+			})
+		}
+	}
+	flush()
+	return out
+}
@@ -0,0 +1,110 @@
+package providers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v3/responses"
+)
+
+var testImage = Image{MediaType: "image/png", Data: []byte("png-bytes")}
+
+func toolCallMessages(images []Image) []Message {
+	return []Message{
+		{Role: "user", Content: "Take screenshots"},
+		{Role: "assistant", ToolCalls: []ToolCall{
+			{ID: "call_1", Name: "screenshot"},
+			{ID: "call_2", Name: "screenshot"},
+		}},
+		{Role: "tool", Content: "Screenshot taken", ToolCallID: "call_1", Images: images},
+		{Role: "tool", Content: "Screenshot taken", ToolCallID: "call_2"},
+	}
+}
+
+func TestHTTPProvider_ToolImagesFollowToolResults(t *testing.T) {
+	var body map[string]interface{}
+	server := newChatCompletionServer(t, "ok", &body)
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	if _, err := p.Chat(t.Context(), toolCallMessages([]Image{testImage}), nil, "gpt-4o", nil); err != nil {
+		t.Fatalf("Chat error = %v", err)
+	}
+
+	sent, _ := body["messages"].([]interface{})
+	if len(sent) != 5 {
+		t.Fatalf("sent %d messages, want 5", len(sent))
+	}
+	for i, want := range []string{"user", "assistant", "tool", "tool", "user"} {
+		if role := sent[i].(map[string]interface{})["role"]; role != want {
+			t.Errorf("message %d role = %v, want %s", i, role, want)
+		}
+	}
+	if _, ok := sent[2].(map[string]interface{})["images"]; ok {
+		t.Error("tool message carries an images field")
+	}
+	data, _ := json.Marshal(sent[4])
+	if !strings.Contains(string(data), "data:image/png;base64,"+testImage.Base64()) {
+		t.Errorf("image message = %s, want data URL", data)
+	}
+}
+
+func TestHTTPProvider_NoImagesKeepsMessages(t *testing.T) {
+	var body map[string]interface{}
+	server := newChatCompletionServer(t, "ok", &body)
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	messages := toolCallMessages(nil)
+	if _, err := p.Chat(t.Context(), messages, nil, "gpt-4o", nil); err != nil {
+		t.Fatalf("Chat error = %v", err)
+	}
+
+	got, _ := json.Marshal(body["messages"])
+	var want interface{}
+	raw, _ := json.Marshal(messages)
+	json.Unmarshal(raw, &want)
+	wantJSON, _ := json.Marshal(want)
+	if string(got) != string(wantJSON) {
+		t.Errorf("messages = %s, want %s", got, wantJSON)
+	}
+}
+
+func TestBuildClaudeParams_ToolImages(t *testing.T) {
+	params, err := buildClaudeParams(toolCallMessages([]Image{testImage}), nil, "claude-sonnet-4-5-20250929", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("buildClaudeParams() error: %v", err)
+	}
+	result := params.Messages[2].Content[0].OfToolResult
+	if result == nil || len(result.Content) != 2 {
+		t.Fatalf("tool result = %#v, want text and image", result)
+	}
+	img := result.Content[1].OfImage
+	if img == nil || img.Source.OfBase64 == nil || img.Source.OfBase64.Data != testImage.Base64() {
+		t.Errorf("image block = %#v", img)
+	}
+	if n := len(params.Messages[3].Content[0].OfToolResult.Content); n != 1 {
+		t.Errorf("text-only tool result has %d blocks, want 1", n)
+	}
+}
+
+func TestBuildCodexParams_ToolImages(t *testing.T) {
+	params := buildCodexParams(toolCallMessages([]Image{testImage}), nil, "gpt-4o", map[string]interface{}{}, "")
+	var outputs []responses.ResponseInputItemFunctionCallOutputOutputUnionParam
+	for _, item := range params.Input.OfInputItemList {
+		if item.OfFunctionCallOutput != nil {
+			outputs = append(outputs, item.OfFunctionCallOutput.Output)
+		}
+	}
+	if len(outputs) != 2 {
+		t.Fatalf("got %d function call outputs, want 2", len(outputs))
+	}
+	items := outputs[0].OfResponseFunctionCallOutputItemArray
+	if len(items) != 2 || items[1].OfInputImage == nil || items[1].OfInputImage.ImageURL.Or("") != testImage.DataURL() {
+		t.Errorf("output with image = %#v, want text and image items", items)
+	}
+	if !outputs[1].OfString.Valid() {
+		t.Errorf("text-only output = %#v, want plain string", outputs[1])
+	}
+}
@@ -0,0 +1,168 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrTooManyRequests is returned by LimitedProvider when both the in-flight
+// slots and the wait queue are full.
+var ErrTooManyRequests = errors.New("too many concurrent provider requests")
+
+// LimitedProvider bounds the number of concurrent Chat calls to the wrapped
+// provider. Calls beyond the limit wait for a free slot; once maxQueued calls
+// are already waiting, further calls fail immediately with ErrTooManyRequests.
+type LimitedProvider struct {
+	inner     LLMProvider
+	slots     chan struct{}
+	maxQueued int
+
+	mu     sync.Mutex
+	queued int
+}
+
+// NewLimitedProvider wraps inner so at most maxInFlight requests run at once.
+// maxQueued <= 0 lets callers wait without bound (until their context ends).
+func NewLimitedProvider(inner LLMProvider, maxInFlight, maxQueued int) *LimitedProvider {
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+	return &LimitedProvider{
+		inner:     inner,
+		slots:     make(chan struct{}, maxInFlight),
+		maxQueued: maxQueued,
+	}
+}
+
+// LimitProvider wraps inner like NewLimitedProvider but keeps its optional
+// capabilities: when inner is a StreamingProvider the result is one too, with
+// ChatStream sharing the same slots as Chat.
+func LimitProvider(inner LLMProvider, maxInFlight, maxQueued int) LLMProvider {
+	limited := NewLimitedProvider(inner, maxInFlight, maxQueued)
+	if stream, ok := inner.(StreamingProvider); ok {
+		return &limitedStreamingProvider{LimitedProvider: limited, stream: stream}
+	}
+	return limited
+}
+
+func (p *LimitedProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	if err := p.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer p.release()
+
+	return p.inner.Chat(ctx, messages, tools, model, options)
+}
+
+func (p *LimitedProvider) GetDefaultModel() string {
+	return p.inner.GetDefaultModel()
+}
+
+// Ping forwards the health check to the wrapped provider. It occupies an
+// in-flight slot like any other request.
+func (p *LimitedProvider) Ping(ctx context.Context) error {
+	if err := p.acquire(ctx); err != nil {
+		return err
+	}
+	defer p.release()
+
+	return Ping(ctx, p.inner)
+}
+
+// ListModels forwards to the wrapped provider. It occupies an in-flight
+// slot like any other request.
+func (p *LimitedProvider) ListModels(ctx context.Context) ([]string, error) {
+	if err := p.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer p.release()
+
+	return ListModels(ctx, p.inner)
+}
+
+// CountTokens forwards to the wrapped provider's token counting, or returns
+// the local estimate when it has none. It occupies an in-flight slot only
+// when a request is sent.
+func (p *LimitedProvider) CountTokens(ctx context.Context, messages []Message, tools []ToolDefinition, model string) (int, error) {
+	tc, ok := p.inner.(TokenCounter)
+	if !ok {
+		return CountTokens(messages, model) + countToolTokens(tools, model), nil
+	}
+	if err := p.acquire(ctx); err != nil {
+		return 0, err
+	}
+	defer p.release()
+
+	return tc.CountTokens(ctx, messages, tools, model)
+}
+
+// Close releases the wrapped provider.
+func (p *LimitedProvider) Close() error {
+	return Close(p.inner)
+}
+
+// SetUsageObserver forwards the observer to the wrapped provider when
+// supported.
+func (p *LimitedProvider) SetUsageObserver(observer UsageObserver) {
+	if u, ok := p.inner.(UsageObservableProvider); ok {
+		u.SetUsageObserver(observer)
+	}
+}
+
+// SetDebugHook forwards the hook to the wrapped provider when supported.
+func (p *LimitedProvider) SetDebugHook(hook DebugHook) {
+	if d, ok := p.inner.(DebuggableProvider); ok {
+		d.SetDebugHook(hook)
+	}
+}
+
+func (p *LimitedProvider) acquire(ctx context.Context) error {
+	// Fast path: a slot is free
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	p.mu.Lock()
+	if p.maxQueued > 0 && p.queued >= p.maxQueued {
+		p.mu.Unlock()
+		return fmt.Errorf("%w: %d in flight, %d queued", ErrTooManyRequests, cap(p.slots), p.maxQueued)
+	}
+	p.queued++
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.queued--
+		p.mu.Unlock()
+	}()
+
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *LimitedProvider) release() {
+	<-p.slots
+}
+
+// limitedStreamingProvider is a LimitedProvider over a StreamingProvider.
+type limitedStreamingProvider struct {
+	*LimitedProvider
+	stream StreamingProvider
+}
+
+func (p *limitedStreamingProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta StreamCallback) (*LLMResponse, error) {
+	if err := p.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer p.release()
+
+	return p.stream.ChatStream(ctx, messages, tools, model, options, onDelta)
+}
@@ -0,0 +1,197 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingProvider holds every Chat call until release is closed.
+type blockingProvider struct {
+	started  chan struct{}
+	release  chan struct{}
+	inFlight atomic.Int32
+	peak     atomic.Int32
+}
+
+func newBlockingProvider() *blockingProvider {
+	return &blockingProvider{
+		started: make(chan struct{}, 16),
+		release: make(chan struct{}),
+	}
+}
+
+func (p *blockingProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	n := p.inFlight.Add(1)
+	defer p.inFlight.Add(-1)
+	for {
+		peak := p.peak.Load()
+		if n <= peak || p.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	p.started <- struct{}{}
+	<-p.release
+	return &LLMResponse{Content: "ok"}, nil
+}
+
+func (p *blockingProvider) GetDefaultModel() string {
+	return "blocking"
+}
+
+func waitStarted(t *testing.T, p *blockingProvider, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-p.started:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("only %d of %d calls started", i, n)
+		}
+	}
+}
+
+func waitQueued(t *testing.T, p *LimitedProvider, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		p.mu.Lock()
+		queued := p.queued
+		p.mu.Unlock()
+		if queued == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("queued = %d, want %d", queued, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLimitedProvider_BoundsInFlight(t *testing.T) {
+	inner := newBlockingProvider()
+	p := NewLimitedProvider(inner, 2, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := p.Chat(context.Background(), nil, nil, "", nil); err != nil {
+				t.Errorf("Chat() error: %v", err)
+			}
+		}()
+	}
+
+	waitStarted(t, inner, 2)
+	waitQueued(t, p, 3)
+	close(inner.release)
+	wg.Wait()
+
+	if peak := inner.peak.Load(); peak != 2 {
+		t.Errorf("peak in-flight = %d, want 2", peak)
+	}
+}
+
+func TestLimitedProvider_RejectsWhenQueueFull(t *testing.T) {
+	inner := newBlockingProvider()
+	p := NewLimitedProvider(inner, 1, 1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Chat(context.Background(), nil, nil, "", nil)
+		}()
+	}
+	waitStarted(t, inner, 1)
+	waitQueued(t, p, 1)
+
+	_, err := p.Chat(context.Background(), nil, nil, "", nil)
+	if !errors.Is(err, ErrTooManyRequests) {
+		t.Errorf("Chat() error = %v, want ErrTooManyRequests", err)
+	}
+
+	close(inner.release)
+	wg.Wait()
+}
+
+func TestLimitedProvider_QueuedCallHonorsContext(t *testing.T) {
+	inner := newBlockingProvider()
+	p := NewLimitedProvider(inner, 1, 0)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.Chat(context.Background(), nil, nil, "", nil)
+	}()
+	waitStarted(t, inner, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.Chat(ctx, nil, nil, "", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Chat() error = %v, want context.DeadlineExceeded", err)
+	}
+	waitQueued(t, p, 0)
+
+	close(inner.release)
+	<-done
+}
+
+// streamingBlockingProvider adds ChatStream to blockingProvider.
+type streamingBlockingProvider struct {
+	*blockingProvider
+}
+
+func (p *streamingBlockingProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta StreamCallback) (*LLMResponse, error) {
+	resp, err := p.Chat(ctx, messages, tools, model, options)
+	if err == nil && onDelta != nil {
+		onDelta(resp.Content)
+	}
+	return resp, err
+}
+
+func TestLimitProvider_KeepsStreamingCapability(t *testing.T) {
+	if _, ok := LimitProvider(newBlockingProvider(), 1, 0).(StreamingProvider); ok {
+		t.Error("Expected non-streaming inner provider to stay non-streaming")
+	}
+
+	inner := &streamingBlockingProvider{newBlockingProvider()}
+	wrapped, ok := LimitProvider(inner, 1, 1).(StreamingProvider)
+	if !ok {
+		t.Fatal("Expected streaming inner provider to stay streaming")
+	}
+
+	done := make(chan struct{})
+	var deltas []string
+	go func() {
+		defer close(done)
+		wrapped.ChatStream(context.Background(), nil, nil, "", nil, func(delta string) {
+			deltas = append(deltas, delta)
+		})
+	}()
+	waitStarted(t, inner.blockingProvider, 1)
+
+	// ChatStream holds the only slot, so a concurrent Chat must queue
+	queued := make(chan error, 1)
+	go func() {
+		_, err := wrapped.Chat(context.Background(), nil, nil, "", nil)
+		queued <- err
+	}()
+	waitQueued(t, wrapped.(*limitedStreamingProvider).LimitedProvider, 1)
+
+	close(inner.release)
+	<-done
+	if err := <-queued; err != nil {
+		t.Errorf("queued Chat() error = %v", err)
+	}
+	if len(deltas) != 1 || deltas[0] != "ok" {
+		t.Errorf("deltas = %v, want [ok]", deltas)
+	}
+	if peak := inner.peak.Load(); peak != 1 {
+		t.Errorf("peak in-flight = %d, want 1", peak)
+	}
+}
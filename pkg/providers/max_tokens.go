@@ -0,0 +1,135 @@
+package providers
+
+import (
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// DefaultMaxTokens is the completion budget used when a call does not pass
+// max_tokens and providers.default_max_tokens is unset. Every provider
+// applies the same default, so switching providers does not change how long
+// an answer may get.
+const DefaultMaxTokens = 4096
+
+// MaxTokensDefaulter is implemented by providers whose completion budget for
+// calls without max_tokens can be configured.
+type MaxTokensDefaulter interface {
+	SetDefaultMaxTokens(n int)
+}
+
+// withDefaultMaxTokens returns options with max_tokens set to def (or
+// DefaultMaxTokens when def <= 0) unless the caller already set it. The
+// caller's map is never modified.
+func withDefaultMaxTokens(options map[string]interface{}, def int) map[string]interface{} {
+	if _, ok := options["max_tokens"].(int); ok {
+		return options
+	}
+	if def <= 0 {
+		def = DefaultMaxTokens
+	}
+	merged := make(map[string]interface{}, len(options)+1)
+	for k, v := range options {
+		merged[k] = v
+	}
+	merged["max_tokens"] = def
+	return merged
+}
+
+// MaxTokensCapper is implemented by providers that clamp max_tokens to the
+// output ceiling of the requested model. caps adds to or overrides
+// modelOutputCaps; a cap <= 0 turns clamping off for that prefix.
+type MaxTokensCapper interface {
+	SetModelMaxTokens(caps map[string]int)
+}
+
+// modelOutputCaps maps model name prefixes to the most output tokens the
+// model accepts. The longest matching prefix wins; models matching no
+// prefix are not clamped.
+var modelOutputCaps = map[string]int{
+	"claude-3-haiku":    4096,
+	"claude-3-sonnet":   4096,
+	"claude-3-opus":     4096,
+	"claude-3-5-haiku":  8192,
+	"claude-3-5-sonnet": 8192,
+	"claude-3-7-sonnet": 64000,
+	"claude-sonnet-4":   64000,
+	"claude-haiku-4":    64000,
+	"claude-opus-4":     32000,
+	"claude-opus-4-5":   64000,
+	"gpt-3.5-turbo":     4096,
+	"gpt-4":             8192,
+	"gpt-4-turbo":       4096,
+	"gpt-4o":            16384,
+	"gpt-4.1":           32768,
+	"gpt-5":             128000,
+	"o1":                100000,
+	"o1-mini":           65536,
+	"o3":                100000,
+	"o4-mini":           100000,
+	"deepseek-chat":     8192,
+	"deepseek-reasoner": 65536,
+}
+
+// modelOutputCap returns the output ceiling for model, ignoring any routing
+// prefix such as "openrouter/anthropic/". Entries in overrides take
+// precedence over modelOutputCaps for the same prefix.
+func modelOutputCap(model string, overrides map[string]int) (int, bool) {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	best, limit := "", 0
+	consider := func(prefix string, n int, replaceTie bool) {
+		prefix = strings.ToLower(prefix)
+		if !strings.HasPrefix(name, prefix) {
+			return
+		}
+		if len(prefix) > len(best) || (replaceTie && len(prefix) == len(best)) {
+			best, limit = prefix, n
+		}
+	}
+	for prefix, n := range modelOutputCaps {
+		consider(prefix, n, false)
+	}
+	for prefix, n := range overrides {
+		consider(prefix, n, true)
+	}
+	return limit, best != "" && limit > 0
+}
+
+// clampMaxTokens limits n to the output ceiling of model, logging a warning
+// when the requested value had to be lowered.
+func clampMaxTokens(model string, n int, overrides map[string]int) int {
+	limit, ok := modelOutputCap(model, overrides)
+	if !ok || n <= limit {
+		return n
+	}
+	logger.WarnCF("provider", "max_tokens above model output limit, clamping",
+		map[string]interface{}{
+			"model":     model,
+			"requested": n,
+			"used":      limit,
+		})
+	return limit
+}
+
+// withMaxTokensCap returns options with max_tokens clamped to the output
+// ceiling of model. The caller's map is never modified.
+func withMaxTokensCap(options map[string]interface{}, model string, overrides map[string]int) map[string]interface{} {
+	n, ok := options["max_tokens"].(int)
+	if !ok {
+		return options
+	}
+	capped := clampMaxTokens(model, n, overrides)
+	if capped == n {
+		return options
+	}
+	merged := make(map[string]interface{}, len(options))
+	for k, v := range options {
+		merged[k] = v
+	}
+	merged["max_tokens"] = capped
+	return merged
+}
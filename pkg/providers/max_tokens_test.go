@@ -0,0 +1,153 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestWithDefaultMaxTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		options map[string]interface{}
+		def     int
+		want    int
+	}{
+		{"nil options", nil, 2048, 2048},
+		{"unset default", map[string]interface{}{"temperature": 0.2}, 0, DefaultMaxTokens},
+		{"explicit wins", map[string]interface{}{"max_tokens": 512}, 2048, 512},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := withDefaultMaxTokens(tt.options, tt.def)
+			if got["max_tokens"] != tt.want {
+				t.Errorf("max_tokens = %v, want %d", got["max_tokens"], tt.want)
+			}
+		})
+	}
+
+	options := map[string]interface{}{"temperature": 0.2}
+	withDefaultMaxTokens(options, 1000)
+	if _, ok := options["max_tokens"]; ok {
+		t.Error("caller's options were modified")
+	}
+}
+
+func TestHTTPProvider_DefaultMaxTokens(t *testing.T) {
+	var body map[string]interface{}
+	server := newChatCompletionServer(t, "ok", &body)
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	p.SetDefaultMaxTokens(1500)
+	messages := []Message{{Role: "user", Content: "Hi"}}
+
+	if _, err := p.Chat(t.Context(), messages, nil, "llama-3", nil); err != nil {
+		t.Fatalf("Chat error = %v", err)
+	}
+	if body["max_tokens"] != float64(1500) {
+		t.Errorf("max_tokens = %v, want 1500", body["max_tokens"])
+	}
+
+	if _, err := p.Chat(t.Context(), messages, nil, "llama-3", map[string]interface{}{"max_tokens": 300}); err != nil {
+		t.Fatalf("Chat error = %v", err)
+	}
+	if body["max_tokens"] != float64(300) {
+		t.Errorf("explicit max_tokens = %v, want 300", body["max_tokens"])
+	}
+}
+
+func TestClaudeProvider_DefaultMaxTokens(t *testing.T) {
+	params, err := buildClaudeParams([]Message{{Role: "user", Content: "Hi"}}, nil, "claude-sonnet-4",
+		withDefaultMaxTokens(nil, 2000))
+	if err != nil {
+		t.Fatalf("buildClaudeParams error = %v", err)
+	}
+	if params.MaxTokens != 2000 {
+		t.Errorf("MaxTokens = %d, want 2000", params.MaxTokens)
+	}
+}
+
+func TestCreateProvider_AppliesDefaultMaxTokens(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Model = "gpt-4o"
+	cfg.Providers.OpenAI.APIKey = "sk"
+	cfg.Providers.DefaultMaxTokens = 777
+
+	provider, err := CreateProvider(cfg)
+	if err != nil {
+		t.Fatalf("CreateProvider error = %v", err)
+	}
+	hp, ok := provider.(*HTTPProvider)
+	if !ok {
+		t.Fatalf("CreateProvider returned %T, want *HTTPProvider", provider)
+	}
+	if hp.defaultMaxTokens != 777 {
+		t.Errorf("defaultMaxTokens = %d, want 777", hp.defaultMaxTokens)
+	}
+}
+
+func TestModelOutputCap(t *testing.T) {
+	tests := []struct {
+		model     string
+		overrides map[string]int
+		want      int
+		ok        bool
+	}{
+		{"gpt-4o-mini", nil, 16384, true},
+		{"gpt-4", nil, 8192, true},
+		{"gpt-4-turbo-2024-04-09", nil, 4096, true},
+		{"openrouter/anthropic/claude-3-5-haiku-20241022", nil, 8192, true},
+		{"claude-opus-4-5-20251101", nil, 64000, true},
+		{"claude-opus-4-1-20250805", nil, 32000, true},
+		{"llama-3", nil, 0, false},
+		{"gpt-4o", map[string]int{"gpt-4o": 4000}, 4000, true},
+		{"llama-3-8b", map[string]int{"llama-3": 2048}, 2048, true},
+		{"gpt-4o", map[string]int{"gpt-4o": 0}, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := modelOutputCap(tt.model, tt.overrides)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("modelOutputCap(%q, %v) = %d, %v; want %d, %v", tt.model, tt.overrides, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestWithMaxTokensCap(t *testing.T) {
+	options := map[string]interface{}{"max_tokens": 100000}
+	got := withMaxTokensCap(options, "claude-3-5-sonnet-20241022", nil)
+	if got["max_tokens"] != 8192 {
+		t.Errorf("max_tokens = %v, want 8192", got["max_tokens"])
+	}
+	if options["max_tokens"] != 100000 {
+		t.Error("caller's options were modified")
+	}
+	if got := withMaxTokensCap(options, "llama-3", nil); got["max_tokens"] != 100000 {
+		t.Errorf("unknown model max_tokens = %v, want unchanged", got["max_tokens"])
+	}
+
+	params := buildCodexParams([]Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4o",
+		withMaxTokensCap(map[string]interface{}{"max_tokens": 50000}, "gpt-4o", nil), "")
+	if params.MaxOutputTokens.Or(0) != 16384 {
+		t.Errorf("codex MaxOutputTokens = %d, want 16384", params.MaxOutputTokens.Or(0))
+	}
+}
+
+func TestHTTPProvider_ClampsMaxTokens(t *testing.T) {
+	var body map[string]interface{}
+	server := newChatCompletionServer(t, "ok", &body)
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	p.SetModelMaxTokens(map[string]int{"llama-3": 2048})
+	messages := []Message{{Role: "user", Content: "Hi"}}
+
+	for model, want := range map[string]float64{"gpt-4o": 16384, "llama-3-70b": 2048, "mistral-large": 50000} {
+		if _, err := p.Chat(t.Context(), messages, nil, model, map[string]interface{}{"max_tokens": 50000}); err != nil {
+			t.Fatalf("Chat error = %v", err)
+		}
+		if body["max_tokens"] != want {
+			t.Errorf("%s: max_tokens = %v, want %v", model, body["max_tokens"], want)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package providers
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrListModelsUnsupported is returned by ListModels for providers that
+// cannot enumerate their models.
+var ErrListModelsUnsupported = errors.New("provider cannot list models")
+
+// ModelLister is implemented by providers that can report the model IDs
+// their endpoint serves.
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]string, error)
+}
+
+// ListModels returns the provider's model IDs, sorted, or
+// ErrListModelsUnsupported when the provider cannot list them.
+func ListModels(ctx context.Context, provider LLMProvider) ([]string, error) {
+	l, ok := provider.(ModelLister)
+	if !ok {
+		return nil, ErrListModelsUnsupported
+	}
+	return l.ListModels(ctx)
+}
@@ -0,0 +1,59 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestHTTPProvider_ListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			t.Errorf("path = %q, want /models", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer key" {
+			t.Errorf("Authorization = %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"object":"list","data":[{"id":"llama-3.3-70b"},{"id":"gemma2-9b"}]}`))
+	}))
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	models, err := ListModels(context.Background(), p)
+	if err != nil {
+		t.Fatalf("ListModels error = %v", err)
+	}
+	if want := []string{"gemma2-9b", "llama-3.3-70b"}; !reflect.DeepEqual(models, want) {
+		t.Errorf("models = %v, want %v", models, want)
+	}
+
+	p.modelPrefix = "groq/"
+	models, _ = p.ListModels(context.Background())
+	if want := []string{"groq/gemma2-9b", "groq/llama-3.3-70b"}; !reflect.DeepEqual(models, want) {
+		t.Errorf("prefixed models = %v, want %v", models, want)
+	}
+}
+
+func TestHTTPProvider_ListModelsUnauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":{"message":"bad key"}}`))
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPProvider("key", server.URL, "").ListModels(context.Background())
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("err = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestListModels_Unsupported(t *testing.T) {
+	p := LimitProvider(NewClaudeCliProvider("."), 1, 0)
+	if _, err := ListModels(context.Background(), p); !errors.Is(err, ErrListModelsUnsupported) {
+		t.Errorf("err = %v, want ErrListModelsUnsupported", err)
+	}
+}
@@ -0,0 +1,26 @@
+package providers
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrPingUnsupported is returned by Ping for providers without a health check.
+var ErrPingUnsupported = errors.New("provider does not support health checks")
+
+// Pinger is implemented by providers that can cheaply verify they are
+// reachable and that their credentials are accepted. Bad credentials are
+// reported as an error matching ErrUnauthorized.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping runs the provider's health check, or returns ErrPingUnsupported when
+// the provider has none.
+func Ping(ctx context.Context, provider LLMProvider) error {
+	p, ok := provider.(Pinger)
+	if !ok {
+		return ErrPingUnsupported
+	}
+	return p.Ping(ctx)
+}
@@ -0,0 +1,67 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPProvider_Ping(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/models" {
+			http.Error(w, "unexpected request", http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer good-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"message":"Incorrect API key provided","type":"invalid_request_error"}}`))
+			return
+		}
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer server.Close()
+
+	if err := NewHTTPProvider("good-key", server.URL, "").Ping(t.Context()); err != nil {
+		t.Errorf("Ping() with valid key error: %v", err)
+	}
+
+	err := NewHTTPProvider("bad-key", server.URL, "").Ping(t.Context())
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Ping() with bad key error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestClaudeProvider_PingUnauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+	}))
+	defer server.Close()
+
+	p := NewClaudeProvider("bad-token")
+	p.client = createAnthropicTestClient(server.URL, "bad-token")
+
+	err := p.Ping(t.Context())
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Ping() error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestCodexProvider_PingTokenSourceFailure(t *testing.T) {
+	p := NewCodexProviderWithTokenSource("tok", "", func() (string, string, error) {
+		return "", "", errors.New("refresh token expired")
+	})
+
+	if err := p.Ping(t.Context()); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Ping() error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestPing_Unsupported(t *testing.T) {
+	if err := Ping(context.Background(), &blockingProvider{}); !errors.Is(err, ErrPingUnsupported) {
+		t.Errorf("Ping() error = %v, want ErrPingUnsupported", err)
+	}
+}
@@ -0,0 +1,204 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimitedProvider paces Chat calls per model with a token bucket so the
+// provider's requests-per-minute limit is not exceeded. Calls wait for their
+// turn, failing early with an error wrapping ErrRateLimited when the wait
+// would outlast the context deadline. Models without a configured rate are not limited.
+type RateLimitedProvider struct {
+	inner    LLMProvider
+	rpm      int
+	modelRPM map[string]int
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+}
+
+// NewRateLimitedProvider wraps inner so each model gets its own bucket
+// allowing rpm requests per minute; modelRPM overrides the rate by model
+// name. A rate <= 0 leaves the model unlimited.
+func NewRateLimitedProvider(inner LLMProvider, rpm int, modelRPM map[string]int) *RateLimitedProvider {
+	return &RateLimitedProvider{
+		inner:    inner,
+		rpm:      rpm,
+		modelRPM: modelRPM,
+		buckets:  make(map[string]*tokenBucket),
+		now:      time.Now,
+	}
+}
+
+// RateLimitProvider wraps inner like NewRateLimitedProvider but keeps its
+// optional capabilities: when inner is a StreamingProvider the result is one
+// too, with ChatStream drawing from the same buckets as Chat.
+func RateLimitProvider(inner LLMProvider, rpm int, modelRPM map[string]int) LLMProvider {
+	limited := NewRateLimitedProvider(inner, rpm, modelRPM)
+	if stream, ok := inner.(StreamingProvider); ok {
+		return &rateLimitedStreamingProvider{RateLimitedProvider: limited, stream: stream}
+	}
+	return limited
+}
+
+func (p *RateLimitedProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	if err := p.wait(ctx, model); err != nil {
+		return nil, err
+	}
+	return p.inner.Chat(ctx, messages, tools, model, options)
+}
+
+func (p *RateLimitedProvider) GetDefaultModel() string {
+	return p.inner.GetDefaultModel()
+}
+
+// Ping forwards the health check to the wrapped provider without drawing
+// from any bucket.
+func (p *RateLimitedProvider) Ping(ctx context.Context) error {
+	return Ping(ctx, p.inner)
+}
+
+// ListModels forwards to the wrapped provider without drawing from any
+// bucket.
+func (p *RateLimitedProvider) ListModels(ctx context.Context) ([]string, error) {
+	return ListModels(ctx, p.inner)
+}
+
+// CountTokens forwards to the wrapped provider's token counting, or returns
+// the local estimate when it has none. Token counting is not a completion
+// request, so it draws from no bucket.
+func (p *RateLimitedProvider) CountTokens(ctx context.Context, messages []Message, tools []ToolDefinition, model string) (int, error) {
+	tc, ok := p.inner.(TokenCounter)
+	if !ok {
+		return CountTokens(messages, model) + countToolTokens(tools, model), nil
+	}
+	return tc.CountTokens(ctx, messages, tools, model)
+}
+
+// Close releases the wrapped provider.
+func (p *RateLimitedProvider) Close() error {
+	return Close(p.inner)
+}
+
+// SetUsageObserver forwards the observer to the wrapped provider when
+// supported.
+func (p *RateLimitedProvider) SetUsageObserver(observer UsageObserver) {
+	if u, ok := p.inner.(UsageObservableProvider); ok {
+		u.SetUsageObserver(observer)
+	}
+}
+
+// SetDebugHook forwards the hook to the wrapped provider when supported.
+func (p *RateLimitedProvider) SetDebugHook(hook DebugHook) {
+	if d, ok := p.inner.(DebuggableProvider); ok {
+		d.SetDebugHook(hook)
+	}
+}
+
+// bucket returns the bucket for model, or nil when the model is unlimited.
+func (p *RateLimitedProvider) bucket(model string) *tokenBucket {
+	if model == "" {
+		model = p.inner.GetDefaultModel()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if b, ok := p.buckets[model]; ok {
+		return b
+	}
+	rpm := p.rpm
+	if n, ok := p.modelRPM[model]; ok {
+		rpm = n
+	}
+	var b *tokenBucket
+	if rpm > 0 {
+		b = newTokenBucket(rpm, p.now())
+	}
+	p.buckets[model] = b
+	return b
+}
+
+func (p *RateLimitedProvider) wait(ctx context.Context, model string) error {
+	b := p.bucket(model)
+	if b == nil {
+		return nil
+	}
+
+	delay := b.reserve(p.now())
+	if delay <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && p.now().Add(delay).After(deadline) {
+		b.cancel()
+		return fmt.Errorf("%w for %s: next request allowed in %s", ErrRateLimited, model, delay.Round(time.Millisecond))
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
+
+// tokenBucket holds at most one token, refilled at rpm per minute, so
+// requests are spaced evenly and no minute sees more than rpm of them.
+// Tokens go negative while callers wait for ones not yet refilled.
+type tokenBucket struct {
+	mu       sync.Mutex
+	interval time.Duration // time to refill one token
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(rpm int, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		interval: time.Minute / time.Duration(rpm),
+		tokens:   1,
+		last:     now,
+	}
+}
+
+// reserve takes a token and returns how long to wait before using it.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(1, b.tokens+float64(elapsed)/float64(b.interval))
+		b.last = now
+	}
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens * float64(b.interval))
+}
+
+// cancel returns a token taken by reserve that will not be used.
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(1, b.tokens+1)
+}
+
+// rateLimitedStreamingProvider is a RateLimitedProvider over a
+// StreamingProvider.
+type rateLimitedStreamingProvider struct {
+	*RateLimitedProvider
+	stream StreamingProvider
+}
+
+func (p *rateLimitedStreamingProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta StreamCallback) (*LLMResponse, error) {
+	if err := p.wait(ctx, model); err != nil {
+		return nil, err
+	}
+	return p.stream.ChatStream(ctx, messages, tools, model, options, onDelta)
+}
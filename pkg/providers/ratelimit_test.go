@@ -0,0 +1,130 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+type countingProvider struct {
+	calls atomic.Int32
+}
+
+func (p *countingProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	p.calls.Add(1)
+	return &LLMResponse{Content: "ok"}, nil
+}
+
+func (p *countingProvider) GetDefaultModel() string {
+	return "counting"
+}
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	b := newTokenBucket(60, start) // one request per second
+
+	if d := b.reserve(start); d != 0 {
+		t.Errorf("first reserve waits %s, want 0", d)
+	}
+	if d := b.reserve(start); d != time.Second {
+		t.Errorf("second reserve waits %s, want 1s", d)
+	}
+	if d := b.reserve(start); d != 2*time.Second {
+		t.Errorf("third reserve waits %s, want 2s", d)
+	}
+	b.cancel()
+	if d := b.reserve(start.Add(1500 * time.Millisecond)); d != 500*time.Millisecond {
+		t.Errorf("reserve after cancel waits %s, want 500ms", d)
+	}
+	// An idle bucket refills to a single token, not a burst
+	if d := b.reserve(start.Add(time.Hour)); d != 0 {
+		t.Errorf("reserve after idle waits %s, want 0", d)
+	}
+	if d := b.reserve(start.Add(time.Hour)); d != time.Second {
+		t.Errorf("second reserve after idle waits %s, want 1s", d)
+	}
+}
+
+func TestRateLimitedProvider_PacesPerModel(t *testing.T) {
+	inner := &countingProvider{}
+	p := NewRateLimitedProvider(inner, 0, map[string]int{"slow": 600}) // 100ms apart
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := p.Chat(ctx, nil, nil, "slow", nil); err != nil {
+			t.Fatalf("Chat error = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Errorf("3 calls at 600 rpm took %s, want about 200ms", elapsed)
+	}
+
+	start = time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := p.Chat(ctx, nil, nil, "fast", nil); err != nil {
+			t.Fatalf("Chat error = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("unlimited model took %s", elapsed)
+	}
+	if n := inner.calls.Load(); n != 8 {
+		t.Errorf("inner calls = %d, want 8", n)
+	}
+}
+
+func TestRateLimitedProvider_FailsPastDeadline(t *testing.T) {
+	inner := &countingProvider{}
+	p := NewRateLimitedProvider(inner, 1, nil) // one request per minute
+
+	if _, err := p.Chat(context.Background(), nil, nil, "", nil); err != nil {
+		t.Fatalf("first Chat error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := p.Chat(ctx, nil, nil, "", nil)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Chat error = %v, want ErrRateLimited", err)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Error("Chat waited although the deadline was too close")
+	}
+	if n := inner.calls.Load(); n != 1 {
+		t.Errorf("inner calls = %d, want 1", n)
+	}
+}
+
+func TestCreateProvider_AppliesRateLimit(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Model = "gpt-4o"
+	cfg.Providers.OpenAI.APIKey = "sk"
+	cfg.Providers.MaxInFlight = 2
+
+	provider, err := CreateProvider(cfg)
+	if err != nil {
+		t.Fatalf("CreateProvider error = %v", err)
+	}
+	if _, ok := provider.(*RateLimitedProvider); ok {
+		t.Error("provider rate limited without configuration")
+	}
+
+	cfg.Providers.RequestsPerMinute = 30
+	provider, err = CreateProvider(cfg)
+	if err != nil {
+		t.Fatalf("CreateProvider error = %v", err)
+	}
+	limited, ok := provider.(*RateLimitedProvider)
+	if !ok {
+		t.Fatalf("provider = %T, want *RateLimitedProvider", provider)
+	}
+	if _, ok := limited.inner.(*LimitedProvider); !ok {
+		t.Errorf("rate limiter wraps %T, want the concurrency limiter", limited.inner)
+	}
+}
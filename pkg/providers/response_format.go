@@ -0,0 +1,214 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ResponseFormat requests structured output from providers that support it.
+// Pass it as options["response_format"] to Chat.
+type ResponseFormat struct {
+	Type       string          `json:"type"` // "json_object" or "json_schema"
+	JSONSchema *JSONSchemaSpec `json:"json_schema,omitempty"`
+}
+
+// JSONSchemaSpec describes the schema the model output must match when
+// ResponseFormat.Type is "json_schema".
+type JSONSchemaSpec struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+	Strict bool                   `json:"strict,omitempty"`
+}
+
+// responseFormatOption extracts the response_format option, accepting either a
+// ResponseFormat value or pointer. Unsupported types return an error so a
+// misconfigured caller does not silently get free-form text back.
+func responseFormatOption(options map[string]interface{}) (*ResponseFormat, error) {
+	var rf *ResponseFormat
+	switch v := options["response_format"].(type) {
+	case nil:
+		return nil, nil
+	case ResponseFormat:
+		rf = &v
+	case *ResponseFormat:
+		rf = v
+	default:
+		return nil, fmt.Errorf("unsupported response_format option type %T", v)
+	}
+	if rf == nil {
+		return nil, nil
+	}
+
+	switch rf.Type {
+	case "json_object":
+	case "json_schema":
+		if rf.JSONSchema == nil || rf.JSONSchema.Name == "" || rf.JSONSchema.Schema == nil {
+			return nil, fmt.Errorf("response_format json_schema requires a name and schema")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported response_format type %q (valid: json_object, json_schema)", rf.Type)
+	}
+	return rf, nil
+}
+
+// validateStructuredOutput checks that content is valid JSON and, for
+// json_schema formats, that it matches the requested schema.
+func validateStructuredOutput(content string, rf *ResponseFormat) error {
+	if rf == nil {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(content), &value); err != nil {
+		return fmt.Errorf("structured output is not valid JSON: %w", err)
+	}
+
+	if rf.Type == "json_object" {
+		if _, ok := value.(map[string]interface{}); !ok {
+			return fmt.Errorf("structured output is not a JSON object")
+		}
+		return nil
+	}
+
+	if err := validateJSONSchema(value, rf.JSONSchema.Schema, "$"); err != nil {
+		return fmt.Errorf("structured output does not match schema %q: %w", rf.JSONSchema.Name, err)
+	}
+	return nil
+}
+
+// validateJSONSchema validates value against the subset of JSON Schema used for
+// structured outputs: type, enum, properties, required, additionalProperties
+// and items.
+func validateJSONSchema(value interface{}, schema map[string]interface{}, path string) error {
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if jsonTypeMatches(value, t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(types, " or "), jsonTypeName(value))
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if jsonEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value is not one of the allowed enum values", path)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		for _, r := range schemaStrings(schema["required"]) {
+			if _, ok := v[r]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, r)
+			}
+		}
+		for key, item := range v {
+			propSchema, ok := props[key].(map[string]interface{})
+			if !ok {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					return fmt.Errorf("%s: unexpected property %q", path, key)
+				}
+				continue
+			}
+			if err := validateJSONSchema(item, propSchema, path+"."+key); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validateJSONSchema(item, items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func schemaTypes(v interface{}) []string {
+	if s, ok := v.(string); ok {
+		return []string{s}
+	}
+	return schemaStrings(v)
+}
+
+func schemaStrings(v interface{}) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		result := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func jsonTypeMatches(value interface{}, t string) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == float64(int64(f))
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func jsonEqual(a, b interface{}) bool {
+	aj, err1 := json.Marshal(a)
+	bj, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && string(aj) == string(bj)
+}
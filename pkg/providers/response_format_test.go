@@ -0,0 +1,94 @@
+package providers
+
+import (
+	"strings"
+	"testing"
+)
+
+var testWeatherFormat = &ResponseFormat{
+	Type: "json_schema",
+	JSONSchema: &JSONSchemaSpec{
+		Name: "weather",
+		Schema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"city": map[string]interface{}{"type": "string"},
+				"temp": map[string]interface{}{"type": "integer"},
+				"unit": map[string]interface{}{"type": "string", "enum": []interface{}{"C", "F"}},
+				"tags": map[string]interface{}{
+					"type":  "array",
+					"items": map[string]interface{}{"type": "string"},
+				},
+			},
+			"required":             []interface{}{"city", "temp"},
+			"additionalProperties": false,
+		},
+	},
+}
+
+func TestResponseFormatOption(t *testing.T) {
+	rf, err := responseFormatOption(map[string]interface{}{})
+	if err != nil || rf != nil {
+		t.Errorf("responseFormatOption(empty) = %v, %v; want nil, nil", rf, err)
+	}
+
+	rf, err = responseFormatOption(map[string]interface{}{"response_format": ResponseFormat{Type: "json_object"}})
+	if err != nil || rf == nil || rf.Type != "json_object" {
+		t.Errorf("responseFormatOption(value) = %v, %v; want json_object", rf, err)
+	}
+
+	if _, err := responseFormatOption(map[string]interface{}{"response_format": &ResponseFormat{Type: "xml"}}); err == nil {
+		t.Error("expected error for unsupported type")
+	}
+	if _, err := responseFormatOption(map[string]interface{}{"response_format": &ResponseFormat{Type: "json_schema"}}); err == nil {
+		t.Error("expected error for json_schema without schema")
+	}
+	if _, err := responseFormatOption(map[string]interface{}{"response_format": "json"}); err == nil {
+		t.Error("expected error for unsupported option type")
+	}
+}
+
+func TestValidateStructuredOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"valid", `{"city":"SF","temp":72,"unit":"F","tags":["sunny"]}`, ""},
+		{"not json", `The weather is nice`, "not valid JSON"},
+		{"missing required", `{"city":"SF"}`, `missing required property "temp"`},
+		{"wrong type", `{"city":"SF","temp":"hot"}`, "$.temp: expected integer, got string"},
+		{"non-integer", `{"city":"SF","temp":72.5}`, "$.temp: expected integer"},
+		{"enum", `{"city":"SF","temp":72,"unit":"K"}`, "$.unit: value is not one of"},
+		{"array items", `{"city":"SF","temp":72,"tags":[1]}`, "$.tags[0]: expected string"},
+		{"additional property", `{"city":"SF","temp":72,"wind":3}`, `unexpected property "wind"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStructuredOutput(tt.content, testWeatherFormat)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateStructuredOutput_JSONObject(t *testing.T) {
+	rf := &ResponseFormat{Type: "json_object"}
+	if err := validateStructuredOutput(`{"ok":true}`, rf); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := validateStructuredOutput(`[1,2]`, rf); err == nil {
+		t.Error("expected error for JSON array")
+	}
+	if err := validateStructuredOutput("anything", nil); err != nil {
+		t.Errorf("nil format should not validate, got %v", err)
+	}
+}
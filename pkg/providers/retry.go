@@ -0,0 +1,97 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// RetryOptions controls how every provider retries a failed request. Rate
+// limits (429) and server errors (5xx) are retried; other failures are not.
+type RetryOptions struct {
+	MaxRetries int           // retries after the first attempt, 0 = none
+	BaseDelay  time.Duration // backoff before the first retry, doubled each time
+	MaxDelay   time.Duration // cap on the backoff and on Retry-After waits
+}
+
+// DefaultRetryOptions returns the policy used when providers.max_retries is
+// not configured.
+func DefaultRetryOptions() RetryOptions {
+	return RetryOptions{
+		MaxRetries: 2,
+		BaseDelay:  time.Second,
+		MaxDelay:   30 * time.Second,
+	}
+}
+
+// Retrier is implemented by providers whose retry policy can be configured.
+type Retrier interface {
+	SetRetryOptions(opts RetryOptions)
+}
+
+// withRetry runs call, retrying rate-limit and server errors with exponential
+// backoff. A Retry-After from the server replaces the backoff; when it asks
+// for a longer wait than opts.MaxDelay, the error is returned instead. A
+// cancelled context aborts the wait immediately.
+func withRetry[T any](ctx context.Context, opts RetryOptions, call func() (T, error)) (T, error) {
+	delay := opts.BaseDelay
+	for attempt := 0; ; attempt++ {
+		result, err := call()
+		if err == nil || attempt >= opts.MaxRetries || !isRetryable(err) {
+			return result, err
+		}
+
+		wait := delay
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			if opts.MaxDelay > 0 && apiErr.RetryAfter > opts.MaxDelay {
+				return result, err
+			}
+			wait = apiErr.RetryAfter
+		}
+		if opts.MaxDelay > 0 && wait > opts.MaxDelay {
+			wait = opts.MaxDelay
+		}
+
+		logger.WarnCF("provider", "Retrying LLM request", map[string]interface{}{
+			"attempt": attempt + 2,
+			"delay":   wait.String(),
+			"error":   err.Error(),
+		})
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			var zero T
+			return zero, ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+func isRetryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrServer)
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP
+// date. It returns 0 when the header is absent or invalid.
+func parseRetryAfter(header http.Header) time.Duration {
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,142 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+)
+
+var fastRetry = RetryOptions{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+// newFlakyServer fails the first `failures` requests with status and then
+// serves a chat completion.
+func newFlakyServer(t *testing.T, failures int32, status int, header http.Header, calls *int32) *httptest.Server {
+	t.Helper()
+	ok := newChatCompletionServer(t, "ok", nil)
+	t.Cleanup(ok.Close)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(calls, 1) <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":{"message":"try again"}}`))
+			return
+		}
+		ok.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPProvider_Retry(t *testing.T) {
+	messages := []Message{{Role: "user", Content: "Hi"}}
+
+	tests := []struct {
+		name      string
+		failures  int32
+		status    int
+		header    http.Header
+		wantErr   error
+		wantCalls int32
+	}{
+		{name: "server error recovers", failures: 2, status: http.StatusServiceUnavailable, wantCalls: 3},
+		{name: "rate limit with retry-after", failures: 1, status: http.StatusTooManyRequests, header: http.Header{"Retry-After": {"0"}}, wantCalls: 2},
+		{name: "gives up after max retries", failures: 5, status: http.StatusBadGateway, wantErr: ErrServer, wantCalls: 3},
+		{name: "bad request not retried", failures: 5, status: http.StatusBadRequest, wantErr: ErrBadRequest, wantCalls: 1},
+		{name: "retry-after beyond max delay not retried", failures: 5, status: http.StatusTooManyRequests, header: http.Header{"Retry-After": {"3600"}}, wantErr: ErrRateLimited, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			server := newFlakyServer(t, tt.failures, tt.status, tt.header, &calls)
+
+			p := NewHTTPProvider("key", server.URL, "")
+			p.SetRetryOptions(fastRetry)
+			resp, err := p.Chat(t.Context(), messages, nil, "gpt-4o", nil)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil || resp.Content != "ok" {
+				t.Fatalf("Chat = %+v, %v", resp, err)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestWithRetry_ContextCancelAbortsWait(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	opts := RetryOptions{MaxRetries: 5, BaseDelay: time.Hour}
+
+	calls := 0
+	start := time.Now()
+	_, err := withRetry(ctx, opts, func() (string, error) {
+		calls++
+		cancel()
+		return "", &APIError{StatusCode: http.StatusServiceUnavailable, kind: ErrServer}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("cancellation did not abort the backoff")
+	}
+}
+
+func TestClaudeProvider_Retry(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"type":"error","error":{"type":"api_error","message":"boom"}}`))
+			return
+		}
+		w.Write([]byte(`{"id":"msg","type":"message","role":"assistant","model":"claude-sonnet-4","stop_reason":"end_turn",` +
+			`"content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	p := NewClaudeProvider("test-token")
+	client := anthropic.NewClient(
+		anthropicoption.WithAuthToken("test-token"),
+		anthropicoption.WithBaseURL(server.URL),
+	)
+	p.client = &client
+	p.SetRetryOptions(fastRetry)
+
+	resp, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "claude-sonnet-4", nil)
+	if err != nil || resp.Content != "ok" {
+		t.Fatalf("Chat = %+v, %v", resp, err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter(http.Header{"Retry-After": {"7"}}); got != 7*time.Second {
+		t.Errorf("seconds = %v, want 7s", got)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(http.Header{"Retry-After": {date}}); got <= 0 || got > time.Minute {
+		t.Errorf("date = %v, want within a minute", got)
+	}
+	if got := parseRetryAfter(http.Header{}); got != 0 {
+		t.Errorf("missing = %v, want 0", got)
+	}
+}
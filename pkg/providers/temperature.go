@@ -0,0 +1,64 @@
+package providers
+
+import (
+	"math"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// TemperatureDefaulter is implemented by providers whose sampling temperature
+// for calls without temperature can be configured.
+type TemperatureDefaulter interface {
+	SetDefaultTemperature(t float64)
+}
+
+// withDefaultTemperature returns options with temperature set to def unless
+// the caller already set it or def is nil. The caller's map is never
+// modified. Model-specific overrides are applied later and still win.
+func withDefaultTemperature(options map[string]interface{}, def *float64) map[string]interface{} {
+	if _, ok := options["temperature"].(float64); ok || def == nil {
+		return options
+	}
+	merged := make(map[string]interface{}, len(options)+1)
+	for k, v := range options {
+		merged[k] = v
+	}
+	merged["temperature"] = *def
+	return merged
+}
+
+// temperatureRange returns the sampling temperature range accepted for model.
+// OpenAI-style APIs take [0, 2]; Anthropic models take [0, 1].
+func temperatureRange(model string) (lo, hi float64) {
+	lowerModel := strings.ToLower(model)
+	if strings.Contains(lowerModel, "claude") {
+		return 0, 1
+	}
+	return 0, 2
+}
+
+// clampTemperature limits temperature to the range model accepts, logging a
+// warning when the requested value had to be changed.
+func clampTemperature(model string, temperature float64) float64 {
+	lo, hi := temperatureRange(model)
+	clamped := temperature
+	switch {
+	case math.IsNaN(temperature):
+		clamped = lo
+	case temperature < lo:
+		clamped = lo
+	case temperature > hi:
+		clamped = hi
+	}
+
+	if clamped != temperature || math.IsNaN(temperature) {
+		logger.WarnCF("provider", "Temperature out of range, clamping",
+			map[string]interface{}{
+				"model":     model,
+				"requested": temperature,
+				"used":      clamped,
+			})
+	}
+	return clamped
+}
@@ -0,0 +1,169 @@
+package providers
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestClampTemperature(t *testing.T) {
+	tests := []struct {
+		name  string
+		model string
+		temp  float64
+		want  float64
+	}{
+		{"openai in range", "gpt-4o", 0.7, 0.7},
+		{"openai upper bound", "gpt-4o", 2, 2},
+		{"openai too high", "gpt-4o", 3.5, 2},
+		{"negative", "deepseek-chat", -0.5, 0},
+		{"claude in range", "claude-sonnet-4-5", 0.7, 0.7},
+		{"claude too high", "claude-sonnet-4-5", 1.5, 1},
+		{"claude via openrouter", "anthropic/claude-3.5-sonnet", 1.8, 1},
+		{"nan", "gpt-4o", math.NaN(), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clampTemperature(tt.model, tt.temp); got != tt.want {
+				t.Errorf("clampTemperature(%q, %v) = %v, want %v", tt.model, tt.temp, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildCodexParams_ClampsTemperature(t *testing.T) {
+	params := buildCodexParams([]Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4o", map[string]interface{}{
+		"temperature": 5.0,
+	}, "")
+	if got := params.Temperature.Or(0); got != 2 {
+		t.Errorf("Temperature = %v, want 2", got)
+	}
+}
+
+func TestBuildClaudeParams_ClampsTemperature(t *testing.T) {
+	params, err := buildClaudeParams([]Message{{Role: "user", Content: "Hi"}}, nil, "claude-sonnet-4-5", map[string]interface{}{
+		"temperature": 1.7,
+	})
+	if err != nil {
+		t.Fatalf("buildClaudeParams() error: %v", err)
+	}
+	if got := params.Temperature.Value; got != 1 {
+		t.Errorf("Temperature = %v, want 1", got)
+	}
+}
+
+func TestHTTPProvider_ClampsTemperature(t *testing.T) {
+	var body map[string]interface{}
+	server := newChatCompletionServer(t, "ok", &body)
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	if _, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4o", map[string]interface{}{
+		"temperature": 2.5,
+	}); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if body["temperature"] != 2.0 {
+		t.Errorf("temperature = %v, want 2", body["temperature"])
+	}
+}
+
+func TestWithDefaultTemperature(t *testing.T) {
+	def := 0.3
+	tests := []struct {
+		name    string
+		options map[string]interface{}
+		def     *float64
+		want    interface{}
+	}{
+		{"nil options", nil, &def, 0.3},
+		{"no default", map[string]interface{}{"max_tokens": 100}, nil, nil},
+		{"explicit wins", map[string]interface{}{"temperature": 0.9}, &def, 0.9},
+		{"explicit zero wins", map[string]interface{}{"temperature": 0.0}, &def, 0.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := withDefaultTemperature(tt.options, tt.def)
+			if got["temperature"] != tt.want {
+				t.Errorf("temperature = %v, want %v", got["temperature"], tt.want)
+			}
+		})
+	}
+
+	options := map[string]interface{}{"max_tokens": 100}
+	withDefaultTemperature(options, &def)
+	if _, ok := options["temperature"]; ok {
+		t.Error("caller's options were modified")
+	}
+}
+
+func TestHTTPProvider_DefaultTemperature(t *testing.T) {
+	var body map[string]interface{}
+	server := newChatCompletionServer(t, "ok", &body)
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	messages := []Message{{Role: "user", Content: "Hi"}}
+
+	if _, err := p.Chat(t.Context(), messages, nil, "llama-3", nil); err != nil {
+		t.Fatalf("Chat error = %v", err)
+	}
+	if _, ok := body["temperature"]; ok {
+		t.Errorf("temperature = %v, want none without a default", body["temperature"])
+	}
+
+	p.SetDefaultTemperature(0.3)
+	if _, err := p.Chat(t.Context(), messages, nil, "llama-3", nil); err != nil {
+		t.Fatalf("Chat error = %v", err)
+	}
+	if body["temperature"] != 0.3 {
+		t.Errorf("temperature = %v, want 0.3", body["temperature"])
+	}
+
+	if _, err := p.Chat(t.Context(), messages, nil, "llama-3", map[string]interface{}{"temperature": 0.8}); err != nil {
+		t.Fatalf("Chat error = %v", err)
+	}
+	if body["temperature"] != 0.8 {
+		t.Errorf("explicit temperature = %v, want 0.8", body["temperature"])
+	}
+
+	if _, err := p.Chat(t.Context(), messages, nil, "kimi-k2-0711", nil); err != nil {
+		t.Fatalf("Chat error = %v", err)
+	}
+	if body["temperature"] != 1.0 {
+		t.Errorf("kimi temperature = %v, want 1.0", body["temperature"])
+	}
+}
+
+func TestBuildClaudeParams_DefaultTemperature(t *testing.T) {
+	def := 0.3
+	params, err := buildClaudeParams([]Message{{Role: "user", Content: "Hi"}}, nil, "claude-sonnet-4",
+		withDefaultMaxTokens(withDefaultTemperature(nil, &def), 0))
+	if err != nil {
+		t.Fatalf("buildClaudeParams error = %v", err)
+	}
+	if got := params.Temperature.Value; got != 0.3 {
+		t.Errorf("Temperature = %v, want 0.3", got)
+	}
+}
+
+func TestCreateProvider_AppliesDefaultTemperature(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Model = "gpt-4o"
+	cfg.Agents.Defaults.Temperature = 0.3
+	cfg.Providers.OpenAI.APIKey = "sk"
+
+	provider, err := CreateProvider(cfg)
+	if err != nil {
+		t.Fatalf("CreateProvider error = %v", err)
+	}
+	hp, ok := provider.(*HTTPProvider)
+	if !ok {
+		t.Fatalf("CreateProvider returned %T, want *HTTPProvider", provider)
+	}
+	if hp.defaultTemperature == nil || *hp.defaultTemperature != 0.3 {
+		t.Errorf("defaultTemperature = %v, want 0.3", hp.defaultTemperature)
+	}
+}
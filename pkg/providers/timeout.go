@@ -0,0 +1,22 @@
+package providers
+
+import "time"
+
+// DefaultRequestTimeout bounds a single LLM request attempt when
+// providers.request_timeout_seconds is unset, so a hung connection fails
+// instead of stalling the agent.
+const DefaultRequestTimeout = 120 * time.Second
+
+// RequestTimeouter is implemented by providers whose per-request timeout can
+// be configured.
+type RequestTimeouter interface {
+	SetRequestTimeout(d time.Duration)
+}
+
+// requestTimeout returns d, or DefaultRequestTimeout when d <= 0.
+func requestTimeout(d time.Duration) time.Duration {
+	if d <= 0 {
+		return DefaultRequestTimeout
+	}
+	return d
+}
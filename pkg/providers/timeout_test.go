@@ -0,0 +1,90 @@
+package providers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// newHangingServer returns a server that never answers until the test ends.
+func newHangingServer(t *testing.T) *httptest.Server {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(func() {
+		close(done)
+		server.Close()
+	})
+	return server
+}
+
+func TestHTTPProvider_RequestTimeout(t *testing.T) {
+	server := newHangingServer(t)
+
+	p := NewHTTPProvider("key", server.URL, "")
+	p.SetRequestTimeout(100 * time.Millisecond)
+
+	start := time.Now()
+	_, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "llama-3", nil)
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Chat took %v, want it bounded by the request timeout", elapsed)
+	}
+}
+
+func TestClaudeProvider_RequestTimeout(t *testing.T) {
+	server := newHangingServer(t)
+
+	p := NewClaudeProvider("test-token")
+	client := anthropic.NewClient(
+		anthropicoption.WithAuthToken("test-token"),
+		anthropicoption.WithBaseURL(server.URL),
+		anthropicoption.WithMaxRetries(0),
+	)
+	p.client = &client
+	p.SetRequestTimeout(100 * time.Millisecond)
+
+	start := time.Now()
+	_, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "claude-sonnet-4", nil)
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Chat took %v, want it bounded by the request timeout", elapsed)
+	}
+}
+
+func TestCreateProvider_AppliesRequestTimeout(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Model = "gpt-4o"
+	cfg.Providers.OpenAI.APIKey = "sk"
+
+	cfg.Providers.RequestTimeoutSeconds = 30
+	provider, err := CreateProvider(cfg)
+	if err != nil {
+		t.Fatalf("CreateProvider error = %v", err)
+	}
+	if got := provider.(*HTTPProvider).httpClient.Timeout; got != 30*time.Second {
+		t.Errorf("timeout = %v, want 30s", got)
+	}
+
+	cfg.Providers.RequestTimeoutSeconds = 0
+	provider, err = CreateProvider(cfg)
+	if err != nil {
+		t.Fatalf("CreateProvider error = %v", err)
+	}
+	if got := provider.(*HTTPProvider).httpClient.Timeout; got != DefaultRequestTimeout {
+		t.Errorf("timeout = %v, want %v", got, DefaultRequestTimeout)
+	}
+}
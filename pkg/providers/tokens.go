@@ -0,0 +1,173 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	// messageTokenOverhead is the per-message framing (role, separators)
+	// OpenAI's chat format adds; other backends are in the same range.
+	messageTokenOverhead = 3
+	// replyTokenOverhead primes the assistant reply.
+	replyTokenOverhead = 3
+	// imageTokenEstimate is a middle-of-the-road cost for one image; the real
+	// cost depends on its size and the provider.
+	imageTokenEstimate = 1000
+)
+
+// CountTokens estimates the prompt tokens messages take for model, without a
+// network call. OpenAI models (gpt-*, o1, o3, ...) use an approximation of
+// their BPE tokenizers; other models use a character-based heuristic. The
+// estimate is meant for budgeting and truncation, not billing.
+func CountTokens(messages []Message, model string) int {
+	countText := estimateTextTokens
+	if isOpenAITokenizerModel(model) {
+		countText = estimateBPETokens
+	}
+
+	total := replyTokenOverhead
+	for _, m := range messages {
+		total += messageTokenOverhead + countText(m.Role) + countText(m.Content)
+		for _, tc := range m.ToolCalls {
+			if tc.Function != nil {
+				total += messageTokenOverhead + countText(tc.Function.Name) + countText(tc.Function.Arguments)
+			}
+		}
+		total += len(m.Images) * imageTokenEstimate
+	}
+	return total
+}
+
+// countToolTokens estimates the tokens tool definitions add to a prompt.
+func countToolTokens(tools []ToolDefinition, model string) int {
+	if len(tools) == 0 {
+		return 0
+	}
+	data, err := json.Marshal(tools)
+	if err != nil {
+		return 0
+	}
+	if isOpenAITokenizerModel(model) {
+		return estimateBPETokens(string(data))
+	}
+	return estimateTextTokens(string(data))
+}
+
+// TokenCounter is implemented by providers that can count prompt tokens
+// exactly through their API, such as Anthropic's count_tokens endpoint.
+type TokenCounter interface {
+	CountTokens(ctx context.Context, messages []Message, tools []ToolDefinition, model string) (int, error)
+}
+
+// CountPromptTokens returns the prompt tokens of a request to provider. It
+// uses the provider's own counting when available and falls back to the
+// CountTokens estimate, so it always returns a usable number.
+func CountPromptTokens(ctx context.Context, provider LLMProvider, messages []Message, tools []ToolDefinition, model string) int {
+	if tc, ok := provider.(TokenCounter); ok {
+		n, err := tc.CountTokens(ctx, messages, tools, model)
+		if err == nil {
+			return n
+		}
+		logger.DebugCF("provider", "Token counting failed, using estimate",
+			map[string]interface{}{
+				"model": model,
+				"error": err.Error(),
+			})
+	}
+	return CountTokens(messages, model) + countToolTokens(tools, model)
+}
+
+// isOpenAITokenizerModel reports whether model uses one of OpenAI's BPE
+// tokenizers (cl100k/o200k).
+func isOpenAITokenizerModel(model string) bool {
+	m := strings.ToLower(model)
+	if i := strings.LastIndex(m, "/"); i >= 0 {
+		m = m[i+1:]
+	}
+	for _, prefix := range []string{"gpt-", "chatgpt", "o1", "o3", "o4", "codex", "text-embedding"} {
+		if strings.HasPrefix(m, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// estimateTextTokens is the heuristic for tokenizers we do not model: about
+// three characters per token, counted in runes so CJK text is not
+// over-counted by its UTF-8 length.
+func estimateTextTokens(s string) int {
+	return (utf8.RuneCountInString(s) + 2) / 3
+}
+
+// estimateBPETokens approximates OpenAI's tokenizers. Text is split like
+// their pre-tokenizer (words with a leading space, digit groups, punctuation
+// runs, whitespace), then each piece is costed: common-length words are one
+// token, digits go in groups of three, and CJK characters are about one
+// token each.
+func estimateBPETokens(s string) int {
+	tokens := 0
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == ' ' && i+1 < len(runes) && isWordRune(runes[i+1]):
+			// A single space is merged into the following word
+			i++
+		case isCJK(r):
+			tokens++
+			i++
+		case isWordRune(r):
+			j := i
+			nonASCII := 0
+			for j < len(runes) && isWordRune(runes[j]) && !isCJK(runes[j]) {
+				if runes[j] > unicode.MaxASCII {
+					nonASCII++
+				}
+				j++
+			}
+			n := j - i
+			// Long words split into subwords of about five letters; accented
+			// and non-Latin letters split more
+			tokens += (n-nonASCII+4)/5 + (nonASCII+1)/2
+			i = j
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && unicode.IsDigit(runes[j]) {
+				j++
+			}
+			tokens += (j - i + 2) / 3
+			i = j
+		case unicode.IsSpace(r):
+			j := i
+			for j < len(runes) && unicode.IsSpace(runes[j]) {
+				j++
+			}
+			tokens++
+			i = j
+		default:
+			// Punctuation and symbols: common pairs (":/", "{\"") merge
+			j := i
+			for j < len(runes) && !unicode.IsSpace(runes[j]) && !isWordRune(runes[j]) && !unicode.IsDigit(runes[j]) && !isCJK(runes[j]) {
+				j++
+			}
+			tokens += (j - i + 1) / 2
+			i = j
+		}
+	}
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsMark(r)
+}
+
+// isCJK reports whether r is a Han, Hiragana, Katakana or Hangul character.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
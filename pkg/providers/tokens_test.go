@@ -0,0 +1,104 @@
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEstimateBPETokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"Hello world", 2},
+		{"The quick brown fox jumps over the lazy dog.", 10},
+		{"1234567", 3},
+		{"internationalization", 4},
+		{"你好世界", 4},
+		{"a\n\nb", 3},
+	}
+	for _, tt := range tests {
+		if got := estimateBPETokens(tt.text); got != tt.want {
+			t.Errorf("estimateBPETokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCountTokens(t *testing.T) {
+	msgs := []Message{{Role: "user", Content: "Hello world"}}
+
+	// 3 reply priming + 3 framing + 1 role + 2 content
+	if got := CountTokens(msgs, "gpt-4o"); got != 9 {
+		t.Errorf("CountTokens(gpt-4o) = %d, want 9", got)
+	}
+	if got := CountTokens(msgs, "openrouter/openai/gpt-4o"); got != 9 {
+		t.Errorf("CountTokens(openrouter/openai/gpt-4o) = %d, want 9", got)
+	}
+	// Heuristic: "user" is 2, "Hello world" is 4
+	if got := CountTokens(msgs, "claude-sonnet-4-5"); got != 12 {
+		t.Errorf("CountTokens(claude-sonnet-4-5) = %d, want 12", got)
+	}
+
+	withImage := []Message{{Role: "tool", Content: "", Images: []Image{{MediaType: "image/png"}}}}
+	if got := CountTokens(withImage, "gpt-4o"); got < imageTokenEstimate {
+		t.Errorf("CountTokens with image = %d, want at least %d", got, imageTokenEstimate)
+	}
+
+	withToolCall := []Message{{Role: "assistant", ToolCalls: []ToolCall{{
+		ID:       "call_1",
+		Function: &FunctionCall{Name: "read_file", Arguments: `{"path":"/tmp/notes.txt"}`},
+	}}}}
+	if CountTokens(withToolCall, "gpt-4o") <= CountTokens([]Message{{Role: "assistant"}}, "gpt-4o") {
+		t.Error("tool call arguments should be counted")
+	}
+}
+
+func TestCountPromptTokens_UsesProviderCounter(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages/count_tokens" {
+			http.Error(w, `{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}`, http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"input_tokens": 42}`))
+	}))
+	defer server.Close()
+
+	p := NewClaudeProvider("test-token")
+	p.client = createAnthropicTestClient(server.URL, "test-token")
+	msgs := []Message{{Role: "system", Content: "Be brief."}, {Role: "user", Content: "Hello"}}
+
+	if got := CountPromptTokens(t.Context(), p, msgs, nil, "claude-sonnet-4-5"); got != 42 {
+		t.Errorf("CountPromptTokens() = %d, want 42", got)
+	}
+	if body["model"] != "claude-sonnet-4-5" || body["system"] == nil {
+		t.Errorf("count_tokens request = %v", body)
+	}
+}
+
+func TestCountPromptTokens_FallsBackToEstimate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	p := NewClaudeProvider("test-token")
+	p.client = createAnthropicTestClient(server.URL, "test-token")
+	msgs := []Message{{Role: "user", Content: "Hello world"}}
+
+	if got, want := CountPromptTokens(t.Context(), p, msgs, nil, "claude-sonnet-4-5"), CountTokens(msgs, "claude-sonnet-4-5"); got != want {
+		t.Errorf("CountPromptTokens() = %d, want estimate %d", got, want)
+	}
+
+	// Providers without a counter use the estimate, plus the tools
+	hp := NewHTTPProvider("key", "http://unused", "")
+	tools := []ToolDefinition{{Type: "function", Function: ToolFunctionDefinition{Name: "read_file", Description: "Read a file"}}}
+	if got := CountPromptTokens(t.Context(), hp, msgs, tools, "gpt-4o"); got <= CountTokens(msgs, "gpt-4o") {
+		t.Errorf("CountPromptTokens() = %d, want tools counted", got)
+	}
+}
@@ -0,0 +1,107 @@
+package providers
+
+import (
+	"encoding/json"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// parseToolArguments decodes the JSON arguments of a tool call. Slightly
+// malformed JSON, e.g. output cut off mid-object, is repaired on a best-effort
+// basis; arguments that cannot be recovered are passed on as {"raw": text}.
+func parseToolArguments(raw string) map[string]interface{} {
+	args := make(map[string]interface{})
+	if raw == "" {
+		return args
+	}
+	if err := json.Unmarshal([]byte(raw), &args); err == nil {
+		return args
+	}
+
+	if repaired, ok := repairJSON(raw); ok {
+		args = make(map[string]interface{})
+		if err := json.Unmarshal([]byte(repaired), &args); err == nil {
+			logger.WarnCF("provider", "Repaired malformed tool call arguments",
+				map[string]interface{}{
+					"raw":      raw,
+					"repaired": repaired,
+				})
+			return args
+		}
+	}
+	return map[string]interface{}{"raw": raw}
+}
+
+// repairJSON fixes the malformations truncated or sloppy model output tends
+// to have: trailing commas, an unterminated string, a key left without a
+// value and unclosed objects or arrays. It reports false if the result is
+// still not valid JSON.
+func repairJSON(s string) (string, bool) {
+	out := make([]byte, 0, len(s)+8)
+	var stack []byte
+	inString, escaped := false, false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			out = append(out, c)
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			stack = append(stack, c)
+		case '}', ']':
+			if len(stack) == 0 || (stack[len(stack)-1] == '{') != (c == '}') {
+				return "", false
+			}
+			stack = stack[:len(stack)-1]
+			out = trimTrailingComma(out)
+		}
+		out = append(out, c)
+	}
+
+	if inString {
+		if escaped {
+			out = out[:len(out)-1]
+		}
+		out = append(out, '"')
+	}
+	out = trimTrailingComma(out)
+	if len(out) > 0 && out[len(out)-1] == ':' {
+		out = append(out, "null"...)
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] == '{' {
+			out = append(out, '}')
+		} else {
+			out = append(out, ']')
+		}
+	}
+	return string(out), json.Valid(out)
+}
+
+// trimTrailingComma drops trailing whitespace and a comma after it.
+func trimTrailingComma(b []byte) []byte {
+	for len(b) > 0 && isJSONSpace(b[len(b)-1]) {
+		b = b[:len(b)-1]
+	}
+	if len(b) > 0 && b[len(b)-1] == ',' {
+		b = b[:len(b)-1]
+	}
+	return b
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
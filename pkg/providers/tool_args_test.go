@@ -0,0 +1,34 @@
+package providers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseToolArguments(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want map[string]interface{}
+	}{
+		{"empty", "", map[string]interface{}{}},
+		{"valid", `{"path":"a.txt"}`, map[string]interface{}{"path": "a.txt"}},
+		{"trailing comma", `{"path":"a.txt",}`, map[string]interface{}{"path": "a.txt"}},
+		{"trailing comma in array", `{"ids":[1,2,],}`, map[string]interface{}{"ids": []interface{}{1.0, 2.0}}},
+		{"unterminated string", `{"content":"hello wor`, map[string]interface{}{"content": "hello wor"}},
+		{"cut after escape", `{"content":"line\`, map[string]interface{}{"content": "line"}},
+		{"missing value", `{"path":"a.txt","mode":`, map[string]interface{}{"path": "a.txt", "mode": nil}},
+		{"unclosed nested", `{"opts":{"a":[1`, map[string]interface{}{"opts": map[string]interface{}{"a": []interface{}{1.0}}}},
+		{"braces inside string", `{"code":"if x { y }"`, map[string]interface{}{"code": "if x { y }"}},
+		{"unrecoverable", `{"path" "a.txt"}`, map[string]interface{}{"raw": `{"path" "a.txt"}`}},
+		{"mismatched closer", `{"a":[1}`, map[string]interface{}{"raw": `{"a":[1}`}},
+		{"not an object", `["a"]`, map[string]interface{}{"raw": `["a"]`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseToolArguments(tt.raw); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseToolArguments(%q) = %#v, want %#v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
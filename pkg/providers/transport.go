@@ -0,0 +1,111 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// TransportOptions tunes the connection pool of HTTP-based providers. Long
+// idle keep-alive connections are often silently dropped by NATs and proxies,
+// which makes the first request after a quiet period fail.
+type TransportOptions struct {
+	// MaxIdleConns caps pooled keep-alive connections.
+	MaxIdleConns int
+	// IdleConnTimeout closes pooled connections unused for this long.
+	IdleConnTimeout time.Duration
+	// MaxConnLifetime stops reusing a connection this long after it was
+	// dialed, however busy it is. 0 disables the limit.
+	MaxConnLifetime time.Duration
+}
+
+// DefaultTransportOptions returns the pool settings used when
+// providers.max_idle_conns, idle_conn_timeout_seconds or
+// conn_max_lifetime_seconds are unset.
+func DefaultTransportOptions() TransportOptions {
+	return TransportOptions{
+		MaxIdleConns:    10,
+		IdleConnTimeout: 30 * time.Second,
+		MaxConnLifetime: 5 * time.Minute,
+	}
+}
+
+// TransportTuner is implemented by providers whose connection pool can be
+// configured.
+type TransportTuner interface {
+	SetTransportOptions(opts TransportOptions)
+}
+
+// newProviderTransport builds a transport with the given pool settings,
+// routing through proxy when it is non-nil and otherwise honoring the
+// environment's proxy variables.
+func newProviderTransport(proxy *url.URL, opts TransportOptions) *http.Transport {
+	defaults := DefaultTransportOptions()
+	if opts.MaxIdleConns <= 0 {
+		opts.MaxIdleConns = defaults.MaxIdleConns
+	}
+	if opts.IdleConnTimeout <= 0 {
+		opts.IdleConnTimeout = defaults.IdleConnTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = opts.MaxIdleConns
+	transport.MaxIdleConnsPerHost = opts.MaxIdleConns
+	transport.IdleConnTimeout = opts.IdleConnTimeout
+	if proxy != nil {
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if opts.MaxConnLifetime > 0 {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		lifetime := opts.MaxConnLifetime
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return &lifetimeConn{Conn: conn, expires: time.Now().Add(lifetime)}, nil
+		}
+		// The lifetime is enforced per request write, which only works when
+		// each connection carries one request at a time.
+		transport.ForceAttemptHTTP2 = false
+	}
+	return transport
+}
+
+var errConnExpired = errors.New("connection exceeded max lifetime")
+
+// lifetimeConn refuses writes once it has expired. A request that fails
+// before writing anything on a reused connection is retried by net/http on
+// a fresh one, so expiry is invisible to callers.
+type lifetimeConn struct {
+	net.Conn
+	expires time.Time
+
+	mu      sync.Mutex
+	writing bool // a request is partially written
+}
+
+func (c *lifetimeConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	if !c.writing && time.Now().After(c.expires) {
+		c.mu.Unlock()
+		c.Conn.Close()
+		return 0, errConnExpired
+	}
+	c.writing = true
+	c.mu.Unlock()
+	return c.Conn.Write(b)
+}
+
+func (c *lifetimeConn) Read(b []byte) (int, error) {
+	// Reading the response means the request was fully written
+	c.mu.Lock()
+	c.writing = false
+	c.mu.Unlock()
+	return c.Conn.Read(b)
+}
@@ -0,0 +1,90 @@
+package providers
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newConnCountingServer returns a server and a counter of accepted connections.
+func newConnCountingServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte("ok"))
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server, &conns
+}
+
+func postTwice(t *testing.T, client *http.Client, url string, pause time.Duration) {
+	t.Helper()
+	for i := 0; i < 2; i++ {
+		if i > 0 {
+			time.Sleep(pause)
+		}
+		resp, err := client.Post(url, "application/json", bytes.NewReader([]byte(`{}`)))
+		if err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+func TestProviderTransport_ReusesConnections(t *testing.T) {
+	server, conns := newConnCountingServer(t)
+	client := &http.Client{Transport: newProviderTransport(nil, TransportOptions{})}
+
+	postTwice(t, client, server.URL, 10*time.Millisecond)
+	if n := conns.Load(); n != 1 {
+		t.Errorf("connections = %d, want 1", n)
+	}
+}
+
+func TestProviderTransport_IdleConnTimeout(t *testing.T) {
+	server, conns := newConnCountingServer(t)
+	client := &http.Client{Transport: newProviderTransport(nil, TransportOptions{IdleConnTimeout: 50 * time.Millisecond})}
+
+	postTwice(t, client, server.URL, 150*time.Millisecond)
+	if n := conns.Load(); n != 2 {
+		t.Errorf("connections = %d, want 2", n)
+	}
+}
+
+func TestProviderTransport_MaxConnLifetime(t *testing.T) {
+	server, conns := newConnCountingServer(t)
+	client := &http.Client{Transport: newProviderTransport(nil, TransportOptions{
+		IdleConnTimeout: time.Minute,
+		MaxConnLifetime: 50 * time.Millisecond,
+	})}
+
+	// The expired connection is still pooled; the request must transparently
+	// move to a new one
+	postTwice(t, client, server.URL, 100*time.Millisecond)
+	if n := conns.Load(); n != 2 {
+		t.Errorf("connections = %d, want 2", n)
+	}
+}
+
+func TestHTTPProvider_SetTransportOptions(t *testing.T) {
+	server, conns := newConnCountingServer(t)
+	p := NewHTTPProvider("key", server.URL, "")
+	p.SetTransportOptions(TransportOptions{MaxConnLifetime: 50 * time.Millisecond})
+
+	postTwice(t, p.httpClient, server.URL, 100*time.Millisecond)
+	if n := conns.Load(); n != 2 {
+		t.Errorf("connections = %d, want 2", n)
+	}
+}
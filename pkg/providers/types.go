@@ -15,11 +15,19 @@ type FunctionCall struct {
 	Arguments string `json:"arguments"`
 }
 
+// FinishReasonContentFilter marks a response the provider withheld or cut
+// short because of its content policy. Content is empty or holds the
+// provider's refusal text.
+const FinishReasonContentFilter = "content_filter"
+
 type LLMResponse struct {
-	Content      string     `json:"content"`
-	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
-	FinishReason string     `json:"finish_reason"`
-	Usage        *UsageInfo `json:"usage,omitempty"`
+	Content string `json:"content"`
+	// ReasoningContent is the model's visible reasoning ("thinking"), when
+	// the provider returns it. It is never sent back to the model.
+	ReasoningContent string     `json:"reasoning_content,omitempty"`
+	ToolCalls        []ToolCall `json:"tool_calls,omitempty"`
+	FinishReason     string     `json:"finish_reason"`
+	Usage            *UsageInfo `json:"usage,omitempty"`
 }
 
 type UsageInfo struct {
@@ -33,6 +41,9 @@ type Message struct {
 	Content    string     `json:"content"`
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string     `json:"tool_call_id,omitempty"`
+	// Images holds images returned by a tool, on "tool" messages. They are
+	// sent to the model but not stored in session history.
+	Images []Image `json:"-"`
 }
 
 type LLMProvider interface {
@@ -40,6 +51,16 @@ type LLMProvider interface {
 	GetDefaultModel() string
 }
 
+// StreamCallback receives incremental response text as it is generated.
+type StreamCallback func(delta string)
+
+// StreamingProvider is implemented by providers that can stream text deltas
+// while still returning the complete response.
+type StreamingProvider interface {
+	LLMProvider
+	ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta StreamCallback) (*LLMResponse, error)
+}
+
 type ToolDefinition struct {
 	Type     string                 `json:"type"`
 	Function ToolFunctionDefinition `json:"function"`
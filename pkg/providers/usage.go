@@ -0,0 +1,48 @@
+package providers
+
+import "github.com/sipeed/picoclaw/pkg/logger"
+
+// UsageObserver is told about the token usage of every successful Chat call,
+// for accounting such as per-day or per-chat totals. provider names the
+// backend ("anthropic", "groq", ...) and model is the model passed to Chat.
+// Usage is zero when the backend does not report it. ObserveUsage runs on
+// the request path and should return quickly.
+type UsageObserver interface {
+	ObserveUsage(provider, model string, usage UsageInfo)
+}
+
+// UsageObserverFunc adapts a function to UsageObserver.
+type UsageObserverFunc func(provider, model string, usage UsageInfo)
+
+func (f UsageObserverFunc) ObserveUsage(provider, model string, usage UsageInfo) {
+	f(provider, model, usage)
+}
+
+// UsageObservableProvider is implemented by providers that report usage to
+// a UsageObserver.
+type UsageObservableProvider interface {
+	SetUsageObserver(observer UsageObserver)
+}
+
+// reportUsage passes resp's usage to observer. A nil observer is a no-op,
+// and a panicking observer is logged instead of failing the request.
+func reportUsage(observer UsageObserver, provider, model string, resp *LLMResponse) {
+	if observer == nil || resp == nil {
+		return
+	}
+	var usage UsageInfo
+	if resp.Usage != nil {
+		usage = *resp.Usage
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.ErrorCF("provider", "Usage observer panicked",
+				map[string]interface{}{
+					"provider": provider,
+					"panic":    r,
+				})
+		}
+	}()
+	observer.ObserveUsage(provider, model, usage)
+}
@@ -0,0 +1,95 @@
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type usageRecord struct {
+	provider, model string
+	usage           UsageInfo
+}
+
+func recordUsage(records *[]usageRecord) UsageObserver {
+	return UsageObserverFunc(func(provider, model string, usage UsageInfo) {
+		*records = append(*records, usageRecord{provider, model, usage})
+	})
+}
+
+func TestHTTPProvider_ReportsUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]interface{}{"content": "hi"}, "finish_reason": "stop"},
+			},
+			"usage": map[string]interface{}{"prompt_tokens": 12, "completion_tokens": 3, "total_tokens": 15},
+		})
+	}))
+	defer server.Close()
+
+	var records []usageRecord
+	p := NewHTTPProvider("key", server.URL, "")
+	p.name = "groq"
+	p.modelPrefix = "groq/"
+	p.SetUsageObserver(recordUsage(&records))
+
+	if _, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "groq/llama-3", nil); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	want := usageRecord{"groq", "groq/llama-3", UsageInfo{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15}}
+	if len(records) != 1 || records[0] != want {
+		t.Errorf("records = %+v, want [%+v]", records, want)
+	}
+}
+
+func TestHTTPProvider_NoUsageReportOnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"bad"}}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	var records []usageRecord
+	p := NewHTTPProvider("key", server.URL, "")
+	p.SetUsageObserver(recordUsage(&records))
+
+	if _, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4o", nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(records) != 0 {
+		t.Errorf("records = %+v, want none", records)
+	}
+}
+
+func TestReportUsage(t *testing.T) {
+	// No observer is a no-op
+	reportUsage(nil, "openai", "gpt-4o", &LLMResponse{})
+
+	// Missing usage reports zero
+	var records []usageRecord
+	reportUsage(recordUsage(&records), "openai", "gpt-4o", &LLMResponse{Content: "hi"})
+	if len(records) != 1 || records[0].usage != (UsageInfo{}) {
+		t.Errorf("records = %+v", records)
+	}
+
+	// A panicking observer does not reach the caller
+	reportUsage(UsageObserverFunc(func(string, string, UsageInfo) { panic("boom") }), "openai", "gpt-4o", &LLMResponse{})
+}
+
+func TestLimitedProvider_ForwardsUsageObserver(t *testing.T) {
+	server := newChatCompletionServer(t, "hi", nil)
+	defer server.Close()
+
+	var records []usageRecord
+	provider := LimitProvider(NewHTTPProvider("key", server.URL, ""), 1, 0)
+	provider.(UsageObservableProvider).SetUsageObserver(recordUsage(&records))
+
+	if _, err := provider.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "gpt-4o", nil); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if len(records) != 1 || records[0].provider != "openai_compat" {
+		t.Errorf("records = %+v", records)
+	}
+}
@@ -0,0 +1,19 @@
+package providers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// userOption returns the value for the OpenAI "user" request field from the
+// "user" option, or "" when the option is unset. The caller's identifier
+// (e.g. "telegram:123456") is hashed so endpoints can tell end users apart
+// for abuse monitoring without seeing their chat IDs.
+func userOption(options map[string]interface{}) string {
+	user, _ := options["user"].(string)
+	if user == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte("picoclaw-user:" + user))
+	return hex.EncodeToString(sum[:16])
+}
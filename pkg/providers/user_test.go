@@ -0,0 +1,49 @@
+package providers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHTTPProvider_UserField(t *testing.T) {
+	var body map[string]interface{}
+	server := newChatCompletionServer(t, "ok", &body)
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	messages := []Message{{Role: "user", Content: "Hi"}}
+
+	if _, err := p.Chat(t.Context(), messages, nil, "gpt-4o", nil); err != nil {
+		t.Fatalf("Chat error = %v", err)
+	}
+	if _, ok := body["user"]; ok {
+		t.Errorf("user = %v, want field omitted", body["user"])
+	}
+
+	options := map[string]interface{}{"user": "telegram:123456"}
+	if _, err := p.Chat(t.Context(), messages, nil, "gpt-4o", options); err != nil {
+		t.Fatalf("Chat error = %v", err)
+	}
+	first, _ := body["user"].(string)
+	if first == "" || strings.Contains(first, "123456") {
+		t.Fatalf("user = %q, want a hash of the sender", first)
+	}
+
+	if _, err := p.Chat(t.Context(), messages, nil, "gpt-4o", options); err != nil {
+		t.Fatalf("Chat error = %v", err)
+	}
+	if body["user"] != first {
+		t.Errorf("user = %v, want stable %q", body["user"], first)
+	}
+}
+
+func TestUserOption_DistinctSenders(t *testing.T) {
+	a := userOption(map[string]interface{}{"user": "telegram:1"})
+	b := userOption(map[string]interface{}{"user": "discord:1"})
+	if a == b {
+		t.Errorf("different senders hashed to the same user %q", a)
+	}
+	if got := userOption(map[string]interface{}{"user": ""}); got != "" {
+		t.Errorf("empty user = %q, want empty", got)
+	}
+}
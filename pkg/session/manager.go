@@ -1,13 +1,10 @@
 package session
 
 import (
-	"encoding/json"
-	"os"
-	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
 )
 
@@ -22,24 +19,38 @@ type Session struct {
 type SessionManager struct {
 	sessions map[string]*Session
 	mu       sync.RWMutex
-	storage  string
+	store    ConversationStore
 }
 
+// NewSessionManager keeps sessions in a FileStore under storage, or only in
+// memory when storage is empty or cannot be created.
 func NewSessionManager(storage string) *SessionManager {
-	sm := &SessionManager{
-		sessions: make(map[string]*Session),
-		storage:  storage,
+	if storage == "" {
+		return NewSessionManagerWithStore(NopStore{})
 	}
-
-	if storage != "" {
-		os.MkdirAll(storage, 0755)
-		sm.loadSessions()
+	store, err := NewFileStore(storage, DefaultMaxSessionBytes)
+	if err != nil {
+		logger.WarnCF("session", "Session storage unavailable, keeping sessions in memory", map[string]interface{}{
+			"path":  storage,
+			"error": err.Error(),
+		})
+		return NewSessionManagerWithStore(NopStore{})
 	}
+	return NewSessionManagerWithStore(store)
+}
 
-	return sm
+// NewSessionManagerWithStore returns a manager that loads sessions from
+// store on first use and writes them back on Save.
+func NewSessionManagerWithStore(store ConversationStore) *SessionManager {
+	return &SessionManager{
+		sessions: make(map[string]*Session),
+		store:    store,
+	}
 }
 
 func (sm *SessionManager) GetOrCreate(key string) *Session {
+	sm.load(key)
+
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
@@ -69,6 +80,8 @@ func (sm *SessionManager) AddMessage(sessionKey, role, content string) {
 // AddFullMessage adds a complete message with tool calls and tool call ID to the session.
 // This is used to save the full conversation flow including tool calls and tool results.
 func (sm *SessionManager) AddFullMessage(sessionKey string, msg providers.Message) {
+	sm.load(sessionKey)
+
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
@@ -87,6 +100,8 @@ func (sm *SessionManager) AddFullMessage(sessionKey string, msg providers.Messag
 }
 
 func (sm *SessionManager) GetHistory(key string) []providers.Message {
+	sm.load(key)
+
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
@@ -101,6 +116,8 @@ func (sm *SessionManager) GetHistory(key string) []providers.Message {
 }
 
 func (sm *SessionManager) GetSummary(key string) string {
+	sm.load(key)
+
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
@@ -112,6 +129,8 @@ func (sm *SessionManager) GetSummary(key string) string {
 }
 
 func (sm *SessionManager) SetSummary(key string, summary string) {
+	sm.load(key)
+
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
@@ -123,6 +142,8 @@ func (sm *SessionManager) SetSummary(key string, summary string) {
 }
 
 func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
+	sm.load(key)
+
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
@@ -145,30 +166,27 @@ func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 	session.Updated = time.Now()
 }
 
-// sanitizeFilename converts a session key into a cross-platform safe filename.
-// Session keys use "channel:chatID" (e.g. "telegram:123456") but ':' is the
-// volume separator on Windows, so filepath.Base would misinterpret the key.
-// We replace it with '_'. The original key is preserved inside the JSON file,
-// so loadSessions still maps back to the right in-memory key.
-func sanitizeFilename(key string) string {
-	return strings.ReplaceAll(key, ":", "_")
-}
-
-func (sm *SessionManager) Save(key string) error {
-	if sm.storage == "" {
-		return nil
-	}
+// Reset clears the history and summary of a session, starting a new
+// conversation under the same key. It reports whether there was anything to
+// clear.
+func (sm *SessionManager) Reset(key string) bool {
+	sm.load(key)
 
-	filename := sanitizeFilename(key)
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
 
-	// filepath.IsLocal rejects empty names, "..", absolute paths, and
-	// OS-reserved device names (NUL, COM1 … on Windows).
-	// The extra checks reject "." and any directory separators so that
-	// the session file is always written directly inside sm.storage.
-	if filename == "." || !filepath.IsLocal(filename) || strings.ContainsAny(filename, `/\`) {
-		return os.ErrInvalid
+	session, ok := sm.sessions[key]
+	if !ok || (len(session.Messages) == 0 && session.Summary == "") {
+		return false
 	}
+	session.Messages = []providers.Message{}
+	session.Summary = ""
+	session.Updated = time.Now()
+	return true
+}
 
+// Save persists the session to the store.
+func (sm *SessionManager) Save(key string) error {
 	// Snapshot under read lock, then perform slow file I/O after unlock.
 	sm.mu.RLock()
 	stored, ok := sm.sessions[key]
@@ -191,76 +209,41 @@ func (sm *SessionManager) Save(key string) error {
 	}
 	sm.mu.RUnlock()
 
-	data, err := json.MarshalIndent(snapshot, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	sessionPath := filepath.Join(sm.storage, filename+".json")
-	tmpFile, err := os.CreateTemp(sm.storage, "session-*.tmp")
-	if err != nil {
-		return err
-	}
-
-	tmpPath := tmpFile.Name()
-	cleanup := true
-	defer func() {
-		if cleanup {
-			_ = os.Remove(tmpPath)
-		}
-	}()
+	return sm.store.Save(&snapshot)
+}
 
-	if _, err := tmpFile.Write(data); err != nil {
-		_ = tmpFile.Close()
-		return err
-	}
-	if err := tmpFile.Chmod(0644); err != nil {
-		_ = tmpFile.Close()
-		return err
-	}
-	if err := tmpFile.Sync(); err != nil {
-		_ = tmpFile.Close()
-		return err
-	}
-	if err := tmpFile.Close(); err != nil {
-		return err
-	}
+// Delete forgets the session and removes it from the store.
+func (sm *SessionManager) Delete(key string) error {
+	sm.mu.Lock()
+	delete(sm.sessions, key)
+	sm.mu.Unlock()
+	return sm.store.Delete(key)
+}
 
-	if err := os.Rename(tmpPath, sessionPath); err != nil {
-		return err
+// load brings the session for key into memory from the store on first use.
+func (sm *SessionManager) load(key string) {
+	sm.mu.RLock()
+	_, ok := sm.sessions[key]
+	sm.mu.RUnlock()
+	if ok {
+		return
 	}
-	cleanup = false
-	return nil
-}
 
-func (sm *SessionManager) loadSessions() error {
-	files, err := os.ReadDir(sm.storage)
+	session, err := sm.store.Load(key)
 	if err != nil {
-		return err
+		logger.WarnCF("session", "Failed to load session", map[string]interface{}{
+			"key":   key,
+			"error": err.Error(),
+		})
+		return
 	}
-
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-
-		if filepath.Ext(file.Name()) != ".json" {
-			continue
-		}
-
-		sessionPath := filepath.Join(sm.storage, file.Name())
-		data, err := os.ReadFile(sessionPath)
-		if err != nil {
-			continue
-		}
-
-		var session Session
-		if err := json.Unmarshal(data, &session); err != nil {
-			continue
-		}
-
-		sm.sessions[session.Key] = &session
+	if session == nil {
+		return
 	}
 
-	return nil
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if _, ok := sm.sessions[key]; !ok {
+		sm.sessions[key] = session
+	}
 }
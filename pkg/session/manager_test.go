@@ -72,3 +72,31 @@ func TestSave_RejectsPathTraversal(t *testing.T) {
 		}
 	}
 }
+
+func TestReset(t *testing.T) {
+	sm := NewSessionManager(t.TempDir())
+
+	if sm.Reset("telegram:1") {
+		t.Error("Reset of unknown session reported cleared")
+	}
+
+	sm.AddMessage("telegram:1", "user", "hello")
+	sm.SetSummary("telegram:1", "earlier chat")
+	sm.AddMessage("telegram:2", "user", "hi")
+
+	if !sm.Reset("telegram:1") {
+		t.Fatal("Reset reported nothing cleared")
+	}
+	if got := sm.GetHistory("telegram:1"); len(got) != 0 {
+		t.Errorf("history after reset = %v, want empty", got)
+	}
+	if got := sm.GetSummary("telegram:1"); got != "" {
+		t.Errorf("summary after reset = %q, want empty", got)
+	}
+	if got := sm.GetHistory("telegram:2"); len(got) != 1 {
+		t.Errorf("other session history = %v, want 1 message", got)
+	}
+	if sm.Reset("telegram:1") {
+		t.Error("second Reset reported cleared")
+	}
+}
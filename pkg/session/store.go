@@ -0,0 +1,174 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// ConversationStore persists sessions by key so conversations survive a
+// restart. Load returns nil, nil when nothing is stored for the key.
+type ConversationStore interface {
+	Load(key string) (*Session, error)
+	Save(session *Session) error
+	Delete(key string) error
+}
+
+// NopStore keeps nothing; sessions live only in memory.
+type NopStore struct{}
+
+func (NopStore) Load(key string) (*Session, error) { return nil, nil }
+func (NopStore) Save(session *Session) error       { return nil }
+func (NopStore) Delete(key string) error           { return nil }
+
+// DefaultMaxSessionBytes bounds the size of one session file written by
+// FileStore.
+const DefaultMaxSessionBytes = 1 << 20
+
+// FileStore keeps one JSON file per session in a directory. Files are
+// written atomically, and when a session would exceed maxBytes its oldest
+// turns are left out of the file until it fits.
+type FileStore struct {
+	dir      string
+	maxBytes int
+}
+
+// NewFileStore returns a store writing to dir, creating it if needed.
+// maxBytes <= 0 leaves session files unbounded.
+func NewFileStore(dir string, maxBytes int) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir, maxBytes: maxBytes}, nil
+}
+
+// sanitizeFilename converts a session key into a cross-platform safe filename.
+// Session keys use "channel:chatID" (e.g. "telegram:123456") but ':' is the
+// volume separator on Windows, so filepath.Base would misinterpret the key.
+// We replace it with '_'. The original key is preserved inside the JSON file,
+// so Load can tell keys that share a filename apart.
+func sanitizeFilename(key string) string {
+	return strings.ReplaceAll(key, ":", "_")
+}
+
+func (s *FileStore) path(key string) (string, error) {
+	filename := sanitizeFilename(key)
+
+	// filepath.IsLocal rejects empty names, "..", absolute paths, and
+	// OS-reserved device names (NUL, COM1 … on Windows).
+	// The extra checks reject "." and any directory separators so that
+	// the session file is always written directly inside s.dir.
+	if filename == "." || !filepath.IsLocal(filename) || strings.ContainsAny(filename, `/\`) {
+		return "", os.ErrInvalid
+	}
+	return filepath.Join(s.dir, filename+".json"), nil
+}
+
+func (s *FileStore) Load(key string) (*Session, error) {
+	sessionPath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(sessionPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	if session.Key != key {
+		return nil, nil
+	}
+	return &session, nil
+}
+
+// Save writes session to its file. The session's messages are not modified;
+// eviction only affects what is written.
+func (s *FileStore) Save(session *Session) error {
+	sessionPath, err := s.path(session.Key)
+	if err != nil {
+		return err
+	}
+
+	snapshot := *session
+	if snapshot.Messages == nil {
+		snapshot.Messages = []providers.Message{}
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	for s.maxBytes > 0 && len(data) > s.maxBytes && len(snapshot.Messages) > 0 {
+		snapshot.Messages = dropOldestTurn(snapshot.Messages)
+		if data, err = json.MarshalIndent(snapshot, "", "  "); err != nil {
+			return err
+		}
+	}
+
+	tmpFile, err := os.CreateTemp(s.dir, "session-*.tmp")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := tmpFile.Name()
+	cleanup := true
+	defer func() {
+		if cleanup {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Chmod(0644); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, sessionPath); err != nil {
+		return err
+	}
+	cleanup = false
+	return nil
+}
+
+func (s *FileStore) Delete(key string) error {
+	sessionPath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(sessionPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// dropOldestTurn removes the first turn: everything before the second user
+// message, so tool calls and their results are dropped together.
+func dropOldestTurn(messages []providers.Message) []providers.Message {
+	for i := 1; i < len(messages); i++ {
+		if messages[i].Role == "user" {
+			return messages[i:]
+		}
+	}
+	return []providers.Message{}
+}
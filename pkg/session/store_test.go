@@ -0,0 +1,101 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestFileStore_RoundTripAndDelete(t *testing.T) {
+	store, err := NewFileStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("NewFileStore() error: %v", err)
+	}
+
+	if got, err := store.Load("telegram:1"); got != nil || err != nil {
+		t.Fatalf("Load() of missing session = %v, %v; want nil, nil", got, err)
+	}
+
+	session := &Session{Key: "telegram:1", Summary: "earlier", Messages: []providers.Message{{Role: "user", Content: "hi"}}}
+	if err := store.Save(session); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	got, err := store.Load("telegram:1")
+	if err != nil || got == nil || got.Summary != "earlier" || len(got.Messages) != 1 {
+		t.Fatalf("Load() = %+v, %v", got, err)
+	}
+
+	// "telegram_1" shares the file name but is a different session
+	if got, _ := store.Load("telegram_1"); got != nil {
+		t.Errorf("Load() of a colliding key returned %+v", got)
+	}
+
+	if err := store.Delete("telegram:1"); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if got, _ := store.Load("telegram:1"); got != nil {
+		t.Errorf("Load() after Delete = %+v, want nil", got)
+	}
+	if err := store.Delete("telegram:1"); err != nil {
+		t.Errorf("Delete() of missing session error: %v", err)
+	}
+}
+
+func TestFileStore_EvictsOldestTurns(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := NewFileStore(dir, 1000)
+
+	var messages []providers.Message
+	for i := 0; i < 10; i++ {
+		messages = append(messages,
+			providers.Message{Role: "user", Content: strings.Repeat("q", 50)},
+			providers.Message{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "call"}}},
+			providers.Message{Role: "tool", ToolCallID: "call", Content: strings.Repeat("r", 50)},
+		)
+	}
+	session := &Session{Key: "onebot:1", Messages: messages}
+	if err := store.Save(session); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if len(session.Messages) != 30 {
+		t.Errorf("Save() modified the session: %d messages", len(session.Messages))
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "onebot_1.json"))
+	if err != nil || info.Size() > 1000 {
+		t.Fatalf("session file = %v, %v; want at most 1000 bytes", info, err)
+	}
+	got, _ := store.Load("onebot:1")
+	if len(got.Messages) == 0 || len(got.Messages)%3 != 0 || got.Messages[0].Role != "user" {
+		t.Errorf("stored messages = %d starting with %q, want whole turns", len(got.Messages), got.Messages[0].Role)
+	}
+}
+
+func TestSessionManager_NopStore(t *testing.T) {
+	sm := NewSessionManagerWithStore(NopStore{})
+	sm.AddMessage("cli:1", "user", "hi")
+	if err := sm.Save("cli:1"); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if got := NewSessionManagerWithStore(NopStore{}).GetHistory("cli:1"); len(got) != 0 {
+		t.Errorf("history in a new manager = %v, want empty", got)
+	}
+}
+
+func TestSessionManager_DeleteRemovesStoredSession(t *testing.T) {
+	dir := t.TempDir()
+	sm := NewSessionManager(dir)
+	sm.AddMessage("cli:1", "user", "hi")
+	if err := sm.Save("cli:1"); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if err := sm.Delete("cli:1"); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if got := NewSessionManager(dir).GetHistory("cli:1"); len(got) != 0 {
+		t.Errorf("history after Delete = %v, want empty", got)
+	}
+}
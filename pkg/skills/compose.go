@@ -0,0 +1,104 @@
+package skills
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// DefaultMaxSkillPromptChars bounds the skill text added to the system prompt
+// when ComposeSystemPrompt is given no limit.
+const DefaultMaxSkillPromptChars = 16000
+
+// ComposedPrompt is the system prompt and tool set for a conversation with
+// active skills.
+type ComposedPrompt struct {
+	SystemPrompt string
+	// Tools are the tools the conversation may use: the given tools when no
+	// active skill restricts them, otherwise those allowed by at least one
+	// restricting skill.
+	Tools []string
+	// Skills names the skills whose bodies made it into SystemPrompt.
+	Skills []string
+}
+
+// ComposeSystemPrompt prepends the bodies of the active skills to the base
+// system prompt and filters tools by the skills' allowed-tools. Skills are
+// added once each, in order; a body already present in the base prompt is
+// skipped. The skill text is capped at maxChars runes (0 uses
+// DefaultMaxSkillPromptChars): later skills are dropped and the one that
+// crosses the limit is truncated. The base prompt is never cut.
+func (sl *SkillsLoader) ComposeSystemPrompt(base string, active []SkillInfo, tools []string, maxChars int) ComposedPrompt {
+	if maxChars <= 0 {
+		maxChars = DefaultMaxSkillPromptChars
+	}
+
+	var (
+		sections []string
+		included []string
+		allowed  map[string]bool
+		seen     = make(map[string]bool)
+		budget   = maxChars
+	)
+	for _, skill := range active {
+		if seen[skill.Name] {
+			continue
+		}
+		seen[skill.Name] = true
+
+		if len(skill.AllowedTools) > 0 {
+			if allowed == nil {
+				allowed = make(map[string]bool)
+			}
+			for _, name := range skill.AllowedTools {
+				allowed[name] = true
+			}
+		}
+
+		body := strings.TrimSpace(sl.skillBody(skill))
+		if body == "" || strings.Contains(base, body) || budget <= 0 {
+			continue
+		}
+		section := fmt.Sprintf("### Skill: %s\n\n%s", skill.Name, body)
+		if n := len([]rune(section)); n > budget {
+			section = utils.Truncate(section, budget)
+			budget = 0
+		} else {
+			budget -= n
+		}
+		sections = append(sections, section)
+		included = append(included, skill.Name)
+	}
+
+	composed := ComposedPrompt{SystemPrompt: base, Tools: tools, Skills: included}
+	if len(sections) > 0 {
+		parts := append(sections, base)
+		if strings.TrimSpace(base) == "" {
+			parts = sections
+		}
+		composed.SystemPrompt = strings.Join(parts, "\n\n---\n\n")
+	}
+	if allowed != nil {
+		composed.Tools = make([]string, 0, len(allowed))
+		for _, name := range tools {
+			if allowed[name] {
+				composed.Tools = append(composed.Tools, name)
+			}
+		}
+	}
+	return composed
+}
+
+// skillBody returns the skill's SKILL.md without frontmatter, falling back to
+// a lookup by name when the path is unset.
+func (sl *SkillsLoader) skillBody(skill SkillInfo) string {
+	if skill.Path != "" {
+		if content, err := os.ReadFile(skill.Path); err == nil {
+			return sl.stripFrontmatter(string(content))
+		}
+	}
+	body, _ := sl.LoadSkill(skill.Name)
+	return body
+}
@@ -0,0 +1,67 @@
+package skills
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSkill(t *testing.T, workspace, name, content string) {
+	t.Helper()
+	dir := filepath.Join(workspace, "skills", name)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(content), 0644))
+}
+
+func TestComposeSystemPrompt(t *testing.T) {
+	workspace := t.TempDir()
+	writeSkill(t, workspace, "github", "---\nname: github\ndescription: GitHub helper\nallowed-tools: [exec, read_file]\n---\nUse the gh CLI.\n")
+	writeSkill(t, workspace, "weather", "---\nname: weather\ndescription: Weather lookup\nallowed-tools: web_fetch\n---\nQuery wttr.in.\n")
+	writeSkill(t, workspace, "notes", "---\nname: notes\ndescription: Note taking\n---\nKeep notes in memory/.\n")
+
+	sl := NewSkillsLoader(workspace, "", "")
+	byName := make(map[string]SkillInfo)
+	for _, s := range sl.ListSkills() {
+		byName[s.Name] = s
+	}
+	require.Len(t, byName, 3)
+	assert.Equal(t, []string{"exec", "read_file"}, byName["github"].AllowedTools)
+
+	tools := []string{"exec", "read_file", "web_fetch", "write_file"}
+
+	t.Run("prepends bodies and filters tools", func(t *testing.T) {
+		got := sl.ComposeSystemPrompt("You are picoclaw.", []SkillInfo{byName["github"], byName["weather"], byName["github"]}, tools, 0)
+		assert.Equal(t, "### Skill: github\n\nUse the gh CLI.\n\n---\n\n### Skill: weather\n\nQuery wttr.in.\n\n---\n\nYou are picoclaw.", got.SystemPrompt)
+		assert.Equal(t, []string{"github", "weather"}, got.Skills)
+		assert.Equal(t, []string{"exec", "read_file", "web_fetch"}, got.Tools)
+		assert.NotContains(t, got.SystemPrompt, "allowed-tools")
+	})
+
+	t.Run("unrestricted skill keeps all tools", func(t *testing.T) {
+		got := sl.ComposeSystemPrompt("base", []SkillInfo{byName["notes"]}, tools, 0)
+		assert.Equal(t, tools, got.Tools)
+	})
+
+	t.Run("skips body already in base prompt", func(t *testing.T) {
+		got := sl.ComposeSystemPrompt("base\n\nUse the gh CLI.", []SkillInfo{byName["github"]}, tools, 0)
+		assert.Equal(t, "base\n\nUse the gh CLI.", got.SystemPrompt)
+		assert.Empty(t, got.Skills)
+	})
+
+	t.Run("bounds skill text", func(t *testing.T) {
+		got := sl.ComposeSystemPrompt("base", []SkillInfo{byName["github"], byName["weather"]}, tools, 20)
+		assert.Equal(t, []string{"github"}, got.Skills)
+		assert.True(t, strings.HasSuffix(got.SystemPrompt, "\n\n---\n\nbase"))
+		assert.LessOrEqual(t, len([]rune(strings.TrimSuffix(got.SystemPrompt, "\n\n---\n\nbase"))), 20)
+	})
+}
+
+func TestParseToolList(t *testing.T) {
+	assert.Equal(t, []string{"exec", "read_file"}, parseToolList("[exec, 'read_file']"))
+	assert.Equal(t, []string{"a", "b"}, parseToolList("a b"))
+	assert.Nil(t, parseToolList(""))
+}
@@ -19,8 +19,10 @@ const (
 )
 
 type SkillMetadata struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	AllowedTools []string `json:"allowed_tools,omitempty"`
+	Triggers     []string `json:"triggers,omitempty"`
 }
 
 type SkillInfo struct {
@@ -28,6 +30,13 @@ type SkillInfo struct {
 	Path        string `json:"path"`
 	Source      string `json:"source"`
 	Description string `json:"description"`
+	// AllowedTools restricts the tools available while the skill is active;
+	// empty leaves them unrestricted.
+	AllowedTools []string `json:"allowed_tools,omitempty"`
+	// Triggers are keywords, or /regular expressions/, that activate the
+	// skill automatically; see SkillMatcher. Without triggers the skill is
+	// only activated explicitly.
+	Triggers []string `json:"triggers,omitempty"`
 }
 
 func (info SkillInfo) validate() error {
@@ -85,6 +94,8 @@ func (sl *SkillsLoader) ListSkills() []SkillInfo {
 						if metadata != nil {
 							info.Description = metadata.Description
 							info.Name = metadata.Name
+							info.AllowedTools = metadata.AllowedTools
+							info.Triggers = metadata.Triggers
 						}
 						if err := info.validate(); err != nil {
 							slog.Warn("invalid skill from workspace", "name", info.Name, "error", err)
@@ -125,6 +136,8 @@ func (sl *SkillsLoader) ListSkills() []SkillInfo {
 						if metadata != nil {
 							info.Description = metadata.Description
 							info.Name = metadata.Name
+							info.AllowedTools = metadata.AllowedTools
+							info.Triggers = metadata.Triggers
 						}
 						if err := info.validate(); err != nil {
 							slog.Warn("invalid skill from global", "name", info.Name, "error", err)
@@ -164,6 +177,8 @@ func (sl *SkillsLoader) ListSkills() []SkillInfo {
 						if metadata != nil {
 							info.Description = metadata.Description
 							info.Name = metadata.Name
+							info.AllowedTools = metadata.AllowedTools
+							info.Triggers = metadata.Triggers
 						}
 						if err := info.validate(); err != nil {
 							slog.Warn("invalid skill from builtin", "name", info.Name, "error", err)
@@ -262,23 +277,52 @@ func (sl *SkillsLoader) getSkillMetadata(skillPath string) *SkillMetadata {
 	}
 
 	// Try JSON first (for backward compatibility)
-	var jsonMeta struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
-	}
+	var jsonMeta SkillMetadata
 	if err := json.Unmarshal([]byte(frontmatter), &jsonMeta); err == nil {
-		return &SkillMetadata{
-			Name:        jsonMeta.Name,
-			Description: jsonMeta.Description,
-		}
+		return &jsonMeta
 	}
 
 	// Fall back to simple YAML parsing
 	yamlMeta := sl.parseSimpleYAML(frontmatter)
 	return &SkillMetadata{
-		Name:        yamlMeta["name"],
-		Description: yamlMeta["description"],
+		Name:         yamlMeta["name"],
+		Description:  yamlMeta["description"],
+		AllowedTools: parseToolList(yamlMeta["allowed-tools"]),
+		Triggers:     parseTriggerList(yamlMeta["triggers"]),
+	}
+}
+
+// parseTriggerList splits a comma-separated triggers value such as
+// "i2c, air quality, /temp(erature)?/" into triggers. Keywords may contain
+// spaces.
+func parseTriggerList(value string) []string {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		value = value[1 : len(value)-1]
+	}
+	var triggers []string
+	for _, t := range strings.Split(value, ",") {
+		if t = strings.Trim(strings.TrimSpace(t), "\"'"); t != "" {
+			triggers = append(triggers, t)
+		}
+	}
+	return triggers
+}
+
+// parseToolList splits an allowed-tools value such as "read_file, exec" or
+// "[read_file, exec]" into tool names.
+func parseToolList(value string) []string {
+	value = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(value), "["), "]"))
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	for i, f := range fields {
+		fields[i] = strings.Trim(f, "\"'")
+	}
+	if len(fields) == 0 {
+		return nil
 	}
+	return fields
 }
 
 // parseSimpleYAML parses simple key: value YAML format
@@ -317,7 +361,7 @@ func (sl *SkillsLoader) extractFrontmatter(content string) string {
 }
 
 func (sl *SkillsLoader) stripFrontmatter(content string) string {
-	re := regexp.MustCompile(`^---\n.*?\n---\n`)
+	re := regexp.MustCompile(`(?s)^---\n.*?\n---\n`)
 	return re.ReplaceAllString(content, "")
 }
 
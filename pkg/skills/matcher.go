@@ -0,0 +1,99 @@
+package skills
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SkillMatcher finds the skills whose triggers fire for an inbound message.
+// Only skills that declare triggers take part, so automatic activation is
+// opt-in per skill.
+type SkillMatcher struct {
+	rules []skillRule
+}
+
+type skillRule struct {
+	skill    SkillInfo
+	patterns []*regexp.Regexp
+}
+
+// NewSkillMatcher compiles the triggers of skills. A trigger wrapped in
+// slashes ("/i2c-\d+/") is a regular expression; any other trigger is a
+// keyword matched as a whole word. Matching ignores case. Invalid patterns
+// are logged and skipped.
+func NewSkillMatcher(skills []SkillInfo) *SkillMatcher {
+	m := &SkillMatcher{}
+	for _, skill := range skills {
+		var patterns []*regexp.Regexp
+		for _, trigger := range skill.Triggers {
+			re, err := compileTrigger(trigger)
+			if err != nil {
+				slog.Warn("invalid skill trigger", "skill", skill.Name, "trigger", trigger, "error", err)
+				continue
+			}
+			if re != nil {
+				patterns = append(patterns, re)
+			}
+		}
+		if len(patterns) > 0 {
+			m.rules = append(m.rules, skillRule{skill: skill, patterns: patterns})
+		}
+	}
+	return m
+}
+
+func compileTrigger(trigger string) (*regexp.Regexp, error) {
+	trigger = strings.TrimSpace(trigger)
+	if len(trigger) > 2 && strings.HasPrefix(trigger, "/") && strings.HasSuffix(trigger, "/") {
+		return regexp.Compile("(?i)" + trigger[1:len(trigger)-1])
+	}
+	if trigger == "" {
+		return nil, nil
+	}
+
+	pattern := regexp.QuoteMeta(trigger)
+	// \b only applies next to word characters, so "c++" still matches
+	if first, _ := utf8.DecodeRuneInString(trigger); isWordRune(first) {
+		pattern = `\b` + pattern
+	}
+	if last, _ := utf8.DecodeLastRuneInString(trigger); isWordRune(last) {
+		pattern += `\b`
+	}
+	return regexp.Compile("(?i)" + pattern)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// Match returns the skills with a trigger that fires for message, in the
+// order they were given.
+func (m *SkillMatcher) Match(message string) []SkillInfo {
+	var matched []SkillInfo
+	for _, rule := range m.rules {
+		for _, re := range rule.patterns {
+			if re.MatchString(message) {
+				matched = append(matched, rule.skill)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// Activate returns the explicitly activated skills followed by those whose
+// triggers fire for message, each once.
+func (m *SkillMatcher) Activate(explicit []SkillInfo, message string) []SkillInfo {
+	active := make([]SkillInfo, 0, len(explicit))
+	seen := make(map[string]bool)
+	for _, skill := range append(append([]SkillInfo(nil), explicit...), m.Match(message)...) {
+		if !seen[skill.Name] {
+			seen[skill.Name] = true
+			active = append(active, skill)
+		}
+	}
+	return active
+}
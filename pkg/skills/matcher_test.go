@@ -0,0 +1,64 @@
+package skills
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func skillNames(skills []SkillInfo) []string {
+	names := make([]string, 0, len(skills))
+	for _, s := range skills {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestSkillMatcher(t *testing.T) {
+	hardware := SkillInfo{Name: "hardware", Triggers: []string{"i2c", "sensor", "/spi\\d?/", "传感器"}}
+	cpp := SkillInfo{Name: "cpp", Triggers: []string{"c++"}}
+	weather := SkillInfo{Name: "weather"} // no triggers: explicit only
+	m := NewSkillMatcher([]SkillInfo{hardware, cpp, weather, {Name: "broken", Triggers: []string{"/(/"}}})
+
+	tests := []struct {
+		message string
+		want    []string
+	}{
+		{"Read the I2C bus", []string{"hardware"}},
+		{"what does the sensor say?", []string{"hardware"}},
+		{"scan spi0 please", []string{"hardware"}},
+		{"读取传感器数据", []string{"hardware"}},
+		{"sensors are not whole-word sensor", []string{"hardware"}},
+		{"sensors everywhere", []string{}},
+		{"fix my c++ build", []string{"cpp"}},
+		{"what's the weather", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			assert.Equal(t, tt.want, skillNames(m.Match(tt.message)))
+		})
+	}
+}
+
+func TestSkillMatcherActivate(t *testing.T) {
+	hardware := SkillInfo{Name: "hardware", Triggers: []string{"i2c"}}
+	weather := SkillInfo{Name: "weather"}
+	m := NewSkillMatcher([]SkillInfo{hardware, weather})
+
+	active := m.Activate([]SkillInfo{weather, hardware}, "scan i2c")
+	assert.Equal(t, []string{"weather", "hardware"}, skillNames(active))
+
+	active = m.Activate(nil, "hello")
+	assert.Empty(t, active)
+}
+
+func TestSkillTriggersFromFrontmatter(t *testing.T) {
+	workspace := t.TempDir()
+	writeSkill(t, workspace, "hardware", "---\nname: hardware\ndescription: Hardware access\ntriggers: i2c, air quality, /temp(erature)?/\n---\nUse the i2c tool.\n")
+
+	list := NewSkillsLoader(workspace, "", "").ListSkills()
+	require.Len(t, list, 1)
+	assert.Equal(t, []string{"i2c", "air quality", "/temp(erature)?/"}, list[0].Triggers)
+	assert.Equal(t, []string{"hardware"}, skillNames(NewSkillMatcher(list).Match("check air quality")))
+}
@@ -69,6 +69,14 @@ type AsyncTool interface {
 	SetCallback(cb AsyncCallback)
 }
 
+// isDryRun reports whether the caller asked for a dry run via the "dry_run" argument.
+// Tools that modify files or hardware use it to validate arguments and describe
+// the operation without performing it.
+func isDryRun(args map[string]interface{}) bool {
+	dryRun, _ := args["dry_run"].(bool)
+	return dryRun
+}
+
 func ToolToSchema(tool Tool) map[string]interface{} {
 	return map[string]interface{}{
 		"type": "function",
@@ -0,0 +1,160 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// boardInfo is what the board_info tool reports.
+type boardInfo struct {
+	Model      string          `json:"model,omitempty"`
+	Compatible []string        `json:"compatible,omitempty"`
+	Modules    []kernelModule  `json:"modules"`
+	IIODevices []iioDeviceInfo `json:"iio_devices"`
+}
+
+// kernelModule is one entry of /proc/modules.
+type kernelModule struct {
+	Name  string `json:"name"`
+	Size  int64  `json:"size"`
+	State string `json:"state,omitempty"`
+}
+
+// iioDeviceInfo is one device under /sys/bus/iio/devices.
+type iioDeviceInfo struct {
+	Device   string   `json:"device"`
+	Name     string   `json:"name,omitempty"`
+	Channels []string `json:"channels,omitempty"`
+}
+
+// BoardInfoTool reports board identity (device tree model), loaded kernel
+// modules and IIO sensors, so the agent knows what hardware exists before
+// probing buses. It is read-only. Linux only.
+type BoardInfoTool struct {
+	read func(ctx context.Context) (*boardInfo, error)
+}
+
+func NewBoardInfoTool() *BoardInfoTool {
+	return &BoardInfoTool{read: readBoardInfo}
+}
+
+func (t *BoardInfoTool) Name() string {
+	return "board_info"
+}
+
+func (t *BoardInfoTool) Description() string {
+	return "Report board identity and available hardware: device tree model, loaded kernel modules and IIO sensors. Use before probing I2C/SPI. Read-only. Linux only."
+}
+
+func (t *BoardInfoTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (t *BoardInfoTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	info, err := t.read(ctx)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read board info: %v", err))
+	}
+	result, _ := json.MarshalIndent(info, "", "  ")
+	return SilentResult(string(result)).WithData(info)
+}
+
+// readBoardInfoFrom collects board info from a filesystem rooted at root.
+// Missing sources are normal (no device tree on x86, no IIO devices) and
+// leave the corresponding fields empty.
+func readBoardInfoFrom(root string) (*boardInfo, error) {
+	info := &boardInfo{Modules: []kernelModule{}, IIODevices: []iioDeviceInfo{}}
+
+	if data, err := os.ReadFile(filepath.Join(root, "proc/device-tree/model")); err == nil {
+		info.Model = strings.TrimRight(string(data), "\x00\n")
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "proc/device-tree/compatible")); err == nil {
+		for _, s := range strings.Split(string(data), "\x00") {
+			if s != "" {
+				info.Compatible = append(info.Compatible, s)
+			}
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "proc/modules"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading modules: %w", err)
+	}
+	info.Modules = append(info.Modules, parseProcModules(string(data))...)
+
+	devices, err := readIIODevices(filepath.Join(root, "sys/bus/iio/devices"))
+	if err != nil {
+		return nil, err
+	}
+	info.IIODevices = append(info.IIODevices, devices...)
+
+	return info, nil
+}
+
+// parseProcModules parses /proc/modules lines:
+// "name size refcount deps state address".
+func parseProcModules(data string) []kernelModule {
+	var modules []kernelModule
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		size, _ := strconv.ParseInt(fields[1], 10, 64)
+		m := kernelModule{Name: fields[0], Size: size}
+		if len(fields) >= 5 {
+			m.State = fields[4]
+		}
+		modules = append(modules, m)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Name < modules[j].Name })
+	return modules
+}
+
+// readIIODevices lists iio:deviceN entries with their name and the channels
+// they expose (in_*_raw and in_*_input attributes).
+func readIIODevices(dir string) ([]iioDeviceInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading IIO devices: %w", err)
+	}
+
+	var devices []iioDeviceInfo
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), "iio:device") {
+			continue
+		}
+		devDir := filepath.Join(dir, e.Name())
+		dev := iioDeviceInfo{Device: e.Name()}
+		if name, err := os.ReadFile(filepath.Join(devDir, "name")); err == nil {
+			dev.Name = strings.TrimSpace(string(name))
+		}
+		attrs, _ := os.ReadDir(devDir)
+		for _, a := range attrs {
+			n := a.Name()
+			if !strings.HasPrefix(n, "in_") {
+				continue
+			}
+			if ch, ok := strings.CutSuffix(n, "_raw"); ok {
+				dev.Channels = append(dev.Channels, strings.TrimPrefix(ch, "in_"))
+			} else if ch, ok := strings.CutSuffix(n, "_input"); ok {
+				dev.Channels = append(dev.Channels, strings.TrimPrefix(ch, "in_"))
+			}
+		}
+		sort.Strings(dev.Channels)
+		devices = append(devices, dev)
+	}
+	return devices, nil
+}
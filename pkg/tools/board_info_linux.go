@@ -0,0 +1,8 @@
+package tools
+
+import "context"
+
+// readBoardInfo reads board info from procfs and sysfs.
+func readBoardInfo(ctx context.Context) (*boardInfo, error) {
+	return readBoardInfoFrom("/")
+}
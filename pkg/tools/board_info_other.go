@@ -0,0 +1,13 @@
+//go:build !linux
+
+package tools
+
+import (
+	"context"
+	"fmt"
+)
+
+// readBoardInfo is a stub for non-Linux platforms.
+func readBoardInfo(ctx context.Context) (*boardInfo, error) {
+	return nil, fmt.Errorf("board info is only supported on Linux")
+}
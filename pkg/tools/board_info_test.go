@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeSysfsFile(t *testing.T, root, path, content string) {
+	t.Helper()
+	full := filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadBoardInfoFrom(t *testing.T) {
+	root := t.TempDir()
+	writeSysfsFile(t, root, "proc/device-tree/model", "Sipeed LicheeRV Nano\x00")
+	writeSysfsFile(t, root, "proc/device-tree/compatible", "sipeed,licheerv-nano\x00sophgo,sg2002\x00")
+	writeSysfsFile(t, root, "proc/modules",
+		"i2c_dev 20480 0 - Live 0x0000000000000000\n"+
+			"bmp280 24576 1 bmp280_i2c, Live 0x0000000000000000\n")
+	writeSysfsFile(t, root, "sys/bus/iio/devices/iio:device0/name", "bmp280\n")
+	writeSysfsFile(t, root, "sys/bus/iio/devices/iio:device0/in_temp_input", "")
+	writeSysfsFile(t, root, "sys/bus/iio/devices/iio:device0/in_pressure_input", "")
+	writeSysfsFile(t, root, "sys/bus/iio/devices/iio:device0/sampling_frequency", "")
+	writeSysfsFile(t, root, "sys/bus/iio/devices/trigger0/name", "ignored\n")
+
+	info, err := readBoardInfoFrom(root)
+	if err != nil {
+		t.Fatalf("readBoardInfoFrom: %v", err)
+	}
+	if info.Model != "Sipeed LicheeRV Nano" {
+		t.Errorf("Model = %q", info.Model)
+	}
+	if !reflect.DeepEqual(info.Compatible, []string{"sipeed,licheerv-nano", "sophgo,sg2002"}) {
+		t.Errorf("Compatible = %v", info.Compatible)
+	}
+	wantModules := []kernelModule{
+		{Name: "bmp280", Size: 24576, State: "Live"},
+		{Name: "i2c_dev", Size: 20480, State: "Live"},
+	}
+	if !reflect.DeepEqual(info.Modules, wantModules) {
+		t.Errorf("Modules = %+v", info.Modules)
+	}
+	wantIIO := []iioDeviceInfo{{Device: "iio:device0", Name: "bmp280", Channels: []string{"pressure", "temp"}}}
+	if !reflect.DeepEqual(info.IIODevices, wantIIO) {
+		t.Errorf("IIODevices = %+v", info.IIODevices)
+	}
+}
+
+func TestReadBoardInfoFromMissingSources(t *testing.T) {
+	info, err := readBoardInfoFrom(t.TempDir())
+	if err != nil {
+		t.Fatalf("readBoardInfoFrom: %v", err)
+	}
+	if info.Model != "" || len(info.Modules) != 0 || len(info.IIODevices) != 0 {
+		t.Errorf("expected empty info, got %+v", info)
+	}
+}
+
+func TestBoardInfoToolExecute(t *testing.T) {
+	tool := &BoardInfoTool{read: func(ctx context.Context) (*boardInfo, error) {
+		return &boardInfo{Model: "Test Board", Modules: []kernelModule{}, IIODevices: []iioDeviceInfo{}}, nil
+	}}
+	result := tool.Execute(context.Background(), map[string]interface{}{})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, `"model": "Test Board"`) || !strings.Contains(result.ForLLM, `"iio_devices": []`) {
+		t.Errorf("unexpected output: %s", result.ForLLM)
+	}
+}
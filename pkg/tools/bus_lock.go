@@ -0,0 +1,18 @@
+package tools
+
+import "sync"
+
+// busLocks holds one mutex per hardware device node, keyed by path (for
+// example "/dev/i2c-1" or "/dev/spidev0.0"). Tool calls may run in parallel,
+// and interleaved ioctls from two calls on the same bus corrupt each other's
+// transactions, so every operation that talks to a device takes its lock.
+var busLocks sync.Map // map[string]*sync.Mutex
+
+// lockBus blocks until devPath is free and returns the function releasing it.
+// Operations on different paths proceed in parallel.
+func lockBus(devPath string) (unlock func()) {
+	v, _ := busLocks.LoadOrStore(devPath, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockBus_SerializesSamePath(t *testing.T) {
+	unlock := lockBus("/dev/i2c-test-same")
+
+	acquired := make(chan struct{})
+	go func() {
+		defer lockBus("/dev/i2c-test-same")()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Expected second lock on the same path to wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Expected second lock to be acquired after unlock")
+	}
+}
+
+func TestLockBus_DifferentPathsIndependent(t *testing.T) {
+	defer lockBus("/dev/i2c-test-a")()
+
+	acquired := make(chan struct{})
+	go func() {
+		defer lockBus("/dev/i2c-test-b")()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Expected lock on a different path not to wait")
+	}
+}
@@ -0,0 +1,267 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	defaultCommandTimeout        = 60 * time.Second
+	defaultCommandMaxOutputBytes = 16 * 1024
+)
+
+// CommandToolOptions configures a CommandTool.
+type CommandToolOptions struct {
+	WorkingDir      string
+	Restrict        bool
+	AllowedCommands []string      // Programs that may run (bare names or absolute paths)
+	AllowShell      bool          // Permit shell: true, which runs command through sh -c
+	Timeout         time.Duration // Maximum run time; requests may only lower it
+	MaxOutputBytes  int           // Cap per stream (stdout and stderr)
+}
+
+// CommandTool runs a single program in argv form. Only programs on the
+// operator's allowlist may run, and shell parsing is only available when
+// explicitly enabled. Every command line also passes ExecTool's safety guard,
+// so dangerous patterns and paths outside the workspace are rejected.
+type CommandTool struct {
+	workingDir     string
+	allowed        map[string]bool
+	allowShell     bool
+	timeout        time.Duration
+	maxOutputBytes int
+	guard          *ExecTool
+}
+
+func NewCommandTool(opts CommandToolOptions) *CommandTool {
+	allowed := make(map[string]bool, len(opts.AllowedCommands))
+	for _, c := range opts.AllowedCommands {
+		if c = strings.TrimSpace(c); c != "" {
+			allowed[c] = true
+		}
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultCommandTimeout
+	}
+	maxOutput := opts.MaxOutputBytes
+	if maxOutput <= 0 {
+		maxOutput = defaultCommandMaxOutputBytes
+	}
+
+	return &CommandTool{
+		workingDir:     opts.WorkingDir,
+		allowed:        allowed,
+		allowShell:     opts.AllowShell,
+		timeout:        timeout,
+		maxOutputBytes: maxOutput,
+		guard:          NewExecTool(opts.WorkingDir, opts.Restrict),
+	}
+}
+
+func (t *CommandTool) Name() string {
+	return "run_command"
+}
+
+func (t *CommandTool) Description() string {
+	return "Run an allowlisted program with arguments (no shell) and return its exit code, stdout and stderr."
+}
+
+func (t *CommandTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"command": map[string]interface{}{
+				"type":        "string",
+				"description": "Program to run (e.g. \"dmesg\"), or a full command line when shell is true",
+			},
+			"args": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Arguments passed to the program verbatim, without shell interpretation",
+			},
+			"working_dir": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional working directory for the command",
+			},
+			"timeout_seconds": map[string]interface{}{
+				"type":        "integer",
+				"description": "Optional timeout in seconds (cannot exceed the configured maximum)",
+			},
+			"shell": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Run command through the shell (pipes, redirects). Only available if enabled in config.",
+			},
+		},
+		"required": []string{"command"},
+	}
+}
+
+func (t *CommandTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	command, ok := args["command"].(string)
+	command = strings.TrimSpace(command)
+	if !ok || command == "" {
+		return ErrorResult("command is required")
+	}
+
+	argv, err := parseStringArgs(args["args"])
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	useShell, _ := args["shell"].(bool)
+
+	if useShell {
+		if !t.allowShell {
+			return ErrorResult("shell mode is disabled. Pass the program in command and its arguments in args")
+		}
+		if len(argv) > 0 {
+			return ErrorResult("args cannot be combined with shell mode; put the full command line in command")
+		}
+	} else if !t.allowed[command] {
+		return ErrorResult(fmt.Sprintf("command %q is not in the allowlist configured under tools.command.allowed_commands", command))
+	}
+
+	cwd := t.workingDir
+	if wd, ok := args["working_dir"].(string); ok && wd != "" {
+		resolved, err := validatePath(wd, t.workingDir, t.guard.restrictToWorkspace)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		cwd = resolved
+	}
+	if cwd == "" {
+		if wd, err := os.Getwd(); err == nil {
+			cwd = wd
+		}
+	}
+
+	// The program itself is vetted by the allowlist; check what it is asked
+	// to do. The base name is kept so patterns like "rm -rf" still match.
+	commandLine := command
+	if !useShell {
+		commandLine = strings.Join(append([]string{filepath.Base(command)}, argv...), " ")
+	}
+	if guardError := t.guard.guardCommand(commandLine, cwd); guardError != "" {
+		return ErrorResult(guardError)
+	}
+
+	timeout := t.timeout
+	if v, ok := args["timeout_seconds"].(float64); ok && v > 0 {
+		if requested := time.Duration(v) * time.Second; requested < timeout {
+			timeout = requested
+		}
+	}
+
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	switch {
+	case !useShell:
+		cmd = exec.CommandContext(cmdCtx, command, argv...)
+	case runtime.GOOS == "windows":
+		cmd = exec.CommandContext(cmdCtx, "powershell", "-NoProfile", "-NonInteractive", "-Command", command)
+	default:
+		cmd = exec.CommandContext(cmdCtx, "sh", "-c", command)
+	}
+	cmd.Dir = cwd
+
+	stdout := &cappedBuffer{max: t.maxOutputBytes}
+	stderr := &cappedBuffer{max: t.maxOutputBytes}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	runErr := cmd.Run()
+
+	if cmdCtx.Err() == context.DeadlineExceeded {
+		msg := fmt.Sprintf("Command timed out after %v", timeout)
+		return &ToolResult{
+			ForLLM:  msg,
+			ForUser: msg,
+			IsError: true,
+		}
+	}
+
+	exitCode := 0
+	if runErr != nil {
+		exitErr, ok := runErr.(*exec.ExitError)
+		if !ok {
+			return ErrorResult(fmt.Sprintf("failed to run %s: %v", command, runErr))
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Exit code: %d\n", exitCode))
+	sb.WriteString("STDOUT:\n")
+	sb.WriteString(stdout.String())
+	if stderr.Len() > 0 || stderr.dropped > 0 {
+		sb.WriteString("\nSTDERR:\n")
+		sb.WriteString(stderr.String())
+	}
+	output := sb.String()
+
+	return &ToolResult{
+		ForLLM:  output,
+		ForUser: output,
+		IsError: exitCode != 0,
+	}
+}
+
+// parseStringArgs converts a JSON array argument into a string slice.
+func parseStringArgs(raw interface{}) ([]string, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("args must be an array of strings")
+	}
+	argv := make([]string, 0, len(list))
+	for i, v := range list {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("args[%d] must be a string", i)
+		}
+		argv = append(argv, s)
+	}
+	return argv, nil
+}
+
+// cappedBuffer keeps the first max bytes written to it and counts the rest.
+type cappedBuffer struct {
+	buf     []byte
+	max     int
+	dropped int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	room := b.max - len(b.buf)
+	if room > len(p) {
+		room = len(p)
+	}
+	if room > 0 {
+		b.buf = append(b.buf, p[:room]...)
+	}
+	b.dropped += len(p) - room
+	return len(p), nil
+}
+
+func (b *cappedBuffer) Len() int {
+	return len(b.buf)
+}
+
+func (b *cappedBuffer) String() string {
+	if b.dropped == 0 {
+		return string(b.buf)
+	}
+	return string(b.buf) + fmt.Sprintf("\n[output truncated, %d bytes omitted]", b.dropped)
+}
@@ -0,0 +1,176 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandTool_AllowedCommandRuns(t *testing.T) {
+	tool := NewCommandTool(CommandToolOptions{AllowedCommands: []string{"echo"}})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command": "echo",
+		"args":    []interface{}{"hello", "$HOME; ls"},
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got error: %s", result.ForLLM)
+	}
+	// Arguments must be passed verbatim, not interpreted by a shell
+	if !strings.Contains(result.ForLLM, "hello $HOME; ls") {
+		t.Errorf("Expected literal arguments in output, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "Exit code: 0") {
+		t.Errorf("Expected exit code in output, got: %s", result.ForLLM)
+	}
+}
+
+func TestCommandTool_RejectsProgramsOutsideAllowlist(t *testing.T) {
+	tool := NewCommandTool(CommandToolOptions{AllowedCommands: []string{"uname"}})
+
+	for _, args := range []map[string]interface{}{
+		{"command": "echo", "args": []interface{}{"hi"}},
+		// confirm is not an escape hatch; the allowlist is set by the operator
+		{"command": "echo", "args": []interface{}{"hi"}, "confirm": true},
+	} {
+		result := tool.Execute(context.Background(), args)
+		if !result.IsError || !strings.Contains(result.ForLLM, "not in the allowlist") {
+			t.Errorf("Expected allowlist error for %v, got: %s", args, result.ForLLM)
+		}
+	}
+}
+
+func TestCommandTool_GuardsArguments(t *testing.T) {
+	workspace := t.TempDir()
+	tool := NewCommandTool(CommandToolOptions{
+		WorkingDir:      workspace,
+		Restrict:        true,
+		AllowedCommands: []string{"rm", "cat", "/bin/cat"},
+	})
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want string
+	}{
+		{"dangerous pattern", map[string]interface{}{"command": "rm", "args": []interface{}{"-rf", "data"}}, "dangerous pattern"},
+		{"absolute path outside workspace", map[string]interface{}{"command": "cat", "args": []interface{}{"/etc/passwd"}}, "outside working dir"},
+		{"path traversal", map[string]interface{}{"command": "cat", "args": []interface{}{"../secret"}}, "path traversal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tool.Execute(context.Background(), tt.args)
+			if !result.IsError || !strings.Contains(result.ForLLM, tt.want) {
+				t.Errorf("Expected %q, got: %s", tt.want, result.ForLLM)
+			}
+		})
+	}
+
+	// An allowlisted absolute program path is not itself a workspace violation
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command": "/bin/cat",
+		"args":    []interface{}{"missing.txt"},
+	})
+	if strings.Contains(result.ForLLM, "safety guard") {
+		t.Errorf("Expected program path to pass the guard, got: %s", result.ForLLM)
+	}
+}
+
+func TestCommandTool_AllowlistMatchesExactCommand(t *testing.T) {
+	tool := NewCommandTool(CommandToolOptions{AllowedCommands: []string{"echo"}})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command": "/tmp/evil/echo",
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "not in the allowlist") {
+		t.Errorf("Expected path with allowlisted base name to be rejected, got: %s", result.ForLLM)
+	}
+}
+
+func TestCommandTool_ShellModeDisabledByDefault(t *testing.T) {
+	tool := NewCommandTool(CommandToolOptions{})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command": "echo hi | tr a-z A-Z",
+		"shell":   true,
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "shell mode is disabled") {
+		t.Errorf("Expected shell mode to be rejected, got: %s", result.ForLLM)
+	}
+}
+
+func TestCommandTool_ShellModeOptIn(t *testing.T) {
+	tool := NewCommandTool(CommandToolOptions{AllowShell: true})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command": "echo hi | tr a-z A-Z",
+		"shell":   true,
+	})
+	if result.IsError || !strings.Contains(result.ForLLM, "HI") {
+		t.Errorf("Expected shell pipeline output, got: %s", result.ForLLM)
+	}
+}
+
+func TestCommandTool_NonZeroExit(t *testing.T) {
+	tool := NewCommandTool(CommandToolOptions{AllowedCommands: []string{"ls"}})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command": "ls",
+		"args":    []interface{}{"/nonexistent_directory_12345"},
+	})
+	if !result.IsError {
+		t.Fatal("Expected non-zero exit to be an error")
+	}
+	if !strings.Contains(result.ForLLM, "STDERR:") {
+		t.Errorf("Expected stderr in output, got: %s", result.ForLLM)
+	}
+}
+
+func TestCommandTool_Timeout(t *testing.T) {
+	tool := NewCommandTool(CommandToolOptions{AllowedCommands: []string{"sleep"}, Timeout: 100 * time.Millisecond})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command": "sleep",
+		"args":    []interface{}{"10"},
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "timed out") {
+		t.Errorf("Expected timeout error, got: %s", result.ForLLM)
+	}
+}
+
+func TestCommandTool_OutputCap(t *testing.T) {
+	tool := NewCommandTool(CommandToolOptions{AllowedCommands: []string{"head"}, MaxOutputBytes: 100})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command": "head",
+		"args":    []interface{}{"-c", "1000", "/dev/zero"},
+	})
+	if !strings.Contains(result.ForLLM, "[output truncated, 900 bytes omitted]") {
+		t.Errorf("Expected truncation marker, got: %q", result.ForLLM)
+	}
+}
+
+func TestCommandTool_InvalidArgs(t *testing.T) {
+	tool := NewCommandTool(CommandToolOptions{AllowedCommands: []string{"echo"}})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command": "echo",
+		"args":    []interface{}{"ok", 3},
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "args[1]") {
+		t.Errorf("Expected invalid args error, got: %s", result.ForLLM)
+	}
+}
+
+func TestCommandTool_ShellModeIsGuarded(t *testing.T) {
+	tool := NewCommandTool(CommandToolOptions{AllowShell: true})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command": "echo hi && shutdown now",
+		"shell":   true,
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "dangerous pattern") {
+		t.Errorf("Expected shell command line to be guarded, got: %s", result.ForLLM)
+	}
+}
@@ -10,16 +10,12 @@ import (
 // EditFileTool edits a file by replacing old_text with new_text.
 // The old_text must exist exactly in the file.
 type EditFileTool struct {
-	allowedDir string
-	restrict   bool
+	workspace *Workspace
 }
 
-// NewEditFileTool creates a new EditFileTool with optional directory restriction.
-func NewEditFileTool(allowedDir string, restrict bool) *EditFileTool {
-	return &EditFileTool{
-		allowedDir: allowedDir,
-		restrict:   restrict,
-	}
+// NewEditFileTool creates a new EditFileTool working in workspace.
+func NewEditFileTool(workspace *Workspace) *EditFileTool {
+	return &EditFileTool{workspace: workspace}
 }
 
 func (t *EditFileTool) Name() string {
@@ -46,6 +42,15 @@ func (t *EditFileTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "The text to replace with",
 			},
+			"line_ending": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"preserve", "lf", "crlf"},
+				"description": "Line endings of the edited file: preserve (default, only the replacement changes) or lf/crlf (convert the whole file)",
+			},
+			"dry_run": map[string]interface{}{
+				"type":        "boolean",
+				"description": "If true, validate arguments and return the diff that would be applied without modifying the file",
+			},
 		},
 		"required": []string{"path", "old_text", "new_text"},
 	}
@@ -67,12 +72,18 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult("new_text is required")
 	}
 
-	resolvedPath, err := validatePath(path, t.allowedDir, t.restrict)
+	lineEnding, err := parseLineEnding(args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	resolvedPath, err := t.workspace.Resolve(path)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
 
-	if _, err := os.Stat(resolvedPath); os.IsNotExist(err) {
+	info, err := os.Stat(resolvedPath)
+	if os.IsNotExist(err) {
 		return ErrorResult(fmt.Sprintf("file not found: %s", path))
 	}
 
@@ -92,22 +103,40 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult(fmt.Sprintf("old_text appears %d times. Please provide more context to make it unique", count))
 	}
 
-	newContent := strings.Replace(contentStr, oldText, newText, 1)
+	if isDryRun(args) {
+		return SilentResult(fmt.Sprintf("[dry run] Would apply the following change to %s:\n%s", path, formatEditDiff(path, oldText, newText)))
+	}
+
+	newContent := applyLineEnding(strings.Replace(contentStr, oldText, newText, 1), lineEnding)
 
-	if err := os.WriteFile(resolvedPath, []byte(newContent), 0644); err != nil {
+	// Keep the original permissions, e.g. the executable bit on scripts.
+	if err := writeFileAtomic(resolvedPath, []byte(newContent), info.Mode().Perm()); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to write file: %v", err))
 	}
 
 	return SilentResult(fmt.Sprintf("File edited: %s", path))
 }
 
+// formatEditDiff renders a minimal unified-style diff of a single replacement.
+func formatEditDiff(path, oldText, newText string) string {
+	var sb strings.Builder
+	sb.WriteString("--- a/" + path + "\n")
+	sb.WriteString("+++ b/" + path + "\n")
+	for _, line := range strings.Split(oldText, "\n") {
+		sb.WriteString("-" + line + "\n")
+	}
+	for _, line := range strings.Split(newText, "\n") {
+		sb.WriteString("+" + line + "\n")
+	}
+	return sb.String()
+}
+
 type AppendFileTool struct {
-	workspace string
-	restrict  bool
+	workspace *Workspace
 }
 
-func NewAppendFileTool(workspace string, restrict bool) *AppendFileTool {
-	return &AppendFileTool{workspace: workspace, restrict: restrict}
+func NewAppendFileTool(workspace *Workspace) *AppendFileTool {
+	return &AppendFileTool{workspace: workspace}
 }
 
 func (t *AppendFileTool) Name() string {
@@ -115,7 +144,7 @@ func (t *AppendFileTool) Name() string {
 }
 
 func (t *AppendFileTool) Description() string {
-	return "Append content to the end of a file"
+	return "Append content to the end of a file. Use separator (e.g. a newline) to keep entries apart and max_size to stop a log file from growing without bound."
 }
 
 func (t *AppendFileTool) Parameters() map[string]interface{} {
@@ -130,6 +159,19 @@ func (t *AppendFileTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "The content to append",
 			},
+			"separator": map[string]interface{}{
+				"type":        "string",
+				"description": "Text inserted before the content when the file is not empty, e.g. \"\\n\"",
+			},
+			"max_size": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum file size in bytes; the append fails if the file would grow past it",
+			},
+			"line_ending": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"preserve", "lf", "crlf"},
+				"description": "Line endings of the appended text, including separator: preserve (default, as given), lf or crlf. Existing file content is not changed",
+			},
 		},
 		"required": []string{"path", "content"},
 	}
@@ -146,7 +188,24 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]interface{
 		return ErrorResult("content is required")
 	}
 
-	resolvedPath, err := validatePath(path, t.workspace, t.restrict)
+	separator, _ := args["separator"].(string)
+
+	var maxSize int64
+	if v, ok := args["max_size"].(float64); ok {
+		if v < 1 {
+			return ErrorResult("max_size must be a positive number of bytes")
+		}
+		maxSize = int64(v)
+	}
+
+	lineEnding, err := parseLineEnding(args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	content = applyLineEnding(content, lineEnding)
+	separator = applyLineEnding(separator, lineEnding)
+
+	resolvedPath, err := t.workspace.Resolve(path)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
@@ -157,6 +216,19 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]interface{
 	}
 	defer f.Close()
 
+	if separator != "" || maxSize > 0 {
+		info, err := f.Stat()
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to stat file: %v", err))
+		}
+		if info.Size() > 0 {
+			content = separator + content
+		}
+		if newSize := info.Size() + int64(len(content)); maxSize > 0 && newSize > maxSize {
+			return ErrorResult(fmt.Sprintf("appending would grow %s to %d bytes, over max_size %d", path, newSize, maxSize))
+		}
+	}
+
 	if _, err := f.WriteString(content); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to append to file: %v", err))
 	}
@@ -14,7 +14,7 @@ func TestEditTool_EditFile_Success(t *testing.T) {
 	testFile := filepath.Join(tmpDir, "test.txt")
 	os.WriteFile(testFile, []byte("Hello World\nThis is a test"), 0644)
 
-	tool := NewEditFileTool(tmpDir, true)
+	tool := NewEditFileTool(NewWorkspace(tmpDir, true))
 	ctx := context.Background()
 	args := map[string]interface{}{
 		"path":     testFile,
@@ -58,7 +58,7 @@ func TestEditTool_EditFile_NotFound(t *testing.T) {
 	tmpDir := t.TempDir()
 	testFile := filepath.Join(tmpDir, "nonexistent.txt")
 
-	tool := NewEditFileTool(tmpDir, true)
+	tool := NewEditFileTool(NewWorkspace(tmpDir, true))
 	ctx := context.Background()
 	args := map[string]interface{}{
 		"path":     testFile,
@@ -85,7 +85,7 @@ func TestEditTool_EditFile_OldTextNotFound(t *testing.T) {
 	testFile := filepath.Join(tmpDir, "test.txt")
 	os.WriteFile(testFile, []byte("Hello World"), 0644)
 
-	tool := NewEditFileTool(tmpDir, true)
+	tool := NewEditFileTool(NewWorkspace(tmpDir, true))
 	ctx := context.Background()
 	args := map[string]interface{}{
 		"path":     testFile,
@@ -112,7 +112,7 @@ func TestEditTool_EditFile_MultipleMatches(t *testing.T) {
 	testFile := filepath.Join(tmpDir, "test.txt")
 	os.WriteFile(testFile, []byte("test test test"), 0644)
 
-	tool := NewEditFileTool(tmpDir, true)
+	tool := NewEditFileTool(NewWorkspace(tmpDir, true))
 	ctx := context.Background()
 	args := map[string]interface{}{
 		"path":     testFile,
@@ -140,7 +140,7 @@ func TestEditTool_EditFile_OutsideAllowedDir(t *testing.T) {
 	testFile := filepath.Join(otherDir, "test.txt")
 	os.WriteFile(testFile, []byte("content"), 0644)
 
-	tool := NewEditFileTool(tmpDir, true) // Restrict to tmpDir
+	tool := NewEditFileTool(NewWorkspace(tmpDir, true)) // Restrict to tmpDir
 	ctx := context.Background()
 	args := map[string]interface{}{
 		"path":     testFile,
@@ -163,7 +163,7 @@ func TestEditTool_EditFile_OutsideAllowedDir(t *testing.T) {
 
 // TestEditTool_EditFile_MissingPath verifies error handling for missing path
 func TestEditTool_EditFile_MissingPath(t *testing.T) {
-	tool := NewEditFileTool("", false)
+	tool := NewEditFileTool(nil)
 	ctx := context.Background()
 	args := map[string]interface{}{
 		"old_text": "old",
@@ -180,7 +180,7 @@ func TestEditTool_EditFile_MissingPath(t *testing.T) {
 
 // TestEditTool_EditFile_MissingOldText verifies error handling for missing old_text
 func TestEditTool_EditFile_MissingOldText(t *testing.T) {
-	tool := NewEditFileTool("", false)
+	tool := NewEditFileTool(nil)
 	ctx := context.Background()
 	args := map[string]interface{}{
 		"path":     "/tmp/test.txt",
@@ -197,7 +197,7 @@ func TestEditTool_EditFile_MissingOldText(t *testing.T) {
 
 // TestEditTool_EditFile_MissingNewText verifies error handling for missing new_text
 func TestEditTool_EditFile_MissingNewText(t *testing.T) {
-	tool := NewEditFileTool("", false)
+	tool := NewEditFileTool(nil)
 	ctx := context.Background()
 	args := map[string]interface{}{
 		"path":     "/tmp/test.txt",
@@ -212,13 +212,120 @@ func TestEditTool_EditFile_MissingNewText(t *testing.T) {
 	}
 }
 
+// TestEditTool_EditFile_DryRun verifies dry run reports a diff without modifying the file
+func TestEditTool_EditFile_DryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.txt")
+	os.WriteFile(testFile, []byte("Hello World\nThis is a test"), 0644)
+
+	tool := NewEditFileTool(NewWorkspace(tmpDir, true))
+	ctx := context.Background()
+	args := map[string]interface{}{
+		"path":     testFile,
+		"old_text": "World",
+		"new_text": "Universe",
+		"dry_run":  true,
+	}
+
+	result := tool.Execute(ctx, args)
+
+	if result.IsError {
+		t.Errorf("Expected success, got IsError=true: %s", result.ForLLM)
+	}
+
+	// ForLLM should contain the diff
+	if !strings.Contains(result.ForLLM, "-World") || !strings.Contains(result.ForLLM, "+Universe") {
+		t.Errorf("Expected diff in ForLLM, got: %s", result.ForLLM)
+	}
+
+	// File must be unchanged
+	content, _ := os.ReadFile(testFile)
+	if string(content) != "Hello World\nThis is a test" {
+		t.Errorf("Expected file to be unchanged in dry run, got: %s", string(content))
+	}
+}
+
+// TestEditTool_EditFile_KeepsMode verifies editing keeps the file's permissions
+func TestEditTool_EditFile_KeepsMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "run.sh")
+	os.WriteFile(testFile, []byte("#!/bin/sh\necho hello\n"), 0750)
+	os.Chmod(testFile, 0750)
+
+	tool := NewEditFileTool(NewWorkspace(tmpDir, true))
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path":     testFile,
+		"old_text": "hello",
+		"new_text": "world",
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got IsError=true: %s", result.ForLLM)
+	}
+
+	info, err := os.Stat(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0750 {
+		t.Errorf("Expected mode 0750, got %o", info.Mode().Perm())
+	}
+}
+
+// TestEditTool_EditFile_LineEnding verifies that lf converts the whole
+// edited file and that the default leaves other lines alone
+func TestEditTool_EditFile_LineEnding(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "app.conf")
+	os.WriteFile(testFile, []byte("a=1\r\nb=2\r\n"), 0644)
+
+	tool := NewEditFileTool(NewWorkspace(tmpDir, true))
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path": testFile, "old_text": "b=2", "new_text": "b=3\nc=4",
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if data, _ := os.ReadFile(testFile); string(data) != "a=1\r\nb=3\nc=4\r\n" {
+		t.Errorf("preserve: got %q", data)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{
+		"path": testFile, "old_text": "c=4", "new_text": "c=5", "line_ending": "lf",
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if data, _ := os.ReadFile(testFile); string(data) != "a=1\nb=3\nc=5\n" {
+		t.Errorf("lf: got %q", data)
+	}
+}
+
+// TestEditTool_AppendFile_LineEnding verifies that only appended text is
+// converted
+func TestEditTool_AppendFile_LineEnding(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "log.txt")
+	os.WriteFile(testFile, []byte("old\n"), 0644)
+
+	tool := NewAppendFileTool(NewWorkspace(tmpDir, true))
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path": testFile, "content": "one\ntwo", "separator": "\n", "line_ending": "crlf",
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if data, _ := os.ReadFile(testFile); string(data) != "old\n\r\none\r\ntwo" {
+		t.Errorf("got %q", data)
+	}
+}
+
 // TestEditTool_AppendFile_Success verifies successful file appending
 func TestEditTool_AppendFile_Success(t *testing.T) {
 	tmpDir := t.TempDir()
 	testFile := filepath.Join(tmpDir, "test.txt")
 	os.WriteFile(testFile, []byte("Initial content"), 0644)
 
-	tool := NewAppendFileTool("", false)
+	tool := NewAppendFileTool(nil)
 	ctx := context.Background()
 	args := map[string]interface{}{
 		"path":    testFile,
@@ -258,7 +365,7 @@ func TestEditTool_AppendFile_Success(t *testing.T) {
 
 // TestEditTool_AppendFile_MissingPath verifies error handling for missing path
 func TestEditTool_AppendFile_MissingPath(t *testing.T) {
-	tool := NewAppendFileTool("", false)
+	tool := NewAppendFileTool(nil)
 	ctx := context.Background()
 	args := map[string]interface{}{
 		"content": "test",
@@ -274,7 +381,7 @@ func TestEditTool_AppendFile_MissingPath(t *testing.T) {
 
 // TestEditTool_AppendFile_MissingContent verifies error handling for missing content
 func TestEditTool_AppendFile_MissingContent(t *testing.T) {
-	tool := NewAppendFileTool("", false)
+	tool := NewAppendFileTool(nil)
 	ctx := context.Background()
 	args := map[string]interface{}{
 		"path": "/tmp/test.txt",
@@ -287,3 +394,70 @@ func TestEditTool_AppendFile_MissingContent(t *testing.T) {
 		t.Errorf("Expected error when content is missing")
 	}
 }
+
+// TestEditTool_AppendFile_Separator verifies the separator is only added to non-empty files
+func TestEditTool_AppendFile_Separator(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "log.txt")
+	tool := NewAppendFileTool(nil)
+	ctx := context.Background()
+
+	for _, entry := range []string{"first", "second"} {
+		result := tool.Execute(ctx, map[string]interface{}{
+			"path":      testFile,
+			"content":   entry,
+			"separator": "\n",
+		})
+		if result.IsError {
+			t.Fatalf("Expected success, got error: %s", result.ForLLM)
+		}
+	}
+
+	content, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != "first\nsecond" {
+		t.Errorf("Expected %q, got %q", "first\nsecond", content)
+	}
+}
+
+// TestEditTool_AppendFile_MaxSize verifies appends past max_size are rejected
+func TestEditTool_AppendFile_MaxSize(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "log.txt")
+	os.WriteFile(testFile, []byte("12345"), 0644)
+	tool := NewAppendFileTool(nil)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{
+		"path":      testFile,
+		"content":   "6789",
+		"separator": ",",
+		"max_size":  float64(10),
+	})
+	if result.IsError {
+		t.Fatalf("Expected append within limit to succeed, got: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{
+		"path":     testFile,
+		"content":  "x",
+		"max_size": float64(10),
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "max_size") {
+		t.Errorf("Expected max_size error, got: %+v", result)
+	}
+
+	content, _ := os.ReadFile(testFile)
+	if string(content) != "12345,6789" {
+		t.Errorf("Expected file unchanged by rejected append, got %q", content)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{
+		"path":     testFile,
+		"content":  "x",
+		"max_size": float64(0),
+	})
+	if !result.IsError {
+		t.Error("Expected error for non-positive max_size")
+	}
+}
@@ -0,0 +1,219 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// feedItem is a single RSS item or Atom entry.
+type feedItem struct {
+	Title     string `json:"title"`
+	Link      string `json:"link,omitempty"`
+	Published string `json:"published,omitempty"` // RFC 3339, empty if the feed gives no usable date
+	ID        string `json:"id,omitempty"`
+
+	published time.Time
+}
+
+// parsedFeed is the normalized form of an RSS 2.0, RSS 1.0 (RDF) or Atom feed.
+type parsedFeed struct {
+	Title string
+	Items []feedItem
+}
+
+// Raw document shapes; encoding/xml matches local names, so namespaced
+// elements such as dc:date and rdf:RDF need no special handling.
+type rssDocument struct {
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items []rssItem `xml:"item"` // RSS 1.0 puts items beside the channel
+}
+
+type rssItem struct {
+	Title   string `xml:"title"`
+	Link    string `xml:"link"`
+	GUID    string `xml:"guid"`
+	PubDate string `xml:"pubDate"`
+	Date    string `xml:"date"`
+}
+
+type atomDocument struct {
+	Title   string      `xml:"title"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title     string `xml:"title"`
+	ID        string `xml:"id"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+	Links     []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+}
+
+// feedDateLayouts covers the date formats seen in RSS (RFC 822 and friends)
+// and Atom (RFC 3339).
+var feedDateLayouts = []string{
+	time.RFC3339,
+	time.RFC3339Nano,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC822Z,
+	time.RFC822,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+func parseFeedDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range feedDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// feedRoot returns the local name of the document's root element.
+func feedRoot(body []byte) string {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name.Local
+		}
+	}
+}
+
+// looksLikeFeed reports whether body is an RSS or Atom document.
+func looksLikeFeed(body []byte) bool {
+	switch feedRoot(body) {
+	case "rss", "feed", "RDF":
+		return true
+	}
+	return false
+}
+
+// isFeedContentType reports whether a Content-Type header names a feed format.
+func isFeedContentType(contentType string) bool {
+	ct := strings.ToLower(contentType)
+	return strings.Contains(ct, "application/rss+xml") ||
+		strings.Contains(ct, "application/atom+xml") ||
+		strings.Contains(ct, "application/rdf+xml")
+}
+
+// parseFeed parses an RSS or Atom document. Items are returned newest first;
+// items without a usable date keep their document order after dated ones.
+func parseFeed(body []byte) (*parsedFeed, error) {
+	feed := &parsedFeed{}
+
+	switch root := feedRoot(body); root {
+	case "rss", "RDF":
+		var doc rssDocument
+		if err := unmarshalFeed(body, &doc); err != nil {
+			return nil, err
+		}
+		feed.Title = strings.TrimSpace(doc.Channel.Title)
+		for _, it := range append(doc.Channel.Items, doc.Items...) {
+			item := feedItem{
+				Title: strings.TrimSpace(it.Title),
+				Link:  strings.TrimSpace(it.Link),
+				ID:    strings.TrimSpace(it.GUID),
+			}
+			date := it.PubDate
+			if date == "" {
+				date = it.Date
+			}
+			setFeedItemDate(&item, date)
+			feed.Items = append(feed.Items, item)
+		}
+	case "feed":
+		var doc atomDocument
+		if err := unmarshalFeed(body, &doc); err != nil {
+			return nil, err
+		}
+		feed.Title = strings.TrimSpace(doc.Title)
+		for _, e := range doc.Entries {
+			item := feedItem{
+				Title: strings.TrimSpace(e.Title),
+				ID:    strings.TrimSpace(e.ID),
+			}
+			for _, l := range e.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					item.Link = strings.TrimSpace(l.Href)
+					break
+				}
+			}
+			date := e.Published
+			if date == "" {
+				date = e.Updated
+			}
+			setFeedItemDate(&item, date)
+			feed.Items = append(feed.Items, item)
+		}
+	case "":
+		return nil, fmt.Errorf("response is not XML")
+	default:
+		return nil, fmt.Errorf("unsupported feed format (root element <%s>)", root)
+	}
+
+	sort.SliceStable(feed.Items, func(i, j int) bool {
+		a, b := feed.Items[i].published, feed.Items[j].published
+		if a.IsZero() || b.IsZero() {
+			return !a.IsZero() && b.IsZero()
+		}
+		return a.After(b)
+	})
+	return feed, nil
+}
+
+func unmarshalFeed(body []byte, v interface{}) error {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("failed to parse feed: %w", err)
+	}
+	return nil
+}
+
+func setFeedItemDate(item *feedItem, raw string) {
+	if t, ok := parseFeedDate(raw); ok {
+		item.published = t
+		item.Published = t.UTC().Format(time.RFC3339)
+	}
+}
+
+// itemsSince returns the items published strictly after since, and how many
+// items were dropped because they carry no usable date.
+func itemsSince(items []feedItem, since time.Time) ([]feedItem, int) {
+	newer := make([]feedItem, 0, len(items))
+	undated := 0
+	for _, item := range items {
+		switch {
+		case item.published.IsZero():
+			undated++
+		case item.published.After(since):
+			newer = append(newer, item)
+		}
+	}
+	return newer, undated
+}
@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+const testRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+  <channel>
+    <title>Hardware News</title>
+    <item>
+      <title>Older board released</title>
+      <link>https://example.com/older</link>
+      <guid>older</guid>
+      <pubDate>Mon, 06 May 2024 09:00:00 +0000</pubDate>
+    </item>
+    <item>
+      <title>New RISC-V board &amp; more</title>
+      <link>https://example.com/new</link>
+      <guid>new</guid>
+      <pubDate>Wed, 08 May 2024 12:30:00 GMT</pubDate>
+    </item>
+    <item>
+      <title>Undated note</title>
+      <link>https://example.com/undated</link>
+    </item>
+  </channel>
+</rss>`
+
+const testAtom = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Project Releases</title>
+  <entry>
+    <title>v1.1</title>
+    <id>tag:example.com,2024:v1.1</id>
+    <link rel="alternate" href="https://example.com/v1.1"/>
+    <updated>2024-05-02T10:00:00Z</updated>
+  </entry>
+  <entry>
+    <title>v1.2</title>
+    <id>tag:example.com,2024:v1.2</id>
+    <link rel="self" href="https://example.com/v1.2.atom"/>
+    <link href="https://example.com/v1.2"/>
+    <published>2024-05-09T10:00:00+02:00</published>
+  </entry>
+</feed>`
+
+const testRDF = `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel><title>RDF Feed</title></channel>
+  <item>
+    <title>First</title>
+    <link>https://example.com/1</link>
+    <dc:date>2024-05-01T00:00:00Z</dc:date>
+  </item>
+</rdf:RDF>`
+
+func TestParseFeed_RSS(t *testing.T) {
+	feed, err := parseFeed([]byte(testRSS))
+	if err != nil {
+		t.Fatalf("parseFeed() error: %v", err)
+	}
+	if feed.Title != "Hardware News" || len(feed.Items) != 3 {
+		t.Fatalf("Unexpected feed: %+v", feed)
+	}
+	// Newest first, undated last
+	if feed.Items[0].Title != "New RISC-V board & more" || feed.Items[0].Published != "2024-05-08T12:30:00Z" {
+		t.Errorf("Unexpected first item: %+v", feed.Items[0])
+	}
+	if feed.Items[1].ID != "older" || feed.Items[2].Published != "" {
+		t.Errorf("Unexpected item order: %+v", feed.Items)
+	}
+}
+
+func TestParseFeed_Atom(t *testing.T) {
+	feed, err := parseFeed([]byte(testAtom))
+	if err != nil {
+		t.Fatalf("parseFeed() error: %v", err)
+	}
+	if feed.Title != "Project Releases" || len(feed.Items) != 2 {
+		t.Fatalf("Unexpected feed: %+v", feed)
+	}
+	first := feed.Items[0]
+	if first.Title != "v1.2" || first.Link != "https://example.com/v1.2" || first.Published != "2024-05-09T08:00:00Z" {
+		t.Errorf("Unexpected first entry: %+v", first)
+	}
+	if feed.Items[1].Published != "2024-05-02T10:00:00Z" {
+		t.Errorf("Expected updated to be used without published, got %+v", feed.Items[1])
+	}
+}
+
+func TestParseFeed_RDF(t *testing.T) {
+	feed, err := parseFeed([]byte(testRDF))
+	if err != nil {
+		t.Fatalf("parseFeed() error: %v", err)
+	}
+	if len(feed.Items) != 1 || feed.Items[0].Published != "2024-05-01T00:00:00Z" {
+		t.Errorf("Unexpected RDF items: %+v", feed.Items)
+	}
+}
+
+func TestParseFeed_NotAFeed(t *testing.T) {
+	if _, err := parseFeed([]byte("<html><body>hi</body></html>")); err == nil {
+		t.Error("Expected error for HTML document")
+	}
+	if _, err := parseFeed([]byte("plain text")); err == nil {
+		t.Error("Expected error for non-XML body")
+	}
+}
+
+func TestItemsSince(t *testing.T) {
+	feed, _ := parseFeed([]byte(testRSS))
+	since := time.Date(2024, 5, 7, 0, 0, 0, 0, time.UTC)
+
+	items, undated := itemsSince(feed.Items, since)
+	if len(items) != 1 || items[0].ID != "new" {
+		t.Errorf("Expected only the newer item, got %+v", items)
+	}
+	if undated != 1 {
+		t.Errorf("undated = %d, want 1", undated)
+	}
+}
@@ -5,44 +5,17 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 )
 
-// validatePath ensures the given path is within the workspace if restrict is true.
-func validatePath(path, workspace string, restrict bool) (string, error) {
-	if workspace == "" {
-		return path, nil
-	}
-
-	absWorkspace, err := filepath.Abs(workspace)
-	if err != nil {
-		return "", fmt.Errorf("failed to resolve workspace path: %w", err)
-	}
-
-	var absPath string
-	if filepath.IsAbs(path) {
-		absPath = filepath.Clean(path)
-	} else {
-		absPath, err = filepath.Abs(filepath.Join(absWorkspace, path))
-		if err != nil {
-			return "", fmt.Errorf("failed to resolve file path: %w", err)
-		}
-	}
-
-	if restrict && !strings.HasPrefix(absPath, absWorkspace) {
-		return "", fmt.Errorf("access denied: path is outside the workspace")
-	}
-
-	return absPath, nil
-}
-
 type ReadFileTool struct {
-	workspace string
-	restrict  bool
+	workspace *Workspace
 }
 
-func NewReadFileTool(workspace string, restrict bool) *ReadFileTool {
-	return &ReadFileTool{workspace: workspace, restrict: restrict}
+func NewReadFileTool(workspace *Workspace) *ReadFileTool {
+	return &ReadFileTool{workspace: workspace}
 }
 
 func (t *ReadFileTool) Name() string {
@@ -61,6 +34,10 @@ func (t *ReadFileTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Path to the file to read",
 			},
+			"line_numbers": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Prefix each line with its 1-based line number, e.g. to plan an edit",
+			},
 		},
 		"required": []string{"path"},
 	}
@@ -72,7 +49,7 @@ func (t *ReadFileTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult("path is required")
 	}
 
-	resolvedPath, err := validatePath(path, t.workspace, t.restrict)
+	resolvedPath, err := t.workspace.Resolve(path)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
@@ -82,16 +59,34 @@ func (t *ReadFileTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
 	}
 
+	if lineNumbers, _ := args["line_numbers"].(bool); lineNumbers {
+		return NewToolResult(numberLines(string(content)))
+	}
 	return NewToolResult(string(content))
 }
 
+// numberLines prefixes each line of content with its 1-based number,
+// right-aligned to the width of the largest one and followed by a tab.
+func numberLines(content string) string {
+	if content == "" {
+		return ""
+	}
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	width := len(fmt.Sprint(len(lines)))
+
+	var sb strings.Builder
+	for i, line := range lines {
+		fmt.Fprintf(&sb, "%*d\t%s\n", width, i+1, line)
+	}
+	return sb.String()
+}
+
 type WriteFileTool struct {
-	workspace string
-	restrict  bool
+	workspace *Workspace
 }
 
-func NewWriteFileTool(workspace string, restrict bool) *WriteFileTool {
-	return &WriteFileTool{workspace: workspace, restrict: restrict}
+func NewWriteFileTool(workspace *Workspace) *WriteFileTool {
+	return &WriteFileTool{workspace: workspace}
 }
 
 func (t *WriteFileTool) Name() string {
@@ -114,6 +109,19 @@ func (t *WriteFileTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Content to write to the file",
 			},
+			"mode": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional octal file permissions, e.g. \"0755\". Default: keep the existing file's permissions, or 0644 for a new file",
+			},
+			"line_ending": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"preserve", "lf", "crlf"},
+				"description": "Line endings to write: preserve (default, content exactly as given), lf (convert to \\n) or crlf (convert to \\r\\n)",
+			},
+			"dry_run": map[string]interface{}{
+				"type":        "boolean",
+				"description": "If true, validate arguments and report what would be written without modifying the file",
+			},
 		},
 		"required": []string{"path", "content"},
 	}
@@ -130,30 +138,164 @@ func (t *WriteFileTool) Execute(ctx context.Context, args map[string]interface{}
 		return ErrorResult("content is required")
 	}
 
-	resolvedPath, err := validatePath(path, t.workspace, t.restrict)
+	resolvedPath, err := t.workspace.Resolve(path)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
 
+	mode, hasMode, err := parseFileMode(args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	lineEnding, err := parseLineEnding(args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	content = applyLineEnding(content, lineEnding)
+
+	if isDryRun(args) {
+		action := "create"
+		if _, err := os.Stat(resolvedPath); err == nil {
+			action = "overwrite"
+		}
+		return SilentResult(fmt.Sprintf("[dry run] Would %s %s with %d bytes", action, path, len(content)))
+	}
+
 	dir := filepath.Dir(resolvedPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to create directory: %v", err))
 	}
 
-	if err := os.WriteFile(resolvedPath, []byte(content), 0644); err != nil {
+	// An overwritten file keeps its permissions unless mode is given.
+	if !hasMode {
+		mode = 0644
+		if info, err := os.Stat(resolvedPath); err == nil {
+			mode = info.Mode().Perm()
+		}
+	}
+	if err := writeFileAtomic(resolvedPath, []byte(content), mode); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to write file: %v", err))
 	}
 
 	return SilentResult(fmt.Sprintf("File written: %s", path))
 }
 
+// writeFileAtomic writes data to a temp file next to path and renames it into
+// place, so an interrupted write never leaves a partial file. A symlink at
+// path is followed and its target replaced.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := tmpFile.Name()
+	cleanup := true
+	defer func() {
+		if cleanup {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Chmod(perm); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	cleanup = false
+	return nil
+}
+
+// parseFileMode reads the optional octal "mode" argument, e.g. "0755".
+func parseFileMode(args map[string]interface{}) (os.FileMode, bool, error) {
+	raw, ok := args["mode"]
+	if !ok || raw == nil {
+		return 0, false, nil
+	}
+	s, ok := raw.(string)
+	if !ok {
+		return 0, false, fmt.Errorf("mode must be an octal string such as \"0644\"")
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false, nil
+	}
+	n, err := strconv.ParseUint(strings.TrimPrefix(s, "0o"), 8, 32)
+	if err != nil || n > 0777 {
+		return 0, false, fmt.Errorf("invalid mode %q: must be octal permissions between 0000 and 0777", s)
+	}
+	return os.FileMode(n), true, nil
+}
+
+// Line ending modes for the line_ending argument of the file writing tools.
+const (
+	lineEndingPreserve = "preserve"
+	lineEndingLF       = "lf"
+	lineEndingCRLF     = "crlf"
+)
+
+// parseLineEnding reads the optional "line_ending" argument, defaulting to
+// lineEndingPreserve.
+func parseLineEnding(args map[string]interface{}) (string, error) {
+	raw, ok := args["line_ending"]
+	if !ok || raw == nil {
+		return lineEndingPreserve, nil
+	}
+	s, _ := raw.(string)
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", lineEndingPreserve:
+		return lineEndingPreserve, nil
+	case lineEndingLF:
+		return lineEndingLF, nil
+	case lineEndingCRLF:
+		return lineEndingCRLF, nil
+	}
+	return "", fmt.Errorf("invalid line_ending %v (valid: preserve, lf, crlf)", raw)
+}
+
+// applyLineEnding converts every line break in content, whether \r\n, \n or
+// a lone \r, to the given ending. lineEndingPreserve returns content as is.
+func applyLineEnding(content, ending string) string {
+	if ending == lineEndingPreserve {
+		return content
+	}
+	lf := strings.ReplaceAll(content, "\r\n", "\n")
+	lf = strings.ReplaceAll(lf, "\r", "\n")
+	if ending == lineEndingCRLF {
+		return strings.ReplaceAll(lf, "\n", "\r\n")
+	}
+	return lf
+}
+
+// defaultListDirLimit caps list_dir output so huge directories do not flood
+// the context.
+const defaultListDirLimit = 200
+
 type ListDirTool struct {
-	workspace string
-	restrict  bool
+	workspace *Workspace
 }
 
-func NewListDirTool(workspace string, restrict bool) *ListDirTool {
-	return &ListDirTool{workspace: workspace, restrict: restrict}
+func NewListDirTool(workspace *Workspace) *ListDirTool {
+	return &ListDirTool{workspace: workspace}
 }
 
 func (t *ListDirTool) Name() string {
@@ -161,7 +303,7 @@ func (t *ListDirTool) Name() string {
 }
 
 func (t *ListDirTool) Description() string {
-	return "List files and directories in a path"
+	return "List files and directories in a path. Large listings are truncated; use filter, sort and limit to narrow them."
 }
 
 func (t *ListDirTool) Parameters() map[string]interface{} {
@@ -172,6 +314,19 @@ func (t *ListDirTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Path to list",
 			},
+			"filter": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional glob matched against entry names, e.g. \"*.log\"",
+			},
+			"sort": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"name", "mtime", "size"},
+				"description": "Sort order: name (default), mtime (newest first) or size (largest first)",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of entries to return (default %d)", defaultListDirLimit),
+			},
 		},
 		"required": []string{"path"},
 	}
@@ -183,7 +338,28 @@ func (t *ListDirTool) Execute(ctx context.Context, args map[string]interface{})
 		path = "."
 	}
 
-	resolvedPath, err := validatePath(path, t.workspace, t.restrict)
+	filter, _ := args["filter"].(string)
+	if filter != "" {
+		if _, err := filepath.Match(filter, ""); err != nil {
+			return ErrorResult(fmt.Sprintf("invalid filter %q: %v", filter, err))
+		}
+	}
+
+	sortBy, _ := args["sort"].(string)
+	switch sortBy {
+	case "":
+		sortBy = "name"
+	case "name", "mtime", "size":
+	default:
+		return ErrorResult(fmt.Sprintf("invalid sort %q: must be name, mtime or size", sortBy))
+	}
+
+	limit := defaultListDirLimit
+	if v, ok := args["limit"].(float64); ok && v > 0 {
+		limit = int(v)
+	}
+
+	resolvedPath, err := t.workspace.Resolve(path)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
@@ -193,14 +369,53 @@ func (t *ListDirTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult(fmt.Sprintf("failed to read directory: %v", err))
 	}
 
-	result := ""
-	for _, entry := range entries {
+	if filter != "" {
+		matched := entries[:0]
+		for _, entry := range entries {
+			if ok, _ := filepath.Match(filter, entry.Name()); ok {
+				matched = append(matched, entry)
+			}
+		}
+		entries = matched
+	}
+
+	if sortBy != "name" {
+		sortDirEntries(entries, sortBy)
+	}
+
+	var sb strings.Builder
+	for i, entry := range entries {
+		if i == limit {
+			fmt.Fprintf(&sb, "...and %d more\n", len(entries)-limit)
+			break
+		}
 		if entry.IsDir() {
-			result += "DIR:  " + entry.Name() + "\n"
+			sb.WriteString("DIR:  " + entry.Name() + "\n")
 		} else {
-			result += "FILE: " + entry.Name() + "\n"
+			sb.WriteString("FILE: " + entry.Name() + "\n")
 		}
 	}
 
-	return NewToolResult(result)
+	return NewToolResult(sb.String())
+}
+
+// sortDirEntries orders entries newest first ("mtime") or largest first
+// ("size"). os.ReadDir already returns entries sorted by name, which breaks
+// ties. Entries whose info cannot be read sort last.
+func sortDirEntries(entries []os.DirEntry, by string) {
+	keys := make(map[string]int64, len(entries))
+	for _, entry := range entries {
+		key := int64(-1 << 63)
+		if info, err := entry.Info(); err == nil {
+			if by == "mtime" {
+				key = info.ModTime().UnixNano()
+			} else {
+				key = info.Size()
+			}
+		}
+		keys[entry.Name()] = key
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return keys[entries[i].Name()] > keys[entries[j].Name()]
+	})
 }
@@ -2,10 +2,12 @@ package tools
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestFilesystemTool_ReadFile_Success verifies successful file reading
@@ -39,6 +41,33 @@ func TestFilesystemTool_ReadFile_Success(t *testing.T) {
 	}
 }
 
+// TestFilesystemTool_ReadFile_LineNumbers verifies line_numbers prefixes right-aligned numbers
+func TestFilesystemTool_ReadFile_LineNumbers(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.txt")
+	lines := make([]string, 10)
+	for i := range lines {
+		lines[i] = "line"
+	}
+	os.WriteFile(testFile, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+
+	tool := &ReadFileTool{}
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path":         testFile,
+		"line_numbers": true,
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got IsError=true: %s", result.ForLLM)
+	}
+
+	got := strings.Split(result.ForLLM, "\n")
+	if len(got) != 11 || got[10] != "" {
+		t.Fatalf("Expected 10 numbered lines, got %q", result.ForLLM)
+	}
+	if got[0] != " 1\tline" || got[9] != "10\tline" {
+		t.Errorf("Expected right-aligned numbers, got %q and %q", got[0], got[9])
+	}
+}
+
 // TestFilesystemTool_ReadFile_NotFound verifies error handling for missing file
 func TestFilesystemTool_ReadFile_NotFound(t *testing.T) {
 	tool := &ReadFileTool{}
@@ -147,6 +176,134 @@ func TestFilesystemTool_WriteFile_CreateDir(t *testing.T) {
 	}
 }
 
+// TestFilesystemTool_WriteFile_DryRun verifies dry run does not create the file
+func TestFilesystemTool_WriteFile_DryRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "newfile.txt")
+
+	tool := &WriteFileTool{}
+	ctx := context.Background()
+	args := map[string]interface{}{
+		"path":    testFile,
+		"content": "hello",
+		"dry_run": true,
+	}
+
+	result := tool.Execute(ctx, args)
+
+	if result.IsError {
+		t.Errorf("Expected success, got IsError=true: %s", result.ForLLM)
+	}
+
+	if !strings.Contains(result.ForLLM, "Would create") {
+		t.Errorf("Expected dry run description, got: %s", result.ForLLM)
+	}
+
+	if _, err := os.Stat(testFile); !os.IsNotExist(err) {
+		t.Errorf("Expected file not to be created in dry run")
+	}
+}
+
+// TestFilesystemTool_WriteFile_Mode verifies the mode argument and that
+// overwriting keeps existing permissions
+func TestFilesystemTool_WriteFile_Mode(t *testing.T) {
+	tmpDir := t.TempDir()
+	script := filepath.Join(tmpDir, "run.sh")
+
+	tool := &WriteFileTool{}
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{"path": script, "content": "#!/bin/sh\n", "mode": "0755"})
+	if result.IsError {
+		t.Fatalf("Expected success, got IsError=true: %s", result.ForLLM)
+	}
+	if info, _ := os.Stat(script); info.Mode().Perm() != 0755 {
+		t.Errorf("Expected mode 0755, got %o", info.Mode().Perm())
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"path": script, "content": "#!/bin/sh\necho hi\n"})
+	if result.IsError {
+		t.Fatalf("Expected success, got IsError=true: %s", result.ForLLM)
+	}
+	if info, _ := os.Stat(script); info.Mode().Perm() != 0755 {
+		t.Errorf("Expected overwrite to keep mode 0755, got %o", info.Mode().Perm())
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"path": script, "content": "x", "mode": "rwx"})
+	if !result.IsError {
+		t.Errorf("Expected error for invalid mode")
+	}
+}
+
+// TestWriteFileAtomic verifies the file is replaced in one step without
+// leaving temp files behind, and that symlinks are written through
+func TestWriteFileAtomic(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "config.json")
+	link := filepath.Join(tmpDir, "link.json")
+	os.WriteFile(target, []byte("old"), 0600)
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	if err := writeFileAtomic(link, []byte("new"), 0600); err != nil {
+		t.Fatalf("writeFileAtomic error = %v", err)
+	}
+
+	if content, _ := os.ReadFile(target); string(content) != "new" {
+		t.Errorf("Expected target content 'new', got %q", content)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected link to remain a symlink")
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %o", info.Mode().Perm())
+	}
+
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 2 {
+		t.Errorf("Expected no temp files left, got %d entries", len(entries))
+	}
+}
+
+// TestFilesystemTool_WriteFile_LineEnding verifies line_ending normalization
+func TestFilesystemTool_WriteFile_LineEnding(t *testing.T) {
+	tmpDir := t.TempDir()
+	tool := NewWriteFileTool(NewWorkspace(tmpDir, true))
+	mixed := "a\r\nb\nc\rd"
+
+	tests := []struct {
+		lineEnding interface{}
+		want       string
+	}{
+		{nil, mixed},
+		{"preserve", mixed},
+		{"lf", "a\nb\nc\nd"},
+		{"CRLF", "a\r\nb\r\nc\r\nd"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(tmpDir, fmt.Sprintf("%v.conf", tt.lineEnding))
+		args := map[string]interface{}{"path": path, "content": mixed}
+		if tt.lineEnding != nil {
+			args["line_ending"] = tt.lineEnding
+		}
+		if result := tool.Execute(context.Background(), args); result.IsError {
+			t.Fatalf("line_ending %v: %s", tt.lineEnding, result.ForLLM)
+		}
+		data, _ := os.ReadFile(path)
+		if string(data) != tt.want {
+			t.Errorf("line_ending %v: wrote %q, want %q", tt.lineEnding, data, tt.want)
+		}
+	}
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path": filepath.Join(tmpDir, "x"), "content": "x", "line_ending": "cr",
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "line_ending") {
+		t.Errorf("Expected invalid line_ending error, got %+v", result)
+	}
+}
+
 // TestFilesystemTool_WriteFile_MissingPath verifies error handling for missing path
 func TestFilesystemTool_WriteFile_MissingPath(t *testing.T) {
 	tool := &WriteFileTool{}
@@ -213,6 +370,50 @@ func TestFilesystemTool_ListDir_Success(t *testing.T) {
 	}
 }
 
+// TestFilesystemTool_ListDir_Truncates verifies limit and the truncation marker
+func TestFilesystemTool_ListDir_Truncates(t *testing.T) {
+	tmpDir := t.TempDir()
+	for i := 0; i < 5; i++ {
+		os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("f%d.txt", i)), []byte("x"), 0644)
+	}
+
+	tool := &ListDirTool{}
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": tmpDir, "limit": float64(2)})
+
+	want := "FILE: f0.txt\nFILE: f1.txt\n...and 3 more\n"
+	if result.IsError || result.ForLLM != want {
+		t.Errorf("Expected %q, got %q", want, result.ForLLM)
+	}
+}
+
+// TestFilesystemTool_ListDir_FilterAndSort verifies glob filtering and size/mtime ordering
+func TestFilesystemTool_ListDir_FilterAndSort(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "a.log"), []byte("1"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "b.log"), []byte("12345"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "c.txt"), []byte("123"), 0644)
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(tmpDir, "b.log"), old, old)
+
+	tool := &ListDirTool{}
+	ctx := context.Background()
+
+	bySize := tool.Execute(ctx, map[string]interface{}{"path": tmpDir, "filter": "*.log", "sort": "size"})
+	if want := "FILE: b.log\nFILE: a.log\n"; bySize.ForLLM != want {
+		t.Errorf("Expected %q sorted by size, got %q", want, bySize.ForLLM)
+	}
+
+	byMtime := tool.Execute(ctx, map[string]interface{}{"path": tmpDir, "filter": "*.log", "sort": "mtime"})
+	if want := "FILE: a.log\nFILE: b.log\n"; byMtime.ForLLM != want {
+		t.Errorf("Expected %q sorted by mtime, got %q", want, byMtime.ForLLM)
+	}
+
+	bad := tool.Execute(ctx, map[string]interface{}{"path": tmpDir, "filter": "["})
+	if !bad.IsError {
+		t.Errorf("Expected error for invalid filter, got %q", bad.ForLLM)
+	}
+}
+
 // TestFilesystemTool_ListDir_NotFound verifies error handling for non-existent directory
 func TestFilesystemTool_ListDir_NotFound(t *testing.T) {
 	tool := &ListDirTool{}
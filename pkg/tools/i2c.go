@@ -4,16 +4,50 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// I2CToolOptions configures an I2CTool.
+type I2CToolOptions struct {
+	DeviceNames  map[string]string // Friendly names keyed by address ("0x38" or "56")
+	InventoryTTL time.Duration     // How long the bus list from detect is cached
+	AllowedBuses []string          // Bus numbers the tool may open; empty allows all
+}
+
 // I2CTool provides I2C bus interaction for reading sensors and controlling peripherals.
-type I2CTool struct{}
+type I2CTool struct {
+	inventory *deviceInventory
+	names     map[int]string
+	allowed   map[string]bool // nil allows every bus
+}
 
-func NewI2CTool() *I2CTool {
-	return &I2CTool{}
+func NewI2CTool(opts I2CToolOptions) *I2CTool {
+	return &I2CTool{
+		inventory: newDeviceInventory("/dev/i2c-*", opts.InventoryTTL),
+		names:     parseI2CDeviceNames(opts.DeviceNames),
+		allowed:   parseDeviceAllowlist(opts.AllowedBuses, isValidBusID),
+	}
+}
+
+// parseI2CDeviceNames keys configured names by numeric address. Keys may be
+// hex ("0x38"), octal ("070") or decimal ("56"); invalid or out-of-range
+// addresses and empty names are skipped.
+func parseI2CDeviceNames(raw map[string]string) map[int]string {
+	names := make(map[int]string, len(raw))
+	for key, name := range raw {
+		addr, err := strconv.ParseInt(strings.TrimSpace(key), 0, 0)
+		if err != nil || addr < 0x03 || addr > 0x77 || name == "" {
+			continue
+		}
+		names[int(addr)] = name
+	}
+	return names
 }
 
 func (t *I2CTool) Name() string {
@@ -21,7 +55,7 @@ func (t *I2CTool) Name() string {
 }
 
 func (t *I2CTool) Description() string {
-	return "Interact with I2C bus devices for reading sensors and controlling peripherals. Actions: detect (list buses), scan (find devices on a bus), read (read bytes from device), write (send bytes to device). Linux only."
+	return "Interact with I2C bus devices for reading sensors and controlling peripherals. Actions: detect (list buses and capabilities), scan (find devices on a bus), identify (scan and guess what each device is), read (read bytes from device), write (send bytes to device), dump (read a register range as a hex table), monitor (sample a register repeatedly). Linux only."
 }
 
 func (t *I2CTool) Parameters() map[string]interface{} {
@@ -30,16 +64,20 @@ func (t *I2CTool) Parameters() map[string]interface{} {
 		"properties": map[string]interface{}{
 			"action": map[string]interface{}{
 				"type":        "string",
-				"enum":        []string{"detect", "scan", "read", "write"},
-				"description": "Action to perform: detect (list available I2C buses), scan (find devices on a bus), read (read bytes from a device), write (send bytes to a device)",
+				"enum":        []string{"detect", "scan", "identify", "read", "write", "dump", "monitor"},
+				"description": "Action to perform: detect (list available I2C buses with their adapter capabilities), scan (find devices on a bus, optionally as an i2cdetect-style grid), identify (scan, then list likely chips for each address found from a built-in table of common devices; guesses only, confirm with a register read), read (read bytes from a device), write (send bytes to a device), dump (read registers start..end like i2cdump), monitor (read a register at a fixed interval and return the series)",
 			},
 			"bus": map[string]interface{}{
 				"type":        "string",
-				"description": "I2C bus number (e.g. \"1\" for /dev/i2c-1). Required for scan/read/write.",
+				"description": "I2C bus number (e.g. \"1\" for /dev/i2c-1). Required for scan/identify/read/write/dump/monitor.",
 			},
 			"address": map[string]interface{}{
 				"type":        "integer",
-				"description": "7-bit I2C device address (0x03-0x77). Required for read/write.",
+				"description": "7-bit I2C device address (0x03-0x77), or 10-bit (0x000-0x3FF) with ten_bit. Required for read/write/dump/monitor.",
+			},
+			"ten_bit": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Use 10-bit addressing for read/write/dump/monitor. Default: false. Scan and identify always probe 7-bit addresses.",
 			},
 			"register": map[string]interface{}{
 				"type":        "integer",
@@ -52,12 +90,41 @@ func (t *I2CTool) Parameters() map[string]interface{} {
 			},
 			"length": map[string]interface{}{
 				"type":        "integer",
-				"description": "Number of bytes to read (1-256). Default: 1. Used with read action.",
+				"description": "Number of bytes to read (1-256). Default: 1. Used with read and monitor actions (monitor allows at most 32).",
+			},
+			"delay_ms": map[string]interface{}{
+				"type":        "integer",
+				"description": "Milliseconds to wait between writing register and reading the result (0-1000). Default: 0. Used with read action for sensors that need measurement time after a command (e.g. AHT20, SHT3x).",
+			},
+			"start": map[string]interface{}{
+				"type":        "integer",
+				"description": "First register to dump (0x00-0xFF). Default: 0x00. Used with dump action.",
+			},
+			"end": map[string]interface{}{
+				"type":        "integer",
+				"description": "Last register to dump, inclusive (0x00-0xFF). Default: 0xFF. Used with dump action.",
+			},
+			"interval_ms": map[string]interface{}{
+				"type":        "integer",
+				"description": "Milliseconds between samples (10-10000). Default: 1000. Used with monitor action.",
+			},
+			"count": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of samples to take (1-100). Default: 10. Total monitor time is capped at 60 seconds.",
+			},
+			"output": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"json", "grid"},
+				"description": "Scan result format: json (list of found devices, default) or grid (i2cdetect-style address table where -- is no device, UU is in use by a kernel driver, ?? is a probe that timed out).",
 			},
 			"confirm": map[string]interface{}{
 				"type":        "boolean",
 				"description": "Must be true for write operations. Safety guard to prevent accidental writes.",
 			},
+			"dry_run": map[string]interface{}{
+				"type":        "boolean",
+				"description": "If true, validate a write and report the bytes that would be sent without touching the bus. Does not require confirm.",
+			},
 		},
 		"required": []string{"action"},
 	}
@@ -78,18 +145,24 @@ func (t *I2CTool) Execute(ctx context.Context, args map[string]interface{}) *Too
 		return t.detect()
 	case "scan":
 		return t.scan(args)
+	case "identify":
+		return t.identify(args)
 	case "read":
-		return t.readDevice(args)
+		return t.readDevice(ctx, args)
 	case "write":
 		return t.writeDevice(args)
+	case "dump":
+		return t.dump(args)
+	case "monitor":
+		return t.monitor(ctx, args)
 	default:
-		return ErrorResult(fmt.Sprintf("unknown action: %s (valid: detect, scan, read, write)", action))
+		return ErrorResult(fmt.Sprintf("unknown action: %s (valid: detect, scan, identify, read, write, dump, monitor)", action))
 	}
 }
 
 // detect lists available I2C buses by globbing /dev/i2c-*
 func (t *I2CTool) detect() *ToolResult {
-	matches, err := filepath.Glob("/dev/i2c-*")
+	matches, err := t.inventory.Paths()
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to scan for I2C buses: %v", err))
 	}
@@ -99,20 +172,99 @@ func (t *I2CTool) detect() *ToolResult {
 	}
 
 	type busInfo struct {
-		Path string `json:"path"`
-		Bus  string `json:"bus"`
+		Path         string   `json:"path"`
+		Bus          string   `json:"bus"`
+		Functions    string   `json:"functions,omitempty"`
+		Capabilities []string `json:"capabilities,omitempty"`
+		Error        string   `json:"error,omitempty"`
 	}
 
 	buses := make([]busInfo, 0, len(matches))
 	re := regexp.MustCompile(`/dev/i2c-(\d+)`)
 	for _, m := range matches {
 		if sub := re.FindStringSubmatch(m); sub != nil {
-			buses = append(buses, busInfo{Path: m, Bus: sub[1]})
+			info := busInfo{Path: m, Bus: sub[1]}
+			if funcs, err := queryI2CFuncs(m); err != nil {
+				info.Error = fmt.Sprintf("failed to query capabilities: %v", err)
+			} else {
+				info.Functions = fmt.Sprintf("0x%08x", funcs)
+				info.Capabilities = decodeI2CFuncs(funcs)
+			}
+			buses = append(buses, info)
 		}
 	}
 
 	result, _ := json.MarshalIndent(buses, "", "  ")
-	return SilentResult(fmt.Sprintf("Found %d I2C bus(es):\n%s", len(buses), string(result)))
+	output := fmt.Sprintf("Found %d I2C bus(es):\n%s", len(buses), string(result))
+	if configured := t.configuredDevices(); len(configured) > 0 {
+		devices, _ := json.MarshalIndent(configured, "", "  ")
+		output += fmt.Sprintf("\nConfigured device names (any bus; use scan to see which are present):\n%s", string(devices))
+	}
+	return SilentResult(output)
+}
+
+// configuredDevice is a friendly name from config, as reported by detect.
+type configuredDevice struct {
+	Address string `json:"address"`
+	Name    string `json:"name"`
+}
+
+// configuredDevices lists the configured device names ordered by address
+func (t *I2CTool) configuredDevices() []configuredDevice {
+	addrs := make([]int, 0, len(t.names))
+	for addr := range t.names {
+		addrs = append(addrs, addr)
+	}
+	sort.Ints(addrs)
+
+	devices := make([]configuredDevice, 0, len(addrs))
+	for _, addr := range addrs {
+		devices = append(devices, configuredDevice{Address: fmt.Sprintf("0x%02x", addr), Name: t.names[addr]})
+	}
+	return devices
+}
+
+// i2cFuncNames maps I2C_FUNC_* bits from <linux/i2c.h> to readable names
+var i2cFuncNames = []struct {
+	bit  uint64
+	name string
+}{
+	{0x00000001, "i2c (plain I2C transfers)"},
+	{0x00000002, "10-bit addressing"},
+	{0x00000004, "protocol mangling"},
+	{0x00000008, "SMBus PEC"},
+	{0x00000010, "no-start"},
+	{0x00000020, "slave mode"},
+	{0x00008000, "SMBus block process call"},
+	{0x00010000, "SMBus quick"},
+	{0x00020000, "SMBus read byte"},
+	{0x00040000, "SMBus write byte"},
+	{0x00080000, "SMBus read byte data"},
+	{0x00100000, "SMBus write byte data"},
+	{0x00200000, "SMBus read word data"},
+	{0x00400000, "SMBus write word data"},
+	{0x00800000, "SMBus process call"},
+	{0x01000000, "SMBus read block data"},
+	{0x02000000, "SMBus write block data"},
+	{0x04000000, "I2C read block"},
+	{0x08000000, "I2C write block"},
+	{0x10000000, "SMBus host notify"},
+}
+
+// decodeI2CFuncs converts an I2C_FUNCS bitmask into capability names
+func decodeI2CFuncs(funcs uint64) []string {
+	caps := make([]string, 0, len(i2cFuncNames))
+	for _, f := range i2cFuncNames {
+		if funcs&f.bit != 0 {
+			caps = append(caps, f.name)
+		}
+	}
+	return caps
+}
+
+// deviceName returns the configured friendly name for addr, if any
+func (t *I2CTool) deviceName(addr int) string {
+	return t.names[addr]
 }
 
 // isValidBusID checks that a bus identifier is a simple number (prevents path injection)
@@ -121,21 +273,35 @@ func isValidBusID(id string) bool {
 	return matched
 }
 
-// parseI2CAddress extracts and validates an I2C address from args
+// parseI2CTenBit reports whether args ask for 10-bit addressing
+func parseI2CTenBit(args map[string]interface{}) bool {
+	tenBit, _ := args["ten_bit"].(bool)
+	return tenBit
+}
+
+// parseI2CAddress extracts and validates an I2C address from args, in the
+// 10-bit range when ten_bit is set and the 7-bit range otherwise
 func parseI2CAddress(args map[string]interface{}) (int, *ToolResult) {
 	addrFloat, ok := args["address"].(float64)
 	if !ok {
 		return 0, ErrorResult("address is required (e.g. 0x38 for AHT20)")
 	}
 	addr := int(addrFloat)
+	if parseI2CTenBit(args) {
+		if addr < 0x000 || addr > 0x3FF {
+			return 0, ErrorResult("address must be in valid 10-bit range (0x000-0x3FF)")
+		}
+		return addr, nil
+	}
 	if addr < 0x03 || addr > 0x77 {
-		return 0, ErrorResult("address must be in valid 7-bit range (0x03-0x77)")
+		return 0, ErrorResult("address must be in valid 7-bit range (0x03-0x77), or set ten_bit for a 10-bit address")
 	}
 	return addr, nil
 }
 
-// parseI2CBus extracts and validates an I2C bus from args
-func parseI2CBus(args map[string]interface{}) (string, *ToolResult) {
+// parseI2CBus extracts and validates an I2C bus from args. A non-nil allowed
+// set rejects buses not listed in it.
+func parseI2CBus(args map[string]interface{}, allowed map[string]bool) (string, *ToolResult) {
 	bus, ok := args["bus"].(string)
 	if !ok || bus == "" {
 		return "", ErrorResult("bus is required (e.g. \"1\" for /dev/i2c-1)")
@@ -143,5 +309,228 @@ func parseI2CBus(args map[string]interface{}) (string, *ToolResult) {
 	if !isValidBusID(bus) {
 		return "", ErrorResult("invalid bus identifier: must be a number (e.g. \"1\")")
 	}
+	if allowed != nil && !allowed[bus] {
+		return "", ErrorResult(fmt.Sprintf("I2C bus %s is not allowed by configuration (allowed buses: %s)", bus, allowlistString(allowed)))
+	}
 	return bus, nil
 }
+
+// Monitor limits keep a single tool call from occupying the bus for too long.
+const (
+	i2cMonitorMaxSamples  = 100
+	i2cMonitorMaxDuration = 60 * time.Second
+	i2cMonitorMaxLength   = 32
+)
+
+// monitorParams holds validated arguments for the monitor action.
+type monitorParams struct {
+	register int
+	length   int
+	interval time.Duration
+	count    int
+}
+
+// parseMonitorParams extracts and validates monitor arguments from args
+func parseMonitorParams(args map[string]interface{}) (monitorParams, *ToolResult) {
+	p := monitorParams{length: 1, interval: time.Second, count: 10}
+
+	regFloat, ok := args["register"].(float64)
+	if !ok {
+		return p, ErrorResult("register is required for monitor")
+	}
+	p.register = int(regFloat)
+	if p.register < 0 || p.register > 255 {
+		return p, ErrorResult("register must be between 0x00 and 0xFF")
+	}
+
+	if l, ok := args["length"].(float64); ok {
+		p.length = int(l)
+	}
+	if p.length < 1 || p.length > i2cMonitorMaxLength {
+		return p, ErrorResult(fmt.Sprintf("length must be between 1 and %d for monitor", i2cMonitorMaxLength))
+	}
+
+	if ms, ok := args["interval_ms"].(float64); ok {
+		if ms < 10 || ms > 10000 {
+			return p, ErrorResult("interval_ms must be between 10 and 10000")
+		}
+		p.interval = time.Duration(ms) * time.Millisecond
+	}
+
+	if c, ok := args["count"].(float64); ok {
+		p.count = int(c)
+	}
+	if p.count < 1 || p.count > i2cMonitorMaxSamples {
+		return p, ErrorResult(fmt.Sprintf("count must be between 1 and %d", i2cMonitorMaxSamples))
+	}
+
+	if total := time.Duration(p.count-1) * p.interval; total > i2cMonitorMaxDuration {
+		return p, ErrorResult(fmt.Sprintf("monitor would run for %v, exceeding the %v limit; reduce count or interval_ms", total, i2cMonitorMaxDuration))
+	}
+
+	return p, nil
+}
+
+// i2cMaxReadDelay caps delay_ms for the read action.
+const i2cMaxReadDelay = time.Second
+
+// parseReadDelay extracts and validates delay_ms for the read action. The
+// delay separates the register write from the read, so it needs a register.
+func parseReadDelay(args map[string]interface{}) (time.Duration, *ToolResult) {
+	ms, ok := args["delay_ms"].(float64)
+	if !ok || ms == 0 {
+		return 0, nil
+	}
+	if ms < 0 || time.Duration(ms)*time.Millisecond > i2cMaxReadDelay {
+		return 0, ErrorResult(fmt.Sprintf("delay_ms must be between 0 and %d", i2cMaxReadDelay.Milliseconds()))
+	}
+	if _, ok := args["register"].(float64); !ok {
+		return 0, ErrorResult("delay_ms requires register")
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// parseRegisterRange extracts and validates the start/end register range for dump
+func parseRegisterRange(args map[string]interface{}) (int, int, *ToolResult) {
+	start, end := 0x00, 0xFF
+	if v, ok := args["start"].(float64); ok {
+		start = int(v)
+	}
+	if v, ok := args["end"].(float64); ok {
+		end = int(v)
+	}
+	if start < 0 || start > 0xFF || end < 0 || end > 0xFF {
+		return 0, 0, ErrorResult("start and end must be between 0x00 and 0xFF")
+	}
+	if start > end {
+		return 0, 0, ErrorResult("start must be less than or equal to end")
+	}
+	return start, end, nil
+}
+
+// formatI2CDump renders register values as an i2cdump-style offset/hex/ascii table.
+// values[i] holds the value of register start+i, or -1 if the read failed.
+func formatI2CDump(start int, values []int) string {
+	var sb strings.Builder
+	sb.WriteString("     0  1  2  3  4  5  6  7  8  9  a  b  c  d  e  f    0123456789abcdef\n")
+
+	end := start + len(values) - 1
+	for row := start &^ 0x0F; row <= end; row += 0x10 {
+		sb.WriteString(fmt.Sprintf("%02x: ", row))
+		var ascii strings.Builder
+		for col := 0; col < 0x10; col++ {
+			reg := row + col
+			if reg < start || reg > end {
+				sb.WriteString("   ")
+				ascii.WriteByte(' ')
+				continue
+			}
+			v := values[reg-start]
+			if v < 0 {
+				sb.WriteString("XX ")
+				ascii.WriteByte('X')
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("%02x ", v))
+			if v >= 0x20 && v < 0x7F {
+				ascii.WriteByte(byte(v))
+			} else {
+				ascii.WriteByte('.')
+			}
+		}
+		sb.WriteString("   ")
+		sb.WriteString(ascii.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// I2C scan range: addresses outside it are reserved and never probed.
+const (
+	i2cScanFirst = 0x08
+	i2cScanLast  = 0x77
+)
+
+// i2cAddrState is the scan outcome for an address that did not simply NACK.
+type i2cAddrState int
+
+const (
+	i2cAddrPresent i2cAddrState = iota // answered the probe
+	i2cAddrBusy                        // owned by a kernel driver
+	i2cAddrTimeout                     // probe did not complete in time
+)
+
+// formatI2CDetectGrid renders scan results as i2cdetect's 16x8 address grid:
+// "--" for no answer, "UU" for a driver-owned address, the address itself
+// for a device that answered and "??" for a probe that timed out. Addresses
+// after last were not probed and are left blank.
+func formatI2CDetectGrid(states map[int]i2cAddrState, last int) string {
+	var sb strings.Builder
+	sb.WriteString("     0  1  2  3  4  5  6  7  8  9  a  b  c  d  e  f\n")
+	for row := 0x00; row <= 0x70; row += 0x10 {
+		sb.WriteString(fmt.Sprintf("%02x: ", row))
+		for col := 0; col < 0x10; col++ {
+			addr := row + col
+			state, ok := states[addr]
+			switch {
+			case addr < i2cScanFirst || addr > last:
+				sb.WriteString("   ")
+			case !ok:
+				sb.WriteString("-- ")
+			case state == i2cAddrBusy:
+				sb.WriteString("UU ")
+			case state == i2cAddrTimeout:
+				sb.WriteString("?? ")
+			default:
+				sb.WriteString(fmt.Sprintf("%02x ", addr))
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Scan probe limits. A healthy device ACKs a probe in well under a
+// millisecond; a probe still running after i2cProbeTimeout is recorded as a
+// timeout. Once the adapter hangs, every later probe queues behind the stuck
+// one, so the scan stops after i2cMaxProbeTimeouts timeouts in a row.
+const (
+	i2cProbeTimeout     = 100 * time.Millisecond
+	i2cMaxProbeTimeouts = 3
+)
+
+// probeWithTimeout runs probe and waits at most timeout for its result. A
+// probe that times out is left to finish in the background and its result
+// is discarded. Each probe is tracked in inflight until it returns, so the
+// caller can wait for stuck probes before closing the fd they use and
+// releasing the bus.
+func probeWithTimeout(timeout time.Duration, inflight *sync.WaitGroup, probe func() bool) (present, timedOut bool) {
+	done := make(chan bool, 1)
+	inflight.Add(1)
+	go func() {
+		defer inflight.Done()
+		done <- probe()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case present := <-done:
+		return present, false
+	case <-timer.C:
+		return false, true
+	}
+}
+
+// parseScanOutput extracts the scan output format: "json" (default) or "grid".
+func parseScanOutput(args map[string]interface{}) (string, *ToolResult) {
+	output, _ := args["output"].(string)
+	switch output {
+	case "":
+		return "json", nil
+	case "json", "grid":
+		return output, nil
+	default:
+		return "", ErrorResult(fmt.Sprintf("invalid output: %s (valid: json, grid)", output))
+	}
+}
@@ -0,0 +1,72 @@
+package tools
+
+// i2cKnownDevice lists chips commonly found at an address range, for the
+// identify action. Addresses are 7-bit and inclusive; most chips appear at
+// their default address and the alternates selectable by address pins.
+type i2cKnownDevice struct {
+	first, last int
+	names       []string
+}
+
+// i2cKnownDevices is a small database of common hobbyist and embedded parts.
+// It is address-based only: many chips share addresses, so the matches are
+// candidates to confirm with a register read, not identifications.
+var i2cKnownDevices = []i2cKnownDevice{
+	{0x0c, 0x0c, []string{"AK8963 magnetometer (inside MPU9250)"}},
+	{0x0d, 0x0d, []string{"QMC5883L magnetometer"}},
+	{0x10, 0x10, []string{"VEML7700 ambient light sensor"}},
+	{0x18, 0x19, []string{"LIS3DH accelerometer"}},
+	{0x18, 0x1f, []string{"MCP9808 temperature sensor"}},
+	{0x1c, 0x1d, []string{"MMA8452Q accelerometer"}},
+	{0x1d, 0x1d, []string{"ADXL345 accelerometer (alternate address)"}},
+	{0x1e, 0x1e, []string{"HMC5883L magnetometer"}},
+	{0x20, 0x27, []string{"PCF8574 I/O expander", "MCP23017/MCP23008 I/O expander"}},
+	{0x23, 0x23, []string{"BH1750 light sensor"}},
+	{0x28, 0x29, []string{"BNO055 IMU"}},
+	{0x29, 0x29, []string{"VL53L0X/VL53L1X distance sensor", "TSL2591 light sensor", "TCS34725 color sensor"}},
+	{0x36, 0x36, []string{"MAX17048 battery fuel gauge"}},
+	{0x38, 0x38, []string{"AHT10/AHT20 temperature and humidity sensor", "FT6x36 touch controller"}},
+	{0x38, 0x3f, []string{"PCF8574A I/O expander"}},
+	{0x39, 0x39, []string{"TSL2561 light sensor", "APDS-9960 gesture sensor"}},
+	{0x3c, 0x3d, []string{"SSD1306/SH1106 OLED display"}},
+	{0x40, 0x40, []string{"HTU21D/Si7021 temperature and humidity sensor", "HDC1080 temperature and humidity sensor", "PCA9685 PWM driver"}},
+	{0x40, 0x4f, []string{"INA219/INA226 current sensor"}},
+	{0x44, 0x45, []string{"SHT3x/SHT4x temperature and humidity sensor"}},
+	{0x48, 0x4b, []string{"ADS1115/ADS1015 ADC", "TMP102 temperature sensor"}},
+	{0x48, 0x4f, []string{"LM75 temperature sensor", "PCF8591 ADC/DAC"}},
+	{0x50, 0x57, []string{"AT24Cxx EEPROM"}},
+	{0x51, 0x51, []string{"PCF8563 RTC"}},
+	{0x53, 0x53, []string{"ADXL345 accelerometer"}},
+	{0x57, 0x57, []string{"MAX30102 pulse oximeter", "AT24C32 EEPROM (on DS3231 modules)"}},
+	{0x5a, 0x5a, []string{"MLX90614 IR thermometer"}},
+	{0x5a, 0x5b, []string{"CCS811 air quality sensor"}},
+	{0x5a, 0x5d, []string{"MPR121 capacitive touch controller"}},
+	{0x5c, 0x5c, []string{"AM2320 temperature and humidity sensor", "BH1750 light sensor (alternate address)"}},
+	{0x60, 0x60, []string{"Si5351 clock generator", "MPL3115A2 pressure sensor", "ATECC608 crypto chip"}},
+	{0x60, 0x65, []string{"MCP4725 DAC"}},
+	{0x61, 0x61, []string{"SCD30 CO2 sensor"}},
+	{0x62, 0x62, []string{"SCD40/SCD41 CO2 sensor"}},
+	{0x68, 0x68, []string{"DS3231/DS1307 RTC", "PCF8523 RTC"}},
+	{0x68, 0x69, []string{"MPU6050/MPU9250 IMU", "ICM-20948 IMU"}},
+	{0x6a, 0x6b, []string{"LSM6DS3/LSM6DSOX IMU"}},
+	{0x6f, 0x6f, []string{"MCP7940N RTC"}},
+	{0x70, 0x77, []string{"TCA9548A I2C multiplexer", "HT16K33 LED matrix driver"}},
+	{0x76, 0x77, []string{"BME280/BMP280 pressure sensor", "BME680 environmental sensor", "MS5611 pressure sensor"}},
+	{0x77, 0x77, []string{"BMP180/BMP085 pressure sensor"}},
+}
+
+// i2cIdentifyNote accompanies identify results so the model treats the
+// candidates as guesses.
+const i2cIdentifyNote = "Candidates are guesses based on the address alone; several chips share each address. Confirm by reading the chip's ID register (see its datasheet) before relying on one."
+
+// i2cCandidates returns the known devices commonly found at addr, in
+// database order, or nil when there are none.
+func i2cCandidates(addr int) []string {
+	var names []string
+	for _, d := range i2cKnownDevices {
+		if addr >= d.first && addr <= d.last {
+			names = append(names, d.names...)
+		}
+	}
+	return names
+}
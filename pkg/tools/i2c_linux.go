@@ -1,19 +1,24 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"syscall"
+	"time"
 	"unsafe"
 )
 
 // I2C ioctl constants from Linux kernel headers (<linux/i2c-dev.h>, <linux/i2c.h>)
 const (
-	i2cSlave = 0x0703 // Set slave address (fails if in use by driver)
-	i2cFuncs = 0x0705 // Query adapter functionality bitmask
-	i2cSmbus = 0x0720 // Perform SMBus transaction
+	i2cSlave  = 0x0703 // Set slave address (fails if in use by driver)
+	i2cTenBit = 0x0704 // Use 10-bit addresses (0 for 7-bit)
+	i2cFuncs  = 0x0705 // Query adapter functionality bitmask
+	i2cSmbus  = 0x0720 // Perform SMBus transaction
 
 	// I2C_FUNC capability bits
+	i2cFunc10BitAddr     = 0x00000002
 	i2cFuncSmbusQuick    = 0x00010000
 	i2cFuncSmbusReadByte = 0x00020000
 
@@ -22,8 +27,9 @@ const (
 	i2cSmbusWrite = 1
 
 	// SMBus protocol sizes
-	i2cSmbusQuick = 0
-	i2cSmbusByte  = 1
+	i2cSmbusQuick    = 0
+	i2cSmbusByte     = 1
+	i2cSmbusByteData = 2
 )
 
 // i2cSmbusData matches the kernel union i2c_smbus_data (34 bytes max).
@@ -71,79 +77,250 @@ func smbusProbe(fd int, addr int, hasQuick bool) bool {
 	return errno == 0
 }
 
+// smbusReadByteData reads a single register using an SMBus Read Byte Data transaction:
+// [START] [ADDR|W] [REG] [RESTART] [ADDR|R] [DATA] [STOP]
+func smbusReadByteData(fd int, reg byte) (byte, error) {
+	var data i2cSmbusData
+	args := i2cSmbusArgs{
+		readWrite: i2cSmbusRead,
+		command:   reg,
+		size:      i2cSmbusByteData,
+		data:      &data,
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), i2cSmbus, uintptr(unsafe.Pointer(&args)))
+	if errno != 0 {
+		return 0, errno
+	}
+	return data[0], nil
+}
+
+// queryI2CFuncs returns the adapter functionality bitmask for a bus device
+func queryI2CFuncs(devPath string) (uint64, error) {
+	fd, err := syscall.Open(devPath, syscall.O_RDWR, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer syscall.Close(fd)
+
+	// I2C_FUNCS writes an unsigned long, which is word-sized on Linux.
+	var funcs uintptr
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), i2cFuncs, uintptr(unsafe.Pointer(&funcs)))
+	if errno != 0 {
+		return 0, errno
+	}
+	return uint64(funcs), nil
+}
+
+// openI2CDevice opens the bus at devPath and selects the device at addr,
+// switching the descriptor to 10-bit addressing first when tenBit is set.
+// The caller closes the returned descriptor.
+func openI2CDevice(devPath string, addr int, tenBit bool) (int, *ToolResult) {
+	fd, err := syscall.Open(devPath, syscall.O_RDWR, 0)
+	if err != nil {
+		return -1, ErrorResult(fmt.Sprintf("failed to open %s: %v", devPath, err))
+	}
+
+	if tenBit {
+		// The kernel accepts I2C_TENBIT on any adapter; check support here so
+		// the failure names the cause instead of surfacing on the first transfer
+		var funcs uintptr
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), i2cFuncs, uintptr(unsafe.Pointer(&funcs)))
+		if errno != 0 {
+			syscall.Close(fd)
+			return -1, ErrorResult(fmt.Sprintf("failed to query I2C adapter capabilities on %s: %v", devPath, errno))
+		}
+		if funcs&i2cFunc10BitAddr == 0 {
+			syscall.Close(fd)
+			return -1, ErrorResult(fmt.Sprintf("I2C adapter %s does not support 10-bit addressing", devPath))
+		}
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), i2cTenBit, 1); errno != 0 {
+			syscall.Close(fd)
+			return -1, ErrorResult(fmt.Sprintf("failed to enable 10-bit addressing on %s: %v", devPath, errno))
+		}
+	}
+
+	// Set slave address
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), i2cSlave, uintptr(addr))
+	if errno != 0 {
+		syscall.Close(fd)
+		return -1, ErrorResult(fmt.Sprintf("failed to set I2C address 0x%02x: %v", addr, errno))
+	}
+	return fd, nil
+}
+
+// i2cScanDevice is an address that answered, or could not be probed, in a
+// bus scan.
+type i2cScanDevice struct {
+	Address    string   `json:"address"`
+	Name       string   `json:"name,omitempty"`
+	Status     string   `json:"status,omitempty"`
+	Candidates []string `json:"candidates,omitempty"`
+	addr       int
+}
+
+// i2cBusScan is the outcome of probeBus.
+type i2cBusScan struct {
+	devPath string
+	found   []i2cScanDevice
+	states  map[int]i2cAddrState
+	last    int    // last address probed
+	stopped string // why the scan ended before i2cScanLast, if it did
+}
+
 // scan probes valid 7-bit addresses on a bus for connected devices.
-// Uses the same hybrid probe strategy as i2cdetect's MODE_AUTO:
-// SMBus Quick Write for most addresses, SMBus Read Byte for EEPROM ranges.
 func (t *I2CTool) scan(args map[string]interface{}) *ToolResult {
-	bus, errResult := parseI2CBus(args)
+	bus, errResult := parseI2CBus(args, t.allowed)
 	if errResult != nil {
 		return errResult
 	}
+	output, errResult := parseScanOutput(args)
+	if errResult != nil {
+		return errResult
+	}
+
+	res, errResult := t.probeBus(bus)
+	if errResult != nil {
+		return errResult
+	}
+	devPath := res.devPath
+
+	if output == "grid" {
+		text := fmt.Sprintf("Scan of %s:\n%s", devPath, formatI2CDetectGrid(res.states, res.last))
+		for _, d := range res.found {
+			if d.Name != "" {
+				text += fmt.Sprintf("%s: %s\n", d.Address, d.Name)
+			}
+		}
+		if res.stopped != "" {
+			text += res.stopped + "\n"
+		}
+		return SilentResult(text)
+	}
+
+	if len(res.found) == 0 {
+		return SilentResult(fmt.Sprintf("No devices found on %s. Check wiring and pull-up resistors.", devPath))
+	}
+
+	summary := map[string]interface{}{
+		"bus":     devPath,
+		"devices": res.found,
+		"count":   len(res.found),
+	}
+	if res.stopped != "" {
+		summary["stopped"] = res.stopped
+	}
+	result, _ := json.MarshalIndent(summary, "", "  ")
+	return SilentResult(fmt.Sprintf("Scan of %s:\n%s", devPath, string(result))).WithData(summary)
+}
+
+// identify scans a bus and lists, for each device found, the known chips
+// commonly at its address. The candidates are address-based guesses.
+func (t *I2CTool) identify(args map[string]interface{}) *ToolResult {
+	bus, errResult := parseI2CBus(args, t.allowed)
+	if errResult != nil {
+		return errResult
+	}
+
+	res, errResult := t.probeBus(bus)
+	if errResult != nil {
+		return errResult
+	}
+	if len(res.found) == 0 {
+		return SilentResult(fmt.Sprintf("No devices found on %s. Check wiring and pull-up resistors.", res.devPath))
+	}
+
+	for i := range res.found {
+		res.found[i].Candidates = i2cCandidates(res.found[i].addr)
+	}
+	summary := map[string]interface{}{
+		"bus":     res.devPath,
+		"devices": res.found,
+		"count":   len(res.found),
+		"note":    i2cIdentifyNote,
+	}
+	if res.stopped != "" {
+		summary["stopped"] = res.stopped
+	}
+	result, _ := json.MarshalIndent(summary, "", "  ")
+	return SilentResult(fmt.Sprintf("Likely devices on %s (guesses from address only):\n%s", res.devPath, string(result))).WithData(summary)
+}
 
+// probeBus probes every address on a bus.
+// Uses the same hybrid probe strategy as i2cdetect's MODE_AUTO:
+// SMBus Quick Write for most addresses, SMBus Read Byte for EEPROM ranges.
+func (t *I2CTool) probeBus(bus string) (*i2cBusScan, *ToolResult) {
 	devPath := fmt.Sprintf("/dev/i2c-%s", bus)
+	defer lockBus(devPath)()
 	fd, err := syscall.Open(devPath, syscall.O_RDWR, 0)
 	if err != nil {
-		return ErrorResult(fmt.Sprintf("failed to open %s: %v (check permissions and i2c-dev module)", devPath, err))
+		return nil, ErrorResult(fmt.Sprintf("failed to open %s: %v (check permissions and i2c-dev module)", devPath, err))
 	}
 	defer syscall.Close(fd)
+	// Probes that timed out may still be inside an ioctl on fd. Wait for
+	// them, which the kernel's adapter timeout bounds, before the fd is
+	// closed and the bus lock released; deferred calls run in reverse.
+	var inflight sync.WaitGroup
+	defer inflight.Wait()
 
 	// Query adapter capabilities to determine available probe methods.
 	// I2C_FUNCS writes an unsigned long, which is word-sized on Linux.
 	var funcs uintptr
 	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), i2cFuncs, uintptr(unsafe.Pointer(&funcs)))
 	if errno != 0 {
-		return ErrorResult(fmt.Sprintf("failed to query I2C adapter capabilities on %s: %v", devPath, errno))
+		return nil, ErrorResult(fmt.Sprintf("failed to query I2C adapter capabilities on %s: %v", devPath, errno))
 	}
 
 	hasQuick := funcs&i2cFuncSmbusQuick != 0
 	hasReadByte := funcs&i2cFuncSmbusReadByte != 0
 
 	if !hasQuick && !hasReadByte {
-		return ErrorResult(fmt.Sprintf("I2C adapter %s supports neither SMBus Quick nor Read Byte — cannot probe safely", devPath))
+		return nil, ErrorResult(fmt.Sprintf("I2C adapter %s supports neither SMBus Quick nor Read Byte — cannot probe safely", devPath))
 	}
 
-	type deviceEntry struct {
-		Address string `json:"address"`
-		Status  string `json:"status,omitempty"`
+	res := &i2cBusScan{devPath: devPath, states: make(map[int]i2cAddrState), last: i2cScanLast}
+	device := func(addr int, status string) i2cScanDevice {
+		return i2cScanDevice{Address: fmt.Sprintf("0x%02x", addr), Name: t.deviceName(addr), Status: status, addr: addr}
 	}
-
-	var found []deviceEntry
+	consecutiveTimeouts := 0
 	// Scan 0x08-0x77, skipping I2C reserved addresses 0x00-0x07
-	for addr := 0x08; addr <= 0x77; addr++ {
+	for addr := i2cScanFirst; addr <= i2cScanLast; addr++ {
 		// Set slave address — EBUSY means a kernel driver owns this address
 		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), i2cSlave, uintptr(addr))
 		if errno != 0 {
 			if errno == syscall.EBUSY {
-				found = append(found, deviceEntry{
-					Address: fmt.Sprintf("0x%02x", addr),
-					Status:  "busy (in use by kernel driver)",
-				})
+				res.found = append(res.found, device(addr, "busy (in use by kernel driver)"))
+				res.states[addr] = i2cAddrBusy
 			}
 			continue
 		}
 
-		if smbusProbe(fd, addr, hasQuick) {
-			found = append(found, deviceEntry{
-				Address: fmt.Sprintf("0x%02x", addr),
-			})
+		present, timedOut := probeWithTimeout(i2cProbeTimeout, &inflight, func() bool {
+			return smbusProbe(fd, addr, hasQuick)
+		})
+		if timedOut {
+			res.found = append(res.found, device(addr, "timeout (probe did not complete)"))
+			res.states[addr] = i2cAddrTimeout
+			consecutiveTimeouts++
+			if consecutiveTimeouts >= i2cMaxProbeTimeouts && addr < i2cScanLast {
+				res.last = addr
+				res.stopped = fmt.Sprintf("scan stopped after 0x%02x: %d probes in a row timed out, the bus appears stuck", addr, consecutiveTimeouts)
+				break
+			}
+			continue
+		}
+		consecutiveTimeouts = 0
+		if present {
+			res.found = append(res.found, device(addr, ""))
+			res.states[addr] = i2cAddrPresent
 		}
 	}
-
-	if len(found) == 0 {
-		return SilentResult(fmt.Sprintf("No devices found on %s. Check wiring and pull-up resistors.", devPath))
-	}
-
-	result, _ := json.MarshalIndent(map[string]interface{}{
-		"bus":     devPath,
-		"devices": found,
-		"count":   len(found),
-	}, "", "  ")
-	return SilentResult(fmt.Sprintf("Scan of %s:\n%s", devPath, string(result)))
+	return res, nil
 }
 
-// readDevice reads bytes from an I2C device, optionally at a specific register
-func (t *I2CTool) readDevice(args map[string]interface{}) *ToolResult {
-	bus, errResult := parseI2CBus(args)
+// readDevice reads bytes from an I2C device, optionally at a specific register,
+// waiting delay_ms between the register write and the read
+func (t *I2CTool) readDevice(ctx context.Context, args map[string]interface{}) *ToolResult {
+	bus, errResult := parseI2CBus(args, t.allowed)
 	if errResult != nil {
 		return errResult
 	}
@@ -161,18 +338,18 @@ func (t *I2CTool) readDevice(args map[string]interface{}) *ToolResult {
 		return ErrorResult("length must be between 1 and 256")
 	}
 
-	devPath := fmt.Sprintf("/dev/i2c-%s", bus)
-	fd, err := syscall.Open(devPath, syscall.O_RDWR, 0)
-	if err != nil {
-		return ErrorResult(fmt.Sprintf("failed to open %s: %v", devPath, err))
+	delay, errResult := parseReadDelay(args)
+	if errResult != nil {
+		return errResult
 	}
-	defer syscall.Close(fd)
 
-	// Set slave address
-	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), i2cSlave, uintptr(addr))
-	if errno != 0 {
-		return ErrorResult(fmt.Sprintf("failed to set I2C address 0x%02x: %v", addr, errno))
+	devPath := fmt.Sprintf("/dev/i2c-%s", bus)
+	defer lockBus(devPath)()
+	fd, errResult := openI2CDevice(devPath, addr, parseI2CTenBit(args))
+	if errResult != nil {
+		return errResult
 	}
+	defer syscall.Close(fd)
 
 	// If register is specified, write it first
 	if regFloat, ok := args["register"].(float64); ok {
@@ -186,6 +363,17 @@ func (t *I2CTool) readDevice(args map[string]interface{}) *ToolResult {
 		}
 	}
 
+	// Give the device time to complete a measurement started by the write
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ErrorResult(fmt.Sprintf("read from device 0x%02x canceled during delay: %v", addr, ctx.Err()))
+		case <-timer.C:
+		}
+	}
+
 	// Read data
 	buf := make([]byte, length)
 	n, err := syscall.Read(fd, buf)
@@ -201,24 +389,26 @@ func (t *I2CTool) readDevice(args map[string]interface{}) *ToolResult {
 		intBytes[i] = int(buf[i])
 	}
 
-	result, _ := json.MarshalIndent(map[string]interface{}{
+	summary := map[string]interface{}{
 		"bus":     devPath,
 		"address": fmt.Sprintf("0x%02x", addr),
 		"bytes":   intBytes,
 		"hex":     hexBytes,
 		"length":  n,
-	}, "", "  ")
-	return SilentResult(string(result))
+	}
+	result, _ := json.MarshalIndent(summary, "", "  ")
+	return SilentResult(string(result)).WithData(summary)
 }
 
 // writeDevice writes bytes to an I2C device, optionally at a specific register
 func (t *I2CTool) writeDevice(args map[string]interface{}) *ToolResult {
+	dryRun := isDryRun(args)
 	confirm, _ := args["confirm"].(bool)
-	if !confirm {
+	if !confirm && !dryRun {
 		return ErrorResult("write operations require confirm: true. Please confirm with the user before writing to I2C devices, as incorrect writes can misconfigure hardware.")
 	}
 
-	bus, errResult := parseI2CBus(args)
+	bus, errResult := parseI2CBus(args, t.allowed)
 	if errResult != nil {
 		return errResult
 	}
@@ -260,17 +450,28 @@ func (t *I2CTool) writeDevice(args map[string]interface{}) *ToolResult {
 	}
 
 	devPath := fmt.Sprintf("/dev/i2c-%s", bus)
-	fd, err := syscall.Open(devPath, syscall.O_RDWR, 0)
-	if err != nil {
-		return ErrorResult(fmt.Sprintf("failed to open %s: %v", devPath, err))
+
+	if dryRun {
+		hexBytes := make([]string, len(data))
+		for i, b := range data {
+			hexBytes[i] = fmt.Sprintf("0x%02x", b)
+		}
+		result, _ := json.MarshalIndent(map[string]interface{}{
+			"dry_run": true,
+			"bus":     devPath,
+			"address": fmt.Sprintf("0x%02x", addr),
+			"hex":     hexBytes,
+			"length":  len(data),
+		}, "", "  ")
+		return SilentResult(fmt.Sprintf("[dry run] Would write %d byte(s) to device 0x%02x on %s:\n%s", len(data), addr, devPath, string(result)))
 	}
-	defer syscall.Close(fd)
 
-	// Set slave address
-	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), i2cSlave, uintptr(addr))
-	if errno != 0 {
-		return ErrorResult(fmt.Sprintf("failed to set I2C address 0x%02x: %v", addr, errno))
+	defer lockBus(devPath)()
+	fd, errResult := openI2CDevice(devPath, addr, parseI2CTenBit(args))
+	if errResult != nil {
+		return errResult
 	}
+	defer syscall.Close(fd)
 
 	// Write data
 	n, err := syscall.Write(fd, data)
@@ -280,3 +481,131 @@ func (t *I2CTool) writeDevice(args map[string]interface{}) *ToolResult {
 
 	return SilentResult(fmt.Sprintf("Wrote %d byte(s) to device 0x%02x on %s", n, addr, devPath))
 }
+
+// dump reads a contiguous register range and formats it like i2cdump
+func (t *I2CTool) dump(args map[string]interface{}) *ToolResult {
+	bus, errResult := parseI2CBus(args, t.allowed)
+	if errResult != nil {
+		return errResult
+	}
+
+	addr, errResult := parseI2CAddress(args)
+	if errResult != nil {
+		return errResult
+	}
+
+	start, end, errResult := parseRegisterRange(args)
+	if errResult != nil {
+		return errResult
+	}
+
+	devPath := fmt.Sprintf("/dev/i2c-%s", bus)
+	defer lockBus(devPath)()
+	fd, errResult := openI2CDevice(devPath, addr, parseI2CTenBit(args))
+	if errResult != nil {
+		return errResult
+	}
+	defer syscall.Close(fd)
+
+	values := make([]int, 0, end-start+1)
+	failed := 0
+	for reg := start; reg <= end; reg++ {
+		v, err := smbusReadByteData(fd, byte(reg))
+		if err != nil {
+			values = append(values, -1)
+			failed++
+			continue
+		}
+		values = append(values, int(v))
+	}
+
+	if failed == len(values) {
+		return ErrorResult(fmt.Sprintf("failed to read any register from device 0x%02x on %s", addr, devPath))
+	}
+
+	return SilentResult(fmt.Sprintf("Dump of device 0x%02x on %s (registers 0x%02x-0x%02x, %d unreadable):\n%s",
+		addr, devPath, start, end, failed, formatI2CDump(start, values)))
+}
+
+// monitor reads a register at a fixed interval and returns the series of samples.
+// Stops early (returning the samples collected so far) if ctx is canceled.
+func (t *I2CTool) monitor(ctx context.Context, args map[string]interface{}) *ToolResult {
+	bus, errResult := parseI2CBus(args, t.allowed)
+	if errResult != nil {
+		return errResult
+	}
+
+	addr, errResult := parseI2CAddress(args)
+	if errResult != nil {
+		return errResult
+	}
+
+	params, errResult := parseMonitorParams(args)
+	if errResult != nil {
+		return errResult
+	}
+
+	devPath := fmt.Sprintf("/dev/i2c-%s", bus)
+	fd, errResult := openI2CDevice(devPath, addr, parseI2CTenBit(args))
+	if errResult != nil {
+		return errResult
+	}
+	defer syscall.Close(fd)
+
+	type sample struct {
+		ElapsedMs int64    `json:"elapsed_ms"`
+		Bytes     []int    `json:"bytes,omitempty"`
+		Hex       []string `json:"hex,omitempty"`
+		Error     string   `json:"error,omitempty"`
+	}
+
+	samples := make([]sample, 0, params.count)
+	interrupted := false
+	start := time.Now()
+	ticker := time.NewTicker(params.interval)
+	defer ticker.Stop()
+
+	for i := 0; i < params.count; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				interrupted = true
+			case <-ticker.C:
+			}
+			if interrupted {
+				break
+			}
+		}
+
+		s := sample{ElapsedMs: time.Since(start).Milliseconds()}
+		buf := make([]byte, params.length)
+		// Lock per sample so other calls can use the bus between samples
+		unlock := lockBus(devPath)
+		if _, err := syscall.Write(fd, []byte{byte(params.register)}); err != nil {
+			s.Error = fmt.Sprintf("failed to write register 0x%02x: %v", params.register, err)
+		} else if n, err := syscall.Read(fd, buf); err != nil {
+			s.Error = fmt.Sprintf("failed to read: %v", err)
+		} else {
+			s.Bytes = make([]int, n)
+			s.Hex = make([]string, n)
+			for j := 0; j < n; j++ {
+				s.Bytes[j] = int(buf[j])
+				s.Hex[j] = fmt.Sprintf("0x%02x", buf[j])
+			}
+		}
+		unlock()
+		samples = append(samples, s)
+	}
+
+	summary := map[string]interface{}{
+		"bus":         devPath,
+		"address":     fmt.Sprintf("0x%02x", addr),
+		"register":    fmt.Sprintf("0x%02x", params.register),
+		"interval_ms": params.interval.Milliseconds(),
+		"samples":     samples,
+		"count":       len(samples),
+		"interrupted": interrupted,
+	}
+	result, _ := json.MarshalIndent(summary, "", "  ")
+	return SilentResult(string(result)).WithData(summary)
+}
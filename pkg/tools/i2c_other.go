@@ -2,13 +2,28 @@
 
 package tools
 
+import (
+	"context"
+	"fmt"
+)
+
+// queryI2CFuncs is a stub for non-Linux platforms.
+func queryI2CFuncs(devPath string) (uint64, error) {
+	return 0, fmt.Errorf("I2C is only supported on Linux")
+}
+
 // scan is a stub for non-Linux platforms.
 func (t *I2CTool) scan(args map[string]interface{}) *ToolResult {
 	return ErrorResult("I2C is only supported on Linux")
 }
 
+// identify is a stub for non-Linux platforms.
+func (t *I2CTool) identify(args map[string]interface{}) *ToolResult {
+	return ErrorResult("I2C is only supported on Linux")
+}
+
 // readDevice is a stub for non-Linux platforms.
-func (t *I2CTool) readDevice(args map[string]interface{}) *ToolResult {
+func (t *I2CTool) readDevice(ctx context.Context, args map[string]interface{}) *ToolResult {
 	return ErrorResult("I2C is only supported on Linux")
 }
 
@@ -16,3 +31,13 @@ func (t *I2CTool) readDevice(args map[string]interface{}) *ToolResult {
 func (t *I2CTool) writeDevice(args map[string]interface{}) *ToolResult {
 	return ErrorResult("I2C is only supported on Linux")
 }
+
+// dump is a stub for non-Linux platforms.
+func (t *I2CTool) dump(args map[string]interface{}) *ToolResult {
+	return ErrorResult("I2C is only supported on Linux")
+}
+
+// monitor is a stub for non-Linux platforms.
+func (t *I2CTool) monitor(ctx context.Context, args map[string]interface{}) *ToolResult {
+	return ErrorResult("I2C is only supported on Linux")
+}
@@ -0,0 +1,338 @@
+package tools
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseRegisterRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       map[string]interface{}
+		start, end int
+		wantErr    bool
+	}{
+		{"defaults", map[string]interface{}{}, 0x00, 0xFF, false},
+		{"explicit", map[string]interface{}{"start": float64(0x10), "end": float64(0x1F)}, 0x10, 0x1F, false},
+		{"single register", map[string]interface{}{"start": float64(0x42), "end": float64(0x42)}, 0x42, 0x42, false},
+		{"start only", map[string]interface{}{"start": float64(0xF0)}, 0xF0, 0xFF, false},
+		{"inverted", map[string]interface{}{"start": float64(0x20), "end": float64(0x10)}, 0, 0, true},
+		{"start too high", map[string]interface{}{"start": float64(0x100)}, 0, 0, true},
+		{"negative end", map[string]interface{}{"end": float64(-1)}, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, errResult := parseRegisterRange(tt.args)
+			if tt.wantErr {
+				if errResult == nil {
+					t.Fatalf("Expected error, got range %#x-%#x", start, end)
+				}
+				return
+			}
+			if errResult != nil {
+				t.Fatalf("Unexpected error: %s", errResult.ForLLM)
+			}
+			if start != tt.start || end != tt.end {
+				t.Errorf("Expected %#x-%#x, got %#x-%#x", tt.start, tt.end, start, end)
+			}
+		})
+	}
+}
+
+func TestFormatI2CDump(t *testing.T) {
+	out := formatI2CDump(0x0E, []int{0x41, -1, 0x00})
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected header and two rows, got %d lines:\n%s", len(lines), out)
+	}
+	if !strings.HasPrefix(lines[0], "     0  1  2") {
+		t.Errorf("Unexpected header: %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "00: ") || !strings.Contains(lines[1], "41 XX") || !strings.HasSuffix(lines[1], "AX") {
+		t.Errorf("Unexpected first row: %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "10: 00 ") || !strings.HasSuffix(lines[2], ".               ") {
+		t.Errorf("Unexpected second row: %q", lines[2])
+	}
+}
+
+func TestFormatI2CDump_FullRowAlignment(t *testing.T) {
+	values := make([]int, 16)
+	for i := range values {
+		values[i] = 0x30 + i
+	}
+	out := formatI2CDump(0x20, values)
+	want := "20: 30 31 32 33 34 35 36 37 38 39 3a 3b 3c 3d 3e 3f    0123456789:;<=>?"
+	if !strings.Contains(out, want) {
+		t.Errorf("Expected row %q, got:\n%s", want, out)
+	}
+}
+
+func TestFormatI2CDetectGrid(t *testing.T) {
+	out := formatI2CDetectGrid(map[int]i2cAddrState{
+		0x08: i2cAddrPresent,
+		0x38: i2cAddrPresent,
+		0x50: i2cAddrBusy,
+		0x51: i2cAddrTimeout,
+		0x77: i2cAddrPresent,
+	}, i2cScanLast)
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	if len(lines) != 9 {
+		t.Fatalf("Expected header and 8 rows, got %d lines:\n%s", len(lines), out)
+	}
+	if lines[0] != "     0  1  2  3  4  5  6  7  8  9  a  b  c  d  e  f" {
+		t.Errorf("Unexpected header: %q", lines[0])
+	}
+	want := map[int]string{
+		1: "00:                         08 -- -- -- -- -- -- -- ",
+		2: "10: -- -- -- -- -- -- -- -- -- -- -- -- -- -- -- -- ",
+		4: "30: -- -- -- -- -- -- -- -- 38 -- -- -- -- -- -- -- ",
+		6: "50: UU ?? -- -- -- -- -- -- -- -- -- -- -- -- -- -- ",
+		8: "70: -- -- -- -- -- -- -- 77                         ",
+	}
+	for i, w := range want {
+		if lines[i] != w {
+			t.Errorf("Row %d:\n got %q\nwant %q", i, lines[i], w)
+		}
+	}
+}
+
+func TestFormatI2CDetectGrid_StoppedEarly(t *testing.T) {
+	out := formatI2CDetectGrid(map[int]i2cAddrState{0x21: i2cAddrTimeout, 0x22: i2cAddrTimeout, 0x23: i2cAddrTimeout}, 0x23)
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	if want := "20: -- ?? ?? ??                                     "; lines[3] != want {
+		t.Errorf("Row 20:\n got %q\nwant %q", lines[3], want)
+	}
+	if want := "30:                                                 "; lines[4] != want {
+		t.Errorf("Unprobed row should be blank, got %q", lines[4])
+	}
+}
+
+func TestProbeWithTimeout(t *testing.T) {
+	var inflight sync.WaitGroup
+	present, timedOut := probeWithTimeout(time.Second, &inflight, func() bool { return true })
+	if !present || timedOut {
+		t.Errorf("Fast ACK: got present=%v timedOut=%v", present, timedOut)
+	}
+
+	present, timedOut = probeWithTimeout(time.Second, &inflight, func() bool { return false })
+	if present || timedOut {
+		t.Errorf("Fast NACK: got present=%v timedOut=%v", present, timedOut)
+	}
+
+	release := make(chan struct{})
+	start := time.Now()
+	present, timedOut = probeWithTimeout(20*time.Millisecond, &inflight, func() bool {
+		<-release
+		return true
+	})
+	if present || !timedOut {
+		t.Errorf("Stuck probe: got present=%v timedOut=%v", present, timedOut)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Stuck probe blocked for %v", elapsed)
+	}
+
+	// The stuck probe stays tracked until it returns
+	waited := make(chan struct{})
+	go func() {
+		inflight.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		t.Fatal("inflight.Wait returned while the probe was still running")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("inflight.Wait did not return after the probe finished")
+	}
+}
+
+func TestParseScanOutput(t *testing.T) {
+	tests := []struct {
+		output  interface{}
+		want    string
+		wantErr bool
+	}{
+		{nil, "json", false},
+		{"json", "json", false},
+		{"grid", "grid", false},
+		{"table", "", true},
+	}
+	for _, tt := range tests {
+		args := map[string]interface{}{}
+		if tt.output != nil {
+			args["output"] = tt.output
+		}
+		got, errResult := parseScanOutput(args)
+		if tt.wantErr {
+			if errResult == nil {
+				t.Errorf("output %v: expected error, got %q", tt.output, got)
+			}
+			continue
+		}
+		if errResult != nil || got != tt.want {
+			t.Errorf("output %v: got %q, %+v; want %q", tt.output, got, errResult, tt.want)
+		}
+	}
+}
+
+func TestParseMonitorParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		want    monitorParams
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			args: map[string]interface{}{"register": float64(0x10)},
+			want: monitorParams{register: 0x10, length: 1, interval: time.Second, count: 10},
+		},
+		{
+			name: "explicit",
+			args: map[string]interface{}{"register": float64(0x20), "length": float64(2), "interval_ms": float64(50), "count": float64(100)},
+			want: monitorParams{register: 0x20, length: 2, interval: 50 * time.Millisecond, count: 100},
+		},
+		{"missing register", map[string]interface{}{}, monitorParams{}, "register is required"},
+		{"register too high", map[string]interface{}{"register": float64(0x100)}, monitorParams{}, "register must be"},
+		{"length over cap", map[string]interface{}{"register": float64(0), "length": float64(33)}, monitorParams{}, "length must be"},
+		{"interval too short", map[string]interface{}{"register": float64(0), "interval_ms": float64(5)}, monitorParams{}, "interval_ms must be"},
+		{"count over cap", map[string]interface{}{"register": float64(0), "count": float64(101)}, monitorParams{}, "count must be"},
+		{"count zero", map[string]interface{}{"register": float64(0), "count": float64(0)}, monitorParams{}, "count must be"},
+		{
+			name:    "total duration over limit",
+			args:    map[string]interface{}{"register": float64(0), "interval_ms": float64(1000), "count": float64(62)},
+			wantErr: "exceeding",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errResult := parseMonitorParams(tt.args)
+			if tt.wantErr != "" {
+				if errResult == nil || !strings.Contains(errResult.ForLLM, tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %+v", tt.wantErr, errResult)
+				}
+				return
+			}
+			if errResult != nil {
+				t.Fatalf("Unexpected error: %s", errResult.ForLLM)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseReadDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		want    time.Duration
+		wantErr string
+	}{
+		{"absent", map[string]interface{}{}, 0, ""},
+		{"zero without register", map[string]interface{}{"delay_ms": float64(0)}, 0, ""},
+		{"with register", map[string]interface{}{"register": float64(0xAC), "delay_ms": float64(80)}, 80 * time.Millisecond, ""},
+		{"at cap", map[string]interface{}{"register": float64(0), "delay_ms": float64(1000)}, time.Second, ""},
+		{"over cap", map[string]interface{}{"register": float64(0), "delay_ms": float64(1001)}, 0, "delay_ms must be"},
+		{"negative", map[string]interface{}{"register": float64(0), "delay_ms": float64(-5)}, 0, "delay_ms must be"},
+		{"without register", map[string]interface{}{"delay_ms": float64(20)}, 0, "requires register"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errResult := parseReadDelay(tt.args)
+			if tt.wantErr != "" {
+				if errResult == nil || !strings.Contains(errResult.ForLLM, tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %+v", tt.wantErr, errResult)
+				}
+				return
+			}
+			if errResult != nil {
+				t.Fatalf("Unexpected error: %s", errResult.ForLLM)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestI2CCandidates(t *testing.T) {
+	tests := []struct {
+		addr int
+		want []string // substrings expected among the candidates
+	}{
+		{0x38, []string{"AHT20", "PCF8574A"}},
+		{0x76, []string{"BME280"}},
+		{0x77, []string{"BME280", "BMP180", "TCA9548A"}},
+		{0x68, []string{"MPU6050", "DS3231"}},
+		{0x44, []string{"SHT3x", "INA219"}},
+	}
+	for _, tt := range tests {
+		got := strings.Join(i2cCandidates(tt.addr), "; ")
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("i2cCandidates(0x%02x) = %q, want it to include %q", tt.addr, got, want)
+			}
+		}
+	}
+
+	if got := i2cCandidates(0x08); got != nil {
+		t.Errorf("i2cCandidates(0x08) = %v, want none", got)
+	}
+}
+
+func TestI2CKnownDevicesInScanRange(t *testing.T) {
+	for _, d := range i2cKnownDevices {
+		if d.first > d.last || d.first < 0x03 || d.last > i2cScanLast || len(d.names) == 0 {
+			t.Errorf("invalid entry %+v", d)
+		}
+	}
+}
+
+func TestParseI2CAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		want    int
+		wantErr string
+	}{
+		{"7-bit", map[string]interface{}{"address": float64(0x38)}, 0x38, ""},
+		{"7-bit reserved", map[string]interface{}{"address": float64(0x02)}, 0, "7-bit range"},
+		{"7-bit too high", map[string]interface{}{"address": float64(0x78)}, 0, "set ten_bit"},
+		{"10-bit", map[string]interface{}{"address": float64(0x2A5), "ten_bit": true}, 0x2A5, ""},
+		{"10-bit low", map[string]interface{}{"address": float64(0x00), "ten_bit": true}, 0x00, ""},
+		{"10-bit too high", map[string]interface{}{"address": float64(0x400), "ten_bit": true}, 0, "10-bit range"},
+		{"ten_bit false", map[string]interface{}{"address": float64(0x2A5), "ten_bit": false}, 0, "7-bit range"},
+		{"missing", map[string]interface{}{}, 0, "address is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errResult := parseI2CAddress(tt.args)
+			if tt.wantErr != "" {
+				if errResult == nil || !strings.Contains(errResult.ForLLM, tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %+v", tt.wantErr, errResult)
+				}
+				return
+			}
+			if errResult != nil {
+				t.Fatalf("Unexpected error: %s", errResult.ForLLM)
+			}
+			if got != tt.want {
+				t.Errorf("Expected 0x%x, got 0x%x", tt.want, got)
+			}
+		})
+	}
+}
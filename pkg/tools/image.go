@@ -0,0 +1,240 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const (
+	defaultImageModel = "dall-e-3"
+	defaultImageSize  = "1024x1024"
+	maxImageCount     = 4
+	minImageDimension = 256
+	maxImageDimension = 2048
+)
+
+var imageSizePattern = regexp.MustCompile(`^(\d+)x(\d+)$`)
+
+type ImageGenToolOptions struct {
+	Enabled bool
+	APIKey  string
+	APIBase string
+	Model   string
+}
+
+// ImageGenTool generates images through an OpenAI-compatible
+// /images/generations endpoint.
+type ImageGenTool struct {
+	apiKey       string
+	apiBase      string
+	model        string
+	client       *http.Client
+	sendCallback SendCallback
+}
+
+// NewImageGenTool returns nil when no image provider is configured.
+func NewImageGenTool(opts ImageGenToolOptions) *ImageGenTool {
+	if !opts.Enabled || opts.APIKey == "" || opts.APIBase == "" {
+		return nil
+	}
+
+	model := opts.Model
+	if model == "" {
+		model = defaultImageModel
+	}
+
+	return &ImageGenTool{
+		apiKey:  opts.APIKey,
+		apiBase: strings.TrimRight(opts.APIBase, "/"),
+		model:   model,
+		client:  &http.Client{Timeout: 120 * time.Second, Transport: utils.EgressTransport(nil)},
+	}
+}
+
+// SetSendCallback sets the callback used to deliver generated images to the chat.
+func (t *ImageGenTool) SetSendCallback(callback SendCallback) {
+	t.sendCallback = callback
+}
+
+func (t *ImageGenTool) Name() string {
+	return "image_generate"
+}
+
+func (t *ImageGenTool) Description() string {
+	return "Generate images from a text prompt. The images are sent to the user directly."
+}
+
+func (t *ImageGenTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"prompt": map[string]interface{}{
+				"type":        "string",
+				"description": "Description of the image to generate",
+			},
+			"size": map[string]interface{}{
+				"type":        "string",
+				"description": "Image size as WIDTHxHEIGHT (256-2048 per side). Default: 1024x1024",
+			},
+			"n": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of images (1-4). Default: 1",
+				"minimum":     1.0,
+				"maximum":     4.0,
+			},
+		},
+		"required": []string{"prompt"},
+	}
+}
+
+func (t *ImageGenTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	prompt, ok := args["prompt"].(string)
+	if !ok || strings.TrimSpace(prompt) == "" {
+		return ErrorResult("prompt is required")
+	}
+
+	size := defaultImageSize
+	if s, ok := args["size"].(string); ok && s != "" {
+		if err := validateImageSize(s); err != nil {
+			return ErrorResult(err.Error())
+		}
+		size = s
+	}
+
+	n := 1
+	if v, ok := args["n"].(float64); ok {
+		n = int(v)
+		if n < 1 || n > maxImageCount {
+			return ErrorResult(fmt.Sprintf("n must be between 1 and %d", maxImageCount))
+		}
+	}
+
+	images, err := t.generate(ctx, prompt, size, n)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("image generation failed: %v", err))
+	}
+	if len(images) == 0 {
+		return ErrorResult("image generation returned no images")
+	}
+
+	llmParts := make([]string, 0, len(images))
+	hasURL := false
+	for i, img := range images {
+		if img.URL != "" {
+			hasURL = true
+			llmParts = append(llmParts, fmt.Sprintf("%d. %s", i+1, img.URL))
+		} else {
+			llmParts = append(llmParts, fmt.Sprintf("%d. (inline base64 image)", i+1))
+		}
+	}
+	forLLM := fmt.Sprintf("Generated %d image(s) for prompt %q:\n%s", len(images), prompt, strings.Join(llmParts, "\n"))
+
+	// Deliver the images straight to the originating chat when possible
+	if md, ok := MessageMetadataFromContext(ctx); ok && t.sendCallback != nil && md.Channel != "" && md.ChatID != "" {
+		userParts := make([]string, 0, len(images))
+		for _, img := range images {
+			if part := formatImageForChannel(md.Channel, img); part != "" {
+				userParts = append(userParts, part)
+			}
+		}
+		if len(userParts) > 0 {
+			if err := t.sendCallback(md.Channel, md.ChatID, strings.Join(userParts, "\n")); err != nil {
+				return ErrorResult(fmt.Sprintf("generated images but failed to send them: %v", err))
+			}
+			return SilentResult(forLLM + "\nThe images have been sent to the user.")
+		}
+	}
+
+	if !hasURL {
+		return ErrorResult("generated images were returned as base64, which this channel cannot display")
+	}
+	return NewToolResult(forLLM + "\nShare these URLs with the user.")
+}
+
+type generatedImage struct {
+	URL     string `json:"url"`
+	B64JSON string `json:"b64_json"`
+}
+
+func (t *ImageGenTool) generate(ctx context.Context, prompt, size string, n int) ([]generatedImage, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"model":  t.model,
+		"prompt": prompt,
+		"size":   size,
+		"n":      n,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.apiBase+"/images/generations", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Data []generatedImage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return result.Data, nil
+}
+
+func validateImageSize(size string) error {
+	m := imageSizePattern.FindStringSubmatch(size)
+	if m == nil {
+		return fmt.Errorf("invalid size %q (expected WIDTHxHEIGHT, e.g. 1024x1024)", size)
+	}
+	for _, dim := range m[1:] {
+		v, _ := strconv.Atoi(dim)
+		if v < minImageDimension || v > maxImageDimension {
+			return fmt.Errorf("invalid size %q (each side must be between %d and %d)", size, minImageDimension, maxImageDimension)
+		}
+	}
+	return nil
+}
+
+// formatImageForChannel renders an image for the user. OneBot gets a
+// Markdown image, which its outbound formatter turns into a CQ image segment
+// so it is displayed inline; other channels get the URL.
+func formatImageForChannel(channel string, img generatedImage) string {
+	if channel == "onebot" {
+		if img.URL != "" {
+			return "![image](" + strings.NewReplacer(" ", "%20", ")", "%29").Replace(img.URL) + ")"
+		}
+		if img.B64JSON != "" {
+			return "![image](base64://" + img.B64JSON + ")"
+		}
+		return ""
+	}
+	return img.URL
+}
@@ -0,0 +1,125 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newImageTestServer(t *testing.T, data []map[string]string, captured *map[string]interface{}) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/images/generations" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if captured != nil {
+			json.NewDecoder(r.Body).Decode(captured)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+}
+
+func TestNewImageGenTool_NotConfigured(t *testing.T) {
+	if tool := NewImageGenTool(ImageGenToolOptions{Enabled: true}); tool != nil {
+		t.Error("Expected nil tool without API key")
+	}
+	if tool := NewImageGenTool(ImageGenToolOptions{APIKey: "k", APIBase: "http://x"}); tool != nil {
+		t.Error("Expected nil tool when disabled")
+	}
+}
+
+func TestImageGenTool_ReturnsURLsWithoutChat(t *testing.T) {
+	var req map[string]interface{}
+	server := newImageTestServer(t, []map[string]string{{"url": "https://img.example/1.png"}}, &req)
+	defer server.Close()
+
+	tool := NewImageGenTool(ImageGenToolOptions{Enabled: true, APIKey: "test-key", APIBase: server.URL})
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"prompt": "a red fox",
+		"size":   "512x512",
+		"n":      float64(1),
+	})
+
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "https://img.example/1.png") {
+		t.Errorf("Expected URL in ForLLM, got: %s", result.ForLLM)
+	}
+	if req["size"] != "512x512" || req["model"] != defaultImageModel || req["n"] != float64(1) {
+		t.Errorf("Unexpected request body: %v", req)
+	}
+}
+
+func TestImageGenTool_SendsCQImageToOneBot(t *testing.T) {
+	server := newImageTestServer(t, []map[string]string{{"b64_json": "aGVsbG8="}}, nil)
+	defer server.Close()
+
+	tool := NewImageGenTool(ImageGenToolOptions{Enabled: true, APIKey: "test-key", APIBase: server.URL})
+	var sentChannel, sentChatID, sentContent string
+	tool.SetSendCallback(func(channel, chatID, content string) error {
+		sentChannel, sentChatID, sentContent = channel, chatID, content
+		return nil
+	})
+
+	ctx := WithMessageMetadata(context.Background(), MessageMetadata{Channel: "onebot", ChatID: "group:123"})
+	result := tool.Execute(ctx, map[string]interface{}{"prompt": "a red fox"})
+
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if !result.Silent {
+		t.Error("Expected silent result after images were sent")
+	}
+	if sentChannel != "onebot" || sentChatID != "group:123" {
+		t.Errorf("Sent to %s/%s, want onebot/group:123", sentChannel, sentChatID)
+	}
+	if sentContent != "![image](base64://aGVsbG8=)" {
+		t.Errorf("Unexpected image content: %q", sentContent)
+	}
+	if strings.Contains(result.ForLLM, "aGVsbG8=") {
+		t.Error("Base64 image data should not be sent to the LLM")
+	}
+}
+
+func TestImageGenTool_ValidatesParams(t *testing.T) {
+	tool := NewImageGenTool(ImageGenToolOptions{Enabled: true, APIKey: "test-key", APIBase: "http://127.0.0.1:0"})
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want string
+	}{
+		{"missing prompt", map[string]interface{}{}, "prompt is required"},
+		{"bad size format", map[string]interface{}{"prompt": "x", "size": "large"}, "invalid size"},
+		{"size too big", map[string]interface{}{"prompt": "x", "size": "4096x4096"}, "between 256 and 2048"},
+		{"too many images", map[string]interface{}{"prompt": "x", "n": float64(10)}, "n must be between 1 and 4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tool.Execute(context.Background(), tt.args)
+			if !result.IsError || !strings.Contains(result.ForLLM, tt.want) {
+				t.Errorf("Expected error containing %q, got: %s", tt.want, result.ForLLM)
+			}
+		})
+	}
+}
+
+func TestFormatImageForChannel(t *testing.T) {
+	img := generatedImage{URL: "https://img.example/a (1).png?x=1,y=2"}
+	if got := formatImageForChannel("telegram", img); got != img.URL {
+		t.Errorf("formatImageForChannel(telegram) = %q, want URL", got)
+	}
+	if got := formatImageForChannel("onebot", img); got != "![image](https://img.example/a%20(1%29.png?x=1,y=2)" {
+		t.Errorf("formatImageForChannel(onebot) = %q", got)
+	}
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultInventoryTTL = 10 * time.Second
+
+// deviceInventory caches the device nodes matching a glob pattern for a short
+// time, so repeated list/detect calls do not hit the filesystem every time.
+type deviceInventory struct {
+	pattern string
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	paths   []string
+	expires time.Time
+}
+
+func newDeviceInventory(pattern string, ttl time.Duration) *deviceInventory {
+	if ttl <= 0 {
+		ttl = defaultInventoryTTL
+	}
+	return &deviceInventory{pattern: pattern, ttl: ttl, now: time.Now}
+}
+
+// Paths returns the matching device nodes, refreshing the cache once it expires.
+func (inv *deviceInventory) Paths() ([]string, error) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	if inv.paths != nil && inv.now().Before(inv.expires) {
+		return inv.paths, nil
+	}
+
+	matches, err := filepath.Glob(inv.pattern)
+	if err != nil {
+		return nil, err
+	}
+	if matches == nil {
+		matches = []string{}
+	}
+	inv.paths = matches
+	inv.expires = inv.now().Add(inv.ttl)
+	return matches, nil
+}
+
+// parseDeviceAllowlist turns configured bus or device identifiers into a
+// lookup set, skipping entries that fail valid. It returns nil for an empty
+// list, meaning every bus or device is allowed; a list whose entries are all
+// invalid allows nothing rather than everything.
+func parseDeviceAllowlist(entries []string, valid func(string) bool) map[string]bool {
+	if len(entries) == 0 {
+		return nil
+	}
+	allowed := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if valid(entry) {
+			allowed[entry] = true
+		}
+	}
+	return allowed
+}
+
+// allowlistString lists the allowed identifiers for error messages.
+func allowlistString(allowed map[string]bool) string {
+	if len(allowed) == 0 {
+		return "none"
+	}
+	ids := make([]string, 0, len(allowed))
+	for id := range allowed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ", ")
+}
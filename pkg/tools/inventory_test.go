@@ -0,0 +1,149 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeviceInventory_CachesUntilTTLExpires(t *testing.T) {
+	dir := t.TempDir()
+	touch := func(name string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Unix(1000, 0)
+	inv := newDeviceInventory(filepath.Join(dir, "i2c-*"), 10*time.Second)
+	inv.now = func() time.Time { return now }
+
+	touch("i2c-0")
+	if paths, err := inv.Paths(); err != nil || len(paths) != 1 {
+		t.Fatalf("Paths() = %v, %v; want one device", paths, err)
+	}
+
+	touch("i2c-1")
+	now = now.Add(9 * time.Second)
+	if paths, _ := inv.Paths(); len(paths) != 1 {
+		t.Errorf("Expected cached result before TTL, got %v", paths)
+	}
+
+	now = now.Add(time.Second)
+	if paths, _ := inv.Paths(); len(paths) != 2 {
+		t.Errorf("Expected refreshed result once TTL expired, got %v", paths)
+	}
+}
+
+func TestDeviceInventory_CachesEmptyResult(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Unix(1000, 0)
+	inv := newDeviceInventory(filepath.Join(dir, "spidev*"), time.Second)
+	inv.now = func() time.Time { return now }
+
+	paths, err := inv.Paths()
+	if err != nil || paths == nil || len(paths) != 0 {
+		t.Fatalf("Paths() = %#v, %v; want empty non-nil slice", paths, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "spidev0.0"), nil, 0644)
+	if paths, _ := inv.Paths(); len(paths) != 0 {
+		t.Errorf("Expected empty result to be cached, got %v", paths)
+	}
+}
+
+func TestNewDeviceInventory_DefaultTTL(t *testing.T) {
+	if inv := newDeviceInventory("/dev/null*", 0); inv.ttl != defaultInventoryTTL {
+		t.Errorf("ttl = %v, want %v", inv.ttl, defaultInventoryTTL)
+	}
+}
+
+func TestParseI2CDeviceNames(t *testing.T) {
+	names := parseI2CDeviceNames(map[string]string{
+		"0x38":  "AHT20",
+		"56":    "duplicate of 0x38",
+		" 0x76": "BME280",
+		"0x02":  "reserved",
+		"0x78":  "out of range",
+		"0xZZ":  "invalid",
+		"0x40":  "",
+	})
+
+	if names[0x76] != "BME280" {
+		t.Errorf("Expected whitespace-trimmed hex key, got %v", names)
+	}
+	if n := names[0x38]; n != "AHT20" && n != "duplicate of 0x38" {
+		t.Errorf("Expected 0x38 and 56 to name the same address, got %v", names)
+	}
+	if len(names) != 2 {
+		t.Errorf("Expected only valid entries, got %v", names)
+	}
+}
+
+func TestParseSPIDeviceNames(t *testing.T) {
+	names := parseSPIDeviceNames(map[string]string{
+		"0.0":   "MCP3008",
+		" 1.1 ": "display",
+		"spi0":  "invalid",
+		"2.0":   "",
+	})
+
+	if len(names) != 2 || names["0.0"] != "MCP3008" || names["1.1"] != "display" {
+		t.Errorf("Unexpected names: %v", names)
+	}
+}
+
+func TestI2CTool_ConfiguredDevicesSorted(t *testing.T) {
+	tool := NewI2CTool(I2CToolOptions{DeviceNames: map[string]string{"0x76": "BME280", "0x38": "AHT20"}})
+
+	devices := tool.configuredDevices()
+	if len(devices) != 2 || devices[0] != (configuredDevice{"0x38", "AHT20"}) || devices[1] != (configuredDevice{"0x76", "BME280"}) {
+		t.Errorf("Unexpected configured devices: %+v", devices)
+	}
+}
+
+func TestParseDeviceAllowlist(t *testing.T) {
+	if got := parseDeviceAllowlist(nil, isValidBusID); got != nil {
+		t.Errorf("Expected nil (allow all) for empty list, got %v", got)
+	}
+
+	got := parseDeviceAllowlist([]string{" 1 ", "3", "../0"}, isValidBusID)
+	if len(got) != 2 || !got["1"] || !got["3"] {
+		t.Errorf("Expected {1, 3}, got %v", got)
+	}
+
+	got = parseDeviceAllowlist([]string{"bogus"}, isValidBusID)
+	if got == nil || len(got) != 0 {
+		t.Errorf("Expected empty non-nil set for all-invalid list, got %v", got)
+	}
+}
+
+func TestParseI2CBus_Allowlist(t *testing.T) {
+	allowed := parseDeviceAllowlist([]string{"1"}, isValidBusID)
+
+	if bus, errResult := parseI2CBus(map[string]interface{}{"bus": "1"}, allowed); errResult != nil || bus != "1" {
+		t.Fatalf("Expected bus 1 allowed, got %q, %+v", bus, errResult)
+	}
+	_, errResult := parseI2CBus(map[string]interface{}{"bus": "3"}, allowed)
+	if errResult == nil || !strings.Contains(errResult.ForLLM, "not allowed") || !strings.Contains(errResult.ForLLM, "allowed buses: 1") {
+		t.Fatalf("Expected allowlist rejection, got %+v", errResult)
+	}
+	if _, errResult := parseI2CBus(map[string]interface{}{"bus": "3"}, nil); errResult != nil {
+		t.Fatalf("Expected nil allowlist to allow every bus, got %s", errResult.ForLLM)
+	}
+}
+
+func TestParseSPIArgs_Allowlist(t *testing.T) {
+	allowed := parseDeviceAllowlist([]string{"0.0", "0.1"}, spiDevicePattern.MatchString)
+
+	if dev, _, _, _, errMsg := parseSPIArgs(map[string]interface{}{"device": "0.1"}, allowed); errMsg != "" || dev != "0.1" {
+		t.Fatalf("Expected device 0.1 allowed, got %q, %q", dev, errMsg)
+	}
+	_, _, _, _, errMsg := parseSPIArgs(map[string]interface{}{"device": "1.0"}, allowed)
+	if !strings.Contains(errMsg, "not allowed") || !strings.Contains(errMsg, "allowed devices: 0.0, 0.1") {
+		t.Fatalf("Expected allowlist rejection, got %q", errMsg)
+	}
+}
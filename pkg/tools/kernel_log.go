@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	kernelLogDefaultLines = 100
+	kernelLogMaxLines     = 1000
+	kernelLogMaxBytes     = 32 * 1024
+)
+
+// kernelLogLevels maps syslog level names to kernel priorities; lower is
+// more severe.
+var kernelLogLevels = map[string]int{
+	"emerg":  0,
+	"alert":  1,
+	"crit":   2,
+	"err":    3,
+	"warn":   4,
+	"notice": 5,
+	"info":   6,
+	"debug":  7,
+}
+
+// kernelLogEntry is one kernel log record.
+type kernelLogEntry struct {
+	Level   int           // syslog priority, -1 when unknown
+	Time    time.Duration // since boot
+	Message string
+}
+
+// KernelLogTool reads recent kernel log messages (dmesg), which usually
+// explain failed I2C/SPI probes: missing drivers, bus conflicts, device tree
+// errors. It is read-only. Linux only.
+type KernelLogTool struct {
+	read func(ctx context.Context) ([]kernelLogEntry, error)
+}
+
+func NewKernelLogTool() *KernelLogTool {
+	return &KernelLogTool{read: readKernelLog}
+}
+
+func (t *KernelLogTool) Name() string {
+	return "kernel_log"
+}
+
+func (t *KernelLogTool) Description() string {
+	return "Read recent kernel log messages (dmesg) to debug hardware: driver probe failures, I2C/SPI bus errors, missing modules. Read-only. Linux only."
+}
+
+func (t *KernelLogTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"lines": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Number of most recent matching lines to return (1-%d). Default: %d.", kernelLogMaxLines, kernelLogDefaultLines),
+			},
+			"filter": map[string]interface{}{
+				"type":        "string",
+				"description": "Only return lines matching this case-insensitive regular expression, e.g. \"i2c|spi\"",
+			},
+			"level": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"emerg", "alert", "crit", "err", "warn", "notice", "info", "debug"},
+				"description": "Only return messages of this severity or worse, e.g. \"warn\" also returns errors",
+			},
+		},
+	}
+}
+
+func (t *KernelLogTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	lines := kernelLogDefaultLines
+	if v, ok := args["lines"].(float64); ok {
+		if v < 1 || v > kernelLogMaxLines {
+			return ErrorResult(fmt.Sprintf("lines must be between 1 and %d", kernelLogMaxLines))
+		}
+		lines = int(v)
+	}
+
+	var filter *regexp.Regexp
+	if s, _ := args["filter"].(string); s != "" {
+		re, err := regexp.Compile("(?i)" + s)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("invalid filter: %v", err))
+		}
+		filter = re
+	}
+
+	maxLevel := -1 // no level filter
+	if s, _ := args["level"].(string); s != "" {
+		level, ok := kernelLogLevels[strings.ToLower(s)]
+		if !ok {
+			return ErrorResult(fmt.Sprintf("unknown level: %s (valid: emerg, alert, crit, err, warn, notice, info, debug)", s))
+		}
+		maxLevel = level
+	}
+
+	entries, err := t.read(ctx)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read kernel log: %v", err))
+	}
+
+	var matched []kernelLogEntry
+	for _, e := range entries {
+		// Entries of unknown level are dropped by a level filter
+		if maxLevel >= 0 && (e.Level < 0 || e.Level > maxLevel) {
+			continue
+		}
+		if filter != nil && !filter.MatchString(e.Message) {
+			continue
+		}
+		matched = append(matched, e)
+	}
+	if len(matched) == 0 {
+		return SilentResult("No matching kernel log messages")
+	}
+	if len(matched) > lines {
+		matched = matched[len(matched)-lines:]
+	}
+
+	return SilentResult(formatKernelLog(matched, kernelLogMaxBytes))
+}
+
+// formatKernelLog renders entries like dmesg, keeping the newest lines that
+// fit in maxBytes.
+func formatKernelLog(entries []kernelLogEntry, maxBytes int) string {
+	out := make([]string, 0, len(entries))
+	size := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		line := fmt.Sprintf("[%12.6f] %s", entries[i].Time.Seconds(), entries[i].Message)
+		if size+len(line)+1 > maxBytes {
+			out = append(out, fmt.Sprintf("[%d earlier lines omitted]", i+1))
+			break
+		}
+		size += len(line) + 1
+		out = append(out, line)
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return strings.Join(out, "\n")
+}
+
+// parseKmsgRecord parses a /dev/kmsg record: "prio,seq,usec,flags;message"
+// followed by optional " KEY=value" continuation lines, which are dropped.
+func parseKmsgRecord(record string) (kernelLogEntry, bool) {
+	header, message, ok := strings.Cut(record, ";")
+	if !ok {
+		return kernelLogEntry{}, false
+	}
+	fields := strings.Split(header, ",")
+	if len(fields) < 3 {
+		return kernelLogEntry{}, false
+	}
+	prio, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return kernelLogEntry{}, false
+	}
+	usec, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return kernelLogEntry{}, false
+	}
+	message, _, _ = strings.Cut(message, "\n")
+	return kernelLogEntry{
+		Level:   prio & 7,
+		Time:    time.Duration(usec) * time.Microsecond,
+		Message: message,
+	}, true
+}
+
+var dmesgRawLine = regexp.MustCompile(`^(?:<(\d+)>)?\[\s*(\d+)\.(\d+)\]\s?(.*)$`)
+
+// parseDmesgLine parses a line of "dmesg -r" output ("<6>[    1.234567] msg"),
+// also accepting lines without the priority prefix.
+func parseDmesgLine(line string) (kernelLogEntry, bool) {
+	m := dmesgRawLine.FindStringSubmatch(line)
+	if m == nil {
+		return kernelLogEntry{}, false
+	}
+	level := -1
+	if m[1] != "" {
+		prio, _ := strconv.Atoi(m[1])
+		level = prio & 7
+	}
+	secs, _ := strconv.ParseInt(m[2], 10, 64)
+	frac := (m[3] + "000000")[:6]
+	usec, _ := strconv.ParseInt(frac, 10, 64)
+	return kernelLogEntry{
+		Level:   level,
+		Time:    time.Duration(secs)*time.Second + time.Duration(usec)*time.Microsecond,
+		Message: m[4],
+	}, true
+}
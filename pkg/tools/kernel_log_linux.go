@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// readKernelLog reads the kernel ring buffer from /dev/kmsg, falling back to
+// "dmesg -r" when /dev/kmsg cannot be opened (e.g. dmesg_restrict without
+// CAP_SYSLOG on the device but a setuid dmesg).
+func readKernelLog(ctx context.Context) ([]kernelLogEntry, error) {
+	entries, kmsgErr := readKmsg()
+	if kmsgErr == nil {
+		return entries, nil
+	}
+	entries, err := readDmesg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("/dev/kmsg: %v; dmesg: %v", kmsgErr, err)
+	}
+	return entries, nil
+}
+
+// readKmsg drains the records currently in /dev/kmsg without blocking.
+func readKmsg() ([]kernelLogEntry, error) {
+	fd, err := syscall.Open("/dev/kmsg", syscall.O_RDONLY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.Close(fd)
+
+	var entries []kernelLogEntry
+	buf := make([]byte, 8192) // each read returns one record of at most ~8K
+	for {
+		n, err := syscall.Read(fd, buf)
+		if errors.Is(err, syscall.EAGAIN) {
+			return entries, nil
+		}
+		if errors.Is(err, syscall.EPIPE) {
+			continue // records were overwritten while reading; skip ahead
+		}
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if n <= 0 {
+			return entries, nil
+		}
+		if e, ok := parseKmsgRecord(string(buf[:n])); ok {
+			entries = append(entries, e)
+		}
+	}
+}
+
+func readDmesg(ctx context.Context) ([]kernelLogEntry, error) {
+	out, err := exec.CommandContext(ctx, "dmesg", "-r").Output()
+	if err != nil {
+		return nil, err
+	}
+	var entries []kernelLogEntry
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if e, ok := parseDmesgLine(scanner.Text()); ok {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
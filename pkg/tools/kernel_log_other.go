@@ -0,0 +1,13 @@
+//go:build !linux
+
+package tools
+
+import (
+	"context"
+	"fmt"
+)
+
+// readKernelLog is a stub for non-Linux platforms.
+func readKernelLog(ctx context.Context) ([]kernelLogEntry, error) {
+	return nil, fmt.Errorf("kernel log is only supported on Linux")
+}
@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseKmsgRecord(t *testing.T) {
+	e, ok := parseKmsgRecord("3,1234,5123456,-;i2c i2c-1: probe of 1-0038 failed with error -121\n SUBSYSTEM=i2c\n")
+	if !ok {
+		t.Fatal("expected record to parse")
+	}
+	if e.Level != 3 || e.Time != 5123456*time.Microsecond || e.Message != "i2c i2c-1: probe of 1-0038 failed with error -121" {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+
+	// Facility bits are masked off
+	if e, _ := parseKmsgRecord("30,1,0,-;systemd message"); e.Level != 6 {
+		t.Errorf("Level = %d, want 6", e.Level)
+	}
+	if _, ok := parseKmsgRecord("garbage"); ok {
+		t.Error("expected garbage to be rejected")
+	}
+}
+
+func TestParseDmesgLine(t *testing.T) {
+	e, ok := parseDmesgLine("<4>[   12.5] spi-nor spi0.0: unrecognized JEDEC id")
+	if !ok || e.Level != 4 || e.Time != 12500*time.Millisecond || e.Message != "spi-nor spi0.0: unrecognized JEDEC id" {
+		t.Errorf("unexpected entry: %+v, %v", e, ok)
+	}
+	e, ok = parseDmesgLine("[    0.000000] Booting Linux")
+	if !ok || e.Level != -1 || e.Message != "Booting Linux" {
+		t.Errorf("unexpected entry without level: %+v, %v", e, ok)
+	}
+}
+
+func TestKernelLogTool_Execute(t *testing.T) {
+	tool := &KernelLogTool{read: func(ctx context.Context) ([]kernelLogEntry, error) {
+		return []kernelLogEntry{
+			{Level: 6, Time: time.Second, Message: "Booting Linux"},
+			{Level: 3, Time: 2 * time.Second, Message: "i2c i2c-1: probe failed"},
+			{Level: 4, Time: 3 * time.Second, Message: "spi0: bus conflict"},
+			{Level: 6, Time: 4 * time.Second, Message: "i2c-dev: loaded"},
+		}, nil
+	}}
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want []string
+	}{
+		{"all", map[string]interface{}{}, []string{"Booting Linux", "probe failed", "bus conflict", "i2c-dev: loaded"}},
+		{"filter", map[string]interface{}{"filter": "I2C"}, []string{"probe failed", "i2c-dev: loaded"}},
+		{"level", map[string]interface{}{"level": "warn"}, []string{"probe failed", "bus conflict"}},
+		{"last lines", map[string]interface{}{"lines": float64(1), "filter": "i2c"}, []string{"i2c-dev: loaded"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tool.Execute(ctx, tt.args)
+			if result.IsError {
+				t.Fatalf("unexpected error: %s", result.ForLLM)
+			}
+			lines := strings.Split(result.ForLLM, "\n")
+			if len(lines) != len(tt.want) {
+				t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(tt.want), result.ForLLM)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(lines[i], want) {
+					t.Errorf("line %d = %q, want it to contain %q", i, lines[i], want)
+				}
+			}
+		})
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"lines": float64(0)},
+		{"filter": "("},
+		{"level": "loud"},
+	} {
+		if result := tool.Execute(ctx, args); !result.IsError {
+			t.Errorf("Execute(%v) succeeded, want error", args)
+		}
+	}
+}
+
+func TestFormatKernelLog_KeepsNewestWithinLimit(t *testing.T) {
+	entries := []kernelLogEntry{
+		{Time: time.Second, Message: strings.Repeat("a", 40)},
+		{Time: 2 * time.Second, Message: strings.Repeat("b", 40)},
+		{Time: 3 * time.Second, Message: "newest"},
+	}
+	out := formatKernelLog(entries, 80)
+	if !strings.HasPrefix(out, "[1 earlier lines omitted]\n[    2.000000] bbb") {
+		t.Errorf("expected omission marker, got:\n%s", out)
+	}
+	if !strings.HasSuffix(out, "newest") {
+		t.Errorf("expected newest line kept, got:\n%s", out)
+	}
+}
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"context"
+	"strings"
+)
+
+// MessageMetadata describes the inbound message that triggered a tool call.
+type MessageMetadata struct {
+	Channel  string
+	ChatID   string
+	SenderID string
+	Extra    map[string]string // Channel-specific metadata from the inbound message
+}
+
+type messageMetadataKey struct{}
+
+// WithMessageMetadata returns a context carrying the inbound message metadata
+// so tools can see who triggered them.
+func WithMessageMetadata(ctx context.Context, md MessageMetadata) context.Context {
+	return context.WithValue(ctx, messageMetadataKey{}, md)
+}
+
+// MessageMetadataFromContext returns the inbound message metadata, if any.
+// Tools invoked outside of a user message (e.g. heartbeat, CLI) get false.
+func MessageMetadataFromContext(ctx context.Context) (MessageMetadata, bool) {
+	md, ok := ctx.Value(messageMetadataKey{}).(MessageMetadata)
+	return md, ok
+}
+
+// RequireAdmin returns an error result unless the sender in ctx matches one of
+// admins. Sender IDs in compound "id|username" form match on either part.
+// Returns nil when the sender is allowed.
+func RequireAdmin(ctx context.Context, admins []string) *ToolResult {
+	md, ok := MessageMetadataFromContext(ctx)
+	if !ok || md.SenderID == "" {
+		return ErrorResult("this action is restricted to admins and the sender is unknown")
+	}
+
+	idPart, userPart := md.SenderID, ""
+	if idx := strings.Index(md.SenderID, "|"); idx > 0 {
+		idPart = md.SenderID[:idx]
+		userPart = md.SenderID[idx+1:]
+	}
+
+	for _, admin := range admins {
+		admin = strings.TrimPrefix(strings.TrimSpace(admin), "@")
+		if admin == "" {
+			continue
+		}
+		if admin == md.SenderID || admin == idPart || (userPart != "" && admin == userPart) {
+			return nil
+		}
+	}
+
+	return ErrorResult("this action is restricted to admins")
+}
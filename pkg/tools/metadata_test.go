@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMessageMetadata_RoundTrip(t *testing.T) {
+	if _, ok := MessageMetadataFromContext(context.Background()); ok {
+		t.Error("Expected no metadata in empty context")
+	}
+
+	ctx := WithMessageMetadata(context.Background(), MessageMetadata{
+		Channel:  "telegram",
+		ChatID:   "42",
+		SenderID: "1001|alice",
+		Extra:    map[string]string{"is_group": "true"},
+	})
+
+	md, ok := MessageMetadataFromContext(ctx)
+	if !ok {
+		t.Fatal("Expected metadata in context")
+	}
+	if md.Channel != "telegram" || md.ChatID != "42" || md.SenderID != "1001|alice" {
+		t.Errorf("Unexpected metadata: %+v", md)
+	}
+	if md.Extra["is_group"] != "true" {
+		t.Errorf("Expected extra metadata to be preserved, got %v", md.Extra)
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	admins := []string{"1001", "@bob"}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		allowed bool
+	}{
+		{"no metadata", context.Background(), false},
+		{"admin id", WithMessageMetadata(context.Background(), MessageMetadata{SenderID: "1001"}), true},
+		{"compound id", WithMessageMetadata(context.Background(), MessageMetadata{SenderID: "1001|alice"}), true},
+		{"compound username", WithMessageMetadata(context.Background(), MessageMetadata{SenderID: "2002|bob"}), true},
+		{"non-admin", WithMessageMetadata(context.Background(), MessageMetadata{SenderID: "3003|carol"}), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RequireAdmin(tt.ctx, admins)
+			if tt.allowed && result != nil {
+				t.Errorf("Expected sender to be allowed, got: %s", result.ForLLM)
+			}
+			if !tt.allowed && (result == nil || !result.IsError) {
+				t.Error("Expected sender to be rejected")
+			}
+		})
+	}
+}
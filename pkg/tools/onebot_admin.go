@@ -0,0 +1,237 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OneBotActionCaller runs a OneBot API action and returns the response data.
+// *channels.OneBotChannel implements it.
+type OneBotActionCaller interface {
+	CallAction(ctx context.Context, action string, params interface{}) (json.RawMessage, error)
+}
+
+// QQ caps group mutes at 30 days.
+const (
+	oneBotDefaultMute = 10 * time.Minute
+	oneBotMaxMute     = 30 * 24 * time.Hour
+)
+
+// OneBotAdminTool moderates QQ groups through OneBot admin actions. The bot
+// account must be an owner or admin of the group. The tool is denied in every
+// chat unless tools.permissions allows it there by name.
+type OneBotAdminTool struct {
+	api OneBotActionCaller
+
+	mu     sync.Mutex
+	selfID int64 // bot account, looked up on first use
+}
+
+func NewOneBotAdminTool(api OneBotActionCaller) *OneBotAdminTool {
+	return &OneBotAdminTool{api: api}
+}
+
+func (t *OneBotAdminTool) Name() string {
+	return "onebot_admin"
+}
+
+func (t *OneBotAdminTool) Description() string {
+	return "Moderate a QQ group through OneBot. Actions: mute (ban a member from speaking for a duration; duration 0 unmutes), kick (remove a member), set_card (set a member's group nickname). The bot must be a group admin."
+}
+
+func (t *OneBotAdminTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"mute", "kick", "set_card"},
+				"description": "Admin action to perform",
+			},
+			"group_id": map[string]interface{}{
+				"type":        "string",
+				"description": "QQ group number",
+			},
+			"user_id": map[string]interface{}{
+				"type":        "string",
+				"description": "QQ number of the member",
+			},
+			"duration_seconds": map[string]interface{}{
+				"type":        "integer",
+				"description": "Mute length in seconds (0 unmutes, at most 30 days). Default: 600. Used with mute.",
+			},
+			"reject_add_request": map[string]interface{}{
+				"type":        "boolean",
+				"description": "If true, also reject future join requests from the member. Used with kick.",
+			},
+			"card": map[string]interface{}{
+				"type":        "string",
+				"description": "New group nickname; empty clears it. Used with set_card.",
+			},
+		},
+		"required": []string{"action", "group_id", "user_id"},
+	}
+}
+
+func (t *OneBotAdminTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	if t.api == nil {
+		return ErrorResult("OneBot channel not configured")
+	}
+
+	action, _ := args["action"].(string)
+	switch action {
+	case "mute", "kick", "set_card":
+	case "":
+		return ErrorResult("action is required")
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s (valid: mute, kick, set_card)", action))
+	}
+
+	groupID, err := parseQQNumber(args, "group_id")
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	userID, err := parseQQNumber(args, "user_id")
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	selfID, err := t.botID(ctx)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to look up bot account: %v", err))
+	}
+	if userID == selfID {
+		return ErrorResult("refusing to run admin actions on the bot itself")
+	}
+	if err := t.requireAdmin(ctx, groupID, selfID); err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	switch action {
+	case "mute":
+		return t.mute(ctx, groupID, userID, args)
+	case "kick":
+		reject, _ := args["reject_add_request"].(bool)
+		if _, err := t.api.CallAction(ctx, "set_group_kick", map[string]interface{}{
+			"group_id":           groupID,
+			"user_id":            userID,
+			"reject_add_request": reject,
+		}); err != nil {
+			return ErrorResult(fmt.Sprintf("kick failed: %v", err))
+		}
+		return SilentResult(fmt.Sprintf("Kicked %d from group %d", userID, groupID))
+	default:
+		card, _ := args["card"].(string)
+		if _, err := t.api.CallAction(ctx, "set_group_card", map[string]interface{}{
+			"group_id": groupID,
+			"user_id":  userID,
+			"card":     card,
+		}); err != nil {
+			return ErrorResult(fmt.Sprintf("set_card failed: %v", err))
+		}
+		if card == "" {
+			return SilentResult(fmt.Sprintf("Cleared group card of %d in group %d", userID, groupID))
+		}
+		return SilentResult(fmt.Sprintf("Set group card of %d in group %d to %q", userID, groupID, card))
+	}
+}
+
+func (t *OneBotAdminTool) mute(ctx context.Context, groupID, userID int64, args map[string]interface{}) *ToolResult {
+	duration := oneBotDefaultMute
+	if v, ok := args["duration_seconds"].(float64); ok {
+		if v < 0 || v > oneBotMaxMute.Seconds() {
+			return ErrorResult(fmt.Sprintf("duration_seconds must be between 0 and %d", int(oneBotMaxMute.Seconds())))
+		}
+		duration = time.Duration(v) * time.Second
+	}
+
+	if _, err := t.api.CallAction(ctx, "set_group_ban", map[string]interface{}{
+		"group_id": groupID,
+		"user_id":  userID,
+		"duration": int64(duration.Seconds()),
+	}); err != nil {
+		return ErrorResult(fmt.Sprintf("mute failed: %v", err))
+	}
+	if duration == 0 {
+		return SilentResult(fmt.Sprintf("Unmuted %d in group %d", userID, groupID))
+	}
+	return SilentResult(fmt.Sprintf("Muted %d in group %d for %s", userID, groupID, duration))
+}
+
+// botID returns the bot's own QQ number, caching it after the first lookup.
+func (t *OneBotAdminTool) botID(ctx context.Context) (int64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.selfID != 0 {
+		return t.selfID, nil
+	}
+
+	data, err := t.api.CallAction(ctx, "get_login_info", map[string]interface{}{})
+	if err != nil {
+		return 0, err
+	}
+	var info struct {
+		UserID int64 `json:"user_id"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil || info.UserID == 0 {
+		return 0, fmt.Errorf("unexpected get_login_info response: %s", data)
+	}
+	t.selfID = info.UserID
+	return t.selfID, nil
+}
+
+// requireAdmin fails unless the bot is an owner or admin of the group. The
+// role is not cached since it can change at any time.
+func (t *OneBotAdminTool) requireAdmin(ctx context.Context, groupID, selfID int64) error {
+	data, err := t.api.CallAction(ctx, "get_group_member_info", map[string]interface{}{
+		"group_id": groupID,
+		"user_id":  selfID,
+		"no_cache": true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check bot role in group %d: %v", groupID, err)
+	}
+	var member struct {
+		Role string `json:"role"`
+	}
+	if err := json.Unmarshal(data, &member); err != nil {
+		return fmt.Errorf("unexpected get_group_member_info response: %s", data)
+	}
+	if member.Role != "owner" && member.Role != "admin" {
+		return fmt.Errorf("bot is not an admin of group %d", groupID)
+	}
+	return nil
+}
+
+// parseQQNumber reads a group or user number given as a string of digits or
+// as a JSON number.
+func parseQQNumber(args map[string]interface{}, key string) (int64, error) {
+	var id int64
+	switch v := args[key].(type) {
+	case string:
+		s := strings.TrimSpace(v)
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || strings.HasPrefix(s, "+") {
+			return 0, fmt.Errorf("%s must be a QQ number, got %q", key, v)
+		}
+		id = n
+	case float64:
+		if v != float64(int64(v)) {
+			return 0, fmt.Errorf("%s must be a whole number", key)
+		}
+		id = int64(v)
+	case nil:
+		return 0, fmt.Errorf("%s is required", key)
+	default:
+		return 0, fmt.Errorf("%s must be a QQ number", key)
+	}
+	if id <= 0 {
+		return 0, fmt.Errorf("%s must be positive", key)
+	}
+	return id, nil
+}
@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type fakeOneBotCaller struct {
+	role    string
+	calls   []string
+	params  []map[string]interface{}
+	failOn  string
+	selfErr error
+}
+
+func (f *fakeOneBotCaller) CallAction(ctx context.Context, action string, params interface{}) (json.RawMessage, error) {
+	f.calls = append(f.calls, action)
+	p, _ := params.(map[string]interface{})
+	f.params = append(f.params, p)
+	if action == f.failOn {
+		return nil, errors.New("retcode 102")
+	}
+	switch action {
+	case "get_login_info":
+		if f.selfErr != nil {
+			return nil, f.selfErr
+		}
+		return json.RawMessage(`{"user_id":10000,"nickname":"bot"}`), nil
+	case "get_group_member_info":
+		return json.RawMessage(`{"role":"` + f.role + `"}`), nil
+	}
+	return json.RawMessage(`null`), nil
+}
+
+func (f *fakeOneBotCaller) last() (string, map[string]interface{}) {
+	return f.calls[len(f.calls)-1], f.params[len(f.params)-1]
+}
+
+func TestOneBotAdminTool_Mute(t *testing.T) {
+	api := &fakeOneBotCaller{role: "admin"}
+	tool := NewOneBotAdminTool(api)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"action":           "mute",
+		"group_id":         "123456",
+		"user_id":          float64(20001),
+		"duration_seconds": float64(120),
+	})
+	if result.IsError {
+		t.Fatalf("mute failed: %s", result.ForLLM)
+	}
+	action, params := api.last()
+	if action != "set_group_ban" || params["group_id"] != int64(123456) || params["user_id"] != int64(20001) || params["duration"] != int64(120) {
+		t.Errorf("last call = %s %v", action, params)
+	}
+
+	// The bot account is looked up once
+	tool.Execute(context.Background(), map[string]interface{}{"action": "kick", "group_id": "123456", "user_id": "20001"})
+	logins := 0
+	for _, c := range api.calls {
+		if c == "get_login_info" {
+			logins++
+		}
+	}
+	if logins != 1 {
+		t.Errorf("get_login_info called %d times, want 1", logins)
+	}
+	if action, _ := api.last(); action != "set_group_kick" {
+		t.Errorf("last call = %s, want set_group_kick", action)
+	}
+}
+
+func TestOneBotAdminTool_SetCard(t *testing.T) {
+	api := &fakeOneBotCaller{role: "owner"}
+	result := NewOneBotAdminTool(api).Execute(context.Background(), map[string]interface{}{
+		"action": "set_card", "group_id": "123456", "user_id": "20001", "card": "Alice",
+	})
+	if result.IsError {
+		t.Fatalf("set_card failed: %s", result.ForLLM)
+	}
+	if action, params := api.last(); action != "set_group_card" || params["card"] != "Alice" {
+		t.Errorf("last call = %s %v", action, params)
+	}
+}
+
+func TestOneBotAdminTool_Rejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    string
+		args    map[string]interface{}
+		wantErr string
+	}{
+		{"unknown action", "admin", map[string]interface{}{"action": "ban_all", "group_id": "1", "user_id": "2"}, "unknown action"},
+		{"missing group", "admin", map[string]interface{}{"action": "kick", "user_id": "2"}, "group_id is required"},
+		{"bad user id", "admin", map[string]interface{}{"action": "kick", "group_id": "1", "user_id": "abc"}, "must be a QQ number"},
+		{"negative id", "admin", map[string]interface{}{"action": "kick", "group_id": "-5", "user_id": "2"}, "must be positive"},
+		{"fractional id", "admin", map[string]interface{}{"action": "kick", "group_id": "1", "user_id": 2.5}, "whole number"},
+		{"bot itself", "admin", map[string]interface{}{"action": "kick", "group_id": "1", "user_id": "10000"}, "bot itself"},
+		{"bot not admin", "member", map[string]interface{}{"action": "kick", "group_id": "1", "user_id": "2"}, "not an admin"},
+		{"mute too long", "admin", map[string]interface{}{"action": "mute", "group_id": "1", "user_id": "2", "duration_seconds": float64(31 * 24 * 3600)}, "duration_seconds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeOneBotCaller{role: tt.role}
+			result := NewOneBotAdminTool(api).Execute(context.Background(), tt.args)
+			if !result.IsError || !strings.Contains(result.ForLLM, tt.wantErr) {
+				t.Fatalf("result = %+v, want error containing %q", result, tt.wantErr)
+			}
+			for _, c := range api.calls {
+				if strings.HasPrefix(c, "set_group_") {
+					t.Errorf("admin action %s sent despite validation failure", c)
+				}
+			}
+		})
+	}
+}
+
+func TestOneBotAdminTool_ActionError(t *testing.T) {
+	api := &fakeOneBotCaller{role: "admin", failOn: "set_group_kick"}
+	result := NewOneBotAdminTool(api).Execute(context.Background(), map[string]interface{}{
+		"action": "kick", "group_id": "1", "user_id": "2",
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "retcode 102") {
+		t.Errorf("result = %+v, want kick error", result)
+	}
+}
@@ -0,0 +1,166 @@
+package tools
+
+import (
+	"sort"
+	"strings"
+)
+
+// ToolRule selects tools by name. Entries are either a tool name ("i2c") or
+// a tool action ("i2c:write"), matched against the tool's "action" argument.
+// An empty Allow list permits every tool; Deny always wins over Allow within
+// the same rule.
+type ToolRule struct {
+	Allow []string
+	Deny  []string
+}
+
+// ToolPolicy decides which tools are offered to the model in a chat. Rules
+// apply from the global rule through the channel rule ("channel") to the chat
+// rule ("channel:chatID"):
+//   - Deny entries accumulate, so a tool denied globally stays denied in
+//     every chat unless a more specific rule lists it in its own Allow.
+//   - The most specific rule with an Allow list decides which tools are
+//     offered, so an admin DM can be given more tools than a group.
+type ToolPolicy struct {
+	global    compiledToolRule
+	overrides map[string]compiledToolRule
+	entries   []string
+}
+
+type compiledToolRule struct {
+	allow   map[string]bool // nil permits every tool
+	deny    map[string]bool
+	actions map[string]bool // tools with action-level allow entries
+}
+
+func compileToolRule(rule ToolRule) compiledToolRule {
+	c := compiledToolRule{deny: make(map[string]bool, len(rule.Deny))}
+	if len(rule.Allow) > 0 {
+		c.allow = make(map[string]bool, len(rule.Allow))
+		c.actions = make(map[string]bool)
+		for _, entry := range rule.Allow {
+			c.allow[entry] = true
+			if tool, _, ok := strings.Cut(entry, ":"); ok {
+				c.actions[tool] = true
+			}
+		}
+	}
+	for _, entry := range rule.Deny {
+		c.deny[entry] = true
+	}
+	return c
+}
+
+// NewToolPolicy builds a policy from a global rule and per-chat overrides
+// keyed by "channel:chatID" or "channel".
+func NewToolPolicy(global ToolRule, overrides map[string]ToolRule) *ToolPolicy {
+	p := &ToolPolicy{
+		global:    compileToolRule(global),
+		overrides: make(map[string]compiledToolRule, len(overrides)),
+	}
+	seen := make(map[string]bool)
+	addEntries := func(rule ToolRule) {
+		for _, list := range [][]string{rule.Allow, rule.Deny} {
+			for _, entry := range list {
+				if !seen[entry] {
+					seen[entry] = true
+					p.entries = append(p.entries, entry)
+				}
+			}
+		}
+	}
+
+	addEntries(global)
+	for key, rule := range overrides {
+		p.overrides[key] = compileToolRule(rule)
+		addEntries(rule)
+	}
+	sort.Strings(p.entries)
+	return p
+}
+
+// Entries returns every distinct tool or tool action named by the policy.
+func (p *ToolPolicy) Entries() []string {
+	if p == nil {
+		return nil
+	}
+	return p.entries
+}
+
+// chain returns the rules that apply to a chat, from least to most specific.
+func (p *ToolPolicy) chain(channel, chatID string) []compiledToolRule {
+	chain := []compiledToolRule{p.global}
+	if rule, ok := p.overrides[channel]; ok {
+		chain = append(chain, rule)
+	}
+	if rule, ok := p.overrides[channel+":"+chatID]; ok {
+		chain = append(chain, rule)
+	}
+	return chain
+}
+
+// permits evaluates a rule chain for a tool and, if non-empty, one of its
+// actions. An empty action asks whether the tool is offered at all.
+func permits(chain []compiledToolRule, name, action string) bool {
+	keys := []string{name}
+	if action != "" {
+		keys = append(keys, name+":"+action)
+	}
+	for _, key := range keys {
+		denied := false
+		for _, rule := range chain {
+			if rule.deny[key] {
+				denied = true
+			} else if rule.allow[key] || rule.allow[name] {
+				denied = false
+			}
+		}
+		if denied {
+			return false
+		}
+	}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		rule := chain[i]
+		if rule.allow == nil {
+			continue
+		}
+		if rule.allow[name] {
+			return true
+		}
+		if !rule.actions[name] {
+			return false
+		}
+		// Only some actions of this tool are allowed
+		return action == "" || rule.allow[name+":"+action]
+	}
+	return true
+}
+
+// Allowed reports whether the named tool may be offered in the given chat.
+// A tool with only some actions allowed is offered. A nil policy allows
+// everything.
+func (p *ToolPolicy) Allowed(channel, chatID, name string) bool {
+	return p.AllowedAction(channel, chatID, name, "")
+}
+
+// AllowedAction reports whether the named tool may run the given action in
+// the given chat. An empty action checks the tool as a whole.
+func (p *ToolPolicy) AllowedAction(channel, chatID, name, action string) bool {
+	if p == nil {
+		return true
+	}
+	return permits(p.chain(channel, chatID), name, action)
+}
+
+// Filter returns a predicate over tool names for the given chat. A nil policy
+// returns nil, meaning no filtering.
+func (p *ToolPolicy) Filter(channel, chatID string) func(name string) bool {
+	if p == nil {
+		return nil
+	}
+	chain := p.chain(channel, chatID)
+	return func(name string) bool {
+		return permits(chain, name, "")
+	}
+}
@@ -0,0 +1,105 @@
+package tools
+
+import "testing"
+
+func TestToolPolicy_Precedence(t *testing.T) {
+	policy := NewToolPolicy(
+		ToolRule{Deny: []string{"exec"}},
+		map[string]ToolRule{
+			"onebot":           {Allow: []string{"read_file", "web_search"}},
+			"onebot:private:7": {Allow: []string{"read_file", "write_file", "exec"}},
+			"telegram:42":      {Deny: []string{"write_file"}},
+		},
+	)
+
+	tests := []struct {
+		channel, chatID, tool string
+		want                  bool
+	}{
+		{"telegram", "1", "read_file", true},
+		{"telegram", "1", "exec", false},
+		{"onebot", "group:9", "read_file", true},
+		{"onebot", "group:9", "write_file", false},
+		{"onebot", "group:9", "exec", false},
+		// The chat's own allow list lifts the global deny
+		{"onebot", "private:7", "exec", true},
+		{"onebot", "private:7", "write_file", true},
+		{"onebot", "private:7", "web_search", false},
+		// A deny-only override adds to the global deny instead of replacing it
+		{"telegram", "42", "write_file", false},
+		{"telegram", "42", "exec", false},
+		{"telegram", "42", "read_file", true},
+	}
+
+	for _, tt := range tests {
+		if got := policy.Allowed(tt.channel, tt.chatID, tt.tool); got != tt.want {
+			t.Errorf("Allowed(%q, %q, %q) = %v, want %v", tt.channel, tt.chatID, tt.tool, got, tt.want)
+		}
+	}
+}
+
+func TestToolPolicy_ActionEntries(t *testing.T) {
+	policy := NewToolPolicy(
+		ToolRule{Deny: []string{"i2c:write"}},
+		map[string]ToolRule{
+			"onebot":           {Allow: []string{"read_file", "spi:list", "spi:query"}},
+			"onebot:private:7": {Allow: []string{"i2c", "spi"}},
+		},
+	)
+
+	tests := []struct {
+		channel, chatID, tool, action string
+		want                          bool
+	}{
+		{"telegram", "1", "i2c", "", true},
+		{"telegram", "1", "i2c", "read", true},
+		{"telegram", "1", "i2c", "write", false},
+		{"onebot", "group:9", "spi", "", true},
+		{"onebot", "group:9", "spi", "query", true},
+		{"onebot", "group:9", "spi", "transfer", false},
+		{"onebot", "group:9", "i2c", "", false},
+		// Allowing the whole tool lifts an inherited action deny
+		{"onebot", "private:7", "i2c", "write", true},
+		{"onebot", "private:7", "spi", "transfer", true},
+	}
+
+	for _, tt := range tests {
+		if got := policy.AllowedAction(tt.channel, tt.chatID, tt.tool, tt.action); got != tt.want {
+			t.Errorf("AllowedAction(%q, %q, %q, %q) = %v, want %v", tt.channel, tt.chatID, tt.tool, tt.action, got, tt.want)
+		}
+	}
+}
+
+func TestToolPolicy_Entries(t *testing.T) {
+	policy := NewToolPolicy(
+		ToolRule{Deny: []string{"shell", "i2c:write"}},
+		map[string]ToolRule{"onebot": {Allow: []string{"read_file"}, Deny: []string{"shell"}}},
+	)
+	got := policy.Entries()
+	want := []string{"i2c:write", "read_file", "shell"}
+	if len(got) != len(want) {
+		t.Fatalf("Entries() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Entries() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestToolPolicy_DenyWinsOverAllow(t *testing.T) {
+	policy := NewToolPolicy(ToolRule{Allow: []string{"exec"}, Deny: []string{"exec"}}, nil)
+	if policy.Allowed("cli", "direct", "exec") {
+		t.Error("Expected deny to take precedence over allow")
+	}
+}
+
+func TestToolPolicy_NilAllowsEverything(t *testing.T) {
+	var policy *ToolPolicy
+	if !policy.Allowed("onebot", "group:1", "exec") {
+		t.Error("Expected nil policy to allow every tool")
+	}
+	if policy.Filter("onebot", "group:1") != nil {
+		t.Error("Expected nil policy to return no filter")
+	}
+}
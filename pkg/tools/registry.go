@@ -2,7 +2,10 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,8 +14,12 @@ import (
 )
 
 type ToolRegistry struct {
-	tools map[string]Tool
-	mu    sync.RWMutex
+	tools         map[string]Tool
+	mu            sync.RWMutex
+	maxOutput     int
+	toolMaxOutput map[string]int
+	errorPolicies map[string]ErrorPolicy
+	policy        *ToolPolicy
 }
 
 func NewToolRegistry() *ToolRegistry {
@@ -27,6 +34,106 @@ func (r *ToolRegistry) Register(tool Tool) {
 	r.tools[tool.Name()] = tool
 }
 
+// SetOutputLimits caps the size in bytes of the ForLLM content returned by tools.
+// defaultMax applies to every tool without an entry in perTool; a limit <= 0
+// disables truncation.
+func (r *ToolRegistry) SetOutputLimits(defaultMax int, perTool map[string]int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxOutput = defaultMax
+	r.toolMaxOutput = perTool
+}
+
+// SetErrorPolicies sets, per tool name, whether the tool's errors are shown
+// to the user ("surface") or never shown ("silent"). Tools without an entry
+// keep their results as returned. Unknown policies are logged and ignored.
+func (r *ToolRegistry) SetErrorPolicies(perTool map[string]string) {
+	policies := make(map[string]ErrorPolicy, len(perTool))
+	for name, value := range perTool {
+		switch policy := ErrorPolicy(value); policy {
+		case ErrorPolicyDefault, ErrorPolicySurface, ErrorPolicySilent:
+			policies[name] = policy
+		default:
+			logger.WarnCF("tool", "Unknown tool error policy; using default",
+				map[string]interface{}{
+					"tool":   name,
+					"policy": value,
+				})
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errorPolicies = policies
+}
+
+// ErrorPolicy returns the error policy configured for the named tool.
+func (r *ToolRegistry) ErrorPolicy(name string) ErrorPolicy {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.errorPolicies[name]
+}
+
+// SetPolicy restricts which tools are offered and executed per chat.
+// A nil policy allows every tool.
+func (r *ToolRegistry) SetPolicy(policy *ToolPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policy = policy
+}
+
+func (r *ToolRegistry) chatFilter(channel, chatID string) func(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.policy.Filter(channel, chatID)
+}
+
+func (r *ToolRegistry) permitted(channel, chatID, name, action string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.policy.AllowedAction(channel, chatID, name, action)
+}
+
+// UnknownPolicyEntries returns the policy entries that name no registered
+// tool, or an action the tool does not declare in its "action" enum.
+func (r *ToolRegistry) UnknownPolicyEntries() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var unknown []string
+	for _, entry := range r.policy.Entries() {
+		name, action, hasAction := strings.Cut(entry, ":")
+		tool, ok := r.tools[name]
+		if !ok || (hasAction && !declaresAction(tool, action)) {
+			unknown = append(unknown, entry)
+		}
+	}
+	return unknown
+}
+
+// declaresAction reports whether action is listed in the enum of the tool's
+// "action" parameter.
+func declaresAction(tool Tool, action string) bool {
+	props, _ := tool.Parameters()["properties"].(map[string]interface{})
+	param, _ := props["action"].(map[string]interface{})
+	actions, _ := param["enum"].([]string)
+	for _, a := range actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ToolRegistry) outputLimit(name string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if limit, ok := r.toolMaxOutput[name]; ok {
+		return limit
+	}
+	return r.maxOutput
+}
+
 func (r *ToolRegistry) Get(name string) (Tool, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -57,6 +164,21 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 		return ErrorResult(fmt.Sprintf("tool %q not found", name)).WithError(fmt.Errorf("tool not found"))
 	}
 
+	action, _ := args["action"].(string)
+	if !r.permitted(channel, chatID, name, action) {
+		logger.WarnCF("tool", "Tool denied by policy",
+			map[string]interface{}{
+				"tool":    name,
+				"action":  action,
+				"channel": channel,
+				"chat_id": chatID,
+			})
+		if !r.permitted(channel, chatID, name, "") {
+			return ErrorResult(fmt.Sprintf("tool %q is not available in this chat", name)).WithError(fmt.Errorf("tool not permitted"))
+		}
+		return ErrorResult(fmt.Sprintf("action %q of tool %q is not available in this chat", action, name)).WithError(fmt.Errorf("tool action not permitted"))
+	}
+
 	// If tool implements ContextualTool, set context
 	if contextualTool, ok := tool.(ContextualTool); ok && channel != "" && chatID != "" {
 		contextualTool.SetContext(channel, chatID)
@@ -75,6 +197,17 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 	result := tool.Execute(ctx, args)
 	duration := time.Since(start)
 
+	if limit := r.outputLimit(name); limit > 0 && len(result.ForLLM) > limit {
+		logger.WarnCF("tool", "Tool output truncated",
+			map[string]interface{}{
+				"tool":          name,
+				"result_length": len(result.ForLLM),
+				"limit":         limit,
+			})
+		result.TruncateForLLM(limit)
+	}
+	result.ApplyErrorPolicy(r.ErrorPolicy(name))
+
 	// Log based on result type
 	if result.IsError {
 		logger.ErrorCF("tool", "Tool execution failed",
@@ -115,11 +248,24 @@ func (r *ToolRegistry) GetDefinitions() []map[string]interface{} {
 // ToProviderDefs converts tool definitions to provider-compatible format.
 // This is the format expected by LLM provider APIs.
 func (r *ToolRegistry) ToProviderDefs() []providers.ToolDefinition {
+	return r.toProviderDefs(nil)
+}
+
+// ToProviderDefsFor is like ToProviderDefs but leaves out tools the policy
+// does not permit in the given chat.
+func (r *ToolRegistry) ToProviderDefsFor(channel, chatID string) []providers.ToolDefinition {
+	return r.toProviderDefs(r.chatFilter(channel, chatID))
+}
+
+func (r *ToolRegistry) toProviderDefs(allowed func(name string) bool) []providers.ToolDefinition {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	definitions := make([]providers.ToolDefinition, 0, len(r.tools))
 	for _, tool := range r.tools {
+		if allowed != nil && !allowed(tool.Name()) {
+			continue
+		}
 		schema := ToolToSchema(tool)
 
 		// Safely extract nested values with type checks
@@ -144,6 +290,17 @@ func (r *ToolRegistry) ToProviderDefs() []providers.ToolDefinition {
 	return definitions
 }
 
+// DescribeJSON returns every registered tool as a JSON array of the
+// ToolDefinition values sent to providers, sorted by tool name, so callers
+// can list capabilities without making a model request.
+func (r *ToolRegistry) DescribeJSON() ([]byte, error) {
+	definitions := r.ToProviderDefs()
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Function.Name < definitions[j].Function.Name
+	})
+	return json.Marshal(definitions)
+}
+
 // List returns a list of all registered tool names.
 func (r *ToolRegistry) List() []string {
 	r.mu.RLock()
@@ -166,11 +323,24 @@ func (r *ToolRegistry) Count() int {
 // GetSummaries returns human-readable summaries of all registered tools.
 // Returns a slice of "name - description" strings.
 func (r *ToolRegistry) GetSummaries() []string {
+	return r.getSummaries(nil)
+}
+
+// GetSummariesFor is like GetSummaries but leaves out tools the policy does
+// not permit in the given chat.
+func (r *ToolRegistry) GetSummariesFor(channel, chatID string) []string {
+	return r.getSummaries(r.chatFilter(channel, chatID))
+}
+
+func (r *ToolRegistry) getSummaries(allowed func(name string) bool) []string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	summaries := make([]string, 0, len(r.tools))
 	for _, tool := range r.tools {
+		if allowed != nil && !allowed(tool.Name()) {
+			continue
+		}
 		summaries = append(summaries, fmt.Sprintf("- `%s` - %s", tool.Name(), tool.Description()))
 	}
 	return summaries
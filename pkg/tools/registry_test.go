@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+type fixedOutputTool struct {
+	name   string
+	output string
+}
+
+func (t *fixedOutputTool) Name() string        { return t.name }
+func (t *fixedOutputTool) Description() string { return "returns fixed output" }
+func (t *fixedOutputTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+}
+func (t *fixedOutputTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	return UserResult(t.output)
+}
+
+func TestToolRegistry_OutputLimits(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(&fixedOutputTool{name: "big", output: strings.Repeat("a", 1000)})
+	registry.Register(&fixedOutputTool{name: "small", output: strings.Repeat("b", 1000)})
+	registry.SetOutputLimits(500, map[string]int{"small": 100})
+
+	big := registry.Execute(context.Background(), "big", nil)
+	if big.ForLLM != strings.Repeat("a", 461)+"\n[output truncated, 539 bytes omitted]" {
+		t.Errorf("Expected default limit to apply, got %q", big.ForLLM)
+	}
+	if len(big.ForUser) != 1000 {
+		t.Errorf("Expected ForUser untouched, got length %d", len(big.ForUser))
+	}
+
+	small := registry.Execute(context.Background(), "small", nil)
+	if !strings.Contains(small.ForLLM, "939 bytes omitted") || len(small.ForLLM) > 100 {
+		t.Errorf("Expected per-tool limit to apply, got %q", small.ForLLM)
+	}
+}
+
+func TestToolRegistry_NoOutputLimitByDefault(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(&fixedOutputTool{name: "big", output: strings.Repeat("a", 100)})
+
+	result := registry.Execute(context.Background(), "big", nil)
+	if len(result.ForLLM) != 100 {
+		t.Errorf("Expected no truncation without limits, got length %d", len(result.ForLLM))
+	}
+}
+
+type failingTool struct {
+	fixedOutputTool
+}
+
+func (t *failingTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	return ErrorResult(t.output)
+}
+
+func TestToolRegistry_ErrorPolicies(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(&failingTool{fixedOutputTool{name: "probe", output: "sensor not found"}})
+	registry.Register(&failingTool{fixedOutputTool{name: "quiet", output: "ignored"}})
+	registry.Register(&failingTool{fixedOutputTool{name: "plain", output: "failed"}})
+	registry.Register(&fixedOutputTool{name: "ok", output: "done"})
+	registry.SetErrorPolicies(map[string]string{
+		"probe": "surface",
+		"quiet": "silent",
+		"ok":    "silent",
+		"plain": "loud",
+	})
+
+	probe := registry.Execute(context.Background(), "probe", nil)
+	if probe.ForUser != "sensor not found" || probe.Silent {
+		t.Errorf("surfaced error = %+v, want ForUser set and not silent", probe)
+	}
+	if quiet := registry.Execute(context.Background(), "quiet", nil); !quiet.Silent {
+		t.Errorf("silenced error = %+v, want silent", quiet)
+	}
+	if plain := registry.Execute(context.Background(), "plain", nil); plain.ForUser != "" || plain.Silent {
+		t.Errorf("unknown policy changed the result: %+v", plain)
+	}
+	if ok := registry.Execute(context.Background(), "ok", nil); ok.Silent || ok.ForUser != "done" {
+		t.Errorf("policy changed a successful result: %+v", ok)
+	}
+	if got := registry.ErrorPolicy("plain"); got != ErrorPolicyDefault {
+		t.Errorf("ErrorPolicy(plain) = %q, want default", got)
+	}
+}
+
+func TestToolRegistry_PolicyFiltersDefsAndExecution(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(&fixedOutputTool{name: "read_file", output: "ok"})
+	registry.Register(&fixedOutputTool{name: "exec", output: "ran"})
+	registry.SetPolicy(NewToolPolicy(ToolRule{}, map[string]ToolRule{
+		"onebot": {Deny: []string{"exec"}},
+	}))
+
+	defNames := func(channel, chatID string) []string {
+		var names []string
+		for _, def := range registry.ToProviderDefsFor(channel, chatID) {
+			names = append(names, def.Function.Name)
+		}
+		return names
+	}
+
+	if names := defNames("onebot", "group:1"); len(names) != 1 || names[0] != "read_file" {
+		t.Errorf("Expected only read_file in group defs, got %v", names)
+	}
+	if names := defNames("telegram", "42"); len(names) != 2 {
+		t.Errorf("Expected all tools for other channels, got %v", names)
+	}
+	if summaries := registry.GetSummariesFor("onebot", "group:1"); len(summaries) != 1 {
+		t.Errorf("Expected one summary, got %v", summaries)
+	}
+
+	denied := registry.ExecuteWithContext(context.Background(), "exec", nil, "onebot", "group:1", nil)
+	if !denied.IsError || !strings.Contains(denied.ForLLM, "not available") {
+		t.Errorf("Expected denied execution, got %+v", denied)
+	}
+	allowed := registry.ExecuteWithContext(context.Background(), "exec", nil, "telegram", "42", nil)
+	if allowed.IsError {
+		t.Errorf("Expected exec to run outside onebot, got %+v", allowed)
+	}
+}
+
+type actionTool struct {
+	fixedOutputTool
+}
+
+func (t *actionTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{"type": "string", "enum": []string{"read", "write"}},
+		},
+	}
+}
+
+func TestToolRegistry_PolicyGatesActions(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(&actionTool{fixedOutputTool{name: "i2c", output: "ok"}})
+	registry.SetPolicy(NewToolPolicy(ToolRule{Deny: []string{"i2c:write"}}, nil))
+
+	if defs := registry.ToProviderDefsFor("onebot", "group:1"); len(defs) != 1 {
+		t.Fatalf("Expected i2c to stay offered with an action denied, got %d defs", len(defs))
+	}
+	read := registry.ExecuteWithContext(context.Background(), "i2c", map[string]interface{}{"action": "read"}, "onebot", "group:1", nil)
+	if read.IsError {
+		t.Errorf("Expected read to run, got %+v", read)
+	}
+	write := registry.ExecuteWithContext(context.Background(), "i2c", map[string]interface{}{"action": "write"}, "onebot", "group:1", nil)
+	if !write.IsError || !strings.Contains(write.ForLLM, `action "write"`) {
+		t.Errorf("Expected write to be denied, got %+v", write)
+	}
+}
+
+func TestToolRegistry_UnknownPolicyEntries(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(&actionTool{fixedOutputTool{name: "i2c", output: "ok"}})
+	registry.Register(&fixedOutputTool{name: "exec", output: "ok"})
+	registry.SetPolicy(NewToolPolicy(ToolRule{Deny: []string{"shell", "exec", "i2c:write", "i2c:erase", "exec:run"}}, nil))
+
+	got := registry.UnknownPolicyEntries()
+	want := []string{"exec:run", "i2c:erase", "shell"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("UnknownPolicyEntries() = %v, want %v", got, want)
+	}
+}
+
+func TestToolRegistry_DescribeJSON(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(&fixedOutputTool{name: "web_fetch", output: "ok"})
+	registry.Register(&actionTool{fixedOutputTool{name: "i2c", output: "ok"}})
+
+	data, err := registry.DescribeJSON()
+	if err != nil {
+		t.Fatalf("DescribeJSON() error: %v", err)
+	}
+
+	var defs []providers.ToolDefinition
+	if err := json.Unmarshal(data, &defs); err != nil {
+		t.Fatalf("DescribeJSON() returned invalid JSON: %v", err)
+	}
+	if len(defs) != 2 || defs[0].Function.Name != "i2c" || defs[1].Function.Name != "web_fetch" {
+		t.Fatalf("Expected i2c and web_fetch sorted by name, got %+v", defs)
+	}
+	if defs[0].Type != "function" || defs[1].Function.Description != "returns fixed output" {
+		t.Errorf("Expected provider tool definitions, got %+v", defs)
+	}
+	props, _ := defs[0].Function.Parameters["properties"].(map[string]interface{})
+	if _, ok := props["action"]; !ok {
+		t.Errorf("Expected parameters schema to be included, got %v", defs[0].Function.Parameters)
+	}
+}
@@ -1,6 +1,12 @@
 package tools
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
 
 // ToolResult represents the structured return value from tool execution.
 // It provides clear semantics for different types of results and supports
@@ -27,6 +33,15 @@ type ToolResult struct {
 	// When true, the tool will complete later and notify via callback.
 	Async bool `json:"async"`
 
+	// Images are sent to the LLM along with ForLLM, for vision models to
+	// inspect (screenshots, generated images). Providers without image
+	// support only see ForLLM.
+	Images []providers.Image `json:"-"`
+
+	// Data is the structured form of ForLLM (e.g. the bytes read from a
+	// device) for programmatic callers, so they need not parse the text.
+	Data interface{} `json:"-"`
+
 	// Err is the underlying error (not JSON serialized).
 	// Used for internal error handling and logging.
 	Err error `json:"-"`
@@ -120,6 +135,31 @@ func UserResult(content string) *ToolResult {
 	}
 }
 
+// WithData attaches the structured data behind ForLLM and returns the result
+// for chaining.
+//
+// Example:
+//
+//	result := SilentResult(string(text)).WithData(reading)
+func (tr *ToolResult) WithData(data interface{}) *ToolResult {
+	tr.Data = data
+	return tr
+}
+
+// ImageResult creates a silent ToolResult that returns images to the LLM,
+// with forLLM describing them.
+//
+// Example:
+//
+//	result := ImageResult("Screenshot of the login page", providers.Image{MediaType: "image/png", Data: png})
+func ImageResult(forLLM string, images ...providers.Image) *ToolResult {
+	return &ToolResult{
+		ForLLM: forLLM,
+		Images: images,
+		Silent: true,
+	}
+}
+
 // MarshalJSON implements custom JSON serialization.
 // The Err field is excluded from JSON output via the json:"-" tag.
 func (tr *ToolResult) MarshalJSON() ([]byte, error) {
@@ -141,3 +181,70 @@ func (tr *ToolResult) WithError(err error) *ToolResult {
 	tr.Err = err
 	return tr
 }
+
+// ErrorPolicy controls whether a tool's errors are shown to the user.
+type ErrorPolicy string
+
+const (
+	ErrorPolicyDefault ErrorPolicy = ""        // leave error results as the tool returned them
+	ErrorPolicySurface ErrorPolicy = "surface" // also send errors to the user
+	ErrorPolicySilent  ErrorPolicy = "silent"  // never send errors to the user
+)
+
+// ApplyErrorPolicy adjusts how an error result reaches the user. Surfaced
+// errors get the ForLLM text as ForUser when the tool set none; silenced
+// errors are marked Silent. Successful results are left untouched.
+func (tr *ToolResult) ApplyErrorPolicy(policy ErrorPolicy) *ToolResult {
+	if !tr.IsError {
+		return tr
+	}
+	switch policy {
+	case ErrorPolicySurface:
+		if tr.ForUser == "" {
+			tr.ForUser = tr.ForLLM
+		}
+		tr.Silent = false
+	case ErrorPolicySilent:
+		tr.Silent = true
+	}
+	return tr
+}
+
+// TruncateForLLM caps ForLLM at maxBytes, ending it with a marker that
+// records how much was dropped; the marker counts toward maxBytes. The cut is
+// moved back to a rune boundary so multi-byte characters are never split.
+// When maxBytes is too small for the marker, ForLLM is cut without one.
+// ForUser and Silent are left untouched. A maxBytes <= 0 disables truncation.
+func (tr *ToolResult) TruncateForLLM(maxBytes int) *ToolResult {
+	if maxBytes <= 0 || len(tr.ForLLM) <= maxBytes {
+		return tr
+	}
+	// The omitted count is at most the full length, so a marker built from
+	// it is never shorter than the final one
+	budget := maxBytes - len(truncationMarker(len(tr.ForLLM)))
+	if budget <= 0 {
+		tr.ForLLM = truncateUTF8(tr.ForLLM, maxBytes)
+		return tr
+	}
+	kept := truncateUTF8(tr.ForLLM, budget)
+	tr.ForLLM = kept + truncationMarker(len(tr.ForLLM)-len(kept))
+	return tr
+}
+
+// truncationMarker notes the bytes TruncateForLLM dropped.
+func truncationMarker(omitted int) string {
+	return fmt.Sprintf("\n[output truncated, %d bytes omitted]", omitted)
+}
+
+// truncateUTF8 cuts s to at most maxBytes, moving the cut back to a rune
+// boundary so a multi-byte character is never split.
+func truncateUTF8(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
@@ -3,7 +3,11 @@ package tools
 import (
 	"encoding/json"
 	"errors"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
 )
 
 func TestNewToolResult(t *testing.T) {
@@ -227,3 +231,92 @@ func TestToolResultJSONStructure(t *testing.T) {
 		t.Errorf("Expected silent false, got %v", parsed["silent"])
 	}
 }
+
+func TestToolResult_TruncateForLLM(t *testing.T) {
+	content := strings.Repeat("x", 100)
+	result := UserResult(content)
+	result.TruncateForLLM(50)
+
+	// The marker for 100 omitted bytes is 38 bytes, leaving 12 for content
+	if result.ForLLM != strings.Repeat("x", 12)+"\n[output truncated, 88 bytes omitted]" {
+		t.Errorf("Unexpected truncated ForLLM: %q", result.ForLLM)
+	}
+	if len(result.ForLLM) > 50 {
+		t.Errorf("Truncated ForLLM is %d bytes, want at most 50", len(result.ForLLM))
+	}
+	if result.ForUser != content {
+		t.Errorf("Expected ForUser to be unaffected, got %q", result.ForUser)
+	}
+}
+
+func TestToolResult_TruncateForLLM_LimitBelowMarker(t *testing.T) {
+	result := NewToolResult("0123456789")
+	result.TruncateForLLM(4)
+	if result.ForLLM != "0123" {
+		t.Errorf("Expected a plain cut when the marker does not fit, got %q", result.ForLLM)
+	}
+}
+
+func TestToolResult_TruncateForLLM_NoopWithinLimit(t *testing.T) {
+	result := SilentResult("short")
+	result.TruncateForLLM(100)
+	if result.ForLLM != "short" || !result.Silent {
+		t.Errorf("Expected result unchanged, got %+v", result)
+	}
+
+	result.TruncateForLLM(0)
+	if result.ForLLM != "short" {
+		t.Errorf("Expected zero limit to disable truncation, got %q", result.ForLLM)
+	}
+}
+
+func TestToolResult_TruncateForLLM_RuneBoundary(t *testing.T) {
+	// 60 bytes of 3-byte runes; 45 minus the 37-byte marker leaves 8, which
+	// must back off to two whole runes
+	result := NewToolResult(strings.Repeat("你", 20))
+	result.TruncateForLLM(45)
+	if result.ForLLM != "你你\n[output truncated, 54 bytes omitted]" {
+		t.Errorf("Unexpected truncated ForLLM: %q", result.ForLLM)
+	}
+}
+
+func TestImageResult(t *testing.T) {
+	img := providers.Image{MediaType: "image/png", Data: []byte("png")}
+	result := ImageResult("Screenshot of the page", img)
+
+	if result.ForLLM != "Screenshot of the page" {
+		t.Errorf("ForLLM = %q", result.ForLLM)
+	}
+	if !result.Silent {
+		t.Error("Expected Silent to be true")
+	}
+	if len(result.Images) != 1 || result.Images[0].MediaType != "image/png" {
+		t.Errorf("Images = %#v", result.Images)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	if strings.Contains(string(data), "png") {
+		t.Errorf("image data serialized: %s", data)
+	}
+}
+
+func TestToolResult_WithData(t *testing.T) {
+	reading := map[string]interface{}{"bytes": []int{0x12, 0x34}}
+	result := SilentResult(`{"bytes": [18, 52]}`).WithData(reading)
+
+	data, ok := result.Data.(map[string]interface{})
+	if !ok || !reflect.DeepEqual(data["bytes"], []int{0x12, 0x34}) {
+		t.Errorf("Data = %#v, want the attached reading", result.Data)
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	if strings.Contains(string(encoded), `"data"`) {
+		t.Errorf("Data should not be serialized: %s", encoded)
+	}
+}
@@ -4,16 +4,46 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"path/filepath"
 	"regexp"
 	"runtime"
+	"strings"
+	"time"
 )
 
+// SPIToolOptions configures an SPITool.
+type SPIToolOptions struct {
+	DeviceNames    map[string]string // Friendly names keyed by device ("0.0")
+	InventoryTTL   time.Duration     // How long the device list from list is cached
+	AllowedDevices []string          // Devices ("0.0") the tool may open; empty allows all
+}
+
 // SPITool provides SPI bus interaction for high-speed peripheral communication.
-type SPITool struct{}
+type SPITool struct {
+	inventory *deviceInventory
+	names     map[string]string
+	allowed   map[string]bool // nil allows every device
+}
+
+func NewSPITool(opts SPIToolOptions) *SPITool {
+	return &SPITool{
+		inventory: newDeviceInventory("/dev/spidev*", opts.InventoryTTL),
+		names:     parseSPIDeviceNames(opts.DeviceNames),
+		allowed:   parseDeviceAllowlist(opts.AllowedDevices, spiDevicePattern.MatchString),
+	}
+}
 
-func NewSPITool() *SPITool {
-	return &SPITool{}
+// parseSPIDeviceNames keeps configured names whose key is a valid device
+// identifier ("X.Y"), trimming surrounding whitespace.
+func parseSPIDeviceNames(raw map[string]string) map[string]string {
+	names := make(map[string]string, len(raw))
+	for dev, name := range raw {
+		dev = strings.TrimSpace(dev)
+		if name == "" || !spiDevicePattern.MatchString(dev) {
+			continue
+		}
+		names[dev] = name
+	}
+	return names
 }
 
 func (t *SPITool) Name() string {
@@ -21,7 +51,7 @@ func (t *SPITool) Name() string {
 }
 
 func (t *SPITool) Description() string {
-	return "Interact with SPI bus devices for high-speed peripheral communication. Actions: list (find SPI devices), transfer (full-duplex send/receive), read (receive bytes). Linux only."
+	return "Interact with SPI bus devices for high-speed peripheral communication. Actions: list (find SPI devices), transfer (full-duplex send/receive), read (receive bytes), loopback (self-test with MOSI tied to MISO), write_read (send a command then read the response), query (report current mode, bits and speed). Linux only."
 }
 
 func (t *SPITool) Parameters() map[string]interface{} {
@@ -30,12 +60,12 @@ func (t *SPITool) Parameters() map[string]interface{} {
 		"properties": map[string]interface{}{
 			"action": map[string]interface{}{
 				"type":        "string",
-				"enum":        []string{"list", "transfer", "read"},
-				"description": "Action to perform: list (find available SPI devices), transfer (full-duplex send/receive), read (receive bytes by sending zeros)",
+				"enum":        []string{"list", "transfer", "read", "loopback", "write_read", "query"},
+				"description": "Action to perform: list (find available SPI devices), transfer (full-duplex send/receive), read (receive bytes by sending zeros), loopback (send a test pattern and verify it is echoed back; requires MOSI tied to MISO), write_read (send write_data then clock in read_length bytes in one transfer, returning only the read phase), query (read back the device's current mode, bits per word and max speed without changing them)",
 			},
 			"device": map[string]interface{}{
 				"type":        "string",
-				"description": "SPI device identifier (e.g. \"2.0\" for /dev/spidev2.0). Required for transfer/read.",
+				"description": "SPI device identifier (e.g. \"2.0\" for /dev/spidev2.0). Required for transfer/read/loopback/write_read/query.",
 			},
 			"speed": map[string]interface{}{
 				"type":        "integer",
@@ -47,7 +77,7 @@ func (t *SPITool) Parameters() map[string]interface{} {
 			},
 			"bits": map[string]interface{}{
 				"type":        "integer",
-				"description": "Bits per word. Default: 8.",
+				"description": "Bits per word (1-32). Default: 8. Many spidev drivers only support 8; an unsupported value fails with the widths the device accepts.",
 			},
 			"data": map[string]interface{}{
 				"type":        "array",
@@ -58,9 +88,22 @@ func (t *SPITool) Parameters() map[string]interface{} {
 				"type":        "integer",
 				"description": "Number of bytes to read (1-4096). Required for read action.",
 			},
+			"write_data": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "integer"},
+				"description": "Command/address bytes to send first (0-255 each). Required for write_read action.",
+			},
+			"read_length": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of bytes to read after write_data is sent (1-4096). Required for write_read action.",
+			},
 			"confirm": map[string]interface{}{
 				"type":        "boolean",
-				"description": "Must be true for transfer operations. Safety guard to prevent accidental writes.",
+				"description": "Must be true for transfer, loopback and write_read operations. Safety guard to prevent accidental writes.",
+			},
+			"dry_run": map[string]interface{}{
+				"type":        "boolean",
+				"description": "If true, validate a transfer or write_read and report the bytes and bus settings that would be used without touching the bus. Does not require confirm.",
 			},
 		},
 		"required": []string{"action"},
@@ -84,14 +127,20 @@ func (t *SPITool) Execute(ctx context.Context, args map[string]interface{}) *Too
 		return t.transfer(args)
 	case "read":
 		return t.readDevice(args)
+	case "loopback":
+		return t.loopback(args)
+	case "write_read":
+		return t.writeRead(args)
+	case "query":
+		return t.query(args)
 	default:
-		return ErrorResult(fmt.Sprintf("unknown action: %s (valid: list, transfer, read)", action))
+		return ErrorResult(fmt.Sprintf("unknown action: %s (valid: list, transfer, read, loopback, write_read, query)", action))
 	}
 }
 
 // list finds available SPI devices by globbing /dev/spidev*
 func (t *SPITool) list() *ToolResult {
-	matches, err := filepath.Glob("/dev/spidev*")
+	matches, err := t.inventory.Paths()
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to scan for SPI devices: %v", err))
 	}
@@ -103,13 +152,14 @@ func (t *SPITool) list() *ToolResult {
 	type devInfo struct {
 		Path   string `json:"path"`
 		Device string `json:"device"`
+		Name   string `json:"name,omitempty"`
 	}
 
 	devices := make([]devInfo, 0, len(matches))
 	re := regexp.MustCompile(`/dev/spidev(\d+\.\d+)`)
 	for _, m := range matches {
 		if sub := re.FindStringSubmatch(m); sub != nil {
-			devices = append(devices, devInfo{Path: m, Device: sub[1]})
+			devices = append(devices, devInfo{Path: m, Device: sub[1], Name: t.names[sub[1]]})
 		}
 	}
 
@@ -117,15 +167,52 @@ func (t *SPITool) list() *ToolResult {
 	return SilentResult(fmt.Sprintf("Found %d SPI device(s):\n%s", len(devices), string(result)))
 }
 
-// parseSPIArgs extracts and validates common SPI parameters
-func parseSPIArgs(args map[string]interface{}) (device string, speed uint32, mode uint8, bits uint8, errMsg string) {
+// spiDevicePattern matches a spidev identifier such as "2.0"
+var spiDevicePattern = regexp.MustCompile(`^\d+\.\d+$`)
+
+// parseSPIDevice extracts and validates the SPI device identifier. A non-nil
+// allowed set rejects devices not listed in it.
+func parseSPIDevice(args map[string]interface{}, allowed map[string]bool) (string, string) {
 	dev, ok := args["device"].(string)
 	if !ok || dev == "" {
-		return "", 0, 0, 0, "device is required (e.g. \"2.0\" for /dev/spidev2.0)"
+		return "", "device is required (e.g. \"2.0\" for /dev/spidev2.0)"
+	}
+	if !spiDevicePattern.MatchString(dev) {
+		return "", "invalid device identifier: must be in format \"X.Y\" (e.g. \"2.0\")"
+	}
+	if allowed != nil && !allowed[dev] {
+		return "", fmt.Sprintf("SPI device %s is not allowed by configuration (allowed devices: %s)", dev, allowlistString(allowed))
+	}
+	return dev, ""
+}
+
+// decodeSPIModeFlags lists the SPI_* mode flags set beyond CPOL/CPHA
+func decodeSPIModeFlags(mode uint8) []string {
+	names := []struct {
+		bit  uint8
+		name string
+	}{
+		{0x04, "cs_high"},
+		{0x08, "lsb_first"},
+		{0x10, "3wire"},
+		{0x20, "loop"},
+		{0x40, "no_cs"},
+		{0x80, "ready"},
 	}
-	matched, _ := regexp.MatchString(`^\d+\.\d+$`, dev)
-	if !matched {
-		return "", 0, 0, 0, "invalid device identifier: must be in format \"X.Y\" (e.g. \"2.0\")"
+	flags := []string{}
+	for _, n := range names {
+		if mode&n.bit != 0 {
+			flags = append(flags, n.name)
+		}
+	}
+	return flags
+}
+
+// parseSPIArgs extracts and validates common SPI parameters
+func parseSPIArgs(args map[string]interface{}, allowed map[string]bool) (device string, speed uint32, mode uint8, bits uint8, errMsg string) {
+	dev, errMsg := parseSPIDevice(args, allowed)
+	if errMsg != "" {
+		return "", 0, 0, 0, errMsg
 	}
 
 	speed = 1000000 // default 1 MHz
@@ -154,3 +241,50 @@ func parseSPIArgs(args map[string]interface{}) (device string, speed uint32, mod
 
 	return dev, speed, mode, bits, ""
 }
+
+// spiBitsError explains a rejected bits-per-word setting. supported lists the
+// widths the device accepted when probed; it is empty when probing failed.
+func spiBitsError(bits uint8, devPath string, err error, supported []uint8) string {
+	msg := fmt.Sprintf("%s does not support %d bits per word (%v)", devPath, bits, err)
+	if len(supported) == 0 {
+		return msg
+	}
+	widths := make([]string, len(supported))
+	for i, b := range supported {
+		widths[i] = fmt.Sprintf("%d", b)
+	}
+	return fmt.Sprintf("%s; supported: %s. Retry with one of these bits values", msg, strings.Join(widths, ", "))
+}
+
+// spiLoopbackPattern exercises all-zero, all-one, alternating and walking-bit bytes.
+var spiLoopbackPattern = []byte{
+	0x00, 0xFF, 0xAA, 0x55,
+	0x01, 0x02, 0x04, 0x08, 0x10, 0x20, 0x40, 0x80,
+	0xFE, 0xFD, 0xFB, 0xF7, 0xEF, 0xDF, 0xBF, 0x7F,
+}
+
+// loopbackMismatch describes a byte that did not come back as sent.
+type loopbackMismatch struct {
+	Index    int    `json:"index"`
+	Sent     string `json:"sent"`
+	Received string `json:"received"`
+}
+
+// compareLoopback returns the positions where rx differs from tx
+func compareLoopback(tx, rx []byte) []loopbackMismatch {
+	mismatches := []loopbackMismatch{}
+	for i := range tx {
+		var got byte
+		if i < len(rx) {
+			got = rx[i]
+		}
+		if i >= len(rx) || got != tx[i] {
+			mismatches = append(mismatches, loopbackMismatch{
+				Index:    i,
+				Sent:     fmt.Sprintf("0x%02x", tx[i]),
+				Received: fmt.Sprintf("0x%02x", got),
+			})
+		}
+	}
+	return mismatches
+}
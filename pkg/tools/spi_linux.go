@@ -17,6 +17,11 @@ const (
 	spiIocWrBitsPerWord = 0x40016B03 // _IOW('k', 3, __u8)
 	spiIocWrMaxSpeedHz  = 0x40046B04 // _IOW('k', 4, __u32)
 	spiIocMessage1      = 0x40206B00 // _IOW('k', 0, struct spi_ioc_transfer) — 32 bytes
+
+	// Read-back ioctls: _IOR sets direction bit 2 instead of 1
+	spiIocRdMode        = 0x80016B01 // _IOR('k', 1, __u8)
+	spiIocRdBitsPerWord = 0x80016B03 // _IOR('k', 3, __u8)
+	spiIocRdMaxSpeedHz  = 0x80046B04 // _IOR('k', 4, __u32)
 )
 
 // spiTransfer matches Linux kernel struct spi_ioc_transfer (32 bytes on all architectures).
@@ -51,8 +56,9 @@ func configureSPI(devPath string, mode uint8, bits uint8, speed uint32) (int, *T
 	// Set bits per word
 	_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), spiIocWrBitsPerWord, uintptr(unsafe.Pointer(&bits)))
 	if errno != 0 {
+		supported := spiSupportedBits(fd)
 		syscall.Close(fd)
-		return -1, ErrorResult(fmt.Sprintf("failed to set bits per word %d: %v", bits, errno))
+		return -1, ErrorResult(spiBitsError(bits, devPath, errno, supported))
 	}
 
 	// Set max speed
@@ -65,14 +71,71 @@ func configureSPI(devPath string, mode uint8, bits uint8, speed uint32) (int, *T
 	return fd, nil
 }
 
+// spiSupportedBits probes which bits-per-word values the device accepts.
+// spidev has no capability query, so each width from 1 to 32 is tried in turn
+// and the original setting is restored afterwards. Returns nil if the current
+// setting cannot be read.
+func spiSupportedBits(fd int) []uint8 {
+	var orig uint8
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), spiIocRdBitsPerWord, uintptr(unsafe.Pointer(&orig)))
+	if errno != 0 {
+		return nil
+	}
+	defer syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), spiIocWrBitsPerWord, uintptr(unsafe.Pointer(&orig)))
+
+	var supported []uint8
+	for b := uint8(1); b <= 32; b++ {
+		try := b
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), spiIocWrBitsPerWord, uintptr(unsafe.Pointer(&try)))
+		if errno == 0 {
+			supported = append(supported, b)
+		}
+	}
+	return supported
+}
+
+// spiTransferBytes opens and configures devPath, then performs a single full-duplex
+// transfer of txBuf, returning the bytes clocked in on MISO. op names the
+// operation in error messages (e.g. "read" gives "SPI read failed").
+func spiTransferBytes(op, devPath string, mode uint8, bits uint8, speed uint32, txBuf []byte) ([]byte, *ToolResult) {
+	// spidev settings are shared by every open fd, so configure and transfer
+	// must not interleave with another call's
+	defer lockBus(devPath)()
+	fd, errResult := configureSPI(devPath, mode, bits, speed)
+	if errResult != nil {
+		return nil, errResult
+	}
+	defer syscall.Close(fd)
+
+	rxBuf := make([]byte, len(txBuf))
+
+	xfer := spiTransfer{
+		txBuf:       uint64(uintptr(unsafe.Pointer(&txBuf[0]))),
+		rxBuf:       uint64(uintptr(unsafe.Pointer(&rxBuf[0]))),
+		length:      uint32(len(txBuf)),
+		speedHz:     speed,
+		bitsPerWord: bits,
+	}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), spiIocMessage1, uintptr(unsafe.Pointer(&xfer)))
+	runtime.KeepAlive(txBuf)
+	runtime.KeepAlive(rxBuf)
+	if errno != 0 {
+		return nil, ErrorResult(fmt.Sprintf("SPI %s failed: %v", op, errno))
+	}
+
+	return rxBuf, nil
+}
+
 // transfer performs a full-duplex SPI transfer
 func (t *SPITool) transfer(args map[string]interface{}) *ToolResult {
+	dryRun := isDryRun(args)
 	confirm, _ := args["confirm"].(bool)
-	if !confirm {
+	if !confirm && !dryRun {
 		return ErrorResult("transfer operations require confirm: true. Please confirm with the user before sending data to SPI devices.")
 	}
 
-	dev, speed, mode, bits, errMsg := parseSPIArgs(args)
+	dev, speed, mode, bits, errMsg := parseSPIArgs(args, t.allowed)
 	if errMsg != "" {
 		return ErrorResult(errMsg)
 	}
@@ -99,27 +162,27 @@ func (t *SPITool) transfer(args map[string]interface{}) *ToolResult {
 	}
 
 	devPath := fmt.Sprintf("/dev/spidev%s", dev)
-	fd, errResult := configureSPI(devPath, mode, bits, speed)
-	if errResult != nil {
-		return errResult
-	}
-	defer syscall.Close(fd)
-
-	rxBuf := make([]byte, len(txBuf))
 
-	xfer := spiTransfer{
-		txBuf:       uint64(uintptr(unsafe.Pointer(&txBuf[0]))),
-		rxBuf:       uint64(uintptr(unsafe.Pointer(&rxBuf[0]))),
-		length:      uint32(len(txBuf)),
-		speedHz:     speed,
-		bitsPerWord: bits,
+	if dryRun {
+		hexBytes := make([]string, len(txBuf))
+		for i, b := range txBuf {
+			hexBytes[i] = fmt.Sprintf("0x%02x", b)
+		}
+		result, _ := json.MarshalIndent(map[string]interface{}{
+			"dry_run": true,
+			"device":  devPath,
+			"speed":   speed,
+			"mode":    mode,
+			"bits":    bits,
+			"hex":     hexBytes,
+			"length":  len(txBuf),
+		}, "", "  ")
+		return SilentResult(fmt.Sprintf("[dry run] Would transfer %d byte(s) on %s:\n%s", len(txBuf), devPath, string(result)))
 	}
 
-	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), spiIocMessage1, uintptr(unsafe.Pointer(&xfer)))
-	runtime.KeepAlive(txBuf)
-	runtime.KeepAlive(rxBuf)
-	if errno != 0 {
-		return ErrorResult(fmt.Sprintf("SPI transfer failed: %v", errno))
+	rxBuf, errResult := spiTransferBytes("transfer", devPath, mode, bits, speed, txBuf)
+	if errResult != nil {
+		return errResult
 	}
 
 	// Format received bytes
@@ -130,18 +193,70 @@ func (t *SPITool) transfer(args map[string]interface{}) *ToolResult {
 		intBytes[i] = int(b)
 	}
 
-	result, _ := json.MarshalIndent(map[string]interface{}{
+	summary := map[string]interface{}{
 		"device":   devPath,
 		"sent":     len(txBuf),
 		"received": intBytes,
 		"hex":      hexBytes,
-	}, "", "  ")
-	return SilentResult(string(result))
+	}
+	result, _ := json.MarshalIndent(summary, "", "  ")
+	return SilentResult(string(result)).WithData(summary)
+}
+
+// query reports the current mode, bits per word and max speed of a spidev
+// without changing them (read-only, no confirm needed)
+func (t *SPITool) query(args map[string]interface{}) *ToolResult {
+	dev, errMsg := parseSPIDevice(args, t.allowed)
+	if errMsg != "" {
+		return ErrorResult(errMsg)
+	}
+
+	devPath := fmt.Sprintf("/dev/spidev%s", dev)
+	defer lockBus(devPath)()
+	fd, err := syscall.Open(devPath, syscall.O_RDWR, 0)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to open %s: %v (check permissions and spidev module)", devPath, err))
+	}
+	defer syscall.Close(fd)
+
+	var mode uint8
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), spiIocRdMode, uintptr(unsafe.Pointer(&mode)))
+	if errno != 0 {
+		return ErrorResult(fmt.Sprintf("failed to read SPI mode: %v", errno))
+	}
+
+	var bits uint8
+	_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), spiIocRdBitsPerWord, uintptr(unsafe.Pointer(&bits)))
+	if errno != 0 {
+		return ErrorResult(fmt.Sprintf("failed to read bits per word: %v", errno))
+	}
+	// The kernel reports 0 for the default of 8 bits per word
+	if bits == 0 {
+		bits = 8
+	}
+
+	var speed uint32
+	_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), spiIocRdMaxSpeedHz, uintptr(unsafe.Pointer(&speed)))
+	if errno != 0 {
+		return ErrorResult(fmt.Sprintf("failed to read SPI speed: %v", errno))
+	}
+
+	summary := map[string]interface{}{
+		"device":        devPath,
+		"mode":          mode & 0x03,
+		"cpol":          (mode >> 1) & 0x01,
+		"cpha":          mode & 0x01,
+		"flags":         decodeSPIModeFlags(mode),
+		"bits_per_word": bits,
+		"max_speed_hz":  speed,
+	}
+	result, _ := json.MarshalIndent(summary, "", "  ")
+	return SilentResult(string(result)).WithData(summary)
 }
 
 // readDevice reads bytes from SPI by sending zeros (read-only, no confirm needed)
 func (t *SPITool) readDevice(args map[string]interface{}) *ToolResult {
-	dev, speed, mode, bits, errMsg := parseSPIArgs(args)
+	dev, speed, mode, bits, errMsg := parseSPIArgs(args, t.allowed)
 	if errMsg != "" {
 		return ErrorResult(errMsg)
 	}
@@ -155,29 +270,11 @@ func (t *SPITool) readDevice(args map[string]interface{}) *ToolResult {
 	}
 
 	devPath := fmt.Sprintf("/dev/spidev%s", dev)
-	fd, errResult := configureSPI(devPath, mode, bits, speed)
+	txBuf := make([]byte, length) // zeros
+	rxBuf, errResult := spiTransferBytes("read", devPath, mode, bits, speed, txBuf)
 	if errResult != nil {
 		return errResult
 	}
-	defer syscall.Close(fd)
-
-	txBuf := make([]byte, length) // zeros
-	rxBuf := make([]byte, length)
-
-	xfer := spiTransfer{
-		txBuf:       uint64(uintptr(unsafe.Pointer(&txBuf[0]))),
-		rxBuf:       uint64(uintptr(unsafe.Pointer(&rxBuf[0]))),
-		length:      uint32(length),
-		speedHz:     speed,
-		bitsPerWord: bits,
-	}
-
-	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), spiIocMessage1, uintptr(unsafe.Pointer(&xfer)))
-	runtime.KeepAlive(txBuf)
-	runtime.KeepAlive(rxBuf)
-	if errno != 0 {
-		return ErrorResult(fmt.Sprintf("SPI read failed: %v", errno))
-	}
 
 	hexBytes := make([]string, len(rxBuf))
 	intBytes := make([]int, len(rxBuf))
@@ -186,11 +283,138 @@ func (t *SPITool) readDevice(args map[string]interface{}) *ToolResult {
 		intBytes[i] = int(b)
 	}
 
-	result, _ := json.MarshalIndent(map[string]interface{}{
+	summary := map[string]interface{}{
 		"device": devPath,
 		"bytes":  intBytes,
 		"hex":    hexBytes,
 		"length": len(rxBuf),
+	}
+	result, _ := json.MarshalIndent(summary, "", "  ")
+	return SilentResult(string(result)).WithData(summary)
+}
+
+// loopback sends a known pattern and verifies it is echoed back (requires MOSI tied to MISO)
+func (t *SPITool) loopback(args map[string]interface{}) *ToolResult {
+	confirm, _ := args["confirm"].(bool)
+	if !confirm {
+		return ErrorResult("loopback requires confirm: true. It drives the SPI bus, so make sure MOSI is tied to MISO and no device will misinterpret the test pattern.")
+	}
+
+	dev, speed, mode, bits, errMsg := parseSPIArgs(args, t.allowed)
+	if errMsg != "" {
+		return ErrorResult(errMsg)
+	}
+
+	devPath := fmt.Sprintf("/dev/spidev%s", dev)
+	txBuf := append([]byte(nil), spiLoopbackPattern...)
+	rxBuf, errResult := spiTransferBytes("loopback", devPath, mode, bits, speed, txBuf)
+	if errResult != nil {
+		return errResult
+	}
+
+	mismatches := compareLoopback(txBuf, rxBuf)
+	passed := len(mismatches) == 0
+
+	result, _ := json.MarshalIndent(map[string]interface{}{
+		"device":     devPath,
+		"passed":     passed,
+		"sent":       len(txBuf),
+		"mismatches": mismatches,
 	}, "", "  ")
-	return SilentResult(string(result))
+
+	if !passed {
+		return SilentResult(fmt.Sprintf("SPI loopback FAILED on %s (%d of %d bytes mismatched). Check that MOSI is wired to MISO and the mode/speed are supported.\n%s",
+			devPath, len(mismatches), len(txBuf), string(result)))
+	}
+	return SilentResult(fmt.Sprintf("SPI loopback passed on %s (%d bytes verified)\n%s", devPath, len(txBuf), string(result)))
+}
+
+// writeRead sends write_data and then clocks in read_length bytes within a single
+// transfer (chip select held), returning only the bytes received during the read phase.
+func (t *SPITool) writeRead(args map[string]interface{}) *ToolResult {
+	dryRun := isDryRun(args)
+	confirm, _ := args["confirm"].(bool)
+	if !confirm && !dryRun {
+		return ErrorResult("write_read operations require confirm: true. Please confirm with the user before sending data to SPI devices.")
+	}
+
+	dev, speed, mode, bits, errMsg := parseSPIArgs(args, t.allowed)
+	if errMsg != "" {
+		return ErrorResult(errMsg)
+	}
+
+	dataRaw, ok := args["write_data"].([]interface{})
+	if !ok || len(dataRaw) == 0 {
+		return ErrorResult("write_data is required for write_read (array of byte values 0-255)")
+	}
+
+	readLength := 0
+	if l, ok := args["read_length"].(float64); ok {
+		readLength = int(l)
+	}
+	if readLength < 1 || readLength > 4096 {
+		return ErrorResult("read_length is required for write_read (1-4096)")
+	}
+
+	if len(dataRaw)+readLength > 4096 {
+		return ErrorResult("write_data plus read_length exceeds maximum of 4096 bytes per SPI transfer")
+	}
+
+	txBuf := make([]byte, len(dataRaw)+readLength) // read phase is padded with zeros
+	for i, v := range dataRaw {
+		f, ok := v.(float64)
+		if !ok {
+			return ErrorResult(fmt.Sprintf("write_data[%d] is not a valid byte value", i))
+		}
+		b := int(f)
+		if b < 0 || b > 255 {
+			return ErrorResult(fmt.Sprintf("write_data[%d] = %d is out of byte range (0-255)", i, b))
+		}
+		txBuf[i] = byte(b)
+	}
+
+	devPath := fmt.Sprintf("/dev/spidev%s", dev)
+
+	if dryRun {
+		hexBytes := make([]string, len(txBuf))
+		for i, b := range txBuf {
+			hexBytes[i] = fmt.Sprintf("0x%02x", b)
+		}
+		result, _ := json.MarshalIndent(map[string]interface{}{
+			"dry_run":     true,
+			"device":      devPath,
+			"speed":       speed,
+			"mode":        mode,
+			"bits":        bits,
+			"hex":         hexBytes,
+			"write_bytes": len(dataRaw),
+			"read_length": readLength,
+			"length":      len(txBuf),
+		}, "", "  ")
+		return SilentResult(fmt.Sprintf("[dry run] Would send %d byte(s) then read %d byte(s) on %s in one transfer:\n%s", len(dataRaw), readLength, devPath, string(result)))
+	}
+
+	rxBuf, errResult := spiTransferBytes("write_read", devPath, mode, bits, speed, txBuf)
+	if errResult != nil {
+		return errResult
+	}
+
+	// Discard bytes clocked in while the command was being sent
+	readBuf := rxBuf[len(dataRaw):]
+	hexBytes := make([]string, len(readBuf))
+	intBytes := make([]int, len(readBuf))
+	for i, b := range readBuf {
+		hexBytes[i] = fmt.Sprintf("0x%02x", b)
+		intBytes[i] = int(b)
+	}
+
+	summary := map[string]interface{}{
+		"device": devPath,
+		"sent":   len(dataRaw),
+		"bytes":  intBytes,
+		"hex":    hexBytes,
+		"length": len(readBuf),
+	}
+	result, _ := json.MarshalIndent(summary, "", "  ")
+	return SilentResult(string(result)).WithData(summary)
 }
@@ -11,3 +11,18 @@ func (t *SPITool) transfer(args map[string]interface{}) *ToolResult {
 func (t *SPITool) readDevice(args map[string]interface{}) *ToolResult {
 	return ErrorResult("SPI is only supported on Linux")
 }
+
+// loopback is a stub for non-Linux platforms.
+func (t *SPITool) loopback(args map[string]interface{}) *ToolResult {
+	return ErrorResult("SPI is only supported on Linux")
+}
+
+// writeRead is a stub for non-Linux platforms.
+func (t *SPITool) writeRead(args map[string]interface{}) *ToolResult {
+	return ErrorResult("SPI is only supported on Linux")
+}
+
+// query is a stub for non-Linux platforms.
+func (t *SPITool) query(args map[string]interface{}) *ToolResult {
+	return ErrorResult("SPI is only supported on Linux")
+}
@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestCompareLoopback(t *testing.T) {
+	tests := []struct {
+		name    string
+		tx, rx  []byte
+		wantIdx []int
+	}{
+		{"all match", []byte{0x00, 0xFF, 0xAA}, []byte{0x00, 0xFF, 0xAA}, nil},
+		{"single mismatch", []byte{0x00, 0xFF, 0xAA}, []byte{0x00, 0xFE, 0xAA}, []int{1}},
+		{"short rx", []byte{0x01, 0x02, 0x03}, []byte{0x01}, []int{1, 2}},
+		{"all zero rx", []byte{0x01, 0x00, 0x80}, []byte{0x00, 0x00, 0x00}, []int{0, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareLoopback(tt.tx, tt.rx)
+			if len(got) != len(tt.wantIdx) {
+				t.Fatalf("Expected %d mismatches, got %+v", len(tt.wantIdx), got)
+			}
+			for i, m := range got {
+				if m.Index != tt.wantIdx[i] {
+					t.Errorf("Mismatch %d: expected index %d, got %d", i, tt.wantIdx[i], m.Index)
+				}
+			}
+		})
+	}
+}
+
+func TestCompareLoopback_ReportsHexBytes(t *testing.T) {
+	got := compareLoopback([]byte{0xAA}, []byte{0x55})
+	if len(got) != 1 || got[0].Sent != "0xaa" || got[0].Received != "0x55" {
+		t.Errorf("Unexpected mismatch report: %+v", got)
+	}
+}
+
+func TestSPILoopbackPatternCoversEveryBit(t *testing.T) {
+	var ones, zeros byte
+	for _, b := range spiLoopbackPattern {
+		ones |= b
+		zeros |= ^b
+	}
+	if ones != 0xFF || zeros != 0xFF {
+		t.Errorf("Expected pattern to drive every bit high and low, got ones=%#x zeros=%#x", ones, zeros)
+	}
+}
+
+func TestSPIBitsError(t *testing.T) {
+	msg := spiBitsError(12, "/dev/spidev0.0", syscall.EINVAL, []uint8{8, 16})
+	for _, want := range []string{"/dev/spidev0.0", "12 bits per word", "supported: 8, 16"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Expected %q in %q", want, msg)
+		}
+	}
+
+	msg = spiBitsError(12, "/dev/spidev0.0", syscall.EINVAL, nil)
+	if strings.Contains(msg, "supported") {
+		t.Errorf("Expected no supported list when probing failed, got %q", msg)
+	}
+}
@@ -130,10 +130,6 @@ After completing the task, provide a clear summary of what was done.`
 		Model:         sm.defaultModel,
 		Tools:         tools,
 		MaxIterations: maxIter,
-		LLMOptions: map[string]any{
-			"max_tokens":  4096,
-			"temperature": 0.7,
-		},
 	}, messages, task.OriginChannel, task.OriginChatID)
 
 	sm.mu.Lock()
@@ -288,10 +284,6 @@ func (t *SubagentTool) Execute(ctx context.Context, args map[string]interface{})
 		Model:         sm.defaultModel,
 		Tools:         tools,
 		MaxIterations: maxIter,
-		LLMOptions: map[string]any{
-			"max_tokens":  4096,
-			"temperature": 0.7,
-		},
 	}, messages, t.originChannel, t.originChatID)
 
 	if err != nil {
@@ -49,17 +49,12 @@ func RunToolLoop(ctx context.Context, config ToolLoopConfig, messages []provider
 		// 1. Build tool definitions
 		var providerToolDefs []providers.ToolDefinition
 		if config.Tools != nil {
-			providerToolDefs = config.Tools.ToProviderDefs()
+			providerToolDefs = config.Tools.ToProviderDefsFor(channel, chatID)
 		}
 
-		// 2. Set default LLM options
+		// 2. LLM options; max_tokens and temperature left unset take the
+		// provider's configured defaults
 		llmOpts := config.LLMOptions
-		if llmOpts == nil {
-			llmOpts = map[string]any{
-				"max_tokens":  4096,
-				"temperature": 0.7,
-			}
-		}
 
 		// 3. Call LLM
 		response, err := config.Provider.Chat(ctx, messages, providerToolDefs, config.Model, llmOpts)
@@ -142,6 +137,7 @@ func RunToolLoop(ctx context.Context, config ToolLoopConfig, messages []provider
 				Role:       "tool",
 				Content:    contentForLLM,
 				ToolCallID: tc.ID,
+				Images:     toolResult.Images,
 			}
 			messages = append(messages, toolResultMsg)
 		}
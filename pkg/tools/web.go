@@ -1,15 +1,21 @@
 package tools
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"html"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
 const (
@@ -36,7 +42,7 @@ func (p *BraveSearchProvider) Search(ctx context.Context, query string, count in
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("X-Subscription-Token", p.apiKey)
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := &http.Client{Timeout: 10 * time.Second, Transport: utils.EgressTransport(nil)}
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("request failed: %w", err)
@@ -96,7 +102,7 @@ func (p *DuckDuckGoSearchProvider) Search(ctx context.Context, query string, cou
 
 	req.Header.Set("User-Agent", userAgent)
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := &http.Client{Timeout: 10 * time.Second, Transport: utils.EgressTransport(nil)}
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("request failed: %w", err)
@@ -283,7 +289,7 @@ func (t *WebFetchTool) Name() string {
 }
 
 func (t *WebFetchTool) Description() string {
-	return "Fetch a URL and extract readable content (HTML to text). Use this to get weather info, news, articles, or any web content."
+	return "Fetch a URL and extract readable content (HTML to text). Use this to get weather info, news, articles, or any web content. RSS/Atom feeds are returned as items and can be polled for new entries with since."
 }
 
 func (t *WebFetchTool) Parameters() map[string]interface{} {
@@ -299,6 +305,29 @@ func (t *WebFetchTool) Parameters() map[string]interface{} {
 				"description": "Maximum characters to extract",
 				"minimum":     100.0,
 			},
+			"as": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"feed"},
+				"description": "Set to \"feed\" to parse an RSS/Atom feed into items (title, link, published). Feeds served with an RSS/Atom content type are detected automatically.",
+			},
+			"if_none_match": map[string]interface{}{
+				"type":        "string",
+				"description": "ETag from a previous fetch. If the resource is unchanged the server answers 304 and not_modified is true.",
+			},
+			"if_modified_since": map[string]interface{}{
+				"type":        "string",
+				"description": "Last-Modified value from a previous fetch (HTTP date or RFC 3339). If the resource is unchanged, not_modified is true.",
+			},
+			"since": map[string]interface{}{
+				"type":        "string",
+				"description": "RFC 3339 timestamp (e.g. 2024-05-01T08:00:00Z). In feed mode, only items published after it are returned. Pass the previous result's latest value to poll for new items.",
+			},
+			"follow_links": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Also fetch the first N links in an HTML page's content (0-%d, one hop) and return the start of each linked page under linked. Default: 0", maxFollowLinks),
+				"minimum":     0.0,
+				"maximum":     float64(maxFollowLinks),
+			},
 		},
 		"required": []string{"url"},
 	}
@@ -330,29 +359,51 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 		}
 	}
 
+	as, _ := args["as"].(string)
+	if as != "" && as != "feed" {
+		return ErrorResult(fmt.Sprintf("unsupported as value %q (valid: feed)", as))
+	}
+	var since time.Time
+	if s, ok := args["since"].(string); ok && s != "" {
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("invalid since %q: must be an RFC 3339 timestamp such as 2024-05-01T08:00:00Z", s))
+		}
+		since = parsed
+		as = "feed"
+	}
+
+	followLinks := 0
+	if n, ok := args["follow_links"].(float64); ok {
+		if n < 0 || n > maxFollowLinks {
+			return ErrorResult(fmt.Sprintf("follow_links must be between 0 and %d", maxFollowLinks))
+		}
+		followLinks = int(n)
+	}
+
+	var ifModifiedSince string
+	if v, ok := args["if_modified_since"].(string); ok && v != "" {
+		ims, err := httpDate(v)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		ifModifiedSince = ims
+	}
+
 	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to create request: %v", err))
 	}
 
 	req.Header.Set("User-Agent", userAgent)
-
-	client := &http.Client{
-		Timeout: 60 * time.Second,
-		Transport: &http.Transport{
-			MaxIdleConns:        10,
-			IdleConnTimeout:     30 * time.Second,
-			DisableCompression:  false,
-			TLSHandshakeTimeout: 15 * time.Second,
-		},
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if len(via) >= 5 {
-				return fmt.Errorf("stopped after 5 redirects")
-			}
-			return nil
-		},
+	if etag, ok := args["if_none_match"].(string); ok && etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if ifModifiedSince != "" {
+		req.Header.Set("If-Modified-Since", ifModifiedSince)
 	}
 
+	client := newWebFetchClient()
 	resp, err := client.Do(req)
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("request failed: %v", err))
@@ -364,11 +415,32 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult(fmt.Sprintf("failed to read response: %v", err))
 	}
 
+	if resp.StatusCode == http.StatusNotModified {
+		result := map[string]interface{}{
+			"url":          urlStr,
+			"status":       resp.StatusCode,
+			"not_modified": true,
+		}
+		addCacheValidators(result, resp.Header)
+		resultJSON, _ := json.MarshalIndent(result, "", "  ")
+		return &ToolResult{
+			ForLLM:  fmt.Sprintf("%s has not changed since the previous fetch (304 Not Modified)", urlStr),
+			ForUser: string(resultJSON),
+		}
+	}
+
 	contentType := resp.Header.Get("Content-Type")
 
+	if as == "feed" || isFeedContentType(contentType) ||
+		(strings.Contains(contentType, "xml") && looksLikeFeed(body)) {
+		return t.feedResult(urlStr, resp, body, since, maxChars)
+	}
+
 	var text, extractor string
+	var links []string
 
-	if strings.Contains(contentType, "application/json") {
+	switch fetchContentKind(contentType, body) {
+	case "json":
 		var jsonData interface{}
 		if err := json.Unmarshal(body, &jsonData); err == nil {
 			formatted, _ := json.MarshalIndent(jsonData, "", "  ")
@@ -378,18 +450,20 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 			text = string(body)
 			extractor = "raw"
 		}
-	} else if strings.Contains(contentType, "text/html") || len(body) > 0 &&
-		(strings.HasPrefix(string(body), "<!DOCTYPE") || strings.HasPrefix(strings.ToLower(string(body)), "<html")) {
+	case "html":
 		text = t.extractText(string(body))
 		extractor = "text"
-	} else {
+		if followLinks > 0 {
+			links = extractContentLinks(string(body), resp.Request.URL, followLinks)
+		}
+	default:
 		text = string(body)
 		extractor = "raw"
 	}
 
 	truncated := len(text) > maxChars
 	if truncated {
-		text = text[:maxChars]
+		text = truncateUTF8(text, maxChars)
 	}
 
 	result := map[string]interface{}{
@@ -400,15 +474,295 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 		"length":    len(text),
 		"text":      text,
 	}
+	addCacheValidators(result, resp.Header)
+
+	summary := fmt.Sprintf("Fetched %d bytes from %s (extractor: %s, truncated: %v)", len(text), urlStr, extractor, truncated)
+	if len(links) > 0 {
+		linked := t.fetchLinked(ctx, client, links, min(maxChars, linkedPageMaxChars))
+		result["linked"] = linked
+		fetched := 0
+		for _, page := range linked {
+			if page.Error == "" {
+				fetched++
+			}
+		}
+		summary += fmt.Sprintf(", followed %d of %d link(s)", fetched, len(links))
+	}
 
 	resultJSON, _ := json.MarshalIndent(result, "", "  ")
 
 	return &ToolResult{
-		ForLLM:  fmt.Sprintf("Fetched %d bytes from %s (extractor: %s, truncated: %v)", len(text), urlStr, extractor, truncated),
+		ForLLM:  summary,
+		ForUser: string(resultJSON),
+	}
+}
+
+// newWebFetchClient returns the HTTP client for web_fetch. Requests go
+// through the egress policy, redirects included.
+func newWebFetchClient() *http.Client {
+	return &http.Client{
+		Timeout: 60 * time.Second,
+		Transport: utils.EgressTransport(&http.Transport{
+			MaxIdleConns:        10,
+			IdleConnTimeout:     30 * time.Second,
+			DisableCompression:  false,
+			TLSHandshakeTimeout: 15 * time.Second,
+		}),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return fmt.Errorf("stopped after 5 redirects")
+			}
+			return nil
+		},
+	}
+}
+
+// Limits for follow_links: at most maxFollowLinks pages are followed, all of
+// them within followLinksBudget, and each is cut to linkedPageMaxChars.
+const (
+	maxFollowLinks     = 5
+	followLinksBudget  = 30 * time.Second
+	linkedPageMaxChars = 2000
+)
+
+// linkedPage is one page fetched for follow_links.
+type linkedPage struct {
+	URL       string `json:"url"`
+	Status    int    `json:"status,omitempty"`
+	Text      string `json:"text,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// fetchLinked fetches links concurrently within followLinksBudget and
+// returns them in order, each cut to maxChars.
+func (t *WebFetchTool) fetchLinked(ctx context.Context, client *http.Client, links []string, maxChars int) []linkedPage {
+	ctx, cancel := context.WithTimeout(ctx, followLinksBudget)
+	defer cancel()
+
+	pages := make([]linkedPage, len(links))
+	var wg sync.WaitGroup
+	for i, link := range links {
+		wg.Add(1)
+		go func(i int, link string) {
+			defer wg.Done()
+			pages[i] = t.fetchLinkedPage(ctx, client, link, maxChars)
+		}(i, link)
+	}
+	wg.Wait()
+	return pages
+}
+
+func (t *WebFetchTool) fetchLinkedPage(ctx context.Context, client *http.Client, link string, maxChars int) linkedPage {
+	page := linkedPage{URL: link}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		page.Error = err.Error()
+		return page
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		page.Error = fmt.Sprintf("request failed: %v", err)
+		return page
+	}
+	defer resp.Body.Close()
+	page.Status = resp.StatusCode
+
+	// Only the start of the page is kept, so there is no need to read
+	// all of a large one
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		page.Error = fmt.Sprintf("failed to read response: %v", err)
+		return page
+	}
+	if resp.StatusCode >= 400 {
+		page.Error = fmt.Sprintf("HTTP %d", resp.StatusCode)
+		return page
+	}
+
+	text := string(body)
+	if contentType := resp.Header.Get("Content-Type"); strings.Contains(contentType, "text/html") || contentType == "" {
+		text = t.extractText(text)
+	} else if !strings.HasPrefix(contentType, "text/") {
+		page.Error = fmt.Sprintf("skipped non-text content (%s)", contentType)
+		return page
+	}
+	if len(text) > maxChars {
+		text = truncateUTF8(text, maxChars)
+		page.Truncated = true
+	}
+	page.Text = text
+	return page
+}
+
+var (
+	reAnchorHref = regexp.MustCompile(`(?is)<a\s[^>]*?href\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	reMainBlock  = regexp.MustCompile(`(?is)<(main|article)[\s>].*?</(?:main|article)>`)
+	rePageChrome = regexp.MustCompile(`(?is)<(script|style|nav|header|footer|aside)[\s>].*?</(?:script|style|nav|header|footer|aside)>`)
+)
+
+// extractContentLinks returns up to n distinct http(s) links from the page's
+// content, in document order and resolved against base. Links in the main
+// or article element are preferred when there is one; navigation, headers,
+// footers and sidebars are skipped, as are links back to the page itself.
+func extractContentLinks(htmlContent string, base *url.URL, n int) []string {
+	content := htmlContent
+	if block := reMainBlock.FindString(htmlContent); block != "" {
+		content = block
+	}
+	content = rePageChrome.ReplaceAllLiteralString(content, "")
+
+	self := *base
+	self.Fragment = ""
+	seen := map[string]bool{self.String(): true}
+
+	var links []string
+	for _, m := range reAnchorHref.FindAllStringSubmatch(content, -1) {
+		href := strings.TrimSpace(html.UnescapeString(m[1] + m[2] + m[3]))
+		if href == "" || strings.HasPrefix(href, "#") {
+			continue
+		}
+		u, err := base.Parse(href)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			continue
+		}
+		u.Fragment = ""
+		link := u.String()
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+		links = append(links, link)
+		if len(links) == n {
+			break
+		}
+	}
+	return links
+}
+
+// feedResult renders an RSS/Atom response as a list of items, newest first,
+// keeping only those published after since when it is set.
+func (t *WebFetchTool) feedResult(urlStr string, resp *http.Response, body []byte, since time.Time, maxChars int) *ToolResult {
+	feed, err := parseFeed(body)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read %s as a feed: %v", urlStr, err))
+	}
+
+	// latest lets the caller poll again with since set to it
+	latest := since
+	for _, item := range feed.Items {
+		if item.published.After(latest) {
+			latest = item.published
+		}
+	}
+
+	items := feed.Items
+	undated := 0
+	if !since.IsZero() {
+		items, undated = itemsSince(feed.Items, since)
+	}
+
+	result := map[string]interface{}{
+		"url":       urlStr,
+		"status":    resp.StatusCode,
+		"extractor": "feed",
+		"title":     feed.Title,
+	}
+	addCacheValidators(result, resp.Header)
+	if !since.IsZero() {
+		result["since"] = since.UTC().Format(time.RFC3339)
+		result["undated_skipped"] = undated
+	}
+	if !latest.IsZero() {
+		result["latest"] = latest.UTC().Format(time.RFC3339)
+	}
+
+	// Drop the oldest items until the listing fits in maxChars
+	total := len(items)
+	var resultJSON []byte
+	for {
+		result["items"] = items
+		result["count"] = len(items)
+		result["truncated"] = len(items) < total
+		resultJSON, _ = json.MarshalIndent(result, "", "  ")
+		if len(resultJSON) <= maxChars || len(items) == 0 {
+			break
+		}
+		items = items[:len(items)-1]
+	}
+
+	summary := fmt.Sprintf("Fetched %d feed item(s) from %s", len(items), urlStr)
+	if !since.IsZero() {
+		summary = fmt.Sprintf("Fetched %d feed item(s) newer than %s from %s", len(items), since.UTC().Format(time.RFC3339), urlStr)
+	}
+	return &ToolResult{
+		ForLLM:  summary + ":\n" + string(resultJSON),
 		ForUser: string(resultJSON),
 	}
 }
 
+// addCacheValidators copies the response's ETag and Last-Modified into
+// result so the caller can make a conditional request next time.
+func addCacheValidators(result map[string]interface{}, header http.Header) {
+	if etag := header.Get("ETag"); etag != "" {
+		result["etag"] = etag
+	}
+	if lastModified := header.Get("Last-Modified"); lastModified != "" {
+		result["last_modified"] = lastModified
+	}
+}
+
+// httpDate normalizes an If-Modified-Since value to the HTTP date format,
+// accepting either an HTTP date or an RFC 3339 timestamp.
+func httpDate(value string) (string, error) {
+	if t, err := http.ParseTime(value); err == nil {
+		return t.UTC().Format(http.TimeFormat), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC().Format(http.TimeFormat), nil
+	}
+	return "", fmt.Errorf("invalid if_modified_since %q: use the Last-Modified value from a previous fetch", value)
+}
+
+// fetchContentKind decides how a fetched body is rendered: "json", "html" or
+// "raw". Content-Type parameters such as charset are ignored, and because
+// servers often mislabel responses the body is sniffed as well: an object or
+// array that parses as JSON is treated as JSON whatever the header says, and
+// a body that opens like an HTML document is extracted as HTML even when
+// labelled JSON.
+func fetchContentKind(contentType string, body []byte) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, _, _ = strings.Cut(contentType, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	}
+
+	trimmed := bytes.TrimLeft(body, "\ufeff \t\r\n")
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return "json"
+	}
+	if looksLikeHTML(trimmed) {
+		return "html"
+	}
+
+	switch {
+	case mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json"):
+		return "json"
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		return "html"
+	}
+	return "raw"
+}
+
+// looksLikeHTML reports whether body starts like an HTML document.
+func looksLikeHTML(body []byte) bool {
+	head := strings.ToLower(string(body[:min(len(body), 64)]))
+	return strings.HasPrefix(head, "<!doctype html") || strings.HasPrefix(head, "<html")
+}
+
 func (t *WebFetchTool) extractText(htmlContent string) string {
 	re := regexp.MustCompile(`<script[\s\S]*?</script>`)
 	result := re.ReplaceAllLiteralString(htmlContent, "")
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 // TestWebTool_WebFetch_Success verifies successful URL fetching
@@ -73,6 +75,82 @@ func TestWebTool_WebFetch_JSON(t *testing.T) {
 	}
 }
 
+func TestFetchContentKind(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"json", "application/json", `{"a":1}`, "json"},
+		{"json with charset", "application/json; charset=utf-8", `{"a":1}`, "json"},
+		{"json uppercase", "Application/JSON", `[1,2]`, "json"},
+		{"json suffix", "application/problem+json", `{"title":"x"}`, "json"},
+		{"json labelled text", "text/plain", "  [1, 2, 3]\n", "json"},
+		{"json labelled html", "text/html", `{"a":1}`, "json"},
+		{"json without header", "", "\ufeff{\"a\":1}", "json"},
+		{"invalid json labelled json", "application/json", "not json", "json"},
+		{"html labelled json", "application/json; charset=utf-8", "<!DOCTYPE html><html><body>oops</body></html>", "html"},
+		{"html with charset", "text/html; charset=ISO-8859-1", "<p>hi</p>", "html"},
+		{"html without header", "", "\n<html><body>hi</body></html>", "html"},
+		{"xhtml", "application/xhtml+xml", "<p>hi</p>", "html"},
+		{"plain text", "text/plain", "hello", "raw"},
+		{"json scalar stays raw", "text/plain", `"quoted"`, "raw"},
+		{"malformed header", "application/json;;", `{"a":1}`, "json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fetchContentKind(tt.contentType, []byte(tt.body)); got != tt.want {
+				t.Errorf("fetchContentKind(%q, %q) = %q, want %q", tt.contentType, tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWebTool_WebFetch_MislabeledContentType verifies that the extractor
+// follows the body when the Content-Type header is parameterized or wrong
+func TestWebTool_WebFetch_MislabeledContentType(t *testing.T) {
+	tests := []struct {
+		name          string
+		contentType   string
+		body          string
+		wantExtractor string
+		wantText      string
+	}{
+		{"json with charset", "application/json; charset=utf-8", `{"key":"value"}`, "json", "\"key\": \"value\""},
+		{"json labelled text", "text/plain", `{"key":"value"}`, "json", "\"key\": \"value\""},
+		{"html labelled json", "application/json", "<!DOCTYPE html><html><body><p>Server error</p></body></html>", "text", "Server error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			result := NewWebFetchTool(50000).Execute(context.Background(), map[string]interface{}{"url": server.URL})
+			if result.IsError {
+				t.Fatalf("Expected success, got error: %s", result.ForLLM)
+			}
+
+			var got struct {
+				Extractor string `json:"extractor"`
+				Text      string `json:"text"`
+			}
+			if err := json.Unmarshal([]byte(result.ForUser), &got); err != nil {
+				t.Fatalf("ForUser is not JSON: %v", err)
+			}
+			if got.Extractor != tt.wantExtractor {
+				t.Errorf("Expected extractor %q, got %q", tt.wantExtractor, got.Extractor)
+			}
+			if !strings.Contains(got.Text, tt.wantText) {
+				t.Errorf("Expected text to contain %q, got %q", tt.wantText, got.Text)
+			}
+		})
+	}
+}
+
 // TestWebTool_WebFetch_InvalidURL verifies error handling for invalid URL
 func TestWebTool_WebFetch_InvalidURL(t *testing.T) {
 	tool := NewWebFetchTool(50000)
@@ -173,6 +251,37 @@ func TestWebTool_WebFetch_Truncation(t *testing.T) {
 	}
 }
 
+func TestWebTool_WebFetch_TruncationKeepsRunesWhole(t *testing.T) {
+	// 101 ASCII bytes then 3-byte runes, so a 200-byte cut lands mid-rune
+	content := strings.Repeat("a", 101) + strings.Repeat("世", 100)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	result := NewWebFetchTool(200).Execute(context.Background(), map[string]interface{}{"url": server.URL})
+	if result.IsError {
+		t.Fatalf("Expected success, got error: %s", result.ForLLM)
+	}
+
+	var resultMap map[string]interface{}
+	if err := json.Unmarshal([]byte(result.ForUser), &resultMap); err != nil {
+		t.Fatalf("ForUser is not JSON: %v", err)
+	}
+	text, _ := resultMap["text"].(string)
+	if !utf8.ValidString(text) {
+		t.Errorf("truncated text is not valid UTF-8: %q", text)
+	}
+	if want := strings.Repeat("a", 101) + strings.Repeat("世", 33); text != want {
+		t.Errorf("text = %q (%d bytes), want %d bytes", text, len(text), len(want))
+	}
+	if truncated, _ := resultMap["truncated"].(bool); !truncated {
+		t.Error("Expected 'truncated' to be true")
+	}
+}
+
 // TestWebTool_WebSearch_NoApiKey verifies that nil is returned when no provider is configured
 func TestWebTool_WebSearch_NoApiKey(t *testing.T) {
 	tool := NewWebSearchTool(WebSearchToolOptions{BraveAPIKey: "", BraveMaxResults: 5})
@@ -250,3 +359,203 @@ func TestWebTool_WebFetch_MissingDomain(t *testing.T) {
 		t.Errorf("Expected domain error message, got ForLLM: %s", result.ForLLM)
 	}
 }
+
+// TestWebTool_WebFetch_FeedSince verifies feed detection and the since filter
+func TestWebTool_WebFetch_FeedSince(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		w.Write([]byte(testRSS))
+	}))
+	defer server.Close()
+
+	tool := NewWebFetchTool(50000)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"url":   server.URL,
+		"since": "2024-05-07T00:00:00Z",
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+
+	var got struct {
+		Extractor string     `json:"extractor"`
+		Count     int        `json:"count"`
+		Latest    string     `json:"latest"`
+		Items     []feedItem `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(result.ForUser), &got); err != nil {
+		t.Fatalf("Expected JSON result, got %q: %v", result.ForUser, err)
+	}
+	if got.Extractor != "feed" || got.Count != 1 || got.Items[0].Link != "https://example.com/new" {
+		t.Errorf("Unexpected feed result: %+v", got)
+	}
+	if got.Latest != "2024-05-08T12:30:00Z" {
+		t.Errorf("latest = %q, want newest item date", got.Latest)
+	}
+	if !strings.Contains(result.ForLLM, "example.com/new") {
+		t.Errorf("Expected items in ForLLM, got: %s", result.ForLLM)
+	}
+}
+
+// TestWebTool_WebFetch_AsFeedOverridesContentType verifies as: "feed" on a generic content type
+func TestWebTool_WebFetch_AsFeedOverridesContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(testAtom))
+	}))
+	defer server.Close()
+
+	tool := NewWebFetchTool(50000)
+	result := tool.Execute(context.Background(), map[string]interface{}{"url": server.URL, "as": "feed"})
+	if result.IsError || !strings.Contains(result.ForUser, `"extractor": "feed"`) {
+		t.Fatalf("Expected feed extraction, got: %s / %s", result.ForLLM, result.ForUser)
+	}
+
+	plain := tool.Execute(context.Background(), map[string]interface{}{"url": server.URL})
+	if !strings.Contains(plain.ForUser, `"extractor": "raw"`) {
+		t.Errorf("Expected non-feed fetch to be unchanged, got: %s", plain.ForUser)
+	}
+}
+
+// TestWebTool_WebFetch_InvalidSince verifies since validation
+func TestWebTool_WebFetch_InvalidSince(t *testing.T) {
+	tool := NewWebFetchTool(50000)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"url":   "https://example.com/feed",
+		"since": "yesterday",
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "RFC 3339") {
+		t.Errorf("Expected since validation error, got: %s", result.ForLLM)
+	}
+}
+
+// TestWebTool_WebFetch_ConditionalRequest verifies ETag/Last-Modified handling
+func TestWebTool_WebFetch_ConditionalRequest(t *testing.T) {
+	const etag = `"v1"`
+	const lastModified = "Wed, 08 May 2024 12:30:00 GMT"
+	var gotIMS string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		gotIMS = r.Header.Get("If-Modified-Since")
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("page body"))
+	}))
+	defer server.Close()
+
+	tool := NewWebFetchTool(50000)
+
+	first := tool.Execute(context.Background(), map[string]interface{}{"url": server.URL})
+	if first.IsError || !strings.Contains(first.ForUser, `"etag": "\"v1\""`) || !strings.Contains(first.ForUser, lastModified) {
+		t.Fatalf("Expected validators in result, got: %s", first.ForUser)
+	}
+	if strings.Contains(first.ForUser, "not_modified") {
+		t.Errorf("Expected unconditional fetch to return the body, got: %s", first.ForUser)
+	}
+
+	second := tool.Execute(context.Background(), map[string]interface{}{
+		"url":               server.URL,
+		"if_none_match":     etag,
+		"if_modified_since": "2024-05-08T12:30:00Z",
+	})
+	if second.IsError || !strings.Contains(second.ForUser, `"not_modified": true`) || !strings.Contains(second.ForUser, `"status": 304`) {
+		t.Errorf("Expected not_modified result, got: %s", second.ForUser)
+	}
+	if gotIMS != lastModified {
+		t.Errorf("If-Modified-Since = %q, want %q", gotIMS, lastModified)
+	}
+
+	bad := tool.Execute(context.Background(), map[string]interface{}{"url": server.URL, "if_modified_since": "last week"})
+	if !bad.IsError {
+		t.Errorf("Expected invalid if_modified_since to fail, got: %s", bad.ForLLM)
+	}
+}
+
+// TestExtractContentLinks verifies link selection for follow_links
+func TestExtractContentLinks(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post?id=1")
+	page := `<html><body>
+<nav><a href="/home">Home</a></nav>
+<main>
+<p>See <a href="part2">part 2</a>, <a href='https://other.org/x#top'>other</a> and
+<a class="ext" href="https://other.org/x#bottom">again</a>.</p>
+<a href="#comments">comments</a> <a href="mailto:a@b.c">mail</a>
+<a href="/blog/post?id=1">self</a> <a href="/a?x=1&amp;y=2">amp</a> <a href="/b">b</a>
+</main>
+<footer><a href="/about">About</a></footer>
+</body></html>`
+
+	got := extractContentLinks(page, base, 3)
+	want := []string{"https://example.com/blog/part2", "https://other.org/x", "https://example.com/a?x=1&y=2"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("extractContentLinks() = %v, want %v", got, want)
+	}
+}
+
+// TestWebTool_WebFetch_FollowLinks verifies that linked pages are fetched
+// and returned under linked
+func TestWebTool_WebFetch_FollowLinks(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><p>Index <a href="/one">one</a> <a href="/missing">missing</a> <a href="/three">three</a></p></body></html>`))
+	})
+	mux.HandleFunc("/one", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body><p>Page one body</p></body></html>"))
+	})
+	mux.HandleFunc("/three", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("fetched a link beyond follow_links")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tool := NewWebFetchTool(50000)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"url":          server.URL,
+		"follow_links": float64(2),
+	})
+	if result.IsError {
+		t.Fatalf("Expected success, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "followed 1 of 2 link(s)") {
+		t.Errorf("ForLLM = %q", result.ForLLM)
+	}
+
+	var out struct {
+		Text   string       `json:"text"`
+		Linked []linkedPage `json:"linked"`
+	}
+	if err := json.Unmarshal([]byte(result.ForUser), &out); err != nil {
+		t.Fatalf("ForUser is not JSON: %v", err)
+	}
+	if !strings.Contains(out.Text, "Index") || len(out.Linked) != 2 {
+		t.Fatalf("got %+v", out)
+	}
+	if out.Linked[0].URL != server.URL+"/one" || out.Linked[0].Text != "Page one body" {
+		t.Errorf("linked[0] = %+v", out.Linked[0])
+	}
+	if out.Linked[1].Error == "" || out.Linked[1].Status != http.StatusNotFound {
+		t.Errorf("linked[1] = %+v, want a 404 error", out.Linked[1])
+	}
+}
+
+// TestWebTool_WebFetch_FollowLinksRange verifies follow_links validation
+func TestWebTool_WebFetch_FollowLinksRange(t *testing.T) {
+	tool := NewWebFetchTool(50000)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"url":          "https://example.com",
+		"follow_links": float64(maxFollowLinks + 1),
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "follow_links") {
+		t.Errorf("Expected follow_links error, got %+v", result)
+	}
+}
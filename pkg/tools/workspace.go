@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Workspace is the directory the filesystem tools work in. It is built once
+// and shared so every tool enforces the same boundary. Relative paths resolve
+// against the root; a restricted workspace refuses paths outside it.
+type Workspace struct {
+	root     string // absolute, symlinks resolved; empty for no workspace
+	restrict bool
+}
+
+// NewWorkspace returns a workspace rooted at root, made absolute with
+// symlinks resolved. An empty root accepts every path as given.
+func NewWorkspace(root string, restrict bool) *Workspace {
+	if root != "" {
+		if abs, err := filepath.Abs(root); err == nil {
+			root = abs
+		}
+		root = resolveSymlinks(filepath.Clean(root))
+	}
+	return &Workspace{root: root, restrict: restrict}
+}
+
+// Root returns the workspace directory, or "" when there is none.
+func (w *Workspace) Root() string {
+	if w == nil {
+		return ""
+	}
+	return w.root
+}
+
+// Restricted reports whether paths outside the root are refused.
+func (w *Workspace) Restricted() bool {
+	return w != nil && w.restrict
+}
+
+// Resolve returns the absolute path for path, resolving relative paths
+// against the root. In a restricted workspace, paths that lead outside the
+// root, including through symlinks, are refused. A nil or rootless
+// Workspace returns path unchanged.
+func (w *Workspace) Resolve(path string) (string, error) {
+	if w.Root() == "" {
+		return path, nil
+	}
+
+	absPath := filepath.Clean(path)
+	if !filepath.IsAbs(path) {
+		absPath = filepath.Join(w.root, path)
+	}
+
+	if w.restrict && !w.contains(resolveSymlinks(absPath)) {
+		return "", fmt.Errorf("access denied: path is outside the workspace")
+	}
+	return absPath, nil
+}
+
+func (w *Workspace) contains(path string) bool {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolveSymlinks evaluates symlinks in the longest existing prefix of path,
+// so paths to files that do not exist yet can be checked too.
+func resolveSymlinks(path string) string {
+	var rest []string
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...)
+		} else if !os.IsNotExist(err) {
+			return path
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return path
+		}
+		rest = append([]string{filepath.Base(dir)}, rest...)
+	}
+}
+
+// validatePath resolves path against a workspace directory; see
+// Workspace.Resolve.
+func validatePath(path, workspace string, restrict bool) (string, error) {
+	return NewWorkspace(workspace, restrict).Resolve(path)
+}
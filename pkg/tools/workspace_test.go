@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkspace_Resolve(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "ws")
+	sibling := filepath.Join(dir, "ws2")
+	outside := filepath.Join(dir, "outside")
+	for _, d := range []string{root, sibling, outside} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	ws := NewWorkspace(root, true)
+	resolvedRoot := ws.Root()
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{name: "relative", path: "notes/todo.md", want: filepath.Join(resolvedRoot, "notes/todo.md")},
+		{name: "absolute inside", path: filepath.Join(root, "a.txt"), want: filepath.Join(root, "a.txt")},
+		{name: "root itself", path: ".", want: resolvedRoot},
+		{name: "dot-dot escape", path: "../outside/a.txt", wantErr: true},
+		{name: "sibling with shared prefix", path: filepath.Join(sibling, "a.txt"), wantErr: true},
+		{name: "symlink escape", path: "escape/a.txt", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ws.Resolve(tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Resolve(%q) = %q, want error", tt.path, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve(%q) error = %v", tt.path, err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkspace_Unrestricted(t *testing.T) {
+	ws := NewWorkspace(t.TempDir(), false)
+	if got, err := ws.Resolve("/etc/hosts"); err != nil || got != "/etc/hosts" {
+		t.Errorf("Resolve(/etc/hosts) = %q, %v", got, err)
+	}
+
+	var none *Workspace
+	if got, err := none.Resolve("a.txt"); err != nil || got != "a.txt" {
+		t.Errorf("nil Resolve(a.txt) = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,271 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// ErrEgressDenied is returned for requests to a host the egress policy
+// does not allow.
+var ErrEgressDenied = errors.New("host not allowed by network egress policy")
+
+// egressPolicy restricts the hosts outbound HTTP requests may reach. Rules
+// are hostnames ("api.openai.com"), subdomain wildcards ("*.github.com"), IP
+// addresses or CIDR ranges ("10.0.0.0/8"). Name rules are matched against
+// the request URL; IP and CIDR rules also against every address a hostname
+// resolves to, when the connection is made.
+type egressPolicy struct {
+	allow []string
+	deny  []string
+	// ipRules is set when allow or deny holds IP or CIDR rules, which
+	// have to be checked on resolved addresses.
+	ipRules bool
+}
+
+// egressVerdict is the outcome of checking a request's host by name.
+type egressVerdict int
+
+const (
+	egressDenied egressVerdict = iota
+	egressAllowed
+	// egressResolve: the name matches no allow rule, but allow has IP
+	// rules that its resolved addresses may match.
+	egressResolve
+)
+
+// egressLookupIP resolves hostnames for the connect-time check; tests
+// replace it.
+var egressLookupIP = net.DefaultResolver.LookupIPAddr
+
+var currentEgressPolicy atomic.Pointer[egressPolicy]
+
+// SetEgressPolicy sets the hosts requests sent through EgressTransport may
+// reach. A host matching deny is always refused; when allow is not empty only
+// hosts matching it are permitted. Empty lists lift the restriction.
+// Connections already open, including pooled keep-alive ones, are not
+// checked again.
+func SetEgressPolicy(allow, deny []string) {
+	if len(allow) == 0 && len(deny) == 0 {
+		currentEgressPolicy.Store(nil)
+		return
+	}
+	p := &egressPolicy{
+		allow: normalizeEgressRules(allow),
+		deny:  normalizeEgressRules(deny),
+	}
+	p.ipRules = slices.ContainsFunc(p.allow, isIPEgressRule) || slices.ContainsFunc(p.deny, isIPEgressRule)
+	currentEgressPolicy.Store(p)
+}
+
+// EgressAllowed reports whether the egress policy permits requests to host
+// by name. IP and CIDR rules are matched against a hostname's resolved
+// addresses only by EgressTransport, when it connects.
+func EgressAllowed(host string) bool {
+	policy := currentEgressPolicy.Load()
+	return policy == nil || policy.check(host) == egressAllowed
+}
+
+// check matches host, as given in a URL, against the rules.
+func (p *egressPolicy) check(host string) egressVerdict {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if matchesEgressRule(host, p.deny) {
+		return egressDenied
+	}
+	if len(p.allow) == 0 || matchesEgressRule(host, p.allow) {
+		return egressAllowed
+	}
+	if net.ParseIP(host) == nil && slices.ContainsFunc(p.allow, isIPEgressRule) {
+		return egressResolve
+	}
+	return egressDenied
+}
+
+// EgressTransport wraps base, or http.DefaultTransport when base is nil, so
+// every request, including redirects, is checked against the egress policy
+// before a connection is made. When base is an *http.Transport, IP and CIDR
+// rules are also enforced on the addresses a hostname resolves to, and the
+// connection is made to the checked address so a DNS rebind cannot slip
+// past. Requests sent through a proxy are checked by name only, as the
+// proxy resolves them.
+func EgressTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &egressTransport{base: base}
+	if tr, ok := base.(*http.Transport); ok {
+		tr = tr.Clone()
+		dial := tr.DialContext
+		if dial == nil {
+			dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+		}
+		tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return egressDial(ctx, dial, network, addr)
+		}
+		t.base = tr
+		t.proxy = tr.Proxy
+	}
+	return t
+}
+
+type egressTransport struct {
+	base  http.RoundTripper
+	proxy func(*http.Request) (*url.URL, error)
+}
+
+// egressDialKey carries an egressDialCheck in a request's context to the
+// transport's DialContext.
+type egressDialKey struct{}
+
+// egressDialCheck asks egressDial to check resolved addresses against
+// policy. needAllow is set when the host's name matched no allow rule, so a
+// resolved address has to match an allow IP rule instead.
+type egressDialCheck struct {
+	policy    *egressPolicy
+	needAllow bool
+}
+
+func (t *egressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	policy := currentEgressPolicy.Load()
+	if policy == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	host := req.URL.Hostname()
+	verdict := policy.check(host)
+	if verdict == egressDenied {
+		logger.WarnCF("network", "Outbound request blocked by egress policy",
+			map[string]interface{}{
+				"host": host,
+			})
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("%w: %s", ErrEgressDenied, host)
+	}
+
+	if policy.ipRules && !t.proxied(req) {
+		check := &egressDialCheck{policy: policy, needAllow: verdict == egressResolve}
+		req = req.WithContext(context.WithValue(req.Context(), egressDialKey{}, check))
+	} else if verdict == egressResolve {
+		// Without our own dial the resolved address cannot be checked
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("%w: %s", ErrEgressDenied, host)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// proxied reports whether req is not dialed by this transport: either base
+// is not an *http.Transport or req goes through a proxy.
+func (t *egressTransport) proxied(req *http.Request) bool {
+	if _, ok := t.base.(*http.Transport); !ok {
+		return true
+	}
+	if t.proxy == nil {
+		return false
+	}
+	proxyURL, err := t.proxy(req)
+	return err != nil || proxyURL != nil
+}
+
+// egressDial resolves addr's host, drops the addresses the policy in ctx
+// refuses and dials the first remaining one that connects. Without a policy
+// check in ctx it dials addr unchanged.
+func egressDial(ctx context.Context, dial func(context.Context, string, string) (net.Conn, error), network, addr string) (net.Conn, error) {
+	check, ok := ctx.Value(egressDialKey{}).(*egressDialCheck)
+	if !ok {
+		return dial(ctx, network, addr)
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := egressLookupIP(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	refused := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		ip := a.IP.String()
+		if matchesEgressRule(ip, check.policy.deny) ||
+			check.needAllow && !matchesEgressRule(ip, check.policy.allow) {
+			refused = append(refused, ip)
+			continue
+		}
+		conn, err := dial(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+
+	logger.WarnCF("network", "Outbound connection blocked by egress policy",
+		map[string]interface{}{
+			"host":      host,
+			"addresses": strings.Join(refused, ", "),
+		})
+	return nil, fmt.Errorf("%w: %s resolves only to refused addresses", ErrEgressDenied, host)
+}
+
+// CloseIdleConnections forwards to the wrapped transport so
+// http.Client.CloseIdleConnections keeps working.
+func (t *egressTransport) CloseIdleConnections() {
+	if c, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+func normalizeEgressRules(rules []string) []string {
+	out := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		rule = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(rule)), ".")
+		if rule != "" {
+			out = append(out, rule)
+		}
+	}
+	return out
+}
+
+// isIPEgressRule reports whether rule is an IP address or CIDR range.
+func isIPEgressRule(rule string) bool {
+	return strings.Contains(rule, "/") || net.ParseIP(rule) != nil
+}
+
+func matchesEgressRule(host string, rules []string) bool {
+	ip := net.ParseIP(host)
+	for _, rule := range rules {
+		switch {
+		case strings.HasPrefix(rule, "*."):
+			if strings.HasSuffix(host, rule[1:]) {
+				return true
+			}
+		case strings.Contains(rule, "/"):
+			if _, cidr, err := net.ParseCIDR(rule); err == nil && ip != nil && cidr.Contains(ip) {
+				return true
+			}
+		case ip != nil:
+			if ruleIP := net.ParseIP(rule); ruleIP != nil && ruleIP.Equal(ip) {
+				return true
+			}
+		case host == rule:
+			return true
+		}
+	}
+	return false
+}
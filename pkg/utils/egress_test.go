@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEgressAllowed(t *testing.T) {
+	t.Cleanup(func() { SetEgressPolicy(nil, nil) })
+
+	if !EgressAllowed("example.com") {
+		t.Fatal("host refused without a policy")
+	}
+
+	SetEgressPolicy(
+		[]string{"api.openai.com", "*.github.com", "10.0.0.0/8", "::1", "Example.org."},
+		[]string{"evil.github.com", "10.0.0.1"},
+	)
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"api.openai.com", true},
+		{"API.OpenAI.com", true},
+		{"openai.com", false},
+		{"raw.github.com", true},
+		{"github.com", false},
+		{"evil.github.com", false},
+		{"10.1.2.3", true},
+		{"10.0.0.1", false},
+		{"::1", true},
+		{"example.org", true},
+		{"192.168.1.1", false},
+	}
+	for _, tt := range tests {
+		if got := EgressAllowed(tt.host); got != tt.want {
+			t.Errorf("EgressAllowed(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+
+	SetEgressPolicy(nil, []string{"metadata.internal"})
+	if EgressAllowed("metadata.internal") || !EgressAllowed("example.com") {
+		t.Error("deny-only policy should refuse listed hosts and allow the rest")
+	}
+}
+
+func TestEgressTransport(t *testing.T) {
+	t.Cleanup(func() { SetEgressPolicy(nil, nil) })
+
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: EgressTransport(nil)}
+
+	SetEgressPolicy([]string{"127.0.0.1"}, nil)
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("allowed request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	SetEgressPolicy([]string{"api.openai.com"}, nil)
+	if _, err := client.Get(server.URL); !errors.Is(err, ErrEgressDenied) {
+		t.Fatalf("err = %v, want ErrEgressDenied", err)
+	}
+	if hits != 1 {
+		t.Errorf("server got %d requests, want 1", hits)
+	}
+}
+
+// fakeEgressLookup resolves the names in hosts and fails for the rest.
+func fakeEgressLookup(t *testing.T, hosts map[string]string) {
+	orig := egressLookupIP
+	t.Cleanup(func() { egressLookupIP = orig })
+	egressLookupIP = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		if ip, ok := hosts[host]; ok {
+			return []net.IPAddr{{IP: net.ParseIP(ip)}}, nil
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			return []net.IPAddr{{IP: ip}}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+}
+
+func TestEgressTransport_ChecksResolvedAddress(t *testing.T) {
+	t.Cleanup(func() { SetEgressPolicy(nil, nil) })
+
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	fakeEgressLookup(t, map[string]string{
+		"metadata.test": "127.0.0.1",
+		"svc.test":      "127.0.0.1",
+	})
+	client := &http.Client{Transport: EgressTransport(nil)}
+
+	// A name resolving into a denied range is refused
+	SetEgressPolicy(nil, []string{"127.0.0.0/8"})
+	if _, err := client.Get("http://metadata.test:" + port); !errors.Is(err, ErrEgressDenied) {
+		t.Fatalf("err = %v, want ErrEgressDenied", err)
+	}
+
+	// A name allowed by name is still refused when it resolves into a
+	// denied range, as after a DNS rebind
+	SetEgressPolicy([]string{"svc.test"}, []string{"127.0.0.1"})
+	if _, err := client.Get("http://svc.test:" + port); !errors.Is(err, ErrEgressDenied) {
+		t.Fatalf("err = %v, want ErrEgressDenied", err)
+	}
+
+	// A name matching no allow name rule passes when its address matches an
+	// allowed range
+	SetEgressPolicy([]string{"api.openai.com", "127.0.0.0/8"}, nil)
+	resp, err := client.Get("http://svc.test:" + port)
+	if err != nil {
+		t.Fatalf("request to allowed range failed: %v", err)
+	}
+	resp.Body.Close()
+
+	// Pooled connections were checked when dialed; drop them so the new
+	// policy applies
+	client.CloseIdleConnections()
+	SetEgressPolicy([]string{"api.openai.com", "10.0.0.0/8"}, nil)
+	if _, err := client.Get("http://svc.test:" + port); !errors.Is(err, ErrEgressDenied) {
+		t.Fatalf("err = %v, want ErrEgressDenied", err)
+	}
+
+	if hits != 1 {
+		t.Errorf("server got %d requests, want 1", hits)
+	}
+}
@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrUnsupportedImage is returned by StripImageMetadata for image formats
+// whose metadata it cannot remove.
+var ErrUnsupportedImage = errors.New("unsupported image format")
+
+// StripImageMetadata removes EXIF, XMP, IPTC and text metadata, which may
+// hold GPS coordinates or camera details, from JPEG, PNG and WebP images.
+// Pixel data and color profiles are kept; note that dropping EXIF also drops
+// the orientation tag. GIF and BMP carry no such metadata and are returned
+// unchanged, as is data that is not an image. Other image formats return
+// ErrUnsupportedImage.
+func StripImageMetadata(data []byte) ([]byte, error) {
+	mime := http.DetectContentType(data)
+	switch mime {
+	case "image/jpeg":
+		return stripJPEGMetadata(data)
+	case "image/png":
+		return stripPNGMetadata(data)
+	case "image/webp":
+		return stripWebPMetadata(data)
+	case "image/gif", "image/bmp":
+		return data, nil
+	}
+	if strings.HasPrefix(mime, "image/") {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedImage, mime)
+	}
+	return data, nil
+}
+
+// StripImageFileMetadata returns the path of a copy of the image at path
+// without metadata, written next to downloaded media. Files that are not
+// images, or that have nothing to strip, are returned as path itself.
+func StripImageFileMetadata(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	stripped, err := StripImageMetadata(data)
+	if err != nil {
+		return "", err
+	}
+	if bytes.Equal(stripped, data) {
+		return path, nil
+	}
+
+	mediaDir := filepath.Join(os.TempDir(), "picoclaw_media")
+	if err := os.MkdirAll(mediaDir, 0700); err != nil {
+		return "", err
+	}
+	cleanPath := filepath.Join(mediaDir, uuid.New().String()[:8]+"_clean"+filepath.Ext(path))
+	if err := os.WriteFile(cleanPath, stripped, 0600); err != nil {
+		return "", err
+	}
+	return cleanPath, nil
+}
+
+// stripJPEGMetadata drops APP1 (EXIF, XMP), APP13 (IPTC) and comment
+// segments. APP0 (JFIF), APP2 (ICC profile) and APP14 (Adobe color
+// transform) affect how the image is decoded and are kept.
+func stripJPEGMetadata(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, errors.New("invalid JPEG: missing SOI marker")
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	i := 2
+	for i < len(data) {
+		if data[i] != 0xFF {
+			return nil, fmt.Errorf("invalid JPEG: expected marker at offset %d", i)
+		}
+		// Markers may be preceded by any number of 0xFF fill bytes
+		for i < len(data) && data[i] == 0xFF {
+			i++
+		}
+		if i >= len(data) {
+			return nil, errors.New("invalid JPEG: truncated marker")
+		}
+		marker := data[i]
+		i++
+
+		switch {
+		case marker == 0xD9: // EOI
+			return append(out, 0xFF, marker), nil
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7): // TEM, RSTn
+			out = append(out, 0xFF, marker)
+			continue
+		}
+
+		if i+2 > len(data) {
+			return nil, errors.New("invalid JPEG: truncated segment length")
+		}
+		length := int(binary.BigEndian.Uint16(data[i:]))
+		if length < 2 || i+length > len(data) {
+			return nil, fmt.Errorf("invalid JPEG: bad segment length at offset %d", i)
+		}
+		segment := data[i : i+length]
+		i += length
+
+		if marker == 0xDA { // SOS: entropy-coded data follows up to EOI
+			out = append(out, 0xFF, marker)
+			out = append(out, segment...)
+			return append(out, data[i:]...), nil
+		}
+		if marker == 0xE1 || marker == 0xED || marker == 0xFE {
+			continue
+		}
+		out = append(out, 0xFF, marker)
+		out = append(out, segment...)
+	}
+	return nil, errors.New("invalid JPEG: missing image data")
+}
+
+// pngMetadataChunks are the PNG chunks that carry text, EXIF or timestamps.
+var pngMetadataChunks = map[string]bool{
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"eXIf": true,
+	"tIME": true,
+}
+
+func stripPNGMetadata(data []byte) ([]byte, error) {
+	const sigLen = 8
+	if len(data) < sigLen {
+		return nil, errors.New("invalid PNG: too short")
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:sigLen]...)
+	i := sigLen
+	for i < len(data) {
+		if i+8 > len(data) {
+			return nil, errors.New("invalid PNG: truncated chunk header")
+		}
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		chunkType := string(data[i+4 : i+8])
+		end := i + 12 + length // header, data and CRC
+		if length < 0 || end > len(data) {
+			return nil, fmt.Errorf("invalid PNG: bad %s chunk length", chunkType)
+		}
+		if !pngMetadataChunks[chunkType] {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+		if chunkType == "IEND" {
+			return out, nil
+		}
+	}
+	return nil, errors.New("invalid PNG: missing IEND chunk")
+}
+
+// WebP VP8X flags for the metadata chunks removed by stripWebPMetadata.
+const (
+	webpFlagXMP  = 0x04
+	webpFlagEXIF = 0x08
+)
+
+func stripWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 12 {
+		return nil, errors.New("invalid WebP: too short")
+	}
+
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+	i := 12
+	for i < len(data) {
+		if i+8 > len(data) {
+			return nil, errors.New("invalid WebP: truncated chunk header")
+		}
+		fourCC := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + size + size%2 // chunks are padded to an even size
+		if size < 0 || i+8+size > len(data) {
+			return nil, fmt.Errorf("invalid WebP: bad %s chunk size", fourCC)
+		}
+		end = min(end, len(data))
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			start := len(out)
+			out = append(out, data[i:end]...)
+			if size > 0 {
+				out[start+8] &^= webpFlagEXIF | webpFlagXMP
+			}
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
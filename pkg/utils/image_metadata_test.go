@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	return img
+}
+
+// withJPEGExif inserts an APP1 EXIF segment and a comment after SOI.
+func withJPEGExif(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	exif := append([]byte("Exif\x00\x00"), []byte("GPS 52.52N 13.40E")...)
+	app1 := append([]byte{0xFF, 0xE1, 0, byte(len(exif) + 2)}, exif...)
+	com := append([]byte{0xFF, 0xFE, 0, 9}, []byte("secret!")...)
+	out := append([]byte{}, data[:2]...)
+	out = append(out, app1...)
+	out = append(out, com...)
+	return append(out, data[2:]...)
+}
+
+func pngChunk(typ string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, typ...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func TestStripImageMetadata_JPEG(t *testing.T) {
+	data := withJPEGExif(t)
+	stripped, err := StripImageMetadata(data)
+	if err != nil {
+		t.Fatalf("StripImageMetadata() error: %v", err)
+	}
+	if bytes.Contains(stripped, []byte("GPS")) || bytes.Contains(stripped, []byte("secret")) {
+		t.Error("metadata still present")
+	}
+	img, err := jpeg.Decode(bytes.NewReader(stripped))
+	if err != nil {
+		t.Fatalf("stripped JPEG does not decode: %v", err)
+	}
+	if img.Bounds() != testImage().Bounds() {
+		t.Errorf("bounds = %v, want %v", img.Bounds(), testImage().Bounds())
+	}
+}
+
+func TestStripImageMetadata_PNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	iend := len(data) - 12
+	withText := append([]byte{}, data[:iend]...)
+	withText = append(withText, pngChunk("tEXt", []byte("Location\x00Berlin"))...)
+	withText = append(withText, pngChunk("eXIf", []byte("GPS"))...)
+	withText = append(withText, data[iend:]...)
+
+	stripped, err := StripImageMetadata(withText)
+	if err != nil {
+		t.Fatalf("StripImageMetadata() error: %v", err)
+	}
+	if !bytes.Equal(stripped, data) {
+		t.Error("stripped PNG differs from the original without metadata")
+	}
+}
+
+func TestStripImageMetadata_WebP(t *testing.T) {
+	webpChunk := func(fourCC string, data []byte) []byte {
+		chunk := append([]byte(fourCC), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...)
+		chunk = append(chunk, data...)
+		if len(data)%2 == 1 {
+			chunk = append(chunk, 0)
+		}
+		return chunk
+	}
+	body := []byte("WEBP")
+	body = append(body, webpChunk("VP8X", []byte{webpFlagEXIF | webpFlagXMP | 0x10, 0, 0, 0, 3, 0, 0, 2, 0, 0})...)
+	body = append(body, webpChunk("VP8L", []byte{0x2F, 1, 2, 3, 4})...)
+	body = append(body, webpChunk("EXIF", []byte("GPS"))...)
+	body = append(body, webpChunk("XMP ", []byte("<x/>"))...)
+	data := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	data = append(data, body...)
+
+	stripped, err := StripImageMetadata(data)
+	if err != nil {
+		t.Fatalf("StripImageMetadata() error: %v", err)
+	}
+	if bytes.Contains(stripped, []byte("EXIF")) || bytes.Contains(stripped, []byte("XMP ")) {
+		t.Error("metadata chunks still present")
+	}
+	if size := binary.LittleEndian.Uint32(stripped[4:]); int(size) != len(stripped)-8 {
+		t.Errorf("RIFF size = %d, want %d", size, len(stripped)-8)
+	}
+	if flags := stripped[20]; flags != 0x10 {
+		t.Errorf("VP8X flags = %#x, want 0x10", flags)
+	}
+	if !bytes.Contains(stripped, []byte("VP8L")) {
+		t.Error("image data chunk was removed")
+	}
+}
+
+func TestStripImageMetadata_NonImages(t *testing.T) {
+	text := []byte("just some text")
+	if got, err := StripImageMetadata(text); err != nil || !bytes.Equal(got, text) {
+		t.Errorf("StripImageMetadata(text) = %q, %v; want unchanged", got, err)
+	}
+	ico := []byte{0, 0, 1, 0, 1, 0, 16, 16}
+	if _, err := StripImageMetadata(ico); !errors.Is(err, ErrUnsupportedImage) {
+		t.Errorf("StripImageMetadata(ico) error = %v, want ErrUnsupportedImage", err)
+	}
+}
+
+func TestStripImageFileMetadata(t *testing.T) {
+	dir := t.TempDir()
+	photo := filepath.Join(dir, "photo.jpg")
+	os.WriteFile(photo, withJPEGExif(t), 0644)
+	notes := filepath.Join(dir, "notes.txt")
+	os.WriteFile(notes, []byte("hello"), 0644)
+
+	clean, err := StripImageFileMetadata(photo)
+	if err != nil {
+		t.Fatalf("StripImageFileMetadata() error: %v", err)
+	}
+	defer os.Remove(clean)
+	if clean == photo || filepath.Ext(clean) != ".jpg" {
+		t.Errorf("clean path = %q, want a new .jpg file", clean)
+	}
+	if data, _ := os.ReadFile(clean); bytes.Contains(data, []byte("GPS")) {
+		t.Error("clean copy still has metadata")
+	}
+	if original, _ := os.ReadFile(photo); !bytes.Contains(original, []byte("GPS")) {
+		t.Error("original file was modified")
+	}
+
+	if got, err := StripImageFileMetadata(notes); err != nil || got != notes {
+		t.Errorf("StripImageFileMetadata(text) = %q, %v; want the original path", got, err)
+	}
+}
@@ -1,16 +1,19 @@
 package utils
 
-// Truncate returns a truncated version of s with at most maxLen runes.
-// Handles multi-byte Unicode characters properly.
-// If the string is truncated, "..." is appended to indicate truncation.
+// Truncate shortens s to at most maxLen runes, ending in "..." when anything
+// was cut. It never splits a multi-byte character. When maxLen leaves no
+// room for text after the ellipsis (maxLen <= 3), only dots are returned.
 func Truncate(s string, maxLen int) string {
+	if maxLen <= 0 {
+		return ""
+	}
 	runes := []rune(s)
 	if len(runes) <= maxLen {
 		return s
 	}
-	// Reserve 3 chars for "..."
-	if maxLen <= 3 {
-		return string(runes[:maxLen])
+	const ellipsis = "..."
+	if maxLen <= len(ellipsis) {
+		return ellipsis[:maxLen]
 	}
-	return string(runes[:maxLen-3]) + "..."
+	return string(runes[:maxLen-len(ellipsis)]) + ellipsis
 }
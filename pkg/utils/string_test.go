@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name   string
+		s      string
+		maxLen int
+		want   string
+	}{
+		{"empty", "", 5, ""},
+		{"short", "hi", 5, "hi"},
+		{"exact length", "hello", 5, "hello"},
+		{"one over", "hello!", 5, "he..."},
+		{"multibyte kept whole", "你好世界和平", 5, "你好..."},
+		{"multibyte exact", "你好世界", 4, "你好世界"},
+		{"emoji", "😀😀😀😀😀😀", 4, "😀..."},
+		{"no room for text", "hello", 2, ".."},
+		{"zero", "hello", 0, ""},
+		{"negative", "hello", -1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Truncate(tt.s, tt.maxLen)
+			if got != tt.want {
+				t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.maxLen, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("Truncate(%q, %d) = %q is not valid UTF-8", tt.s, tt.maxLen, got)
+			}
+			if tt.maxLen >= 0 && utf8.RuneCountInString(got) > tt.maxLen {
+				t.Errorf("Truncate(%q, %d) = %q is longer than %d runes", tt.s, tt.maxLen, got, tt.maxLen)
+			}
+		})
+	}
+}
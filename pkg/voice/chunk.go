@@ -0,0 +1,213 @@
+package voice
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ChunkOptions configures chunked transcription of long recordings.
+type ChunkOptions struct {
+	Enabled       bool
+	ChunkDuration time.Duration // Length of each chunk when splitting with ffmpeg
+	MaxFileBytes  int64         // Files above this size are split; also the per-chunk size limit
+}
+
+const (
+	defaultChunkDuration = 10 * time.Minute
+	defaultMaxFileBytes  = 25 * 1024 * 1024 // Groq's upload limit
+)
+
+// byteSplittableExts lists formats made of self-synchronizing frames, which
+// decoders accept even when the file is cut at an arbitrary byte.
+var byteSplittableExts = map[string]bool{
+	".mp3": true,
+	".aac": true,
+}
+
+// splitAudio cuts audioPath into chunks inside dir and returns their paths in
+// order. ffmpeg is used when installed; otherwise frame-based formats are cut
+// by size.
+func splitAudio(ctx context.Context, audioPath, dir string, opts ChunkOptions) ([]string, error) {
+	if ffmpeg, err := exec.LookPath("ffmpeg"); err == nil {
+		return splitWithFFmpeg(ctx, ffmpeg, audioPath, dir, opts.ChunkDuration, opts.MaxFileBytes)
+	}
+
+	ext := strings.ToLower(filepath.Ext(audioPath))
+	if !byteSplittableExts[ext] {
+		return nil, fmt.Errorf("ffmpeg not found and %s files cannot be split by size", ext)
+	}
+	return splitBySize(audioPath, dir, opts.MaxFileBytes)
+}
+
+// maxSplitAttempts bounds how often splitWithFFmpeg re-splits with a shorter
+// segment length when a chunk comes out larger than the upload limit.
+const maxSplitAttempts = 4
+
+// splitWithFFmpeg cuts audioPath into segments of the given duration. Since
+// the size of a segment depends on the bitrate, chunks larger than maxBytes
+// cause a re-split with a segment length scaled down to fit.
+func splitWithFFmpeg(ctx context.Context, ffmpeg, audioPath, dir string, chunk time.Duration, maxBytes int64) ([]string, error) {
+	segment := chunk
+	for attempt := 1; ; attempt++ {
+		chunks, err := runFFmpegSegment(ctx, ffmpeg, audioPath, dir, segment)
+		if err != nil {
+			return nil, err
+		}
+
+		largest, err := largestFile(chunks)
+		if err != nil {
+			return nil, err
+		}
+		if maxBytes <= 0 || largest <= maxBytes {
+			return chunks, nil
+		}
+
+		next := shorterSegment(segment, largest, maxBytes)
+		if attempt >= maxSplitAttempts || next >= segment {
+			return nil, fmt.Errorf("chunk of %d bytes exceeds the %d byte limit even with %v segments", largest, maxBytes, segment)
+		}
+		for _, c := range chunks {
+			os.Remove(c)
+		}
+		segment = next
+	}
+}
+
+func runFFmpegSegment(ctx context.Context, ffmpeg, audioPath, dir string, segment time.Duration) ([]string, error) {
+	ext := filepath.Ext(audioPath)
+	pattern := filepath.Join(dir, "chunk_%03d"+ext)
+
+	cmd := exec.CommandContext(ctx, ffmpeg,
+		"-hide_banner", "-loglevel", "error",
+		"-i", audioPath,
+		"-f", "segment",
+		"-segment_time", strconv.Itoa(int(segment.Seconds())),
+		"-c", "copy",
+		pattern,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("ffmpeg split failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	chunks, err := filepath.Glob(filepath.Join(dir, "chunk_*"+ext))
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("ffmpeg produced no chunks")
+	}
+	sort.Strings(chunks)
+	return chunks, nil
+}
+
+// shorterSegment scales segment so that a chunk of largest bytes would fit in
+// maxBytes, with a 10% margin for bitrate variation. It never returns less
+// than one second.
+func shorterSegment(segment time.Duration, largest, maxBytes int64) time.Duration {
+	scaled := time.Duration(float64(segment) * float64(maxBytes) / float64(largest) * 0.9).Truncate(time.Second)
+	if scaled < time.Second {
+		scaled = time.Second
+	}
+	return scaled
+}
+
+// largestFile returns the size of the biggest file in paths.
+func largestFile(paths []string) (int64, error) {
+	var largest int64
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return 0, err
+		}
+		if info.Size() > largest {
+			largest = info.Size()
+		}
+	}
+	return largest, nil
+}
+
+func splitBySize(audioPath, dir string, chunkBytes int64) ([]string, error) {
+	src, err := os.Open(audioPath)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	ext := filepath.Ext(audioPath)
+	var chunks []string
+	for i := 0; ; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("chunk_%03d%s", i, ext))
+		dst, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		n, err := io.CopyN(dst, src, chunkBytes)
+		dst.Close()
+		if n > 0 {
+			chunks = append(chunks, path)
+		} else {
+			os.Remove(path)
+		}
+		if err == io.EOF {
+			return chunks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// maxBoundaryOverlap bounds how many words are compared at a chunk boundary.
+const maxBoundaryOverlap = 8
+
+// mergeTranscripts joins chunk transcripts, dropping words at the start of a
+// chunk that repeat the end of the previous one (Whisper often re-transcribes
+// a word cut at the boundary).
+func mergeTranscripts(parts []string) string {
+	var words []string
+	for _, part := range parts {
+		next := strings.Fields(part)
+		words = append(words, next[boundaryOverlap(words, next):]...)
+	}
+	return strings.Join(words, " ")
+}
+
+// boundaryOverlap returns the length of the longest suffix of prev that equals
+// a prefix of next, ignoring case and punctuation.
+func boundaryOverlap(prev, next []string) int {
+	limit := maxBoundaryOverlap
+	if len(prev) < limit {
+		limit = len(prev)
+	}
+	if len(next) < limit {
+		limit = len(next)
+	}
+	for k := limit; k > 0; k-- {
+		match := true
+		for i := 0; i < k; i++ {
+			if normalizeWord(prev[len(prev)-k+i]) != normalizeWord(next[i]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return k
+		}
+	}
+	return 0
+}
+
+func normalizeWord(w string) string {
+	return strings.ToLower(strings.TrimFunc(w, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}))
+}
@@ -0,0 +1,147 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeTranscripts(t *testing.T) {
+	tests := []struct {
+		name  string
+		parts []string
+		want  string
+	}{
+		{"single", []string{"hello world"}, "hello world"},
+		{"no overlap", []string{"the quick brown", "fox jumps"}, "the quick brown fox jumps"},
+		{"repeated word", []string{"we went to the", "the market today"}, "we went to the market today"},
+		{"repeated phrase ignores case and punctuation", []string{"see you at the station.", "At the station, we met"}, "see you at the station. we met"},
+		{"empty chunk", []string{"first part", "", "second part"}, "first part second part"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeTranscripts(tt.parts); got != tt.want {
+				t.Errorf("mergeTranscripts() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitBySize(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "voice.mp3")
+	data := bytes.Repeat([]byte("0123456789"), 25) // 250 bytes
+	if err := os.WriteFile(src, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "chunks")
+	if err := os.Mkdir(out, 0755); err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := splitBySize(src, out, 100)
+	if err != nil {
+		t.Fatalf("splitBySize() error: %v", err)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(chunks))
+	}
+
+	var joined []byte
+	for _, c := range chunks {
+		b, err := os.ReadFile(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		joined = append(joined, b...)
+	}
+	if !bytes.Equal(joined, data) {
+		t.Error("chunks do not reassemble to the original file")
+	}
+}
+
+func TestShorterSegment(t *testing.T) {
+	tests := []struct {
+		name         string
+		segment      time.Duration
+		largest, max int64
+		want         time.Duration
+	}{
+		{"half too big", 600 * time.Second, 50, 25, 270 * time.Second},
+		{"slightly too big", 600 * time.Second, 26, 25, 519 * time.Second},
+		{"floor of one second", 2 * time.Second, 1000, 1, time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shorterSegment(tt.segment, tt.largest, tt.max); got != tt.want {
+				t.Errorf("shorterSegment() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// writeFakeFFmpeg installs a script that writes two chunks of
+// segment_time*1000 bytes, mimicking a constant-bitrate segment split.
+func writeFakeFFmpeg(t *testing.T) (string, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg is a shell script")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls.log")
+	script := `#!/bin/sh
+seg=""; prev=""; last=""
+for a in "$@"; do
+  if [ "$prev" = "-segment_time" ]; then seg="$a"; fi
+  prev="$a"; last="$a"
+done
+echo "$seg" >> "` + log + `"
+for i in 000 001; do
+  head -c $((seg * 1000)) /dev/zero > "$(echo "$last" | sed "s/%03d/$i/")"
+done
+`
+	path := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path, log
+}
+
+func TestSplitWithFFmpeg_ResplitsOversizedChunks(t *testing.T) {
+	ffmpeg, log := writeFakeFFmpeg(t)
+	out := t.TempDir()
+
+	chunks, err := splitWithFFmpeg(context.Background(), ffmpeg, "voice.wav", out, 600*time.Second, 300000)
+	if err != nil {
+		t.Fatalf("splitWithFFmpeg() error: %v", err)
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2", len(chunks))
+	}
+	for _, c := range chunks {
+		if info, _ := os.Stat(c); info.Size() > 300000 {
+			t.Errorf("chunk %s is %d bytes, over the limit", c, info.Size())
+		}
+	}
+
+	calls, _ := os.ReadFile(log)
+	if got := strings.Fields(string(calls)); len(got) != 2 || got[0] != "600" || got[1] != "270" {
+		t.Errorf("segment_time per call = %v, want [600 270]", got)
+	}
+}
+
+func TestSplitWithFFmpeg_GivesUpWhenChunksNeverFit(t *testing.T) {
+	ffmpeg, _ := writeFakeFFmpeg(t)
+
+	// Even one-second chunks are 1000 bytes
+	if _, err := splitWithFFmpeg(context.Background(), ffmpeg, "voice.wav", t.TempDir(), 600*time.Second, 10); err == nil {
+		t.Error("Expected an error when chunks cannot be made small enough")
+	}
+}
@@ -17,9 +17,12 @@ import (
 )
 
 type GroqTranscriber struct {
-	apiKey     string
-	apiBase    string
-	httpClient *http.Client
+	apiKey       string
+	apiBase      string
+	httpClient   *http.Client
+	chunking     ChunkOptions
+	maxAttempts  int
+	retryBackoff time.Duration // Delay before the first retry, doubled each time
 }
 
 type TranscriptionResponse struct {
@@ -36,14 +39,78 @@ func NewGroqTranscriber(apiKey string) *GroqTranscriber {
 		apiKey:  apiKey,
 		apiBase: apiBase,
 		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
+			Timeout:   60 * time.Second,
+			Transport: utils.EgressTransport(nil),
 		},
+		maxAttempts:  3,
+		retryBackoff: time.Second,
 	}
 }
 
+// SetChunking enables splitting recordings larger than the upload limit into
+// chunks that are transcribed separately and joined.
+func (t *GroqTranscriber) SetChunking(opts ChunkOptions) {
+	if opts.ChunkDuration <= 0 {
+		opts.ChunkDuration = defaultChunkDuration
+	}
+	if opts.MaxFileBytes <= 0 {
+		opts.MaxFileBytes = defaultMaxFileBytes
+	}
+	t.chunking = opts
+}
+
 func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string) (*TranscriptionResponse, error) {
 	logger.InfoCF("voice", "Starting transcription", map[string]interface{}{"audio_file": audioFilePath})
 
+	if t.chunking.Enabled {
+		if info, err := os.Stat(audioFilePath); err == nil && info.Size() > t.chunking.MaxFileBytes {
+			return t.transcribeChunked(ctx, audioFilePath, info.Size())
+		}
+	}
+
+	return t.transcribeFile(ctx, audioFilePath)
+}
+
+// transcribeChunked splits a large recording, transcribes each chunk in order
+// and merges the text.
+func (t *GroqTranscriber) transcribeChunked(ctx context.Context, audioFilePath string, size int64) (*TranscriptionResponse, error) {
+	dir, err := os.MkdirTemp("", "picoclaw-voice-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chunk dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	chunks, err := splitAudio(ctx, audioFilePath, dir, t.chunking)
+	if err != nil {
+		logger.ErrorCF("voice", "Failed to split audio", map[string]interface{}{"path": audioFilePath, "error": err})
+		return nil, fmt.Errorf("failed to split audio: %w", err)
+	}
+
+	logger.InfoCF("voice", "Transcribing audio in chunks", map[string]interface{}{
+		"size_bytes": size,
+		"chunks":     len(chunks),
+	})
+
+	result := &TranscriptionResponse{}
+	texts := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		part, err := t.transcribeFile(ctx, chunk)
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), err)
+		}
+		texts = append(texts, part.Text)
+		result.Duration += part.Duration
+		if result.Language == "" {
+			result.Language = part.Language
+		}
+	}
+	result.Text = mergeTranscripts(texts)
+
+	return result, nil
+}
+
+// transcribeFile uploads a single file in one request.
+func (t *GroqTranscriber) transcribeFile(ctx context.Context, audioFilePath string) (*TranscriptionResponse, error) {
 	audioFile, err := os.Open(audioFilePath)
 	if err != nil {
 		logger.ErrorCF("voice", "Failed to open audio file", map[string]interface{}{"path": audioFilePath, "error": err})
@@ -95,14 +162,6 @@ func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string)
 	}
 
 	url := t.apiBase + "/audio/transcriptions"
-	req, err := http.NewRequestWithContext(ctx, "POST", url, &requestBody)
-	if err != nil {
-		logger.ErrorCF("voice", "Failed to create request", map[string]interface{}{"error": err})
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("Authorization", "Bearer "+t.apiKey)
 
 	logger.DebugCF("voice", "Sending transcription request to Groq API", map[string]interface{}{
 		"url":                url,
@@ -110,32 +169,11 @@ func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string)
 		"file_size_bytes":    fileInfo.Size(),
 	})
 
-	resp, err := t.httpClient.Do(req)
-	if err != nil {
-		logger.ErrorCF("voice", "Failed to send request", map[string]interface{}{"error": err})
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := t.postWithRetry(ctx, url, writer.FormDataContentType(), requestBody.Bytes())
 	if err != nil {
-		logger.ErrorCF("voice", "Failed to read response", map[string]interface{}{"error": err})
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		logger.ErrorCF("voice", "API error", map[string]interface{}{
-			"status_code": resp.StatusCode,
-			"response":    string(body),
-		})
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, err
 	}
 
-	logger.DebugCF("voice", "Received response from Groq API", map[string]interface{}{
-		"status_code":         resp.StatusCode,
-		"response_size_bytes": len(body),
-	})
-
 	var result TranscriptionResponse
 	if err := json.Unmarshal(body, &result); err != nil {
 		logger.ErrorCF("voice", "Failed to unmarshal response", map[string]interface{}{"error": err})
@@ -152,6 +190,72 @@ func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string)
 	return &result, nil
 }
 
+// postWithRetry sends the upload, retrying network errors, rate limits and
+// server errors with exponential backoff.
+func (t *GroqTranscriber) postWithRetry(ctx context.Context, url, contentType string, payload []byte) ([]byte, error) {
+	delay := t.retryBackoff
+	var lastErr error
+
+	for attempt := 1; attempt <= t.maxAttempts; attempt++ {
+		if attempt > 1 {
+			logger.WarnCF("voice", "Retrying transcription request", map[string]interface{}{
+				"attempt": attempt,
+				"delay":   delay.String(),
+				"error":   lastErr.Error(),
+			})
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+		if err != nil {
+			logger.ErrorCF("voice", "Failed to create request", map[string]interface{}{"error": err})
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+
+		resp, err := t.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = fmt.Errorf("failed to send request: %w", err)
+			continue
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("failed to read response: %w", err)
+			continue
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			logger.DebugCF("voice", "Received response from Groq API", map[string]interface{}{
+				"status_code":         resp.StatusCode,
+				"response_size_bytes": len(body),
+			})
+			return body, nil
+		}
+
+		logger.ErrorCF("voice", "API error", map[string]interface{}{
+			"status_code": resp.StatusCode,
+			"response":    string(body),
+		})
+		lastErr = fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return nil, lastErr
+		}
+	}
+
+	return nil, lastErr
+}
+
 func (t *GroqTranscriber) IsAvailable() bool {
 	available := t.apiKey != ""
 	logger.DebugCF("voice", "Checking transcriber availability", map[string]interface{}{"available": available})
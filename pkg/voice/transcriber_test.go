@@ -0,0 +1,65 @@
+package voice
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTranscribeRetriesServerErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"text":"hello"}`))
+	}))
+	defer server.Close()
+
+	audio := filepath.Join(t.TempDir(), "voice.ogg")
+	if err := os.WriteFile(audio, []byte("OggS"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := NewGroqTranscriber("key")
+	tr.apiBase = server.URL
+	tr.retryBackoff = time.Millisecond
+
+	result, err := tr.Transcribe(t.Context(), audio)
+	if err != nil {
+		t.Fatalf("Transcribe() error: %v", err)
+	}
+	if result.Text != "hello" || calls != 3 {
+		t.Errorf("Text = %q after %d calls, want \"hello\" after 3", result.Text, calls)
+	}
+}
+
+func TestTranscribeDoesNotRetryClientErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	}))
+	defer server.Close()
+
+	audio := filepath.Join(t.TempDir(), "voice.ogg")
+	if err := os.WriteFile(audio, []byte("OggS"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := NewGroqTranscriber("key")
+	tr.apiBase = server.URL
+	tr.retryBackoff = time.Millisecond
+
+	if _, err := tr.Transcribe(t.Context(), audio); err == nil {
+		t.Fatal("Transcribe() succeeded, want error")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}